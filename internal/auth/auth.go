@@ -0,0 +1,66 @@
+// Package auth carries the user a request is made by through its context, and decides what
+// they may do with the resources they ask for.
+package auth
+
+import (
+	"context"
+
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// Actor is the authenticated user making a request
+type Actor struct {
+	UserID string
+	Role   models.UserRole
+}
+
+func (a Actor) IsAdmin() bool {
+	return a.Role == models.RoleAdmin
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying the actor
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor ctx carries. Contexts without one get pkg.ErrUnauthorized.
+func ActorFrom(ctx context.Context) (Actor, error) {
+	actor, ok := ctx.Value(actorKey{}).(Actor)
+	if !ok || actor.UserID == "" {
+		return Actor{}, pkg.ErrUnauthorized
+	}
+	return actor, nil
+}
+
+// Action is what an actor wants to do with a resource
+type Action int
+
+const (
+	// View reads a resource. Customers view their own resources and admins view every resource.
+	View Action = iota
+	// Manage acts on a resource on its owner's behalf, like paying for an order or editing an
+	// address. Only the owner manages a resource; admins have their own endpoints for changes.
+	Manage
+)
+
+// Authorize checks that the actor of ctx may perform the action on a resource owned by the
+// user ownerID. Customers get pkg.ErrNotFound for other users' resources, the same as for
+// IDs that don't exist, while admins, who view every resource, get pkg.ErrUnauthorized.
+func Authorize(ctx context.Context, action Action, ownerID string) error {
+	actor, err := ActorFrom(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case actor.UserID == ownerID || (action == View && actor.IsAdmin()):
+		return nil
+	case actor.IsAdmin():
+		return pkg.ErrUnauthorized
+	default:
+		return pkg.ErrNotFound
+	}
+}
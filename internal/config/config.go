@@ -9,18 +9,44 @@ import (
 
 // Config represents the application configuration structure
 type Config struct {
-	DSN          string
-	Port         string
-	JWTSecretKey string
-	Environment  string
+	DSN                  string
+	Port                 string
+	JWTSecretKey         string
+	Environment          string
+	FulfilmentStrategy   string
+	TaxMode              string
+	TaxDefaultRegion     string
+	PaymentProvider      string
+	PaymentWebhookSecret string
+	EventPublisher       string
+	PendingOrderTTL      string
+	Mailer               string
+	MailFrom             string
+	MailDir              string
+	SMTPAddr             string
+	SMTPUsername         string
+	SMTPPassword         string
 }
 
 func Load() (*Config, error) {
 	config := &Config{
-		DSN:          os.Getenv("DSN"),
-		Port:         os.Getenv("PORT"),
-		JWTSecretKey: os.Getenv("JWY_SECRET_KEY"),
-		Environment:  os.Getenv("ENVIRONMENT"),
+		DSN:                  os.Getenv("DSN"),
+		Port:                 os.Getenv("PORT"),
+		JWTSecretKey:         os.Getenv("JWY_SECRET_KEY"),
+		Environment:          os.Getenv("ENVIRONMENT"),
+		FulfilmentStrategy:   os.Getenv("FULFILMENT_STRATEGY"),
+		TaxMode:              os.Getenv("TAX_MODE"),
+		TaxDefaultRegion:     os.Getenv("TAX_DEFAULT_REGION"),
+		PaymentProvider:      os.Getenv("PAYMENT_PROVIDER"),
+		PaymentWebhookSecret: os.Getenv("PAYMENT_WEBHOOK_SECRET"),
+		EventPublisher:       os.Getenv("EVENT_PUBLISHER"),
+		PendingOrderTTL:      os.Getenv("PENDING_ORDER_TTL"),
+		Mailer:               os.Getenv("MAILER"),
+		MailFrom:             os.Getenv("MAIL_FROM"),
+		MailDir:              os.Getenv("MAIL_DIR"),
+		SMTPAddr:             os.Getenv("SMTP_ADDR"),
+		SMTPUsername:         os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:         os.Getenv("SMTP_PASSWORD"),
 	}
 
 	if err := config.validate(); err != nil {
@@ -20,8 +20,18 @@ import (
 	"github.com/zde37/instashop-task/internal/config"
 	"github.com/zde37/instashop-task/internal/controller/handler"
 	"github.com/zde37/instashop-task/internal/controller/routes"
+	"github.com/zde37/instashop-task/internal/inventory"
+	"github.com/zde37/instashop-task/internal/jobs"
+	"github.com/zde37/instashop-task/internal/mail"
+	"github.com/zde37/instashop-task/internal/notifier"
+	"github.com/zde37/instashop-task/internal/outbox"
+	"github.com/zde37/instashop-task/internal/payment"
 	"github.com/zde37/instashop-task/internal/repository"
 	"github.com/zde37/instashop-task/internal/service"
+	"github.com/zde37/instashop-task/internal/shipping"
+	"github.com/zde37/instashop-task/internal/stream"
+	"github.com/zde37/instashop-task/internal/tax"
+	"github.com/zde37/instashop-task/internal/webhook"
 	"github.com/zde37/instashop-task/pkg"
 )
 
@@ -32,6 +42,9 @@ type Controller struct {
 	db         *pgxpool.Pool
 	handler    handler.Handler
 	httpServer *http.Server
+	jobs       *jobs.Runner
+	updates    *stream.Broker
+	listener   *stream.Listener
 }
 
 // New creates a new instance of Controller
@@ -63,11 +76,90 @@ func (c *Controller) bootstrap() error {
 		return fmt.Errorf("failed to initialize jwt maker: %v", err)
 	}
 
+	// initialize fulfilment strategy
+	fulfilment, err := inventory.NewStrategy(c.config.FulfilmentStrategy)
+	if err != nil {
+		return fmt.Errorf("failed to initialize fulfilment strategy: %v", err)
+	}
+
+	taxMode, err := tax.ParseMode(c.config.TaxMode)
+	if err != nil {
+		return fmt.Errorf("failed to initialize tax calculator: %v", err)
+	}
+
+	payments, err := payment.NewProvider(c.config.PaymentProvider)
+	if err != nil {
+		return fmt.Errorf("failed to initialize payment provider: %v", err)
+	}
+
+	events, err := outbox.NewPublisher(c.config.EventPublisher)
+	if err != nil {
+		return fmt.Errorf("failed to initialize event publisher: %v", err)
+	}
+
+	mailFrom := c.config.MailFrom
+	if mailFrom == "" {
+		mailFrom = "Instashop <no-reply@instashop.local>"
+	}
+	mailer, err := mail.NewMailer(mail.Config{
+		Mailer:       c.config.Mailer,
+		From:         mailFrom,
+		Dir:          c.config.MailDir,
+		SMTPAddr:     c.config.SMTPAddr,
+		SMTPUsername: c.config.SMTPUsername,
+		SMTPPassword: c.config.SMTPPassword,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize mailer: %v", err)
+	}
+
+	pendingOrderTTL := 24 * time.Hour
+	if c.config.PendingOrderTTL != "" {
+		pendingOrderTTL, err = time.ParseDuration(c.config.PendingOrderTTL)
+		if err != nil || pendingOrderTTL <= 0 {
+			return fmt.Errorf("invalid pending order ttl %q", c.config.PendingOrderTTL)
+		}
+	}
+
 	// initialize repository, service, and handlers
 	repo := repository.New(c.db)
-	srvc := service.New(repo, jwtMaker)
+	taxCalculator := tax.NewRateTable(repo, taxMode, c.config.TaxDefaultRegion)
+	carriers := shipping.NewCarriers(shipping.NewLocalCarrier())
+	c.updates = stream.NewBroker()
+	c.listener = stream.NewListener(c.db, c.updates)
+	srvc := service.New(repo, jwtMaker, fulfilment, notifier.NewLogNotifier(nil), taxCalculator, carriers, payments,
+		payment.NewVerifier(c.config.PaymentWebhookSecret), events, webhook.NewHTTPSender(10*time.Second), c.updates, mailer)
 	c.handler = handler.New(srvc)
 
+	// initialize background jobs
+	c.jobs = jobs.NewRunner(repo, 8,
+		jobs.Definition{Kind: jobs.KindCleanupSessions, Handler: jobs.NoPayload(srvc.CleanupSessions)},
+		jobs.Definition{Kind: jobs.KindPruneJobs, Handler: jobs.NoPayload(srvc.PruneJobs)},
+		jobs.Definition{Kind: jobs.KindExpireOrders, Handler: jobs.NoPayload(func(ctx context.Context) error {
+			return srvc.ExpirePendingOrders(ctx, pendingOrderTTL)
+		})},
+		jobs.Definition{Kind: jobs.KindSendEmail, Handler: jobs.WithPayload(srvc.SendEmail), Concurrency: 4, Timeout: time.Minute},
+		jobs.Definition{Kind: jobs.KindReconcilePayments, Handler: jobs.NoPayload(srvc.ReconcilePayments)},
+		jobs.Definition{Kind: jobs.KindSendRefund, Handler: jobs.WithPayload(srvc.SendRefund), Concurrency: 4, Timeout: time.Minute},
+		jobs.Definition{Kind: jobs.KindProductSchedules, Handler: jobs.NoPayload(srvc.ApplyProductSchedules)},
+		jobs.Definition{Kind: jobs.KindProductPrices, Handler: jobs.NoPayload(srvc.SyncProductPrices)},
+		jobs.Definition{Kind: jobs.KindRelayOutbox, Handler: jobs.NoPayload(srvc.RelayOutboxEvents)},
+		jobs.Definition{Kind: jobs.KindDeliverWebhooks, Handler: jobs.NoPayload(srvc.DeliverWebhooks)},
+	)
+	err = c.jobs.Schedule(
+		jobs.Schedule{Kind: jobs.KindCleanupSessions, Cron: "@hourly"},
+		jobs.Schedule{Kind: jobs.KindPruneJobs, Cron: "@hourly"},
+		jobs.Schedule{Kind: jobs.KindExpireOrders, Cron: "*/5 * * * *"},
+		jobs.Schedule{Kind: jobs.KindReconcilePayments, Cron: "*/5 * * * *"},
+		jobs.Schedule{Kind: jobs.KindProductSchedules, Cron: "* * * * *"},
+		jobs.Schedule{Kind: jobs.KindProductPrices, Cron: "* * * * *"},
+		jobs.Schedule{Kind: jobs.KindRelayOutbox, Every: time.Second},
+		jobs.Schedule{Kind: jobs.KindDeliverWebhooks, Every: 5 * time.Second},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to schedule jobs: %v", err)
+	}
+
 	if c.config.Environment == pkg.Production {
 		gin.SetMode(gin.ReleaseMode)
 	}
@@ -97,6 +189,8 @@ func (c *Controller) configureHTTPServer() {
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 	}
+	// open event streams would keep the server from shutting down
+	c.httpServer.RegisterOnShutdown(c.updates.Close)
 }
 
 // Serve starts the Controller server
@@ -105,6 +199,10 @@ func (c *Controller) Serve() error {
 		return err
 	}
 
+	// start background jobs and order status updates
+	c.jobs.Start()
+	c.listener.Start()
+
 	// start the server
 	go func() {
 		slog.Info("starting server...", slog.String("Port", c.config.Port))
@@ -133,6 +231,8 @@ func (c *Controller) gracefulShutdown() error {
 		return fmt.Errorf("error shutting down server: %v", err)
 	}
 
+	c.jobs.Stop()
+	c.listener.Stop()
 	c.db.Close()
 	slog.Info("services stopped gracefully")
 	return nil
@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// CreateAddress
+// @Summary      Add an address
+// @Description  Add an address to the user's address book. The first address becomes the default one.
+// @Tags         addresses
+// @Accept       json
+// @Produce      json
+// @Param        request body models.CreateAddressRequest true "Address details"
+// @Success      201 {object} models.Address
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /addresses [post]
+func (h *handlerImpl) CreateAddress(c *gin.Context) {
+	var req models.CreateAddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "create_address_validation")
+		return
+	}
+
+	address, err := h.service.CreateAddress(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, err, "create_address")
+		return
+	}
+
+	c.JSON(http.StatusCreated, address)
+}
+
+// ListAddresses
+// @Summary      List addresses
+// @Description  Get the user's address book, default address first
+// @Tags         addresses
+// @Accept       json
+// @Produce      json
+// @Success      200 {array} models.Address
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /addresses [get]
+func (h *handlerImpl) ListAddresses(c *gin.Context) {
+	addresses, err := h.service.ListAddresses(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err, "list_addresses")
+		return
+	}
+
+	c.JSON(http.StatusOK, addresses)
+}
+
+// GetAddress
+// @Summary      Get address by ID
+// @Description  Get an address from the user's address book. Admins can get any address.
+// @Tags         addresses
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Address ID"
+// @Success      200 {object} models.Address
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /addresses/{id} [get]
+func (h *handlerImpl) GetAddress(c *gin.Context) {
+	id := c.Param("id")
+
+	address, err := h.service.GetAddress(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err, "get_address")
+		return
+	}
+
+	c.JSON(http.StatusOK, address)
+}
+
+// UpdateAddress
+// @Summary      Update address
+// @Description  Replace an address in the user's address book. Orders placed with it keep their copy of the old address.
+// @Tags         addresses
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Address ID"
+// @Param        request body models.CreateAddressRequest true "Address details"
+// @Success      200 {object} models.Address
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse "Admins can't manage another user's address"
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /addresses/{id} [put]
+func (h *handlerImpl) UpdateAddress(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.CreateAddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "update_address_validation")
+		return
+	}
+
+	address, err := h.service.UpdateAddress(c.Request.Context(), id, &req)
+	if err != nil {
+		h.handleError(c, err, "update_address")
+		return
+	}
+
+	c.JSON(http.StatusOK, address)
+}
+
+// SetDefaultAddress
+// @Summary      Set default address
+// @Description  Make an address the one orders ship to when they don't name one
+// @Tags         addresses
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Address ID"
+// @Success      200 {object} models.Address
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse "Admins can't manage another user's address"
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /addresses/{id}/default [post]
+func (h *handlerImpl) SetDefaultAddress(c *gin.Context) {
+	id := c.Param("id")
+
+	address, err := h.service.SetDefaultAddress(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err, "set_default_address")
+		return
+	}
+
+	c.JSON(http.StatusOK, address)
+}
+
+// DeleteAddress
+// @Summary      Delete address
+// @Description  Remove an address from the user's address book
+// @Tags         addresses
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Address ID"
+// @Success      204 "No Content"
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse "Admins can't manage another user's address"
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /addresses/{id} [delete]
+func (h *handlerImpl) DeleteAddress(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.service.DeleteAddress(c.Request.Context(), id); err != nil {
+		h.handleError(c, err, "delete_address")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// CreateCoupon
+// @Summary      Create a new coupon
+// @Description  Create a new coupon (admin only). Codes are case insensitive.
+// @Tags         coupons
+// @Accept       json
+// @Produce      json
+// @Param        request body models.CreateCouponRequest true "Coupon details"
+// @Success      201 {object} models.Coupon
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /coupons [post]
+func (h *handlerImpl) CreateCoupon(c *gin.Context) {
+	var req models.CreateCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "create_coupon_validation")
+		return
+	}
+
+	coupon, err := h.service.CreateCoupon(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, err, "create_coupon")
+		return
+	}
+
+	c.JSON(http.StatusCreated, coupon)
+}
+
+// ListCoupons
+// @Summary      List coupons
+// @Description  Get all coupons, newest first (admin only)
+// @Tags         coupons
+// @Accept       json
+// @Produce      json
+// @Success      200 {array} models.Coupon
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /coupons [get]
+func (h *handlerImpl) ListCoupons(c *gin.Context) {
+	coupons, err := h.service.ListCoupons(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err, "list_coupons")
+		return
+	}
+
+	c.JSON(http.StatusOK, coupons)
+}
+
+// GetCoupon
+// @Summary      Get coupon by ID
+// @Description  Get a coupon (admin only)
+// @Tags         coupons
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Coupon ID"
+// @Success      200 {object} models.Coupon
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /coupons/{id} [get]
+func (h *handlerImpl) GetCoupon(c *gin.Context) {
+	id := c.Param("id")
+
+	coupon, err := h.service.GetCouponByID(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err, "get_coupon")
+		return
+	}
+
+	c.JSON(http.StatusOK, coupon)
+}
+
+// UpdateCoupon
+// @Summary      Update coupon
+// @Description  Replace a coupon's details (admin only)
+// @Tags         coupons
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Coupon ID"
+// @Param        request body models.CreateCouponRequest true "Coupon details"
+// @Success      200 {object} models.Coupon
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /coupons/{id} [put]
+func (h *handlerImpl) UpdateCoupon(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.CreateCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "update_coupon_validation")
+		return
+	}
+
+	coupon, err := h.service.UpdateCoupon(c.Request.Context(), id, &req)
+	if err != nil {
+		h.handleError(c, err, "update_coupon")
+		return
+	}
+
+	c.JSON(http.StatusOK, coupon)
+}
+
+// DeleteCoupon
+// @Summary      Delete coupon
+// @Description  Delete a coupon that has never been redeemed (admin only). Deactivate redeemed coupons instead.
+// @Tags         coupons
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Coupon ID"
+// @Success      204 "No Content"
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /coupons/{id} [delete]
+func (h *handlerImpl) DeleteCoupon(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.service.DeleteCoupon(c.Request.Context(), id); err != nil {
+		h.handleError(c, err, "delete_coupon")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// versionETag returns the strong entity tag of a resource version
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ifMatchVersion returns the resource version required by the request's If-Match header,
+// or 0 if the header is absent or "*". Only a single strong entity tag produced by
+// versionETag can match, anything else fails the precondition.
+func ifMatchVersion(c *gin.Context) (int, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	tag, ok := strings.CutPrefix(header, `"`)
+	if !ok {
+		return 0, pkg.ErrVersionMismatch
+	}
+	tag, ok = strings.CutSuffix(tag, `"`)
+	if !ok {
+		return 0, pkg.ErrVersionMismatch
+	}
+
+	version, err := strconv.Atoi(tag)
+	if err != nil || version <= 0 {
+		return 0, pkg.ErrVersionMismatch
+	}
+	return version, nil
+}
@@ -6,16 +6,99 @@ type Handler interface {
 	Register(ctx *gin.Context)
 	Login(ctx *gin.Context)
 	Logout(ctx *gin.Context)
+	ForgotPassword(ctx *gin.Context)
+	ResetPassword(ctx *gin.Context)
 
 	CreateProduct(ctx *gin.Context)
 	GetProduct(ctx *gin.Context)
 	ListProducts(ctx *gin.Context)
 	UpdateProduct(ctx *gin.Context)
+	PatchProduct(ctx *gin.Context)
 	DeleteProduct(ctx *gin.Context)
+	UpdateProductStatus(ctx *gin.Context)
+	ArchiveProduct(ctx *gin.Context)
+	UnarchiveProduct(ctx *gin.Context)
+	SchedulePrice(ctx *gin.Context)
+	ListProductPrices(ctx *gin.Context)
+	CancelScheduledPrice(ctx *gin.Context)
+
+	CreateCoupon(ctx *gin.Context)
+	ListCoupons(ctx *gin.Context)
+	GetCoupon(ctx *gin.Context)
+	UpdateCoupon(ctx *gin.Context)
+	DeleteCoupon(ctx *gin.Context)
+
+	CreatePromotion(ctx *gin.Context)
+	ListPromotions(ctx *gin.Context)
+	GetPromotion(ctx *gin.Context)
+	UpdatePromotion(ctx *gin.Context)
+	DeletePromotion(ctx *gin.Context)
+
+	CreateTaxRate(ctx *gin.Context)
+	ListTaxRates(ctx *gin.Context)
+	UpdateTaxRate(ctx *gin.Context)
+	DeleteTaxRate(ctx *gin.Context)
+
+	CreateShippingMethod(ctx *gin.Context)
+	ListShippingMethods(ctx *gin.Context)
+	UpdateShippingMethod(ctx *gin.Context)
+	DeleteShippingMethod(ctx *gin.Context)
+	QuoteShipping(ctx *gin.Context)
+
+	CreateAddress(ctx *gin.Context)
+	ListAddresses(ctx *gin.Context)
+	GetAddress(ctx *gin.Context)
+	UpdateAddress(ctx *gin.Context)
+	SetDefaultAddress(ctx *gin.Context)
+	DeleteAddress(ctx *gin.Context)
 
 	CreateOrder(ctx *gin.Context)
+	QuoteOrder(ctx *gin.Context)
 	GetOrder(ctx *gin.Context)
+	StreamOrderUpdates(ctx *gin.Context)
 	ListUserOrders(ctx *gin.Context)
+	ListOrders(ctx *gin.Context)
 	UpdateOrderStatus(ctx *gin.Context)
+	BulkUpdateOrderStatus(ctx *gin.Context)
 	CancelOrder(ctx *gin.Context)
+	PayOrder(ctx *gin.Context)
+	PaymentWebhook(ctx *gin.Context)
+	CreateShipment(ctx *gin.Context)
+	MarkShipmentDelivered(ctx *gin.Context)
+	GetOrderInvoice(ctx *gin.Context)
+	ListOrderCreditNotes(ctx *gin.Context)
+	GetOrderCreditNote(ctx *gin.Context)
+
+	RequestReturn(ctx *gin.Context)
+	ListOrderReturns(ctx *gin.Context)
+	ListReturns(ctx *gin.Context)
+	GetReturn(ctx *gin.Context)
+	ApproveReturn(ctx *gin.Context)
+	RejectReturn(ctx *gin.Context)
+	ReceiveReturn(ctx *gin.Context)
+
+	CreateWarehouse(ctx *gin.Context)
+	ListWarehouses(ctx *gin.Context)
+	UpdateWarehouse(ctx *gin.Context)
+	GetProductStock(ctx *gin.Context)
+	SetWarehouseStock(ctx *gin.Context)
+
+	ListLowStockProducts(ctx *gin.Context)
+	SubscribeBackInStock(ctx *gin.Context)
+	UnsubscribeBackInStock(ctx *gin.Context)
+
+	CreateWebhookSubscription(ctx *gin.Context)
+	ListWebhookSubscriptions(ctx *gin.Context)
+	GetWebhookSubscription(ctx *gin.Context)
+	UpdateWebhookSubscription(ctx *gin.Context)
+	DeleteWebhookSubscription(ctx *gin.Context)
+	ListWebhookDeliveries(ctx *gin.Context)
+	GetWebhookDelivery(ctx *gin.Context)
+	RedeliverWebhook(ctx *gin.Context)
+
+	ListNotifications(ctx *gin.Context)
+	MarkNotificationRead(ctx *gin.Context)
+	MarkAllNotificationsRead(ctx *gin.Context)
+	GetNotificationPreferences(ctx *gin.Context)
+	UpdateNotificationPreferences(ctx *gin.Context)
 }
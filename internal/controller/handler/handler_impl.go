@@ -100,6 +100,58 @@ func (h *handlerImpl) Logout(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// ForgotPassword
+// @Summary      Request a password reset
+// @Description  Email a single-use password reset code, valid for an hour, to the address if it belongs to an account. The response is the same whether it does or not.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body models.ForgotPasswordRequest true "Account email"
+// @Success      202 "Accepted"
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Router       /password/forgot [post]
+func (h *handlerImpl) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "forgot_password_validation")
+		return
+	}
+
+	if err := h.service.RequestPasswordReset(c.Request.Context(), &req); err != nil {
+		h.handleError(c, err, "forgot_password")
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// ResetPassword
+// @Summary      Reset password
+// @Description  Set a new password with an emailed password reset code. The user is logged out of all sessions.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body models.ResetPasswordRequest true "Reset code and new password"
+// @Success      204 "No Content"
+// @Failure      400 {object} models.ErrorResponse "Invalid input, or the code is invalid, used or expired"
+// @Failure      500 {object} models.ErrorResponse
+// @Router       /password/reset [post]
+func (h *handlerImpl) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "reset_password_validation")
+		return
+	}
+
+	if err := h.service.ResetPassword(c.Request.Context(), &req); err != nil {
+		h.handleError(c, err, "reset_password")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
 // CreateProduct
 // @Summary      Create a new product
 // @Description  Create a new product (admin only)
@@ -127,17 +179,19 @@ func (h *handlerImpl) CreateProduct(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", versionETag(product.Version))
 	c.JSON(http.StatusCreated, product)
 }
 
 // GetProduct
 // @Summary      Get product by ID
-// @Description  Get detailed information about a specific product
+// @Description  Get detailed information about a specific product. The ETag header holds the product version.
 // @Tags         products
 // @Accept       json
 // @Produce      json
 // @Param        id path string true "Product ID"
-// @Success      201 {object} models.Product
+// @Success      200 {object} models.Product
+// @Header       200 {string} ETag "Product version"
 // @Failure      400 {object} models.ErrorResponse
 // @Failure      401 {object} models.ErrorResponse
 // @Failure      403 {object} models.ErrorResponse
@@ -153,15 +207,24 @@ func (h *handlerImpl) GetProduct(c *gin.Context) {
 		return
 	}
 
+	// unpublished products don't exist as far as customers are concerned
+	if !isAdmin(c) && !product.IsAvailable() {
+		h.handleError(c, pkg.ErrNotFound, "get_product")
+		return
+	}
+
+	c.Header("ETag", versionETag(product.Version))
 	c.JSON(http.StatusOK, product)
 }
 
 // ListProducts
 // @Summary      List all products
-// @Description  Get a list of all available products
+// @Description  Get a list of all available products. Admins see products in every status and can include archived products.
 // @Tags         products
 // @Accept       json
 // @Produce      json
+// @Param        status query []string false "Only products in these statuses (admin only)" collectionFormat(multi) Enums(draft, scheduled, active, discontinued)
+// @Param        include_archived query bool false "Include archived products (admin only)"
 // @Success      200 {array} models.Product
 // @Failure      400 {object} models.ErrorResponse
 // @Failure      401 {object} models.ErrorResponse
@@ -170,7 +233,19 @@ func (h *handlerImpl) GetProduct(c *gin.Context) {
 // @Security     Bearer
 // @Router       /products [get]
 func (h *handlerImpl) ListProducts(c *gin.Context) {
-	products, err := h.service.ListProducts(c.Request.Context())
+	// customers only see what they can order
+	filter := models.ProductFilter{
+		Statuses: []models.ProductStatus{models.ProductActive},
+	}
+	if isAdmin(c) {
+		filter = models.ProductFilter{}
+		if err := c.ShouldBindQuery(&filter); err != nil {
+			h.handleError(c, pkg.ErrInvalidInput, "list_products_validation")
+			return
+		}
+	}
+
+	products, err := h.service.ListProducts(c.Request.Context(), filter)
 	if err != nil {
 		h.handleError(c, err, "list_products")
 		return
@@ -181,41 +256,96 @@ func (h *handlerImpl) ListProducts(c *gin.Context) {
 
 // UpdateProduct
 // @Summary      Update product
-// @Description  Update product details (admin only)
+// @Description  Update product details (admin only). Send the product's ETag in If-Match to only update that version. stock_quantity is the total across warehouses: a change to it is applied to the default warehouse only, and fails with INSUFFICIENT_STOCK if the default warehouse doesn't hold enough to remove. Use PUT /warehouses/{id}/stock/{product_id} to set the stock of other warehouses.
 // @Tags         products
 // @Accept       json
 // @Produce      json
 // @Param        id path string true "Product ID"
+// @Param        If-Match header string false "ETag of the product version being replaced"
 // @Param        request body models.CreateProductRequest true "Product details"
 // @Success      200 {object} models.Product
+// @Header       200 {string} ETag "Product version"
 // @Failure      400 {object} models.ErrorResponse
 // @Failure      401 {object} models.ErrorResponse
 // @Failure      403 {object} models.ErrorResponse
 // @Failure      404 {object} models.ErrorResponse
+// @Failure      412 {object} models.ErrorResponse "Product was modified"
 // @Failure      500 {object} models.ErrorResponse
 // @Security     Bearer
 // @Router       /products/{id} [put]
 func (h *handlerImpl) UpdateProduct(c *gin.Context) {
 	id := c.Param("id")
 
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		h.handleError(c, err, "update_product_precondition")
+		return
+	}
+
 	var req models.CreateProductRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.handleError(c, pkg.ErrInvalidInput, "update_product_validation")
 		return
 	}
 
-	product, err := h.service.UpdateProduct(c.Request.Context(), id, &req)
+	product, err := h.service.UpdateProduct(c.Request.Context(), id, &req, version)
 	if err != nil {
 		h.handleError(c, err, "update_product")
 		return
 	}
 
+	c.Header("ETag", versionETag(product.Version))
+	c.JSON(http.StatusOK, product)
+}
+
+// PatchProduct
+// @Summary      Partially update product
+// @Description  Apply a JSON Merge Patch (RFC 7386) to a product (admin only). Send the product's ETag in If-Match to only patch that version. stock_quantity is the total across warehouses: a change to it is applied to the default warehouse only, and fails with INSUFFICIENT_STOCK if the default warehouse doesn't hold enough to remove. Use PUT /warehouses/{id}/stock/{product_id} to set the stock of other warehouses.
+// @Tags         products
+// @Accept       application/merge-patch+json
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Product ID"
+// @Param        If-Match header string false "ETag of the product version being patched"
+// @Param        request body models.ProductDocument true "Fields to change, null removes a field (except stock_quantity)"
+// @Success      200 {object} models.Product
+// @Header       200 {string} ETag "Product version"
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      412 {object} models.ErrorResponse "Product was modified"
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /products/{id} [patch]
+func (h *handlerImpl) PatchProduct(c *gin.Context) {
+	id := c.Param("id")
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		h.handleError(c, err, "patch_product_precondition")
+		return
+	}
+
+	patch, err := c.GetRawData()
+	if err != nil || len(patch) == 0 {
+		h.handleError(c, pkg.ErrInvalidInput, "patch_product_validation")
+		return
+	}
+
+	product, err := h.service.PatchProduct(c.Request.Context(), id, patch, version)
+	if err != nil {
+		h.handleError(c, err, "patch_product")
+		return
+	}
+
+	c.Header("ETag", versionETag(product.Version))
 	c.JSON(http.StatusOK, product)
 }
 
 // DeleteProduct
 // @Summary      Delete product
-// @Description  Delete a product (admin only)
+// @Description  Delete a product (admin only). Products that have been ordered can't be deleted, archive them instead.
 // @Tags         products
 // @Accept       json
 // @Produce      json
@@ -224,6 +354,7 @@ func (h *handlerImpl) UpdateProduct(c *gin.Context) {
 // @Failure      401 {object} models.ErrorResponse
 // @Failure      403 {object} models.ErrorResponse
 // @Failure      404 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse "Product is referenced by orders"
 // @Failure      500 {object} models.ErrorResponse
 // @Security     Bearer
 // @Router       /products/{id} [delete]
@@ -238,6 +369,104 @@ func (h *handlerImpl) DeleteProduct(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// UpdateProductStatus
+// @Summary      Update product status
+// @Description  Move a product through its publishing lifecycle (admin only). Scheduled products are published at publish_at, active products are discontinued at unpublish_at.
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Product ID"
+// @Param        If-Match header string false "ETag of the product version being changed"
+// @Param        request body models.UpdateProductStatusRequest true "Product status"
+// @Success      200 {object} models.Product
+// @Header       200 {string} ETag "Product version"
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      412 {object} models.ErrorResponse "Product was modified"
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /products/{id}/status [put]
+func (h *handlerImpl) UpdateProductStatus(c *gin.Context) {
+	id := c.Param("id")
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		h.handleError(c, err, "update_product_status_precondition")
+		return
+	}
+
+	var req models.UpdateProductStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "update_product_status_validation")
+		return
+	}
+
+	product, err := h.service.UpdateProductStatus(c.Request.Context(), id, &req, version)
+	if err != nil {
+		h.handleError(c, err, "update_product_status")
+		return
+	}
+
+	c.Header("ETag", versionETag(product.Version))
+	c.JSON(http.StatusOK, product)
+}
+
+// ArchiveProduct
+// @Summary      Archive product
+// @Description  Hide a product from listing and ordering (admin only). Orders keep referencing it.
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Product ID"
+// @Success      200 {object} models.Product
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /products/{id}/archive [post]
+func (h *handlerImpl) ArchiveProduct(c *gin.Context) {
+	id := c.Param("id")
+
+	product, err := h.service.ArchiveProduct(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err, "archive_product")
+		return
+	}
+
+	c.Header("ETag", versionETag(product.Version))
+	c.JSON(http.StatusOK, product)
+}
+
+// UnarchiveProduct
+// @Summary      Unarchive product
+// @Description  Make an archived product available again (admin only)
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Product ID"
+// @Success      200 {object} models.Product
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /products/{id}/unarchive [post]
+func (h *handlerImpl) UnarchiveProduct(c *gin.Context) {
+	id := c.Param("id")
+
+	product, err := h.service.UnarchiveProduct(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err, "unarchive_product")
+		return
+	}
+
+	c.Header("ETag", versionETag(product.Version))
+	c.JSON(http.StatusOK, product)
+}
+
 // CreateOrder
 // @Summary      Create a new order
 // @Description  Create a new order with multiple products
@@ -249,6 +478,7 @@ func (h *handlerImpl) DeleteProduct(c *gin.Context) {
 // @Failure      400 {object} models.ErrorResponse
 // @Failure      401 {object} models.ErrorResponse
 // @Failure      404 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse "Prices changed while placing the order"
 // @Failure      500 {object} models.ErrorResponse
 // @Security     Bearer
 // @Router       /orders [post]
@@ -259,8 +489,7 @@ func (h *handlerImpl) CreateOrder(c *gin.Context) {
 		return
 	}
 
-	userID := c.GetString("user_id")
-	if err := h.service.CreateOrder(c.Request.Context(), userID, &req); err != nil {
+	if err := h.service.CreateOrder(c.Request.Context(), &req); err != nil {
 		h.handleError(c, err, "create_order")
 		return
 	}
@@ -268,9 +497,39 @@ func (h *handlerImpl) CreateOrder(c *gin.Context) {
 	c.Status(http.StatusCreated)
 }
 
+// QuoteOrder
+// @Summary      Price a cart
+// @Description  Price a prospective order with the running promotions and an optional coupon, without placing it or reserving stock
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        request body models.CreateOrderRequest true "Order details"
+// @Success      200 {object} models.OrderQuote
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /orders/quote [post]
+func (h *handlerImpl) QuoteOrder(c *gin.Context) {
+	var req models.CreateOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "quote_order_validation")
+		return
+	}
+
+	quote, err := h.service.QuoteOrder(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, err, "quote_order")
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+
 // GetOrder
 // @Summary      Get order by ID
-// @Description  Get detailed information about a specific order
+// @Description  Get detailed information about one of the user's orders. Admins can get any order.
 // @Tags         orders
 // @Accept       json
 // @Produce      json
@@ -295,30 +554,41 @@ func (h *handlerImpl) GetOrder(c *gin.Context) {
 
 // ListUserOrders
 // @Summary      List user orders
-// @Description  Get a list of all orders for the authenticated user
+// @Description  Get a page of the authenticated user's orders, newest first, with the number of matching orders and the total spent on them. Pass next_cursor as cursor to get the next page.
 // @Tags         orders
 // @Accept       json
 // @Produce      json
-// @Success      200 {array} models.Order
+// @Param        status query []string false "Only orders in these statuses" collectionFormat(multi) Enums(pending, confirmed, partially_shipped, shipped, delivered, partially_returned, returned, cancelled)
+// @Param        created_from query string false "Only orders placed at or after this time (RFC 3339)"
+// @Param        created_to query string false "Only orders placed before this time (RFC 3339)"
+// @Param        include query []string false "Related data to load with the orders" collectionFormat(multi) Enums(items)
+// @Param        cursor query string false "Cursor of the page to get"
+// @Param        limit query int false "Orders per page, at most 100" default(20)
+// @Success      200 {object} models.UserOrderPage
+// @Failure      400 {object} models.ErrorResponse
 // @Failure      401 {object} models.ErrorResponse
 // @Failure      500 {object} models.ErrorResponse
 // @Security     Bearer
 // @Router       /orders [get]
 func (h *handlerImpl) ListUserOrders(c *gin.Context) {
-	userID := c.GetString("user_id")
+	var filter models.UserOrderFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "list_user_orders_validation")
+		return
+	}
 
-	orders, err := h.service.GetUserOrders(c.Request.Context(), userID)
+	page, err := h.service.GetUserOrders(c.Request.Context(), filter)
 	if err != nil {
 		h.handleError(c, err, "list_user_orders")
 		return
 	}
 
-	c.JSON(http.StatusOK, orders)
+	c.JSON(http.StatusOK, page)
 }
 
 // UpdateOrderStatus
 // @Summary      Update order status
-// @Description  Update the status of an order (admin only)
+// @Description  Cancel a pending or confirmed order (admin only), refunding its payment and giving back its stock and coupon use. Orders are confirmed by paying for them and the shipping statuses follow from the order's shipments.
 // @Tags         orders
 // @Accept       json
 // @Produce      json
@@ -329,6 +599,7 @@ func (h *handlerImpl) ListUserOrders(c *gin.Context) {
 // @Failure      401 {object} models.ErrorResponse
 // @Failure      403 {object} models.ErrorResponse
 // @Failure      404 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse
 // @Failure      500 {object} models.ErrorResponse
 // @Security     Bearer
 // @Router       /orders/{id}/status [put]
@@ -349,6 +620,125 @@ func (h *handlerImpl) UpdateOrderStatus(c *gin.Context) {
 	c.Status(http.StatusOK)
 }
 
+// ListOrders
+// @Summary      List all orders
+// @Description  Get a page of all customers' orders, filtered and sorted (admin only)
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        status query []string false "Only orders in these statuses" collectionFormat(multi) Enums(pending, confirmed, partially_shipped, shipped, delivered, partially_returned, returned, cancelled)
+// @Param        created_from query string false "Only orders placed at or after this time (RFC 3339)"
+// @Param        created_to query string false "Only orders placed before this time (RFC 3339)"
+// @Param        customer_email query string false "Only orders of customers whose email contains this text"
+// @Param        min_total query number false "Minimum order total"
+// @Param        max_total query number false "Maximum order total"
+// @Param        product_id query string false "Only orders containing this product"
+// @Param        sort query string false "Sort field, prefixed with - for descending order" Enums(created_at, -created_at, updated_at, -updated_at, total_amount, -total_amount, status, -status) default(-created_at)
+// @Param        page query int false "Page number" default(1)
+// @Param        page_size query int false "Orders per page, at most 100" default(20)
+// @Success      200 {object} models.OrderPage
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /admin/orders [get]
+func (h *handlerImpl) ListOrders(c *gin.Context) {
+	var filter models.OrderFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "list_orders_validation")
+		return
+	}
+
+	page, err := h.service.ListOrders(c.Request.Context(), filter)
+	if err != nil {
+		h.handleError(c, err, "list_orders")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// BulkUpdateOrderStatus
+// @Summary      Bulk update order status
+// @Description  Cancel several orders at once (admin only). Each order is updated on its own under the rules of the single order update, and the result of every order is reported.
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        request body models.BulkUpdateOrderStatusRequest true "Orders and their new status"
+// @Success      200 {array} models.BulkOrderStatusResult
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /admin/orders/status [post]
+func (h *handlerImpl) BulkUpdateOrderStatus(c *gin.Context) {
+	var req models.BulkUpdateOrderStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "bulk_update_order_status_validation")
+		return
+	}
+
+	errs := h.service.BulkUpdateStatus(c.Request.Context(), req.OrderIDs, req.Status)
+
+	results := make([]models.BulkOrderStatusResult, len(req.OrderIDs))
+	for i, id := range req.OrderIDs {
+		results[i] = models.BulkOrderStatusResult{OrderID: id, Updated: errs[i] == nil}
+		if errs[i] == nil {
+			continue
+		}
+
+		statusCode, errResp := errorResponse(errs[i])
+		results[i].Error = &errResp
+		if statusCode >= 500 {
+			slog.Error("failed to update order status", slog.String("order_id", id), slog.String("err", errs[i].Error()))
+		}
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// PayOrder
+// @Summary      Pay for order
+// @Description  Pay for a pending order. The order is confirmed once the payment has been captured; declined payments can be retried. If the payment provider doesn't answer, the payment is returned pending with 202 and settled in the background; the order shows the outcome.
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Order ID"
+// @Param        request body models.PayOrderRequest true "Payment details"
+// @Success      201 {object} models.Payment
+// @Success      202 {object} models.Payment "Payment outcome not known yet"
+// @Failure      400 {object} models.ErrorResponse "Order not in pending status"
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      402 {object} models.ErrorResponse "Payment declined"
+// @Failure      403 {object} models.ErrorResponse "Admins can't act on another user's order"
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse "Another payment of the order is in progress"
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /orders/{id}/pay [post]
+func (h *handlerImpl) PayOrder(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.PayOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "pay_order_validation")
+		return
+	}
+
+	payment, err := h.service.PayOrder(c.Request.Context(), id, &req)
+	if err != nil {
+		h.handleError(c, err, "pay_order")
+		return
+	}
+
+	if payment.Status == models.PaymentPending {
+		c.JSON(http.StatusAccepted, payment)
+		return
+	}
+	c.JSON(http.StatusCreated, payment)
+}
+
 // CancelOrder
 // @Summary      Cancel order
 // @Description  Cancel a pending order
@@ -359,16 +749,15 @@ func (h *handlerImpl) UpdateOrderStatus(c *gin.Context) {
 // @Success      200
 // @Failure      400 {object} models.ErrorResponse "Order not in pending status"
 // @Failure      401 {object} models.ErrorResponse
-// @Failure      403 {object} models.ErrorResponse "Not the order owner"
+// @Failure      403 {object} models.ErrorResponse "Admins can't act on another user's order"
 // @Failure      404 {object} models.ErrorResponse
 // @Failure      500 {object} models.ErrorResponse
 // @Security     Bearer
 // @Router       /orders/{id}/cancel [post]
 func (h *handlerImpl) CancelOrder(c *gin.Context) {
 	id := c.Param("id")
-	userID := c.GetString("user_id")
 
-	if err := h.service.CancelOrder(c.Request.Context(), id, userID); err != nil {
+	if err := h.service.CancelOrder(c.Request.Context(), id); err != nil {
 		h.handleError(c, err, "cancel_order")
 		return
 	}
@@ -376,8 +765,43 @@ func (h *handlerImpl) CancelOrder(c *gin.Context) {
 	c.Status(http.StatusOK)
 }
 
+// isAdmin reports whether the authenticated user has the admin role
+func isAdmin(c *gin.Context) bool {
+	role, _ := c.Get("user_role")
+	return role == models.RoleAdmin
+}
+
 // ErrorHandler provides centralized error handling with detailed logging and consistent responses
 func (h *handlerImpl) handleError(ctx *gin.Context, err error, operation string) {
+	statusCode, errResp := errorResponse(err)
+
+	// log error with context
+	logAttrs := []slog.Attr{
+		slog.String("operation", operation),
+		slog.String("error_code", errResp.Code),
+		slog.String("error", err.Error()),
+		slog.Int("status_code", statusCode),
+		slog.String("path", ctx.Request.URL.Path),
+		slog.String("method", ctx.Request.Method),
+	}
+
+	// add user context if available
+	if userID, exists := ctx.Get("user_id"); exists {
+		logAttrs = append(logAttrs, slog.String("user_id", userID.(string)))
+	}
+
+	// log at appropriate level based on status code
+	if statusCode >= 500 {
+		slog.LogAttrs(ctx.Request.Context(), slog.LevelError, "Internal server error", logAttrs...)
+	} else {
+		slog.LogAttrs(ctx.Request.Context(), slog.LevelInfo, "Client error", logAttrs...)
+	}
+
+	ctx.JSON(statusCode, errResp)
+}
+
+// errorResponse maps an error to the status code and body reported to the client
+func errorResponse(err error) (int, models.ErrorResponse) {
 	// default error response
 	statusCode := http.StatusInternalServerError
 	errResp := models.ErrorResponse{
@@ -392,6 +816,11 @@ func (h *handlerImpl) handleError(ctx *gin.Context, err error, operation string)
 		errResp.Code = "INVALID_CREDENTIALS"
 		errResp.Message = "Invalid email or password"
 
+	case errors.Is(err, pkg.ErrInvalidResetToken):
+		statusCode = http.StatusBadRequest
+		errResp.Code = "INVALID_RESET_TOKEN"
+		errResp.Message = "Password reset code is invalid or has expired"
+
 	case errors.Is(err, pkg.ErrEmailTaken):
 		statusCode = http.StatusConflict
 		errResp.Code = "EMAIL_TAKEN"
@@ -417,6 +846,136 @@ func (h *handlerImpl) handleError(ctx *gin.Context, err error, operation string)
 		errResp.Code = "INVALID_ORDER_STATUS"
 		errResp.Message = "Order cannot be modified in its current status"
 
+	case errors.Is(err, pkg.ErrWarehouseCodeTaken):
+		statusCode = http.StatusConflict
+		errResp.Code = "WAREHOUSE_CODE_TAKEN"
+		errResp.Message = "Warehouse code is already in use"
+
+	case errors.Is(err, pkg.ErrNoActiveWarehouse):
+		statusCode = http.StatusConflict
+		errResp.Code = "NO_ACTIVE_WAREHOUSE"
+		errResp.Message = "There is no active warehouse to hold stock"
+
+	case errors.Is(err, pkg.ErrAlreadySubscribed):
+		statusCode = http.StatusConflict
+		errResp.Code = "ALREADY_SUBSCRIBED"
+		errResp.Message = "You are already subscribed to this product"
+
+	case errors.Is(err, pkg.ErrProductInStock):
+		statusCode = http.StatusConflict
+		errResp.Code = "PRODUCT_IN_STOCK"
+		errResp.Message = "Product is currently in stock"
+
+	case errors.Is(err, pkg.ErrVersionMismatch):
+		statusCode = http.StatusPreconditionFailed
+		errResp.Code = "PRECONDITION_FAILED"
+		errResp.Message = "The resource has been modified since it was last read"
+
+	case errors.Is(err, pkg.ErrProductReferenced):
+		statusCode = http.StatusConflict
+		errResp.Code = "PRODUCT_IN_USE"
+		errResp.Message = "Product is referenced by existing orders, archive it instead"
+
+	case errors.Is(err, pkg.ErrProductUnavailable):
+		statusCode = http.StatusBadRequest
+		errResp.Code = "PRODUCT_UNAVAILABLE"
+		errResp.Message = "One or more products are no longer available"
+
+	case errors.Is(err, pkg.ErrPriceInEffect):
+		statusCode = http.StatusConflict
+		errResp.Code = "PRICE_IN_EFFECT"
+		errResp.Message = "Price change has already taken effect"
+
+	case errors.Is(err, pkg.ErrPricesChanged):
+		statusCode = http.StatusConflict
+		errResp.Code = "PRICES_CHANGED"
+		errResp.Message = "Prices changed while placing the order, please try again"
+
+	case errors.Is(err, pkg.ErrInvalidCoupon):
+		statusCode = http.StatusBadRequest
+		errResp.Code = "INVALID_COUPON"
+		errResp.Message = "Coupon code is not valid"
+
+	case errors.Is(err, pkg.ErrCouponExpired):
+		statusCode = http.StatusBadRequest
+		errResp.Code = "COUPON_EXPIRED"
+		errResp.Message = "Coupon is not valid at this time"
+
+	case errors.Is(err, pkg.ErrCouponUsageLimit):
+		statusCode = http.StatusBadRequest
+		errResp.Code = "COUPON_USAGE_LIMIT"
+		errResp.Message = "Coupon has reached its usage limit"
+
+	case errors.Is(err, pkg.ErrCouponMinimumNotMet):
+		statusCode = http.StatusBadRequest
+		errResp.Code = "COUPON_MINIMUM_NOT_MET"
+		errResp.Message = "Order value is below the coupon minimum"
+
+	case errors.Is(err, pkg.ErrCouponNotApplicable):
+		statusCode = http.StatusBadRequest
+		errResp.Code = "COUPON_NOT_APPLICABLE"
+		errResp.Message = "Coupon does not apply to any of the products"
+
+	case errors.Is(err, pkg.ErrCouponCodeTaken):
+		statusCode = http.StatusConflict
+		errResp.Code = "COUPON_CODE_TAKEN"
+		errResp.Message = "Coupon code is already in use"
+
+	case errors.Is(err, pkg.ErrCouponInUse):
+		statusCode = http.StatusConflict
+		errResp.Code = "COUPON_IN_USE"
+		errResp.Message = "Coupon has been redeemed, deactivate it instead"
+
+	case errors.Is(err, pkg.ErrTaxRateExists):
+		statusCode = http.StatusConflict
+		errResp.Code = "TAX_RATE_EXISTS"
+		errResp.Message = "A tax rate already exists for this region and tax class"
+
+	case errors.Is(err, pkg.ErrShippingMethodCodeTaken):
+		statusCode = http.StatusConflict
+		errResp.Code = "SHIPPING_METHOD_CODE_TAKEN"
+		errResp.Message = "Shipping method code is already in use"
+
+	case errors.Is(err, pkg.ErrShippingUnavailable):
+		statusCode = http.StatusBadRequest
+		errResp.Code = "SHIPPING_UNAVAILABLE"
+		errResp.Message = "Shipping method is not available for this destination"
+
+	case errors.Is(err, pkg.ErrInvalidStatusTransition):
+		statusCode = http.StatusConflict
+		errResp.Code = "INVALID_STATUS_TRANSITION"
+		errResp.Message = "The order can't be moved to this status"
+
+	case errors.Is(err, pkg.ErrOrderNotReturnable):
+		statusCode = http.StatusConflict
+		errResp.Code = "ORDER_NOT_RETURNABLE"
+		errResp.Message = "Only delivered orders can be returned"
+
+	case errors.Is(err, pkg.ErrInvalidReturnStatus):
+		statusCode = http.StatusConflict
+		errResp.Code = "INVALID_RETURN_STATUS"
+		errResp.Message = "Return cannot be changed in its current status"
+
+	case errors.Is(err, pkg.ErrPaymentDeclined):
+		statusCode = http.StatusPaymentRequired
+		errResp.Code = "PAYMENT_DECLINED"
+		errResp.Message = "The payment was declined"
+
+	case errors.Is(err, pkg.ErrPaymentInProgress):
+		statusCode = http.StatusConflict
+		errResp.Code = "PAYMENT_IN_PROGRESS"
+		errResp.Message = "Another payment of the order is in progress"
+
+	case errors.Is(err, pkg.ErrPaymentFailed):
+		statusCode = http.StatusBadGateway
+		errResp.Code = "PAYMENT_FAILED"
+		errResp.Message = "The payment provider could not process the payment, please try again"
+
+	case errors.Is(err, pkg.ErrInvalidSignature):
+		statusCode = http.StatusUnauthorized
+		errResp.Code = "INVALID_SIGNATURE"
+		errResp.Message = "Request signature is invalid or expired"
+
 	case errors.Is(err, pkg.ErrInvalidInput):
 		statusCode = http.StatusBadRequest
 		errResp.Code = "INVALID_INPUT"
@@ -426,28 +985,5 @@ func (h *handlerImpl) handleError(ctx *gin.Context, err error, operation string)
 			errResp.Details = verr.Validation()
 		}
 	}
-
-	// log error with context
-	logAttrs := []slog.Attr{
-		slog.String("operation", operation),
-		slog.String("error_code", errResp.Code),
-		slog.String("error", err.Error()),
-		slog.Int("status_code", statusCode),
-		slog.String("path", ctx.Request.URL.Path),
-		slog.String("method", ctx.Request.Method),
-	}
-
-	// add user context if available
-	if userID, exists := ctx.Get("user_id"); exists {
-		logAttrs = append(logAttrs, slog.String("user_id", userID.(string)))
-	}
-
-	// log at appropriate level based on status code
-	if statusCode >= 500 {
-		slog.LogAttrs(ctx.Request.Context(), slog.LevelError, "Internal server error", logAttrs...)
-	} else {
-		slog.LogAttrs(ctx.Request.Context(), slog.LevelInfo, "Client error", logAttrs...)
-	}
-
-	ctx.JSON(statusCode, errResp)
+	return statusCode, errResp
 }
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zde37/instashop-task/internal/invoice"
+	"github.com/zde37/instashop-task/internal/models"
+)
+
+// GetOrderInvoice
+// @Summary      Get order invoice
+// @Description  Get the invoice of a paid order as JSON, or as PDF with format=pdf or an Accept header of application/pdf. Admins can get the invoice of any order.
+// @Tags         invoices
+// @Accept       json
+// @Produce      json,application/pdf
+// @Param        id path string true "Order ID"
+// @Param        format query string false "Response format" Enums(json, pdf)
+// @Success      200 {object} models.Invoice
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse "Order not found or not paid for"
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /orders/{id}/invoice [get]
+func (h *handlerImpl) GetOrderInvoice(c *gin.Context) {
+	id := c.Param("id")
+
+	inv, err := h.service.GetOrderInvoice(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err, "get_order_invoice")
+		return
+	}
+
+	writeInvoice(c, inv)
+}
+
+// ListOrderCreditNotes
+// @Summary      List order credit notes
+// @Description  Get the credit notes issued for refunds on an order. Admins can list the credit notes of any order.
+// @Tags         invoices
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Order ID"
+// @Success      200 {array} models.Invoice
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /orders/{id}/credit-notes [get]
+func (h *handlerImpl) ListOrderCreditNotes(c *gin.Context) {
+	id := c.Param("id")
+
+	creditNotes, err := h.service.ListOrderCreditNotes(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err, "list_order_credit_notes")
+		return
+	}
+
+	c.JSON(http.StatusOK, creditNotes)
+}
+
+// GetOrderCreditNote
+// @Summary      Get order credit note
+// @Description  Get a credit note of an order as JSON, or as PDF with format=pdf or an Accept header of application/pdf
+// @Tags         invoices
+// @Accept       json
+// @Produce      json,application/pdf
+// @Param        id path string true "Order ID"
+// @Param        credit_note_id path string true "Credit note ID"
+// @Param        format query string false "Response format" Enums(json, pdf)
+// @Success      200 {object} models.Invoice
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /orders/{id}/credit-notes/{credit_note_id} [get]
+func (h *handlerImpl) GetOrderCreditNote(c *gin.Context) {
+	id := c.Param("id")
+	creditNoteID := c.Param("credit_note_id")
+
+	creditNote, err := h.service.GetOrderCreditNote(c.Request.Context(), id, creditNoteID)
+	if err != nil {
+		h.handleError(c, err, "get_order_credit_note")
+		return
+	}
+
+	writeInvoice(c, creditNote)
+}
+
+// writeInvoice responds with the document as JSON, or as a PDF download when asked for
+func writeInvoice(c *gin.Context, inv *models.Invoice) {
+	format := c.Query("format")
+	if format == "" && strings.Contains(c.GetHeader("Accept"), "application/pdf") {
+		format = "pdf"
+	}
+	if format != "pdf" {
+		c.JSON(http.StatusOK, inv)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, inv.Number))
+	c.Data(http.StatusOK, "application/pdf", invoice.RenderPDF(inv))
+}
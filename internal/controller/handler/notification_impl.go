@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// ListNotifications
+// @Summary      List notifications
+// @Description  Get the user's in-app notifications, newest first, with the number of unread ones. Pass next_cursor as cursor to get the next page.
+// @Tags         notifications
+// @Accept       json
+// @Produce      json
+// @Param        unread query bool false "Only unread notifications"
+// @Param        cursor query string false "Cursor from the previous page"
+// @Param        limit query int false "Page size, 50 if not set" minimum(1) maximum(100)
+// @Success      200 {object} models.NotificationInbox
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /notifications [get]
+func (h *handlerImpl) ListNotifications(c *gin.Context) {
+	var filter models.NotificationFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "list_notifications_validation")
+		return
+	}
+
+	inbox, err := h.service.ListNotifications(c.Request.Context(), filter)
+	if err != nil {
+		h.handleError(c, err, "list_notifications")
+		return
+	}
+
+	c.JSON(http.StatusOK, inbox)
+}
+
+// MarkNotificationRead
+// @Summary      Mark a notification read
+// @Description  Mark one of the user's notifications read. Notifications that were read before keep their read time.
+// @Tags         notifications
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Notification ID"
+// @Success      200 {object} models.Notification
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /notifications/{id}/read [post]
+func (h *handlerImpl) MarkNotificationRead(c *gin.Context) {
+	id := c.Param("id")
+
+	notification, err := h.service.MarkNotificationRead(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err, "mark_notification_read")
+		return
+	}
+
+	c.JSON(http.StatusOK, notification)
+}
+
+// MarkAllNotificationsRead
+// @Summary      Mark all notifications read
+// @Description  Mark all of the user's unread notifications read and return how many there were
+// @Tags         notifications
+// @Accept       json
+// @Produce      json
+// @Success      200 {object} map[string]int64
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /notifications/read-all [post]
+func (h *handlerImpl) MarkAllNotificationsRead(c *gin.Context) {
+	marked, err := h.service.MarkAllNotificationsRead(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err, "mark_all_notifications_read")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"marked": marked})
+}
+
+// GetNotificationPreferences
+// @Summary      Get notification preferences
+// @Description  Get the channels (in-app, email) the user gets each event type through, defaults included
+// @Tags         notifications
+// @Accept       json
+// @Produce      json
+// @Success      200 {array} models.NotificationPreference
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /notifications/preferences [get]
+func (h *handlerImpl) GetNotificationPreferences(c *gin.Context) {
+	preferences, err := h.service.GetNotificationPreferences(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err, "get_notification_preferences")
+		return
+	}
+
+	c.JSON(http.StatusOK, preferences)
+}
+
+// UpdateNotificationPreferences
+// @Summary      Update notification preferences
+// @Description  Choose the channels of some event types. Event types that aren't listed keep their channels. Returns the preferences of all event types.
+// @Tags         notifications
+// @Accept       json
+// @Produce      json
+// @Param        request body models.UpdateNotificationPreferencesRequest true "Preferences"
+// @Success      200 {array} models.NotificationPreference
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /notifications/preferences [put]
+func (h *handlerImpl) UpdateNotificationPreferences(c *gin.Context) {
+	var req models.UpdateNotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "update_notification_preferences_validation")
+		return
+	}
+
+	preferences, err := h.service.UpdateNotificationPreferences(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, err, "update_notification_preferences")
+		return
+	}
+
+	c.JSON(http.StatusOK, preferences)
+}
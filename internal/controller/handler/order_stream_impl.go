@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zde37/instashop-task/internal/stream"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+const (
+	// heartbeatInterval keeps idle streams from being closed by proxies
+	heartbeatInterval = 15 * time.Second
+	// reconnectDelay is how long clients wait before reconnecting to a closed stream
+	reconnectDelay = 3 * time.Second
+)
+
+// StreamOrderUpdates
+// @Summary      Stream order status changes
+// @Description  Server-Sent Events stream of the status changes of the user's orders, or of all orders for admins. Each change is an order.status_changed event whose id can be sent back as the Last-Event-ID header, or the last_event_id query parameter, to resume after it. Resumed streams repeat the changes from shortly before that event, since changes can commit out of id order; clients skip the ids they already handled. Comment lines are sent as heartbeats.
+// @Tags         orders
+// @Produce      text/event-stream
+// @Param        Last-Event-ID header string false "ID of the last event received"
+// @Param        last_event_id query string false "ID of the last event received, for clients that can't set headers"
+// @Success      200 {object} stream.OrderStatusEvent "order.status_changed events"
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /orders/stream [get]
+func (h *handlerImpl) StreamOrderUpdates(c *gin.Context) {
+	lastEventID, err := lastEventID(c)
+	if err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "stream_order_updates_validation")
+		return
+	}
+
+	ctx := c.Request.Context()
+	sub, missed, err := h.service.SubscribeOrderUpdates(ctx, lastEventID)
+	if err != nil {
+		h.handleError(c, err, "stream_order_updates")
+		return
+	}
+	defer sub.Close()
+
+	// the stream stays open for longer than the server's write timeout
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", reconnectDelay.Milliseconds())
+	sent := make(map[int64]struct{}, len(missed))
+	for _, event := range missed {
+		if err := writeOrderEvent(c.Writer, event); err != nil {
+			return
+		}
+		sent[event.ID] = struct{}{}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case event, ok := <-sub.Events():
+			if !ok {
+				// dropped for falling behind, or shutting down; the client resumes elsewhere
+				return
+			}
+			if _, ok := sent[event.ID]; ok {
+				continue
+			}
+			if err := writeOrderEvent(c.Writer, event); err != nil {
+				return
+			}
+			c.Writer.Flush()
+
+		case <-heartbeat.C:
+			if _, err := io.WriteString(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// lastEventID is the ID of the last event the client received, 0 if it is new
+func lastEventID(c *gin.Context) (int64, error) {
+	id := c.GetHeader("Last-Event-ID")
+	if id == "" {
+		id = c.Query("last_event_id")
+	}
+	if id == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(id, 10, 64)
+}
+
+func writeOrderEvent(w io.Writer, event stream.OrderStatusEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: order.status_changed\ndata: %s\n\n", event.ID, data)
+	return err
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// SchedulePrice
+// @Summary      Schedule a price change
+// @Description  Set a product's price from effective_from (default now) until effective_to, after which the previous price applies again (admin only)
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Product ID"
+// @Param        request body models.SchedulePriceRequest true "Price change"
+// @Success      201 {object} models.ProductPrice
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /products/{id}/prices [post]
+func (h *handlerImpl) SchedulePrice(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.SchedulePriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "schedule_price_validation")
+		return
+	}
+
+	price, err := h.service.SchedulePrice(c.Request.Context(), id, &req)
+	if err != nil {
+		h.handleError(c, err, "schedule_price")
+		return
+	}
+
+	c.JSON(http.StatusCreated, price)
+}
+
+// ListProductPrices
+// @Summary      Get product price history
+// @Description  Get past, current and scheduled prices of a product, latest first (admin only)
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Product ID"
+// @Success      200 {array} models.ProductPrice
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /products/{id}/prices [get]
+func (h *handlerImpl) ListProductPrices(c *gin.Context) {
+	id := c.Param("id")
+
+	prices, err := h.service.ListProductPrices(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err, "list_product_prices")
+		return
+	}
+
+	c.JSON(http.StatusOK, prices)
+}
+
+// CancelScheduledPrice
+// @Summary      Cancel a scheduled price change
+// @Description  Remove a price change that hasn't taken effect yet (admin only)
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Product ID"
+// @Param        price_id path string true "Price ID"
+// @Success      204 "No Content"
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse "Price change already in effect"
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /products/{id}/prices/{price_id} [delete]
+func (h *handlerImpl) CancelScheduledPrice(c *gin.Context) {
+	id := c.Param("id")
+	priceID := c.Param("price_id")
+
+	if err := h.service.CancelScheduledPrice(c.Request.Context(), id, priceID); err != nil {
+		h.handleError(c, err, "cancel_scheduled_price")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// CreatePromotion
+// @Summary      Create a new promotion
+// @Description  Create a new automatic promotion (admin only). Active promotions are applied to orders in priority order, lowest value first.
+// @Tags         promotions
+// @Accept       json
+// @Produce      json
+// @Param        request body models.CreatePromotionRequest true "Promotion details"
+// @Success      201 {object} models.Promotion
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /promotions [post]
+func (h *handlerImpl) CreatePromotion(c *gin.Context) {
+	var req models.CreatePromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "create_promotion_validation")
+		return
+	}
+
+	promotion, err := h.service.CreatePromotion(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, err, "create_promotion")
+		return
+	}
+
+	c.JSON(http.StatusCreated, promotion)
+}
+
+// ListPromotions
+// @Summary      List promotions
+// @Description  Get all promotions in priority order (admin only)
+// @Tags         promotions
+// @Accept       json
+// @Produce      json
+// @Success      200 {array} models.Promotion
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /promotions [get]
+func (h *handlerImpl) ListPromotions(c *gin.Context) {
+	promotions, err := h.service.ListPromotions(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err, "list_promotions")
+		return
+	}
+
+	c.JSON(http.StatusOK, promotions)
+}
+
+// GetPromotion
+// @Summary      Get promotion by ID
+// @Description  Get a promotion (admin only)
+// @Tags         promotions
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Promotion ID"
+// @Success      200 {object} models.Promotion
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /promotions/{id} [get]
+func (h *handlerImpl) GetPromotion(c *gin.Context) {
+	id := c.Param("id")
+
+	promotion, err := h.service.GetPromotionByID(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err, "get_promotion")
+		return
+	}
+
+	c.JSON(http.StatusOK, promotion)
+}
+
+// UpdatePromotion
+// @Summary      Update promotion
+// @Description  Replace a promotion's details (admin only)
+// @Tags         promotions
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Promotion ID"
+// @Param        request body models.CreatePromotionRequest true "Promotion details"
+// @Success      200 {object} models.Promotion
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /promotions/{id} [put]
+func (h *handlerImpl) UpdatePromotion(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.CreatePromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "update_promotion_validation")
+		return
+	}
+
+	promotion, err := h.service.UpdatePromotion(c.Request.Context(), id, &req)
+	if err != nil {
+		h.handleError(c, err, "update_promotion")
+		return
+	}
+
+	c.JSON(http.StatusOK, promotion)
+}
+
+// DeletePromotion
+// @Summary      Delete promotion
+// @Description  Delete a promotion (admin only). Orders it was applied to keep its name and saving.
+// @Tags         promotions
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Promotion ID"
+// @Success      204 "No Content"
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /promotions/{id} [delete]
+func (h *handlerImpl) DeletePromotion(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.service.DeletePromotion(c.Request.Context(), id); err != nil {
+		h.handleError(c, err, "delete_promotion")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// RequestReturn
+// @Summary      Request a return
+// @Description  Ask to return some of the items of a delivered order, with a reason for each
+// @Tags         returns
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Order ID"
+// @Param        request body models.CreateReturnRequest true "Items to return"
+// @Success      201 {object} models.Return
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse "Admins can't act on another user's order"
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /orders/{id}/returns [post]
+func (h *handlerImpl) RequestReturn(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.CreateReturnRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "request_return_validation")
+		return
+	}
+
+	ret, err := h.service.RequestReturn(c.Request.Context(), id, &req)
+	if err != nil {
+		h.handleError(c, err, "request_return")
+		return
+	}
+
+	c.JSON(http.StatusCreated, ret)
+}
+
+// ListOrderReturns
+// @Summary      List order returns
+// @Description  Get the returns of one of the authenticated user's orders with their refunds. Admins can list the returns of any order.
+// @Tags         returns
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Order ID"
+// @Success      200 {array} models.Return
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /orders/{id}/returns [get]
+func (h *handlerImpl) ListOrderReturns(c *gin.Context) {
+	id := c.Param("id")
+
+	returns, err := h.service.ListOrderReturns(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err, "list_order_returns")
+		return
+	}
+
+	c.JSON(http.StatusOK, returns)
+}
+
+// ListReturns
+// @Summary      List returns
+// @Description  Get all returns, newest first (admin only)
+// @Tags         returns
+// @Accept       json
+// @Produce      json
+// @Param        status query string false "Only returns in this status" Enums(requested, approved, rejected, received)
+// @Success      200 {array} models.Return
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /returns [get]
+func (h *handlerImpl) ListReturns(c *gin.Context) {
+	returns, err := h.service.ListReturns(c.Request.Context(), models.ReturnStatus(c.Query("status")))
+	if err != nil {
+		h.handleError(c, err, "list_returns")
+		return
+	}
+
+	c.JSON(http.StatusOK, returns)
+}
+
+// GetReturn
+// @Summary      Get return
+// @Description  Get a return with its items and refund (admin only)
+// @Tags         returns
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Return ID"
+// @Success      200 {object} models.Return
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /returns/{id} [get]
+func (h *handlerImpl) GetReturn(c *gin.Context) {
+	ret, err := h.service.GetReturn(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.handleError(c, err, "get_return")
+		return
+	}
+
+	c.JSON(http.StatusOK, ret)
+}
+
+// ApproveReturn
+// @Summary      Approve return
+// @Description  Approve a requested return so the customer can send the items back (admin only)
+// @Tags         returns
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Return ID"
+// @Param        request body models.ResolveReturnRequest false "Note to the customer"
+// @Success      200 {object} models.Return
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /returns/{id}/approve [post]
+func (h *handlerImpl) ApproveReturn(c *gin.Context) {
+	var req models.ResolveReturnRequest
+	if !bindOptionalJSON(c, &req) {
+		h.handleError(c, pkg.ErrInvalidInput, "approve_return_validation")
+		return
+	}
+
+	ret, err := h.service.ApproveReturn(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		h.handleError(c, err, "approve_return")
+		return
+	}
+
+	c.JSON(http.StatusOK, ret)
+}
+
+// RejectReturn
+// @Summary      Reject return
+// @Description  Reject a requested return (admin only)
+// @Tags         returns
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Return ID"
+// @Param        request body models.ResolveReturnRequest false "Note to the customer"
+// @Success      200 {object} models.Return
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /returns/{id}/reject [post]
+func (h *handlerImpl) RejectReturn(c *gin.Context) {
+	var req models.ResolveReturnRequest
+	if !bindOptionalJSON(c, &req) {
+		h.handleError(c, pkg.ErrInvalidInput, "reject_return_validation")
+		return
+	}
+
+	ret, err := h.service.RejectReturn(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		h.handleError(c, err, "reject_return")
+		return
+	}
+
+	c.JSON(http.StatusOK, ret)
+}
+
+// ReceiveReturn
+// @Summary      Receive returned items
+// @Description  Record the items of an approved return as received, optionally restocking them, and refund what was paid for them (admin only)
+// @Tags         returns
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Return ID"
+// @Param        request body models.ReceiveReturnRequest false "Receipt details"
+// @Success      200 {object} models.Return
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /returns/{id}/receive [post]
+func (h *handlerImpl) ReceiveReturn(c *gin.Context) {
+	var req models.ReceiveReturnRequest
+	if !bindOptionalJSON(c, &req) {
+		h.handleError(c, pkg.ErrInvalidInput, "receive_return_validation")
+		return
+	}
+
+	ret, err := h.service.ReceiveReturn(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		h.handleError(c, err, "receive_return")
+		return
+	}
+
+	c.JSON(http.StatusOK, ret)
+}
+
+// bindOptionalJSON binds the request body if there is one. It reports false if the body is invalid.
+func bindOptionalJSON(c *gin.Context, obj any) bool {
+	if c.Request.ContentLength == 0 {
+		return true
+	}
+	return c.ShouldBindJSON(obj) == nil
+}
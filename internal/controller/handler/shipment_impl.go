@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// CreateShipment
+// @Summary      Ship order items
+// @Description  Record a shipment of some or all of a paid order's items with its carrier and tracking number (admin only). Without items, everything not shipped yet is included. The order becomes partially shipped or shipped.
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Order ID"
+// @Param        request body models.CreateShipmentRequest true "Shipment details"
+// @Success      201 {object} models.Shipment
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /orders/{id}/shipments [post]
+func (h *handlerImpl) CreateShipment(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.CreateShipmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "create_shipment_validation")
+		return
+	}
+
+	shipment, err := h.service.CreateShipment(c.Request.Context(), id, &req)
+	if err != nil {
+		h.handleError(c, err, "create_shipment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, shipment)
+}
+
+// MarkShipmentDelivered
+// @Summary      Mark shipment delivered
+// @Description  Record the delivery of a shipment (admin only). The order becomes delivered once all items have shipped and every shipment has arrived.
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Order ID"
+// @Param        shipment_id path string true "Shipment ID"
+// @Success      200 {object} models.Shipment
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /orders/{id}/shipments/{shipment_id}/delivered [post]
+func (h *handlerImpl) MarkShipmentDelivered(c *gin.Context) {
+	shipment, err := h.service.MarkShipmentDelivered(c.Request.Context(), c.Param("id"), c.Param("shipment_id"))
+	if err != nil {
+		h.handleError(c, err, "mark_shipment_delivered")
+		return
+	}
+
+	c.JSON(http.StatusOK, shipment)
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// CreateShippingMethod
+// @Summary      Create a shipping method
+// @Description  Create a flat rate or weight-based shipping method rated by a carrier (admin only). Orders worth at least free_over ship for free; methods without countries deliver everywhere.
+// @Tags         shipping
+// @Accept       json
+// @Produce      json
+// @Param        request body models.CreateShippingMethodRequest true "Shipping method details"
+// @Success      201 {object} models.ShippingMethod
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /shipping-methods [post]
+func (h *handlerImpl) CreateShippingMethod(c *gin.Context) {
+	var req models.CreateShippingMethodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "create_shipping_method_validation")
+		return
+	}
+
+	method, err := h.service.CreateShippingMethod(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, err, "create_shipping_method")
+		return
+	}
+
+	c.JSON(http.StatusCreated, method)
+}
+
+// ListShippingMethods
+// @Summary      List shipping methods
+// @Description  Get all shipping methods, including inactive ones (admin only)
+// @Tags         shipping
+// @Accept       json
+// @Produce      json
+// @Success      200 {array} models.ShippingMethod
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /shipping-methods [get]
+func (h *handlerImpl) ListShippingMethods(c *gin.Context) {
+	methods, err := h.service.ListShippingMethods(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err, "list_shipping_methods")
+		return
+	}
+
+	c.JSON(http.StatusOK, methods)
+}
+
+// UpdateShippingMethod
+// @Summary      Update shipping method
+// @Description  Update a shipping method (admin only). Existing orders keep the shipping charge they were quoted.
+// @Tags         shipping
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Shipping method ID"
+// @Param        request body models.CreateShippingMethodRequest true "Shipping method details"
+// @Success      200 {object} models.ShippingMethod
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /shipping-methods/{id} [put]
+func (h *handlerImpl) UpdateShippingMethod(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.CreateShippingMethodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "update_shipping_method_validation")
+		return
+	}
+
+	method, err := h.service.UpdateShippingMethod(c.Request.Context(), id, &req)
+	if err != nil {
+		h.handleError(c, err, "update_shipping_method")
+		return
+	}
+
+	c.JSON(http.StatusOK, method)
+}
+
+// DeleteShippingMethod
+// @Summary      Delete shipping method
+// @Description  Delete a shipping method (admin only)
+// @Tags         shipping
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Shipping method ID"
+// @Success      204 "No Content"
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /shipping-methods/{id} [delete]
+func (h *handlerImpl) DeleteShippingMethod(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.service.DeleteShippingMethod(c.Request.Context(), id); err != nil {
+		h.handleError(c, err, "delete_shipping_method")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// QuoteShipping
+// @Summary      Quote shipping
+// @Description  Get the shipping methods available for a cart and destination with their price. The destination defaults to the customer's default address.
+// @Tags         shipping
+// @Accept       json
+// @Produce      json
+// @Param        request body models.ShippingQuoteRequest true "Cart and destination"
+// @Success      200 {array} models.ShippingOption
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /shipping/quote [post]
+func (h *handlerImpl) QuoteShipping(c *gin.Context) {
+	var req models.ShippingQuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "quote_shipping_validation")
+		return
+	}
+
+	options, err := h.service.QuoteShipping(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, err, "quote_shipping")
+		return
+	}
+
+	c.JSON(http.StatusOK, options)
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListLowStockProducts
+// @Summary      List low stock products
+// @Description  Get the products whose stock is at or below their reorder threshold (admin only)
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Success      200 {array} models.Product
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /products/low-stock [get]
+func (h *handlerImpl) ListLowStockProducts(c *gin.Context) {
+	products, err := h.service.ListLowStockProducts(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err, "list_low_stock_products")
+		return
+	}
+
+	c.JSON(http.StatusOK, products)
+}
+
+// SubscribeBackInStock
+// @Summary      Subscribe to back in stock alerts
+// @Description  Get notified once when an out of stock product is available again
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Product ID"
+// @Success      201 {object} models.StockSubscription
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse "Product in stock or already subscribed"
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /products/{id}/subscription [post]
+func (h *handlerImpl) SubscribeBackInStock(c *gin.Context) {
+	id := c.Param("id")
+	userID := c.GetString("user_id")
+
+	subscription, err := h.service.SubscribeBackInStock(c.Request.Context(), userID, id)
+	if err != nil {
+		h.handleError(c, err, "subscribe_back_in_stock")
+		return
+	}
+
+	c.JSON(http.StatusCreated, subscription)
+}
+
+// UnsubscribeBackInStock
+// @Summary      Unsubscribe from back in stock alerts
+// @Description  Cancel a pending back in stock alert for a product
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Product ID"
+// @Success      204 "No Content"
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /products/{id}/subscription [delete]
+func (h *handlerImpl) UnsubscribeBackInStock(c *gin.Context) {
+	id := c.Param("id")
+	userID := c.GetString("user_id")
+
+	if err := h.service.UnsubscribeBackInStock(c.Request.Context(), userID, id); err != nil {
+		h.handleError(c, err, "unsubscribe_back_in_stock")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// CreateTaxRate
+// @Summary      Create a tax rate
+// @Description  Set the tax percentage of a product tax class in a region (admin only). Regions are country codes or subdivisions such as US-CA.
+// @Tags         tax
+// @Accept       json
+// @Produce      json
+// @Param        request body models.CreateTaxRateRequest true "Tax rate details"
+// @Success      201 {object} models.TaxRate
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /tax-rates [post]
+func (h *handlerImpl) CreateTaxRate(c *gin.Context) {
+	var req models.CreateTaxRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "create_tax_rate_validation")
+		return
+	}
+
+	rate, err := h.service.CreateTaxRate(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, err, "create_tax_rate")
+		return
+	}
+
+	c.JSON(http.StatusCreated, rate)
+}
+
+// ListTaxRates
+// @Summary      List tax rates
+// @Description  Get all tax rates by region and tax class (admin only)
+// @Tags         tax
+// @Accept       json
+// @Produce      json
+// @Success      200 {array} models.TaxRate
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /tax-rates [get]
+func (h *handlerImpl) ListTaxRates(c *gin.Context) {
+	rates, err := h.service.ListTaxRates(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err, "list_tax_rates")
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// UpdateTaxRate
+// @Summary      Update tax rate
+// @Description  Update a tax rate (admin only). Existing orders keep the rate they were charged.
+// @Tags         tax
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Tax rate ID"
+// @Param        request body models.CreateTaxRateRequest true "Tax rate details"
+// @Success      200 {object} models.TaxRate
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /tax-rates/{id} [put]
+func (h *handlerImpl) UpdateTaxRate(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.CreateTaxRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "update_tax_rate_validation")
+		return
+	}
+
+	rate, err := h.service.UpdateTaxRate(c.Request.Context(), id, &req)
+	if err != nil {
+		h.handleError(c, err, "update_tax_rate")
+		return
+	}
+
+	c.JSON(http.StatusOK, rate)
+}
+
+// DeleteTaxRate
+// @Summary      Delete tax rate
+// @Description  Delete a tax rate (admin only)
+// @Tags         tax
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Tax rate ID"
+// @Success      204 "No Content"
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /tax-rates/{id} [delete]
+func (h *handlerImpl) DeleteTaxRate(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.service.DeleteTaxRate(c.Request.Context(), id); err != nil {
+		h.handleError(c, err, "delete_tax_rate")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// CreateWarehouse
+// @Summary      Create a new warehouse
+// @Description  Create a new warehouse (admin only). Lower priority values are used first when fulfilling orders.
+// @Tags         warehouses
+// @Accept       json
+// @Produce      json
+// @Param        request body models.CreateWarehouseRequest true "Warehouse details"
+// @Success      201 {object} models.Warehouse
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /warehouses [post]
+func (h *handlerImpl) CreateWarehouse(c *gin.Context) {
+	var req models.CreateWarehouseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "create_warehouse_validation")
+		return
+	}
+
+	warehouse, err := h.service.CreateWarehouse(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, err, "create_warehouse")
+		return
+	}
+
+	c.JSON(http.StatusCreated, warehouse)
+}
+
+// ListWarehouses
+// @Summary      List warehouses
+// @Description  Get all warehouses in priority order (admin only)
+// @Tags         warehouses
+// @Accept       json
+// @Produce      json
+// @Success      200 {array} models.Warehouse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /warehouses [get]
+func (h *handlerImpl) ListWarehouses(c *gin.Context) {
+	warehouses, err := h.service.ListWarehouses(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err, "list_warehouses")
+		return
+	}
+
+	c.JSON(http.StatusOK, warehouses)
+}
+
+// UpdateWarehouse
+// @Summary      Update warehouse
+// @Description  Update warehouse details (admin only)
+// @Tags         warehouses
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Warehouse ID"
+// @Param        request body models.CreateWarehouseRequest true "Warehouse details"
+// @Success      200 {object} models.Warehouse
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /warehouses/{id} [put]
+func (h *handlerImpl) UpdateWarehouse(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.CreateWarehouseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "update_warehouse_validation")
+		return
+	}
+
+	warehouse, err := h.service.UpdateWarehouse(c.Request.Context(), id, &req)
+	if err != nil {
+		h.handleError(c, err, "update_warehouse")
+		return
+	}
+
+	c.JSON(http.StatusOK, warehouse)
+}
+
+// GetProductStock
+// @Summary      Get product stock per warehouse
+// @Description  Get the stock levels of a product in every warehouse (admin only)
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Product ID"
+// @Success      200 {array} models.WarehouseStock
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /products/{id}/stock [get]
+func (h *handlerImpl) GetProductStock(c *gin.Context) {
+	id := c.Param("id")
+
+	levels, err := h.service.GetProductStock(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err, "get_product_stock")
+		return
+	}
+
+	c.JSON(http.StatusOK, levels)
+}
+
+// SetWarehouseStock
+// @Summary      Set warehouse stock
+// @Description  Set the stock level of a product in a warehouse (admin only)
+// @Tags         warehouses
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Warehouse ID"
+// @Param        product_id path string true "Product ID"
+// @Param        request body models.SetStockRequest true "Stock level"
+// @Success      204 "No Content"
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /warehouses/{id}/stock/{product_id} [put]
+func (h *handlerImpl) SetWarehouseStock(c *gin.Context) {
+	id := c.Param("id")
+	productID := c.Param("product_id")
+
+	var req models.SetStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "set_warehouse_stock_validation")
+		return
+	}
+
+	if err := h.service.SetWarehouseStock(c.Request.Context(), id, productID, req.Quantity); err != nil {
+		h.handleError(c, err, "set_warehouse_stock")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zde37/instashop-task/internal/payment"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// PaymentWebhook
+// @Summary      Receive payment webhook
+// @Description  Callback for the payment provider to report captured, failed and refunded payments. Requests are signed with the shared secret in the X-Payment-Signature header; events are processed once by their ID.
+// @Tags         payments
+// @Accept       json
+// @Produce      json
+// @Param        X-Payment-Signature header string true "t=<unix timestamp>,v1=<hex HMAC-SHA256 of timestamp.body>"
+// @Param        request body payment.Event true "Payment event"
+// @Success      200
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Router       /webhooks/payments [post]
+func (h *handlerImpl) PaymentWebhook(c *gin.Context) {
+	payload, err := c.GetRawData()
+	if err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "payment_webhook_body")
+		return
+	}
+
+	err = h.service.HandlePaymentWebhook(c.Request.Context(), payload, c.GetHeader(payment.SignatureHeader))
+	if err != nil {
+		h.handleError(c, err, "payment_webhook")
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
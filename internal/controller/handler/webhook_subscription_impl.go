@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// CreateWebhookSubscription
+// @Summary      Create a webhook subscription
+// @Description  Subscribe a partner endpoint to events (admin only). Each event is POSTed as JSON with an X-Instashop-Signature header of the form t=<unix timestamp>,v1=<hex HMAC-SHA256 of "<timestamp>.<body>"> computed with the secret. Failed deliveries are retried with exponential backoff.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        request body models.CreateWebhookSubscriptionRequest true "Subscription details"
+// @Success      201 {object} models.WebhookSubscription
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /admin/webhooks [post]
+func (h *handlerImpl) CreateWebhookSubscription(c *gin.Context) {
+	var req models.CreateWebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "create_webhook_subscription_validation")
+		return
+	}
+
+	subscription, err := h.service.CreateWebhookSubscription(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, err, "create_webhook_subscription")
+		return
+	}
+
+	c.JSON(http.StatusCreated, subscription)
+}
+
+// ListWebhookSubscriptions
+// @Summary      List webhook subscriptions
+// @Description  Get all webhook subscriptions (admin only)
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Success      200 {array} models.WebhookSubscription
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /admin/webhooks [get]
+func (h *handlerImpl) ListWebhookSubscriptions(c *gin.Context) {
+	subscriptions, err := h.service.ListWebhookSubscriptions(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err, "list_webhook_subscriptions")
+		return
+	}
+
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+// GetWebhookSubscription
+// @Summary      Get webhook subscription
+// @Description  Get a webhook subscription (admin only)
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Subscription ID"
+// @Success      200 {object} models.WebhookSubscription
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /admin/webhooks/{id} [get]
+func (h *handlerImpl) GetWebhookSubscription(c *gin.Context) {
+	id := c.Param("id")
+
+	subscription, err := h.service.GetWebhookSubscription(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err, "get_webhook_subscription")
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// UpdateWebhookSubscription
+// @Summary      Update webhook subscription
+// @Description  Update a webhook subscription (admin only). Leave out the secret to keep the current one.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Subscription ID"
+// @Param        request body models.CreateWebhookSubscriptionRequest true "Subscription details"
+// @Success      200 {object} models.WebhookSubscription
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /admin/webhooks/{id} [put]
+func (h *handlerImpl) UpdateWebhookSubscription(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.CreateWebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "update_webhook_subscription_validation")
+		return
+	}
+
+	subscription, err := h.service.UpdateWebhookSubscription(c.Request.Context(), id, &req)
+	if err != nil {
+		h.handleError(c, err, "update_webhook_subscription")
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// DeleteWebhookSubscription
+// @Summary      Delete webhook subscription
+// @Description  Delete a webhook subscription together with its deliveries (admin only)
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Subscription ID"
+// @Success      204 "No Content"
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /admin/webhooks/{id} [delete]
+func (h *handlerImpl) DeleteWebhookSubscription(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.service.DeleteWebhookSubscription(c.Request.Context(), id); err != nil {
+		h.handleError(c, err, "delete_webhook_subscription")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries
+// @Summary      List webhook deliveries
+// @Description  Get the most recent deliveries of a webhook subscription, newest first (admin only)
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Subscription ID"
+// @Param        status query string false "Only deliveries in this status" Enums(pending, succeeded, dead)
+// @Param        limit query int false "Number of deliveries, at most 100" default(50)
+// @Success      200 {array} models.WebhookDelivery
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /admin/webhooks/{id}/deliveries [get]
+func (h *handlerImpl) ListWebhookDeliveries(c *gin.Context) {
+	id := c.Param("id")
+
+	var filter models.WebhookDeliveryFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		h.handleError(c, pkg.ErrInvalidInput, "list_webhook_deliveries_validation")
+		return
+	}
+
+	deliveries, err := h.service.ListWebhookDeliveries(c.Request.Context(), id, filter)
+	if err != nil {
+		h.handleError(c, err, "list_webhook_deliveries")
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// GetWebhookDelivery
+// @Summary      Get webhook delivery
+// @Description  Get a webhook delivery with the history of its attempts (admin only)
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Subscription ID"
+// @Param        delivery_id path string true "Delivery ID"
+// @Success      200 {object} models.WebhookDelivery
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /admin/webhooks/{id}/deliveries/{delivery_id} [get]
+func (h *handlerImpl) GetWebhookDelivery(c *gin.Context) {
+	id := c.Param("id")
+	deliveryID := c.Param("delivery_id")
+
+	delivery, err := h.service.GetWebhookDelivery(c.Request.Context(), id, deliveryID)
+	if err != nil {
+		h.handleError(c, err, "get_webhook_delivery")
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// RedeliverWebhook
+// @Summary      Redeliver webhook
+// @Description  Send a delivery again, including dead and succeeded ones (admin only). The delivery is queued with a fresh set of attempts and sent within seconds.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Subscription ID"
+// @Param        delivery_id path string true "Delivery ID"
+// @Success      202 {object} models.WebhookDelivery
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Security     Bearer
+// @Router       /admin/webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+func (h *handlerImpl) RedeliverWebhook(c *gin.Context) {
+	id := c.Param("id")
+	deliveryID := c.Param("delivery_id")
+
+	delivery, err := h.service.RedeliverWebhook(c.Request.Context(), id, deliveryID)
+	if err != nil {
+		h.handleError(c, err, "redeliver_webhook")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
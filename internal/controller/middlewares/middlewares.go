@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/zde37/instashop-task/internal/auth"
 	"github.com/zde37/instashop-task/internal/models"
 	"github.com/zde37/instashop-task/pkg"
 )
@@ -65,6 +66,10 @@ func Auth(token *pkg.JWTMaker) gin.HandlerFunc {
 		ctx.Set("user_id", claims.UserID)
 		ctx.Set("user_email", claims.UserEmail)
 		ctx.Set("user_role", claims.UserRole)
+		ctx.Request = ctx.Request.WithContext(auth.WithActor(ctx.Request.Context(), auth.Actor{
+			UserID: claims.UserID,
+			Role:   claims.UserRole,
+		}))
 
 		ctx.Next()
 	}
@@ -83,7 +88,7 @@ func AdminRequired() gin.HandlerFunc {
 			return
 		}
 
-		if userRole != "admin" {
+		if userRole != models.RoleAdmin {
 			ctx.AbortWithStatusJSON(http.StatusForbidden, models.ErrorResponse{
 				Code:    "ADMIN_REQUIRED",
 				Message: "This operation requires admin privileges",
@@ -18,6 +18,11 @@ func RegisterAllRoutes(rg *gin.RouterGroup, handler handler.Handler, jwt *pkg.JW
 
 	rg.POST("/register", handler.Register)
 	rg.POST("/login", handler.Login)
+	rg.POST("/password/forgot", handler.ForgotPassword)
+	rg.POST("/password/reset", handler.ResetPassword)
+
+	// payment provider callbacks are authenticated by their signature
+	rg.POST("/webhooks/payments", handler.PaymentWebhook)
 
 	// protected routes
 	api := rg.Group("/")
@@ -27,25 +32,140 @@ func RegisterAllRoutes(rg *gin.RouterGroup, handler handler.Handler, jwt *pkg.JW
 		{
 			products.GET("", handler.ListProducts)
 			products.GET("/:id", handler.GetProduct)
+			products.POST("/:id/subscription", handler.SubscribeBackInStock)
+			products.DELETE("/:id/subscription", handler.UnsubscribeBackInStock)
 
 			products.Use(middlewares.AdminRequired())
 			{
 				products.POST("", handler.CreateProduct)
 				products.PUT("/:id", handler.UpdateProduct)
+				products.PATCH("/:id", handler.PatchProduct)
 				products.DELETE("/:id", handler.DeleteProduct)
+				products.PUT("/:id/status", handler.UpdateProductStatus)
+				products.POST("/:id/archive", handler.ArchiveProduct)
+				products.POST("/:id/unarchive", handler.UnarchiveProduct)
+				products.GET("/:id/prices", handler.ListProductPrices)
+				products.POST("/:id/prices", handler.SchedulePrice)
+				products.DELETE("/:id/prices/:price_id", handler.CancelScheduledPrice)
+				products.GET("/:id/stock", handler.GetProductStock)
+				products.GET("/low-stock", handler.ListLowStockProducts)
 			}
 		}
 
+		warehouses := api.Group("/warehouses")
+		warehouses.Use(middlewares.AdminRequired())
+		{
+			warehouses.POST("", handler.CreateWarehouse)
+			warehouses.GET("", handler.ListWarehouses)
+			warehouses.PUT("/:id", handler.UpdateWarehouse)
+			warehouses.PUT("/:id/stock/:product_id", handler.SetWarehouseStock)
+		}
+
+		coupons := api.Group("/coupons")
+		coupons.Use(middlewares.AdminRequired())
+		{
+			coupons.POST("", handler.CreateCoupon)
+			coupons.GET("", handler.ListCoupons)
+			coupons.GET("/:id", handler.GetCoupon)
+			coupons.PUT("/:id", handler.UpdateCoupon)
+			coupons.DELETE("/:id", handler.DeleteCoupon)
+		}
+
+		promotions := api.Group("/promotions")
+		promotions.Use(middlewares.AdminRequired())
+		{
+			promotions.POST("", handler.CreatePromotion)
+			promotions.GET("", handler.ListPromotions)
+			promotions.GET("/:id", handler.GetPromotion)
+			promotions.PUT("/:id", handler.UpdatePromotion)
+			promotions.DELETE("/:id", handler.DeletePromotion)
+		}
+
+		taxRates := api.Group("/tax-rates")
+		taxRates.Use(middlewares.AdminRequired())
+		{
+			taxRates.POST("", handler.CreateTaxRate)
+			taxRates.GET("", handler.ListTaxRates)
+			taxRates.PUT("/:id", handler.UpdateTaxRate)
+			taxRates.DELETE("/:id", handler.DeleteTaxRate)
+		}
+
+		shippingMethods := api.Group("/shipping-methods")
+		shippingMethods.Use(middlewares.AdminRequired())
+		{
+			shippingMethods.POST("", handler.CreateShippingMethod)
+			shippingMethods.GET("", handler.ListShippingMethods)
+			shippingMethods.PUT("/:id", handler.UpdateShippingMethod)
+			shippingMethods.DELETE("/:id", handler.DeleteShippingMethod)
+		}
+
+		api.POST("/shipping/quote", handler.QuoteShipping)
+
+		addresses := api.Group("/addresses")
+		{
+			addresses.POST("", handler.CreateAddress)
+			addresses.GET("", handler.ListAddresses)
+			addresses.GET("/:id", handler.GetAddress)
+			addresses.PUT("/:id", handler.UpdateAddress)
+			addresses.DELETE("/:id", handler.DeleteAddress)
+			addresses.POST("/:id/default", handler.SetDefaultAddress)
+		}
+
+		notifications := api.Group("/notifications")
+		{
+			notifications.GET("", handler.ListNotifications)
+			notifications.POST("/read-all", handler.MarkAllNotificationsRead)
+			notifications.POST("/:id/read", handler.MarkNotificationRead)
+			notifications.GET("/preferences", handler.GetNotificationPreferences)
+			notifications.PUT("/preferences", handler.UpdateNotificationPreferences)
+		}
+
+		returns := api.Group("/returns")
+		returns.Use(middlewares.AdminRequired())
+		{
+			returns.GET("", handler.ListReturns)
+			returns.GET("/:id", handler.GetReturn)
+			returns.POST("/:id/approve", handler.ApproveReturn)
+			returns.POST("/:id/reject", handler.RejectReturn)
+			returns.POST("/:id/receive", handler.ReceiveReturn)
+		}
+
+		admin := api.Group("/admin")
+		admin.Use(middlewares.AdminRequired())
+		{
+			admin.GET("/orders", handler.ListOrders)
+			admin.POST("/orders/status", handler.BulkUpdateOrderStatus)
+
+			admin.POST("/webhooks", handler.CreateWebhookSubscription)
+			admin.GET("/webhooks", handler.ListWebhookSubscriptions)
+			admin.GET("/webhooks/:id", handler.GetWebhookSubscription)
+			admin.PUT("/webhooks/:id", handler.UpdateWebhookSubscription)
+			admin.DELETE("/webhooks/:id", handler.DeleteWebhookSubscription)
+			admin.GET("/webhooks/:id/deliveries", handler.ListWebhookDeliveries)
+			admin.GET("/webhooks/:id/deliveries/:delivery_id", handler.GetWebhookDelivery)
+			admin.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", handler.RedeliverWebhook)
+		}
+
 		orders := api.Group("/orders")
 		{
 			orders.POST("", handler.CreateOrder)
+			orders.POST("/quote", handler.QuoteOrder)
 			orders.GET("", handler.ListUserOrders)
+			orders.GET("/stream", handler.StreamOrderUpdates)
 			orders.GET("/:id", handler.GetOrder)
 			orders.POST("/:id/cancel", handler.CancelOrder)
+			orders.POST("/:id/pay", handler.PayOrder)
+			orders.POST("/:id/returns", handler.RequestReturn)
+			orders.GET("/:id/returns", handler.ListOrderReturns)
+			orders.GET("/:id/invoice", handler.GetOrderInvoice)
+			orders.GET("/:id/credit-notes", handler.ListOrderCreditNotes)
+			orders.GET("/:id/credit-notes/:credit_note_id", handler.GetOrderCreditNote)
 
 			orders.Use(middlewares.AdminRequired())
 			{
 				orders.PUT("/:id/status", handler.UpdateOrderStatus)
+				orders.POST("/:id/shipments", handler.CreateShipment)
+				orders.POST("/:id/shipments/:shipment_id/delivered", handler.MarkShipmentDelivered)
 			}
 		}
 	}
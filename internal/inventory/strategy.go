@@ -0,0 +1,177 @@
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/zde37/instashop-task/internal/models"
+)
+
+const (
+	// SingleWarehouse fulfils the whole order from one warehouse when any warehouse can,
+	// and falls back to priority order otherwise.
+	SingleWarehouse = "single_warehouse"
+	// PriorityOrder fulfils each line from warehouses in priority order.
+	PriorityOrder = "priority"
+)
+
+// ErrUnfulfillable is returned when the warehouses don't hold enough stock for a line
+var ErrUnfulfillable = errors.New("order cannot be fulfilled from available stock")
+
+// Line is a product quantity that has to be allocated to warehouses
+type Line struct {
+	ProductID string
+	Quantity  int
+}
+
+// Strategy picks the warehouses that fulfil each line of an order.
+// It returns one slice of allocations per line, in the order of the lines.
+type Strategy interface {
+	Allocate(lines []Line, levels []models.WarehouseStock) ([][]models.OrderItemAllocation, error)
+}
+
+// NewStrategy returns the strategy registered under name. An empty name selects SingleWarehouse.
+func NewStrategy(name string) (Strategy, error) {
+	switch name {
+	case "", SingleWarehouse:
+		return singleWarehouse{}, nil
+	case PriorityOrder:
+		return priorityOrder{}, nil
+	default:
+		return nil, fmt.Errorf("unknown fulfilment strategy %q", name)
+	}
+}
+
+type singleWarehouse struct{}
+
+func (singleWarehouse) Allocate(lines []Line, levels []models.WarehouseStock) ([][]models.OrderItemAllocation, error) {
+	stock := newStockTable(levels)
+
+	// pick the highest priority warehouse that can ship every line on its own
+	for _, warehouse := range stock.warehouses {
+		if !stock.canFulfil(warehouse.id, lines) {
+			continue
+		}
+		allocations := make([][]models.OrderItemAllocation, len(lines))
+		for i, line := range lines {
+			allocations[i] = []models.OrderItemAllocation{{
+				WarehouseID:   warehouse.id,
+				WarehouseCode: warehouse.code,
+				Quantity:      line.Quantity,
+			}}
+		}
+		return allocations, nil
+	}
+
+	return priorityOrder{}.Allocate(lines, levels)
+}
+
+type priorityOrder struct{}
+
+func (priorityOrder) Allocate(lines []Line, levels []models.WarehouseStock) ([][]models.OrderItemAllocation, error) {
+	stock := newStockTable(levels)
+	allocations := make([][]models.OrderItemAllocation, len(lines))
+
+	for i, line := range lines {
+		// prefer a single warehouse for the line before splitting it
+		if warehouse, ok := stock.firstWithAtLeast(line.ProductID, line.Quantity); ok {
+			stock.take(warehouse.id, line.ProductID, line.Quantity)
+			allocations[i] = []models.OrderItemAllocation{{
+				WarehouseID:   warehouse.id,
+				WarehouseCode: warehouse.code,
+				Quantity:      line.Quantity,
+			}}
+			continue
+		}
+
+		remaining := line.Quantity
+		for _, warehouse := range stock.warehouses {
+			available := stock.available(warehouse.id, line.ProductID)
+			if available == 0 {
+				continue
+			}
+			quantity := min(available, remaining)
+			stock.take(warehouse.id, line.ProductID, quantity)
+			allocations[i] = append(allocations[i], models.OrderItemAllocation{
+				WarehouseID:   warehouse.id,
+				WarehouseCode: warehouse.code,
+				Quantity:      quantity,
+			})
+			remaining -= quantity
+			if remaining == 0 {
+				break
+			}
+		}
+		if remaining > 0 {
+			return nil, fmt.Errorf("product %s: %w", line.ProductID, ErrUnfulfillable)
+		}
+	}
+
+	return allocations, nil
+}
+
+type warehouseRef struct {
+	id       string
+	code     string
+	priority int
+}
+
+// stockTable is a mutable copy of the stock levels used while allocating
+type stockTable struct {
+	warehouses []warehouseRef
+	quantities map[string]map[string]int // warehouse id -> product id -> quantity
+}
+
+func newStockTable(levels []models.WarehouseStock) *stockTable {
+	table := &stockTable{quantities: make(map[string]map[string]int)}
+	for _, level := range levels {
+		if _, ok := table.quantities[level.WarehouseID]; !ok {
+			table.quantities[level.WarehouseID] = make(map[string]int)
+			table.warehouses = append(table.warehouses, warehouseRef{
+				id:       level.WarehouseID,
+				code:     level.WarehouseCode,
+				priority: level.WarehousePriority,
+			})
+		}
+		table.quantities[level.WarehouseID][level.ProductID] += level.Quantity
+	}
+
+	sort.SliceStable(table.warehouses, func(i, j int) bool {
+		if table.warehouses[i].priority != table.warehouses[j].priority {
+			return table.warehouses[i].priority < table.warehouses[j].priority
+		}
+		return table.warehouses[i].code < table.warehouses[j].code
+	})
+	return table
+}
+
+func (t *stockTable) available(warehouseID, productID string) int {
+	return t.quantities[warehouseID][productID]
+}
+
+func (t *stockTable) take(warehouseID, productID string, quantity int) {
+	t.quantities[warehouseID][productID] -= quantity
+}
+
+func (t *stockTable) firstWithAtLeast(productID string, quantity int) (warehouseRef, bool) {
+	for _, warehouse := range t.warehouses {
+		if t.available(warehouse.id, productID) >= quantity {
+			return warehouse, true
+		}
+	}
+	return warehouseRef{}, false
+}
+
+func (t *stockTable) canFulfil(warehouseID string, lines []Line) bool {
+	needed := make(map[string]int)
+	for _, line := range lines {
+		needed[line.ProductID] += line.Quantity
+	}
+	for productID, quantity := range needed {
+		if t.available(warehouseID, productID) < quantity {
+			return false
+		}
+	}
+	return true
+}
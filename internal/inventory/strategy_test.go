@@ -0,0 +1,187 @@
+package inventory
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/zde37/instashop-task/internal/models"
+)
+
+func level(warehouse string, priority int, productID string, quantity int) models.WarehouseStock {
+	return models.WarehouseStock{
+		WarehouseID:       "wh-" + warehouse,
+		WarehouseCode:     warehouse,
+		WarehousePriority: priority,
+		ProductID:         productID,
+		Quantity:          quantity,
+	}
+}
+
+func allocation(warehouse string, quantity int) models.OrderItemAllocation {
+	return models.OrderItemAllocation{WarehouseID: "wh-" + warehouse, WarehouseCode: warehouse, Quantity: quantity}
+}
+
+type allocationTest struct {
+	name    string
+	lines   []Line
+	levels  []models.WarehouseStock
+	want    [][]models.OrderItemAllocation
+	wantErr error
+}
+
+func runAllocationTests(t *testing.T, strategy Strategy, tests []allocationTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			levels := append([]models.WarehouseStock(nil), tt.levels...)
+			got, err := strategy.Allocate(tt.lines, levels)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Allocate() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Allocate() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(levels, tt.levels) {
+				t.Errorf("Allocate() changed the stock levels to %v", levels)
+			}
+		})
+	}
+}
+
+func TestPriorityOrderAllocate(t *testing.T) {
+	runAllocationTests(t, priorityOrder{}, []allocationTest{
+		{
+			name:   "single warehouse",
+			lines:  []Line{{ProductID: "p1", Quantity: 3}},
+			levels: []models.WarehouseStock{level("B", 2, "p1", 5), level("A", 1, "p1", 5)},
+			want:   [][]models.OrderItemAllocation{{allocation("A", 3)}},
+		},
+		{
+			name:   "skips a higher priority warehouse that can't ship the whole line",
+			lines:  []Line{{ProductID: "p1", Quantity: 3}},
+			levels: []models.WarehouseStock{level("A", 1, "p1", 2), level("B", 2, "p1", 5)},
+			want:   [][]models.OrderItemAllocation{{allocation("B", 3)}},
+		},
+		{
+			name:   "split in priority order",
+			lines:  []Line{{ProductID: "p1", Quantity: 6}},
+			levels: []models.WarehouseStock{level("C", 3, "p1", 4), level("A", 1, "p1", 2), level("B", 2, "p1", 0)},
+			want:   [][]models.OrderItemAllocation{{allocation("A", 2), allocation("C", 4)}},
+		},
+		{
+			name: "later lines see the stock taken by earlier ones",
+			lines: []Line{
+				{ProductID: "p1", Quantity: 2},
+				{ProductID: "p1", Quantity: 2},
+			},
+			levels: []models.WarehouseStock{level("A", 1, "p1", 3), level("B", 2, "p1", 3)},
+			want: [][]models.OrderItemAllocation{
+				{allocation("A", 2)},
+				{allocation("B", 2)},
+			},
+		},
+		{
+			name:   "priority ties are broken by warehouse code",
+			lines:  []Line{{ProductID: "p1", Quantity: 1}},
+			levels: []models.WarehouseStock{level("B", 1, "p1", 5), level("A", 1, "p1", 5)},
+			want:   [][]models.OrderItemAllocation{{allocation("A", 1)}},
+		},
+		{
+			name:    "insufficient stock",
+			lines:   []Line{{ProductID: "p1", Quantity: 6}},
+			levels:  []models.WarehouseStock{level("A", 1, "p1", 2), level("B", 2, "p1", 3)},
+			wantErr: ErrUnfulfillable,
+		},
+		{
+			name:    "product not stocked",
+			lines:   []Line{{ProductID: "p2", Quantity: 1}},
+			levels:  []models.WarehouseStock{level("A", 1, "p1", 2)},
+			wantErr: ErrUnfulfillable,
+		},
+		{
+			// the stock of inactive warehouses is left out of the levels when it is locked
+			name:    "inactive warehouses",
+			lines:   []Line{{ProductID: "p1", Quantity: 1}},
+			levels:  nil,
+			wantErr: ErrUnfulfillable,
+		},
+	})
+}
+
+func TestSingleWarehouseAllocate(t *testing.T) {
+	runAllocationTests(t, singleWarehouse{}, []allocationTest{
+		{
+			name: "prefers a warehouse that ships every line",
+			lines: []Line{
+				{ProductID: "p1", Quantity: 2},
+				{ProductID: "p2", Quantity: 1},
+			},
+			levels: []models.WarehouseStock{
+				level("A", 1, "p1", 5),
+				level("B", 2, "p1", 2),
+				level("B", 2, "p2", 1),
+			},
+			want: [][]models.OrderItemAllocation{
+				{allocation("B", 2)},
+				{allocation("B", 1)},
+			},
+		},
+		{
+			name:   "counts lines of the same product together",
+			lines:  []Line{{ProductID: "p1", Quantity: 2}, {ProductID: "p1", Quantity: 2}},
+			levels: []models.WarehouseStock{level("A", 1, "p1", 3), level("B", 2, "p1", 4)},
+			want: [][]models.OrderItemAllocation{
+				{allocation("B", 2)},
+				{allocation("B", 2)},
+			},
+		},
+		{
+			name:   "priority ties are broken by warehouse code",
+			lines:  []Line{{ProductID: "p1", Quantity: 1}},
+			levels: []models.WarehouseStock{level("B", 1, "p1", 5), level("A", 1, "p1", 5)},
+			want:   [][]models.OrderItemAllocation{{allocation("A", 1)}},
+		},
+		{
+			name: "falls back to priority order",
+			lines: []Line{
+				{ProductID: "p1", Quantity: 2},
+				{ProductID: "p2", Quantity: 3},
+			},
+			levels: []models.WarehouseStock{
+				level("A", 1, "p1", 2),
+				level("B", 2, "p2", 1),
+				level("C", 3, "p2", 2),
+			},
+			want: [][]models.OrderItemAllocation{
+				{allocation("A", 2)},
+				{allocation("B", 1), allocation("C", 2)},
+			},
+		},
+		{
+			name:    "insufficient stock",
+			lines:   []Line{{ProductID: "p1", Quantity: 1}, {ProductID: "p2", Quantity: 4}},
+			levels:  []models.WarehouseStock{level("A", 1, "p1", 1), level("A", 1, "p2", 3)},
+			wantErr: ErrUnfulfillable,
+		},
+		{
+			// the stock of inactive warehouses is left out of the levels when it is locked
+			name:   "inactive warehouses",
+			lines:  []Line{{ProductID: "p1", Quantity: 2}},
+			levels: []models.WarehouseStock{level("B", 2, "p1", 2)},
+			want:   [][]models.OrderItemAllocation{{allocation("B", 2)}},
+		},
+	})
+}
+
+func TestNewStrategy(t *testing.T) {
+	for name, want := range map[string]Strategy{"": singleWarehouse{}, SingleWarehouse: singleWarehouse{}, PriorityOrder: priorityOrder{}} {
+		got, err := NewStrategy(name)
+		if err != nil || got != want {
+			t.Errorf("NewStrategy(%q) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+	if _, err := NewStrategy("nearest"); err == nil {
+		t.Error("NewStrategy() accepted an unknown strategy")
+	}
+}
@@ -0,0 +1,234 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/zde37/instashop-task/internal/models"
+)
+
+// A4 page in points, with the margins of the printed area
+const (
+	pageWidth    = 595.0
+	pageHeight   = 842.0
+	marginLeft   = 50.0
+	marginRight  = pageWidth - 50.0
+	marginTop    = pageHeight - 60.0
+	marginBottom = 60.0
+)
+
+// RenderPDF renders an invoice or credit note as a PDF document
+func RenderPDF(invoice *models.Invoice) []byte {
+	w := newPageWriter()
+
+	title := "Invoice"
+	if invoice.Kind == models.InvoiceKindCreditNote {
+		title = "Credit note"
+	}
+	w.row(18, cell{x: marginLeft, text: title + " " + invoice.Number})
+	w.space(6)
+	w.row(10, cell{x: marginLeft, text: "Issued: " + invoice.IssuedAt.Format("2006-01-02")})
+	w.row(10, cell{x: marginLeft, text: "Order: " + invoice.OrderID})
+	if invoice.OriginalNumber != nil {
+		w.row(10, cell{x: marginLeft, text: "Corrects invoice: " + *invoice.OriginalNumber})
+	}
+
+	// addresses side by side
+	w.space(12)
+	billing, shipping := addressLines(invoice.BillingAddress), addressLines(invoice.ShippingAddress)
+	w.row(10, cell{x: marginLeft, text: "Bill to"}, cell{x: 300, text: "Ship to"})
+	for i := 0; i < max(len(billing), len(shipping)); i++ {
+		var left, right string
+		if i < len(billing) {
+			left = billing[i]
+		}
+		if i < len(shipping) {
+			right = shipping[i]
+		}
+		w.row(10, cell{x: marginLeft, text: left}, cell{x: 300, text: right})
+	}
+
+	// line items
+	w.space(16)
+	w.row(10,
+		cell{x: marginLeft, text: "Description"},
+		cell{x: 330, text: "Qty", right: true},
+		cell{x: 400, text: "Unit price", right: true},
+		cell{x: 460, text: "Discount", right: true},
+		cell{x: 505, text: "Tax %", right: true},
+		cell{x: marginRight, text: "Total", right: true},
+	)
+	w.rule()
+	for _, line := range invoice.Lines {
+		w.row(10,
+			cell{x: marginLeft, text: truncate(line.Description, 45)},
+			cell{x: 330, text: fmt.Sprint(line.Quantity), right: true},
+			cell{x: 400, text: money(line.UnitPrice), right: true},
+			cell{x: 460, text: money(line.Discount), right: true},
+			cell{x: 505, text: fmt.Sprintf("%g", line.TaxRate), right: true},
+			cell{x: marginRight, text: money(line.Total), right: true},
+		)
+	}
+	w.rule()
+
+	// totals
+	totals := []struct {
+		label  string
+		amount float64
+	}{
+		{"Subtotal", invoice.Subtotal},
+		{"Discounts", -invoice.DiscountTotal},
+		{"Shipping", invoice.ShippingTotal},
+	}
+	taxLabel := "Tax"
+	if invoice.TaxInclusive {
+		taxLabel = "Included tax"
+	}
+	totals = append(totals, struct {
+		label  string
+		amount float64
+	}{taxLabel, invoice.TaxTotal})
+	for _, total := range totals {
+		w.row(10, cell{x: 400, text: total.label}, cell{x: marginRight, text: money(total.amount), right: true})
+	}
+	w.row(12, cell{x: 400, text: "Total"}, cell{x: marginRight, text: money(invoice.Total), right: true})
+
+	return w.bytes()
+}
+
+func addressLines(address *models.OrderAddress) []string {
+	if address == nil {
+		return nil
+	}
+
+	lines := []string{address.FullName, address.Line1}
+	if address.Line2 != "" {
+		lines = append(lines, address.Line2)
+	}
+	city := strings.TrimSpace(address.PostalCode + " " + address.City)
+	if address.Region != "" {
+		city += ", " + address.Region
+	}
+	return append(lines, city, address.Country)
+}
+
+func money(amount float64) string {
+	return fmt.Sprintf("%.2f", amount)
+}
+
+func truncate(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	return string(runes[:length-3]) + "..."
+}
+
+// cell is a piece of text on a row, starting at x or ending at x if right aligned
+type cell struct {
+	x     float64
+	text  string
+	right bool
+}
+
+// pageWriter lays out rows of text from the top of the page down, starting new pages as needed
+type pageWriter struct {
+	pages []*bytes.Buffer
+	y     float64
+}
+
+func newPageWriter() *pageWriter {
+	w := &pageWriter{}
+	w.newPage()
+	return w
+}
+
+func (w *pageWriter) newPage() {
+	w.pages = append(w.pages, &bytes.Buffer{})
+	w.y = marginTop
+}
+
+func (w *pageWriter) space(points float64) {
+	w.y -= points
+}
+
+func (w *pageWriter) row(size float64, cells ...cell) {
+	height := size * 1.4
+	if w.y-height < marginBottom {
+		w.newPage()
+	}
+	w.y -= height
+
+	page := w.pages[len(w.pages)-1]
+	for _, c := range cells {
+		x := c.x
+		if c.right {
+			x -= textWidth(c.text, size)
+		}
+		fmt.Fprintf(page, "BT /F1 %.1f Tf %.2f %.2f Td (%s) Tj ET\n", size, x, w.y, escape(c.text))
+	}
+}
+
+// rule draws a horizontal line under the last row
+func (w *pageWriter) rule() {
+	w.y -= 4
+	fmt.Fprintf(w.pages[len(w.pages)-1], "%.2f %.2f m %.2f %.2f l S\n", marginLeft, w.y, marginRight, w.y)
+}
+
+// bytes assembles the pages into a PDF file
+func (w *pageWriter) bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n")
+	kids := make([]string, len(w.pages))
+	for i := range w.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(w.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	for i, page := range w.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %g %g] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 5+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+// escape writes text as a PDF string literal. Latin-1 characters map to the same WinAnsi
+// codes; anything else can't be shown with the standard fonts and is replaced.
+func escape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 32 && r < 127:
+			b.WriteRune(r)
+		case r >= 160 && r <= 255:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// textWidth estimates the width of text in Helvetica, whose digits are 0.556 em wide
+func textWidth(text string, size float64) float64 {
+	return float64(len([]rune(text))) * 0.556 * size
+}
@@ -0,0 +1,192 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zde37/instashop-task/internal/models"
+)
+
+func testInvoice(lines int) *models.Invoice {
+	invoice := &models.Invoice{
+		OrderID:  "order-1",
+		Kind:     models.InvoiceKindInvoice,
+		Number:   "INV-2024-000001",
+		Subtotal: 10,
+		Total:    10,
+		IssuedAt: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		BillingAddress: &models.OrderAddress{
+			FullName: "Ada Lovelace", Line1: "1 Main St", City: "London", PostalCode: "N1", Country: "GB",
+		},
+	}
+	for i := 0; i < lines; i++ {
+		invoice.Lines = append(invoice.Lines, models.InvoiceLine{
+			Description: fmt.Sprintf("Item %d", i+1), Quantity: 1, UnitPrice: 10, Total: 10,
+		})
+	}
+	return invoice
+}
+
+var (
+	objectHeader = regexp.MustCompile(`^(\d+) 0 obj\n`)
+	textObject   = regexp.MustCompile(`BT /F1 [\d.]+ Tf (-?[\d.]+) (-?[\d.]+) Td \(((?:[^()\\]|\\.)*)\) Tj ET`)
+	streamObject = regexp.MustCompile(`<< /Length (\d+) >>\nstream\n`)
+)
+
+// checkXref checks that every xref entry points at the header of its object and that
+// startxref points at the table
+func checkXref(t *testing.T, pdf []byte) int {
+	t.Helper()
+
+	trailer := bytes.LastIndex(pdf, []byte("startxref\n"))
+	if trailer < 0 || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatal("PDF has no startxref or end of file marker")
+	}
+	start, err := strconv.Atoi(strings.TrimSpace(strings.TrimSuffix(string(pdf[trailer+len("startxref\n"):]), "%%EOF\n")))
+	if err != nil {
+		t.Fatalf("parsing startxref: %v", err)
+	}
+	if !bytes.HasPrefix(pdf[start:], []byte("xref\n")) {
+		t.Fatalf("startxref %d doesn't point at the xref table", start)
+	}
+
+	lines := strings.Split(string(pdf[start:]), "\n")
+	var first, count int
+	if _, err := fmt.Sscanf(lines[1], "%d %d", &first, &count); err != nil || first != 0 {
+		t.Fatalf("xref subsection %q", lines[1])
+	}
+	if lines[2] != "0000000000 65535 f " {
+		t.Errorf("xref entry 0 = %q, want the free list head", lines[2])
+	}
+	for i := 1; i < count; i++ {
+		entry := lines[2+i]
+		if len(entry) != 19 || !strings.HasSuffix(entry, " 00000 n ") {
+			t.Fatalf("xref entry %d = %q", i, entry)
+		}
+		offset, err := strconv.Atoi(entry[:10])
+		if err != nil {
+			t.Fatalf("xref entry %d = %q", i, entry)
+		}
+		match := objectHeader.FindSubmatch(pdf[offset:])
+		if match == nil || string(match[1]) != strconv.Itoa(i) {
+			t.Errorf("xref entry %d points at %q", i, pdf[offset:min(offset+10, len(pdf))])
+		}
+	}
+	if !strings.Contains(string(pdf[start:]), fmt.Sprintf("trailer\n<< /Size %d ", count)) {
+		t.Errorf("trailer /Size doesn't match the %d xref entries", count)
+	}
+	return count
+}
+
+// pageStreams returns the content stream of every page, checking each against its /Length
+func pageStreams(t *testing.T, pdf []byte) []string {
+	t.Helper()
+
+	var streams []string
+	for _, match := range streamObject.FindAllSubmatchIndex(pdf, -1) {
+		length, _ := strconv.Atoi(string(pdf[match[2]:match[3]]))
+		body := pdf[match[1]:]
+		if !bytes.HasPrefix(body[length:], []byte("endstream")) {
+			t.Errorf("stream /Length %d doesn't end at endstream", length)
+			continue
+		}
+		streams = append(streams, string(body[:length]))
+	}
+	return streams
+}
+
+func TestRenderPDFXref(t *testing.T) {
+	for _, lines := range []int{1, 150} {
+		t.Run(fmt.Sprintf("%d lines", lines), func(t *testing.T) {
+			pdf := RenderPDF(testInvoice(lines))
+			if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) {
+				t.Fatalf("PDF starts with %q", pdf[:min(len(pdf), 9)])
+			}
+
+			count := checkXref(t, pdf)
+			// catalog, pages and font, then a page and its contents per page
+			pages := len(pageStreams(t, pdf))
+			if want := 4 + 2*pages; count != want {
+				t.Errorf("xref has %d entries for %d pages, want %d", count, pages, want)
+			}
+		})
+	}
+}
+
+func TestRenderPDFPages(t *testing.T) {
+	// enough lines to end a page on each row of the totals
+	for lines := 1; lines <= 150; lines++ {
+		pdf := RenderPDF(testInvoice(lines))
+		streams := pageStreams(t, pdf)
+		if !bytes.Contains(pdf, []byte(fmt.Sprintf("/Count %d >>", len(streams)))) {
+			t.Errorf("%d lines: page tree doesn't count %d pages", lines, len(streams))
+		}
+
+		var items []string
+		for i, stream := range streams {
+			for _, match := range textObject.FindAllStringSubmatch(stream, -1) {
+				x, _ := strconv.ParseFloat(match[1], 64)
+				y, _ := strconv.ParseFloat(match[2], 64)
+				if x < 0 || x > pageWidth || y < marginBottom || y > marginTop {
+					t.Errorf("%d lines, page %d: %q at %g,%g is outside the printed area", lines, i+1, match[3], x, y)
+				}
+				if strings.HasPrefix(match[3], "Item ") {
+					items = append(items, match[3])
+				}
+			}
+		}
+
+		// every line is printed once, in order, across the pages
+		if len(items) != lines {
+			t.Fatalf("printed %d of %d lines", len(items), lines)
+		}
+		for i, item := range items {
+			if want := fmt.Sprintf("Item %d", i+1); item != want {
+				t.Fatalf("%d lines: line %d printed as %q, want %q", lines, i+1, item, want)
+			}
+		}
+		if !strings.Contains(streams[len(streams)-1], "(Total) Tj") {
+			t.Errorf("%d lines: totals aren't on the last page", lines)
+		}
+		if lines == 150 && len(streams) < 3 {
+			t.Errorf("150 lines rendered on %d pages", len(streams))
+		}
+	}
+}
+
+func TestRenderPDFEscapesText(t *testing.T) {
+	invoice := testInvoice(0)
+	invoice.Lines = []models.InvoiceLine{{Description: `Cable (2m) \ black)`, Quantity: 1}}
+
+	pdf := RenderPDF(invoice)
+	if !bytes.Contains(pdf, []byte(`(Cable \(2m\) \\ black\)) Tj`)) {
+		t.Errorf("item name isn't escaped in %s", pageStreams(t, pdf)[0])
+	}
+	checkXref(t, pdf)
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"plain", "plain"},
+		{"a (b) c", `a \(b\) c`},
+		{"unbalanced )(", `unbalanced \)\(`},
+		{`back\slash`, `back\\slash`},
+		{`\(`, `\\\(`},
+		{"café £5", `caf\351 \2435`},
+		{"€ 日本", "? ??"},
+		{"tab\tnewline\n", "tab?newline?"},
+	}
+	for _, tt := range tests {
+		if got := escape(tt.text); got != tt.want {
+			t.Errorf("escape(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
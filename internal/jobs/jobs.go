@@ -0,0 +1,377 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// Kinds of the jobs run by the application
+const (
+	KindCleanupSessions   = "session_cleanup"
+	KindExpireOrders      = "order_expiry"
+	KindSendEmail         = "send_email"
+	KindReconcilePayments = "payment_reconciliation"
+	KindSendRefund        = "refund"
+	KindProductSchedules  = "product_schedules"
+	KindProductPrices     = "product_prices"
+	KindRelayOutbox       = "outbox_relay"
+	KindDeliverWebhooks   = "webhook_deliveries"
+	KindPruneJobs         = "job_retention"
+)
+
+const (
+	// DefaultMaxAttempts is the number of times a job is tried unless it says otherwise
+	DefaultMaxAttempts = 5
+	defaultTimeout     = 5 * time.Minute
+	pollInterval       = time.Second
+	baseBackoff        = 10 * time.Second
+	maxBackoff         = time.Hour
+)
+
+// Handler runs a job. A returned error retries the job with exponential backoff until it
+// has been attempted job.MaxAttempts times. Jobs may run more than once, so handlers must
+// be idempotent.
+type Handler func(ctx context.Context, job *models.Job) error
+
+// Definition registers the handler of a kind of job
+type Definition struct {
+	Kind    string
+	Handler Handler
+	// Concurrency is the number of jobs of the kind a runner works on at once, 1 if zero
+	Concurrency int
+	// Timeout bounds a run of a job, 5 minutes if zero
+	Timeout time.Duration
+}
+
+// Schedule enqueues a job of Kind every time Cron fires, or at every multiple of Every for
+// work that is due more often than once a minute
+type Schedule struct {
+	Kind  string
+	Cron  string
+	Every time.Duration
+}
+
+// Store keeps the queue of jobs
+type Store interface {
+	// EnqueueJob adds a job to the queue. It returns false without adding anything if
+	// a job with the same unique key exists.
+	EnqueueJob(ctx context.Context, job *models.Job) (bool, error)
+	// ClaimJobs marks up to limit due jobs of the kind as running until lockedUntil, leased
+	// to the runner and skipping jobs claimed by others, and returns them with their attempt
+	// counted
+	ClaimJobs(ctx context.Context, kind, runner string, now, lockedUntil time.Time, limit int) ([]models.Job, error)
+	// CompleteJob, RetryJob, FailJob and ReleaseJob record the outcome of a job while the
+	// runner still holds its lease, and return pkg.ErrNotFound once it doesn't
+	CompleteJob(ctx context.Context, id, runner string, at time.Time) error
+	RetryJob(ctx context.Context, id, runner string, runAt time.Time, reason string) error
+	FailJob(ctx context.Context, id, runner string, at time.Time, reason string) error
+	// ReleaseJob puts an interrupted job back into the queue without counting the attempt
+	ReleaseJob(ctx context.Context, id, runner string) error
+}
+
+// New returns a job of the kind that is due now
+func New(kind string, payload any) (*models.Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s job payload: %w", kind, err)
+	}
+	return &models.Job{
+		ID:          pkg.GenerateID(),
+		Kind:        kind,
+		Payload:     data,
+		Status:      models.JobQueued,
+		MaxAttempts: DefaultMaxAttempts,
+		RunAt:       time.Now(),
+	}, nil
+}
+
+// NoPayload adapts a function that needs nothing from the job, as scheduled jobs usually don't
+func NoPayload(fn func(ctx context.Context) error) Handler {
+	return func(ctx context.Context, _ *models.Job) error {
+		return fn(ctx)
+	}
+}
+
+// WithPayload adapts a function that takes the job's payload, decoded from JSON into a T
+func WithPayload[T any](fn func(ctx context.Context, payload *T) error) Handler {
+	return func(ctx context.Context, job *models.Job) error {
+		var payload T
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return fmt.Errorf("decoding %s job payload: %w", job.Kind, err)
+		}
+		return fn(ctx, &payload)
+	}
+}
+
+type schedule struct {
+	kind  string
+	cron  Cron
+	every time.Duration
+}
+
+// Runner works off the queue with the registered handlers and enqueues scheduled jobs.
+// Any number of runners can share a queue.
+type Runner struct {
+	// id identifies the runner's leases on the jobs it claims
+	id        string
+	store     Store
+	workers   int
+	defs      []Definition
+	schedules []schedule
+
+	mu      sync.Mutex
+	running map[string]int
+	total   int
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewRunner creates a Runner that works on at most workers jobs at once
+func NewRunner(store Store, workers int, defs ...Definition) *Runner {
+	for i := range defs {
+		if defs[i].Concurrency < 1 {
+			defs[i].Concurrency = 1
+		}
+		if defs[i].Timeout <= 0 {
+			defs[i].Timeout = defaultTimeout
+		}
+	}
+	return &Runner{id: pkg.GenerateID(), store: store, workers: max(workers, 1), defs: defs, running: make(map[string]int)}
+}
+
+// Schedule enqueues jobs of registered kinds on cron schedules. Each run is enqueued once,
+// however many runners share the queue.
+func (r *Runner) Schedule(schedules ...Schedule) error {
+	for _, s := range schedules {
+		if !r.registered(s.Kind) {
+			return fmt.Errorf("scheduling unknown job kind %q", s.Kind)
+		}
+		if s.Every != 0 {
+			if s.Cron != "" || s.Every < time.Second {
+				return fmt.Errorf("scheduling %s: give either a cron expression or an interval of at least a second", s.Kind)
+			}
+			r.schedules = append(r.schedules, schedule{kind: s.Kind, every: s.Every})
+			continue
+		}
+		cron, err := ParseCron(s.Cron)
+		if err != nil {
+			return fmt.Errorf("scheduling %s: %w", s.Kind, err)
+		}
+		r.schedules = append(r.schedules, schedule{kind: s.Kind, cron: cron})
+	}
+	return nil
+}
+
+// Start polls the queue and enqueues scheduled jobs until Stop is called
+func (r *Runner) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	r.wg.Add(2)
+	go func() {
+		defer r.wg.Done()
+		r.poll(ctx)
+	}()
+	go func() {
+		defer r.wg.Done()
+		r.schedule(ctx)
+	}()
+	for _, s := range r.schedules {
+		if s.every > 0 {
+			r.wg.Add(1)
+			go func() {
+				defer r.wg.Done()
+				r.repeat(ctx, s)
+			}()
+		}
+	}
+}
+
+// Stop stops claiming jobs, cancels the running ones and waits for them to return.
+// Cancelled jobs go back into the queue.
+func (r *Runner) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+}
+
+func (r *Runner) registered(kind string) bool {
+	for _, def := range r.defs {
+		if def.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Runner) poll(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for i := range r.defs {
+			if err := r.claim(ctx, &r.defs[i]); err != nil && ctx.Err() == nil {
+				slog.Error("claiming jobs failed", slog.String("kind", r.defs[i].Kind), slog.String("err", err.Error()))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// claim starts as many due jobs of the kind as the concurrency limits allow
+func (r *Runner) claim(ctx context.Context, def *Definition) error {
+	r.mu.Lock()
+	free := min(def.Concurrency-r.running[def.Kind], r.workers-r.total)
+	r.mu.Unlock()
+	if free <= 0 {
+		return nil
+	}
+
+	now := time.Now()
+	// a job that runs past its lease may be claimed again, so the lease outlasts the timeout
+	jobs, err := r.store.ClaimJobs(ctx, def.Kind, r.id, now, now.Add(def.Timeout+time.Minute), free)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.running[def.Kind] += len(jobs)
+	r.total += len(jobs)
+	r.mu.Unlock()
+
+	for i := range jobs {
+		r.wg.Add(1)
+		go func(job *models.Job) {
+			defer r.wg.Done()
+			defer func() {
+				r.mu.Lock()
+				r.running[def.Kind]--
+				r.total--
+				r.mu.Unlock()
+			}()
+			r.run(ctx, def, job)
+		}(&jobs[i])
+	}
+	return nil
+}
+
+// run runs a claimed job and records the outcome
+func (r *Runner) run(ctx context.Context, def *Definition, job *models.Job) {
+	var err error
+	if job.Attempts > job.MaxAttempts {
+		// the job's last worker died while running it
+		err = fmt.Errorf("abandoned after %d attempts", job.MaxAttempts)
+	} else {
+		runCtx, cancel := context.WithTimeout(ctx, def.Timeout)
+		err = handle(runCtx, def.Handler, job)
+		cancel()
+	}
+
+	// the outcome is recorded even while shutting down
+	storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	logger := slog.With(slog.String("job_id", job.ID), slog.String("kind", job.Kind), slog.Int("attempt", job.Attempts))
+	var storeErr error
+	switch {
+	case err == nil:
+		storeErr = r.store.CompleteJob(storeCtx, job.ID, r.id, time.Now())
+	case ctx.Err() != nil:
+		storeErr = r.store.ReleaseJob(storeCtx, job.ID, r.id)
+	case job.Attempts >= job.MaxAttempts:
+		logger.Error("job failed", slog.String("err", err.Error()))
+		storeErr = r.store.FailJob(storeCtx, job.ID, r.id, time.Now(), err.Error())
+	default:
+		logger.Warn("job failed, retrying", slog.String("err", err.Error()))
+		runAt := time.Now().Add(pkg.Backoff(job.Attempts, baseBackoff, maxBackoff))
+		storeErr = r.store.RetryJob(storeCtx, job.ID, r.id, runAt, err.Error())
+	}
+	switch {
+	case errors.Is(storeErr, pkg.ErrNotFound):
+		// the job ran past its lease and may have been claimed by another runner, whose
+		// outcome counts
+		logger.Warn("job lease expired before its outcome was recorded")
+	case storeErr != nil:
+		logger.Error("recording job outcome failed", slog.String("err", storeErr.Error()))
+	}
+}
+
+// handle runs the handler, turning a panic into an error
+func handle(ctx context.Context, handler Handler, job *models.Job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// schedule enqueues the cron scheduled jobs at the start of every minute their schedule matches
+func (r *Runner) schedule(ctx context.Context) {
+	if !slices.ContainsFunc(r.schedules, func(s schedule) bool { return s.every == 0 }) {
+		return
+	}
+
+	for {
+		next := time.Now().Truncate(time.Minute).Add(time.Minute)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		for _, s := range r.schedules {
+			if s.every > 0 || !s.cron.Matches(next) {
+				continue
+			}
+			if err := r.enqueueScheduled(ctx, s.kind, next); err != nil && ctx.Err() == nil {
+				slog.Error("enqueueing scheduled job failed", slog.String("kind", s.kind), slog.String("err", err.Error()))
+			}
+		}
+	}
+}
+
+// repeat enqueues the jobs of an interval schedule at every multiple of its interval
+func (r *Runner) repeat(ctx context.Context, s schedule) {
+	for {
+		next := time.Now().Truncate(s.every).Add(s.every)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		if err := r.enqueueScheduled(ctx, s.kind, next); err != nil && ctx.Err() == nil {
+			slog.Error("enqueueing scheduled job failed", slog.String("kind", s.kind), slog.String("err", err.Error()))
+		}
+	}
+}
+
+func (r *Runner) enqueueScheduled(ctx context.Context, kind string, at time.Time) error {
+	job, err := New(kind, struct{}{})
+	if err != nil {
+		return err
+	}
+	key := kind + "@" + at.UTC().Format(time.RFC3339)
+	job.UniqueKey = &key
+	job.RunAt = at
+
+	_, err = r.store.EnqueueJob(ctx, job)
+	return err
+}
@@ -0,0 +1,54 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type fileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer returns a Mailer that writes every email as an .eml file into dir, which
+// is created if needed. The files open in any mail client, which helps with working on
+// the templates locally.
+func NewFileMailer(dir, from string) (Mailer, error) {
+	if dir == "" {
+		dir = "mail"
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating mail directory: %w", err)
+	}
+	return &fileMailer{dir: dir, from: from}, nil
+}
+
+func (m *fileMailer) Send(_ context.Context, msg *Message) error {
+	now := time.Now()
+	email, err := encode(m.from, msg, now)
+	if err != nil {
+		return fmt.Errorf("encoding email: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), fileSafe(msg.To))
+	if err := os.WriteFile(filepath.Join(m.dir, name), email, 0o644); err != nil {
+		return fmt.Errorf("writing email: %w", err)
+	}
+	return nil
+}
+
+// fileSafe replaces the characters of an address that don't belong in a file name
+func fileSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '@', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
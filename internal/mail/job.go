@@ -0,0 +1,40 @@
+package mail
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Job is a queued email: the template, recipient and data it is rendered from when it is
+// sent. Jobs are stored until they are pruned, so their data must not hold secrets.
+type Job struct {
+	Template Template        `json:"template"`
+	To       string          `json:"to"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// NewJob returns the job that sends the email to the recipient with the data the template
+// expects
+func NewJob(name Template, to string, data any) (*Job, error) {
+	if _, ok := newData[name]; !ok {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s email data: %w", name, err)
+	}
+	return &Job{Template: name, To: to, Data: raw}, nil
+}
+
+// Decode returns the data of the job as a pointer to the type its template expects
+func (j *Job) Decode() (any, error) {
+	newFn, ok := newData[j.Template]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %q", j.Template)
+	}
+	data := newFn()
+	if err := json.Unmarshal(j.Data, data); err != nil {
+		return nil, fmt.Errorf("decoding %s email data: %w", j.Template, err)
+	}
+	return data, nil
+}
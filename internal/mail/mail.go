@@ -0,0 +1,154 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"time"
+)
+
+// Names of the mailers
+const (
+	Log  = "log"
+	File = "file"
+	SMTP = "smtp"
+)
+
+// Message is a rendered email with a plain text and an HTML body
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html"`
+}
+
+// Mailer sends emails. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Config selects and sets up a mailer
+type Config struct {
+	// Mailer is the name of the mailer. It has to be set; the log mailer only sends to the
+	// log, so it is never picked by default.
+	Mailer string
+	// From is the sender address of all emails
+	From string
+	// Dir is where the file mailer writes emails
+	Dir string
+
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+}
+
+// NewMailer returns the mailer named in the config
+func NewMailer(cfg Config) (Mailer, error) {
+	switch cfg.Mailer {
+	case "":
+		return nil, fmt.Errorf("no mailer configured, set it to %s, %s or %s", Log, File, SMTP)
+	case Log:
+		return NewLogMailer(nil), nil
+	case File:
+		return NewFileMailer(cfg.Dir, cfg.From)
+	case SMTP:
+		return NewSMTPMailer(cfg.SMTPAddr, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From)
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", cfg.Mailer)
+	}
+}
+
+type logMailer struct {
+	logger *slog.Logger
+}
+
+// NewLogMailer returns a Mailer that logs the recipient and subject of emails instead of
+// sending them. Bodies are left out since they can hold codes like password resets. A nil
+// logger uses slog.Default().
+func NewLogMailer(logger *slog.Logger) Mailer {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &logMailer{logger: logger}
+}
+
+func (m *logMailer) Send(ctx context.Context, msg *Message) error {
+	m.logger.InfoContext(ctx, "email",
+		slog.String("to", msg.To),
+		slog.String("subject", msg.Subject),
+	)
+	return nil
+}
+
+// encode writes the message as a MIME email with alternative text and HTML parts
+func encode(from string, msg *Message, at time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	body := multipart.NewWriter(&buf)
+
+	var email bytes.Buffer
+	for _, header := range [][2]string{
+		{"From", from},
+		{"To", msg.To},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+		{"Date", at.Format(time.RFC1123Z)},
+		{"Message-ID", fmt.Sprintf("<%s@%s>", messageID(), domain(from))},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + body.Boundary()},
+	} {
+		fmt.Fprintf(&email, "%s: %s\r\n", header[0], header[1])
+	}
+	email.WriteString("\r\n")
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+
+	email.Write(buf.Bytes())
+	return email.Bytes(), nil
+}
+
+func messageID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// domain is the domain part of an address like "Shop <shop@example.com>"
+func domain(address string) string {
+	for i := len(address) - 1; i >= 0; i-- {
+		if address[i] == '@' {
+			d := address[i+1:]
+			if n := len(d); n > 0 && d[n-1] == '>' {
+				d = d[:n-1]
+			}
+			return d
+		}
+	}
+	return "localhost"
+}
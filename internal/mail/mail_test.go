@@ -0,0 +1,60 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogMailerRedactsBodies(t *testing.T) {
+	msg, err := Render(TemplatePasswordReset, "ada@example.com", &PasswordResetData{
+		Email:     "ada@example.com",
+		Token:     "secret-code",
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var logs bytes.Buffer
+	if err := NewLogMailer(slog.New(slog.NewTextHandler(&logs, nil))).Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if !strings.Contains(logs.String(), "ada@example.com") {
+		t.Errorf("log doesn't name the recipient: %s", logs.String())
+	}
+	if strings.Contains(logs.String(), "secret-code") {
+		t.Errorf("log holds the body: %s", logs.String())
+	}
+}
+
+func TestNewMailerRequiresAMailer(t *testing.T) {
+	if _, err := NewMailer(Config{}); err == nil {
+		t.Error("NewMailer() picked a mailer without one configured")
+	}
+}
+
+func TestJobKeepsCodesOut(t *testing.T) {
+	job, err := NewJob(TemplatePasswordReset, "ada@example.com", PasswordResetData{
+		Email:   "ada@example.com",
+		ResetID: "reset-1",
+		Token:   "secret-code",
+	})
+	if err != nil {
+		t.Fatalf("NewJob() error = %v", err)
+	}
+	if strings.Contains(string(job.Data), "secret-code") {
+		t.Errorf("job data holds the code: %s", job.Data)
+	}
+
+	data, err := job.Decode()
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if reset, ok := data.(*PasswordResetData); !ok || reset.ResetID != "reset-1" {
+		t.Errorf("Decode() = %#v, want the reset data", data)
+	}
+}
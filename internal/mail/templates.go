@@ -0,0 +1,142 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/zde37/instashop-task/internal/models"
+)
+
+// Template names an email. Each one has a text template, <name>.txt, that also defines
+// the subject, and an HTML template, <name>.html, rendered into the shared layout.
+type Template string
+
+// Emails sent by the application
+const (
+	TemplateWelcome           Template = "welcome"
+	TemplateOrderConfirmation Template = "order_confirmation"
+	TemplateOrderShipped      Template = "order_shipped"
+	TemplateOrderCancelled    Template = "order_cancelled"
+	TemplatePasswordReset     Template = "password_reset"
+	TemplateBackInStock       Template = "back_in_stock"
+	TemplateLowStock          Template = "low_stock"
+	TemplateStockReplenished  Template = "stock_replenished"
+)
+
+// WelcomeData is the data of the welcome email
+type WelcomeData struct {
+	Email string
+}
+
+// OrderData is the data of the order confirmation and cancellation emails
+type OrderData struct {
+	Order *models.Order
+}
+
+// ShipmentData is the data of the shipping notification
+type ShipmentData struct {
+	Order    *models.Order
+	Shipment *models.Shipment
+	Items    []ShippedItem
+}
+
+// ShippedItem is a line of a shipment
+type ShippedItem struct {
+	Name     string
+	Quantity int
+}
+
+// StockData is the data of the stock emails
+type StockData struct {
+	Product          *models.Product
+	PreviousQuantity int
+}
+
+// PasswordResetData is the data of the password reset email. The code is issued for the
+// reset when the email is sent, so it is never queued with the email.
+type PasswordResetData struct {
+	Email     string
+	ResetID   string
+	Token     string `json:"-"`
+	ExpiresAt time.Time
+}
+
+// newData returns a pointer to the data each template expects
+var newData = map[Template]func() any{
+	TemplateWelcome:           func() any { return &WelcomeData{} },
+	TemplateOrderConfirmation: func() any { return &OrderData{} },
+	TemplateOrderShipped:      func() any { return &ShipmentData{} },
+	TemplateOrderCancelled:    func() any { return &OrderData{} },
+	TemplatePasswordReset:     func() any { return &PasswordResetData{} },
+	TemplateBackInStock:       func() any { return &StockData{} },
+	TemplateLowStock:          func() any { return &StockData{} },
+	TemplateStockReplenished:  func() any { return &StockData{} },
+}
+
+//go:embed templates
+var files embed.FS
+
+var funcs = map[string]any{
+	"money": func(amount float64) string {
+		return fmt.Sprintf("%.2f", amount)
+	},
+	"itemName": func(item models.OrderItem) string {
+		if item.Product != nil {
+			return item.Product.Name
+		}
+		return item.ProductID
+	},
+	"datetime": func(t time.Time) string {
+		return t.UTC().Format("2 Jan 2006 15:04 MST")
+	},
+}
+
+type templates struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+var parsed = map[Template]templates{}
+
+func init() {
+	for _, name := range []Template{
+		TemplateWelcome, TemplateOrderConfirmation, TemplateOrderShipped, TemplateOrderCancelled, TemplatePasswordReset,
+		TemplateBackInStock, TemplateLowStock, TemplateStockReplenished,
+	} {
+		parsed[name] = templates{
+			text: texttemplate.Must(texttemplate.New("").Funcs(funcs).ParseFS(files, "templates/"+string(name)+".txt")),
+			html: htmltemplate.Must(htmltemplate.New("").Funcs(funcs).ParseFS(files, "templates/layout.html", "templates/"+string(name)+".html")),
+		}
+	}
+}
+
+// Render renders the email to the recipient with the data the template expects
+func Render(name Template, to string, data any) (*Message, error) {
+	t, ok := parsed[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := t.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("rendering %s subject: %w", name, err)
+	}
+	if err := t.text.ExecuteTemplate(&text, string(name)+".txt", data); err != nil {
+		return nil, fmt.Errorf("rendering %s text: %w", name, err)
+	}
+	if err := t.html.ExecuteTemplate(&html, "layout", data); err != nil {
+		return nil, fmt.Errorf("rendering %s html: %w", name, err)
+	}
+
+	return &Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
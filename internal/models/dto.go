@@ -1,19 +1,160 @@
 package models
 
+import "time"
+
 type AuthRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,validpassword"`
 }
 
+// ForgotPasswordRequest asks for a password reset code to be emailed
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest sets a new password with a password reset code
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,validpassword"`
+}
+
+// CreateProductRequest holds the product details. The lifecycle fields only apply when creating a product:
+// status defaults to draft, or scheduled if publish_at is set. StockQuantity is stocked in the default
+// warehouse, and on updates the difference to the current total is added to or taken from it.
 type CreateProductRequest struct {
-	Name          string  `json:"name" binding:"required"`
-	Description   string  `json:"description"`
-	Price         float64 `json:"price" binding:"required,gt=0"`
-	StockQuantity int     `json:"stock_quantity" binding:"required,gte=0"`
+	Name             string        `json:"name" binding:"required"`
+	Description      string        `json:"description"`
+	Price            float64       `json:"price" binding:"required,gt=0"`
+	Category         string        `json:"category" binding:"max=100"`
+	TaxClass         TaxClass      `json:"tax_class" binding:"max=50"`
+	WeightKg         float64       `json:"weight_kg" binding:"gte=0"`
+	LengthCm         float64       `json:"length_cm" binding:"gte=0"`
+	WidthCm          float64       `json:"width_cm" binding:"gte=0"`
+	HeightCm         float64       `json:"height_cm" binding:"gte=0"`
+	StockQuantity    int           `json:"stock_quantity" binding:"required,gte=0"`
+	ReorderThreshold int           `json:"reorder_threshold" binding:"gte=0"`
+	Status           ProductStatus `json:"status" binding:"omitempty,oneof=draft scheduled active discontinued"`
+	PublishAt        *time.Time    `json:"publish_at"`
+	UnpublishAt      *time.Time    `json:"unpublish_at"`
+}
+
+type UpdateProductStatusRequest struct {
+	Status      ProductStatus `json:"status" binding:"required,oneof=draft scheduled active discontinued"`
+	PublishAt   *time.Time    `json:"publish_at"`
+	UnpublishAt *time.Time    `json:"unpublish_at"`
+}
+
+// SchedulePriceRequest sets a product's price from EffectiveFrom (now if empty) until
+// EffectiveTo, after which the previous price applies again
+type SchedulePriceRequest struct {
+	Price         float64    `json:"price" binding:"required,gt=0"`
+	EffectiveFrom *time.Time `json:"effective_from"`
+	EffectiveTo   *time.Time `json:"effective_to"`
+}
+
+// ProductDocument is the editable representation of a product that PATCH merge patches are applied to.
+// StockQuantity is the total across warehouses; changing it adjusts the default warehouse only.
+type ProductDocument struct {
+	Name             string   `json:"name" binding:"required"`
+	Description      string   `json:"description"`
+	Price            float64  `json:"price" binding:"gt=0"`
+	Category         string   `json:"category" binding:"max=100"`
+	TaxClass         TaxClass `json:"tax_class" binding:"max=50"`
+	WeightKg         float64  `json:"weight_kg" binding:"gte=0"`
+	LengthCm         float64  `json:"length_cm" binding:"gte=0"`
+	WidthCm          float64  `json:"width_cm" binding:"gte=0"`
+	HeightCm         float64  `json:"height_cm" binding:"gte=0"`
+	StockQuantity    int      `json:"stock_quantity" binding:"gte=0"`
+	ReorderThreshold int      `json:"reorder_threshold" binding:"gte=0"`
+}
+
+// ProductFilter narrows down the products returned by ListProducts
+type ProductFilter struct {
+	IncludeArchived bool `form:"include_archived"`
+	// Statuses limits the products to these statuses, all statuses if empty
+	Statuses []ProductStatus `form:"status" binding:"dive,oneof=draft scheduled active discontinued"`
+}
+
+// OrderFilter narrows down, sorts and pages the orders listed for admins. Sort takes a
+// field name, prefixed with "-" for descending order, and defaults to the newest orders first.
+type OrderFilter struct {
+	Statuses      []OrderStatus `form:"status" binding:"dive,oneof=pending confirmed partially_shipped shipped delivered partially_returned returned cancelled"`
+	CreatedFrom   *time.Time    `form:"created_from"`
+	CreatedTo     *time.Time    `form:"created_to"`
+	CustomerEmail string        `form:"customer_email" binding:"max=255"`
+	MinTotal      *float64      `form:"min_total" binding:"omitempty,gte=0"`
+	MaxTotal      *float64      `form:"max_total" binding:"omitempty,gte=0"`
+	ProductID     string        `form:"product_id"`
+	Sort          string        `form:"sort" binding:"omitempty,oneof=created_at -created_at updated_at -updated_at total_amount -total_amount status -status"`
+	Page          int           `form:"page" binding:"omitempty,gte=1"`
+	PageSize      int           `form:"page_size" binding:"omitempty,gte=1,lte=100"`
+}
+
+// OrderPage is one page of orders with the number of orders matching the filter
+type OrderPage struct {
+	Orders   []Order `json:"orders"`
+	Total    int     `json:"total"`
+	Page     int     `json:"page"`
+	PageSize int     `json:"page_size"`
 }
 
+// UserOrderFilter narrows down and pages a customer's own orders, newest first. Cursor is the
+// next_cursor of the previous page, and include=items loads the items of the listed orders.
+type UserOrderFilter struct {
+	Statuses    []OrderStatus `form:"status" binding:"dive,oneof=pending confirmed partially_shipped shipped delivered partially_returned returned cancelled"`
+	CreatedFrom *time.Time    `form:"created_from"`
+	CreatedTo   *time.Time    `form:"created_to"`
+	Include     []string      `form:"include" binding:"dive,oneof=items"`
+	Cursor      string        `form:"cursor"`
+	Limit       int           `form:"limit" binding:"omitempty,gte=1,lte=100"`
+}
+
+// OrderCursor marks the last order of a page, the next page starts after it
+type OrderCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// UserOrderPage is one page of a customer's orders, with a summary of all their orders that
+// match the filter. NextCursor is empty on the last page.
+type UserOrderPage struct {
+	Orders     []Order      `json:"orders"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+	Summary    OrderSummary `json:"summary"`
+}
+
+// OrderSummary sums up a customer's orders. TotalSpent counts the orders that were paid for,
+// less what was refunded.
+type OrderSummary struct {
+	OrderCount int     `json:"order_count" db:"order_count"`
+	TotalSpent float64 `json:"total_spent" db:"total_spent"`
+}
+
+// BulkUpdateOrderStatusRequest moves several orders to a status at once. Each order is
+// updated on its own, under the same rules as UpdateOrderStatusRequest.
+type BulkUpdateOrderStatusRequest struct {
+	OrderIDs []string    `json:"order_ids" binding:"required,min=1,max=100,unique,dive,required"`
+	Status   OrderStatus `json:"status" binding:"required,oneof=cancelled"`
+}
+
+// BulkOrderStatusResult reports whether the status of one of the orders could be updated
+type BulkOrderStatusResult struct {
+	OrderID string         `json:"order_id"`
+	Updated bool           `json:"updated"`
+	Error   *ErrorResponse `json:"error,omitempty"`
+}
+
+// CreateOrderRequest holds the items of an order. The shipping address is taken from the address
+// book by ID, given inline, or defaults to the customer's default address. Orders without one can be
+// placed, but not with a shipping method. The billing address defaults to the shipping address.
 type CreateOrderRequest struct {
-	Items []CreateOrderItemRequest `json:"items" binding:"required,dive"`
+	Items             []CreateOrderItemRequest `json:"items" binding:"required,dive"`
+	CouponCode        string                   `json:"coupon_code" binding:"max=50"`
+	ShippingMethod    string                   `json:"shipping_method" binding:"max=50"`
+	ShippingAddressID string                   `json:"shipping_address_id"`
+	ShippingAddress   *AddressRequest          `json:"shipping_address"`
+	BillingAddressID  string                   `json:"billing_address_id"`
+	BillingAddress    *AddressRequest          `json:"billing_address"`
 }
 
 type CreateOrderItemRequest struct {
@@ -21,8 +162,143 @@ type CreateOrderItemRequest struct {
 	Quantity  int    `json:"quantity" binding:"required,gt=0"`
 }
 
+// CreateCouponRequest describes a coupon. Percentage coupons take value as a percentage of the
+// eligible items, fixed coupons take it off the eligible items. Coupons with product_ids or
+// categories only apply to matching items.
+type CreateCouponRequest struct {
+	Code           string       `json:"code" binding:"required,max=50"`
+	Description    string       `json:"description"`
+	DiscountType   DiscountType `json:"discount_type" binding:"required,oneof=percentage fixed"`
+	Value          float64      `json:"value" binding:"required,gt=0"`
+	MinOrderValue  float64      `json:"min_order_value" binding:"gte=0"`
+	MaxUses        *int         `json:"max_uses" binding:"omitempty,gt=0"`
+	MaxUsesPerUser *int         `json:"max_uses_per_user" binding:"omitempty,gt=0"`
+	ProductIDs     []string     `json:"product_ids"`
+	Categories     []string     `json:"categories"`
+	StartsAt       *time.Time   `json:"starts_at"`
+	EndsAt         *time.Time   `json:"ends_at"`
+	IsActive       *bool        `json:"is_active"`
+}
+
+// CreatePromotionRequest describes a promotion. Promotions with product_ids or categories
+// only apply to matching items; bundles take their components from product_ids.
+type CreatePromotionRequest struct {
+	Name        string        `json:"name" binding:"required,max=255"`
+	Description string        `json:"description"`
+	Type        PromotionType `json:"type" binding:"required,oneof=buy_x_get_y bundle tiered_price order_threshold"`
+	Rule        PromotionRule `json:"rule"`
+	ProductIDs  []string      `json:"product_ids"`
+	Categories  []string      `json:"categories"`
+	Priority    int           `json:"priority"`
+	Exclusive   bool          `json:"exclusive"`
+	StartsAt    *time.Time    `json:"starts_at"`
+	EndsAt      *time.Time    `json:"ends_at"`
+	IsActive    *bool         `json:"is_active"`
+}
+
+type CreateTaxRateRequest struct {
+	Region   string   `json:"region" binding:"required,max=20"`
+	TaxClass TaxClass `json:"tax_class" binding:"required,max=50"`
+	Name     string   `json:"name" binding:"max=100"`
+	Rate     float64  `json:"rate" binding:"gte=0,lt=100"`
+}
+
+type AddressRequest struct {
+	Label      string `json:"label" binding:"max=50"`
+	FullName   string `json:"full_name" binding:"required,max=255"`
+	Line1      string `json:"line1" binding:"required,max=255"`
+	Line2      string `json:"line2" binding:"max=255"`
+	City       string `json:"city" binding:"required,max=100"`
+	Region     string `json:"region" binding:"max=100"`
+	PostalCode string `json:"postal_code" binding:"max=20"`
+	Country    string `json:"country" binding:"required,iso3166_1_alpha2"`
+	Phone      string `json:"phone" binding:"max=30"`
+}
+
+type CreateAddressRequest struct {
+	AddressRequest
+	IsDefault bool `json:"is_default"`
+}
+
+type CreateShippingMethodRequest struct {
+	Code          string           `json:"code" binding:"required,max=50"`
+	Name          string           `json:"name" binding:"required,max=255"`
+	Carrier       string           `json:"carrier" binding:"required,max=50"`
+	RateType      ShippingRateType `json:"rate_type" binding:"required,oneof=flat weight_based"`
+	BaseRate      float64          `json:"base_rate" binding:"gte=0"`
+	PerKgRate     float64          `json:"per_kg_rate" binding:"gte=0"`
+	FreeOver      *float64         `json:"free_over" binding:"omitempty,gte=0"`
+	Countries     []string         `json:"countries" binding:"dive,iso3166_1_alpha2"`
+	EstimatedDays int              `json:"estimated_days" binding:"gte=0"`
+	IsActive      *bool            `json:"is_active"`
+}
+
+// ShippingQuoteRequest asks for the shipping options of a cart. The destination is taken
+// from the address book by ID, given inline, or defaults to the customer's default address.
+// The coupon to be used for the order counts towards free shipping thresholds.
+type ShippingQuoteRequest struct {
+	Items      []CreateOrderItemRequest `json:"items" binding:"dive"`
+	CouponCode string                   `json:"coupon_code" binding:"max=50"`
+	AddressID  string                   `json:"address_id"`
+	Address    *AddressRequest          `json:"address"`
+}
+
+// UpdateOrderStatusRequest moves an order along by hand. Orders are confirmed by paying
+// for them and the shipping statuses follow from their shipments, so only cancelling is left.
 type UpdateOrderStatusRequest struct {
-	Status OrderStatus `json:"status" binding:"required,oneof=pending confirmed shipped delivered cancelled"`
+	Status OrderStatus `json:"status" binding:"required,oneof=cancelled"`
+}
+
+// PayOrderRequest pays for a pending order with the payment method behind the token
+type PayOrderRequest struct {
+	PaymentToken string `json:"payment_token" binding:"required,max=255"`
+}
+
+// CreateShipmentRequest records a shipment of an order. Without items, everything that
+// hasn't shipped yet goes into the shipment.
+type CreateShipmentRequest struct {
+	Carrier        string                      `json:"carrier" binding:"required,max=50"`
+	TrackingNumber string                      `json:"tracking_number" binding:"max=100"`
+	ShippedAt      *time.Time                  `json:"shipped_at"`
+	Items          []CreateShipmentItemRequest `json:"items" binding:"dive"`
+}
+
+type CreateShipmentItemRequest struct {
+	OrderItemID string `json:"order_item_id" binding:"required"`
+	Quantity    int    `json:"quantity" binding:"required,gt=0"`
+}
+
+type CreateWarehouseRequest struct {
+	Code     string `json:"code" binding:"required,max=50"`
+	Name     string `json:"name" binding:"required"`
+	Priority int    `json:"priority" binding:"gte=0"`
+	IsActive *bool  `json:"is_active"`
+}
+
+type SetStockRequest struct {
+	Quantity int `json:"quantity" binding:"gte=0"`
+}
+
+type CreateReturnRequest struct {
+	Items []CreateReturnItemRequest `json:"items" binding:"required,min=1,dive"`
+	Note  string                    `json:"note" binding:"max=1000"`
+}
+
+type CreateReturnItemRequest struct {
+	OrderItemID string       `json:"order_item_id" binding:"required"`
+	Quantity    int          `json:"quantity" binding:"required,gt=0"`
+	Reason      ReturnReason `json:"reason" binding:"required,oneof=damaged defective wrong_item not_as_described no_longer_needed other"`
+}
+
+type ResolveReturnRequest struct {
+	Note string `json:"note" binding:"max=1000"`
+}
+
+// ReceiveReturnRequest records the goods of an approved return as received. With restock,
+// the items go back into the warehouse they were shipped from.
+type ReceiveReturnRequest struct {
+	Restock bool   `json:"restock"`
+	Note    string `json:"note" binding:"max=1000"`
 }
 
 // ErrorResponse represents the error response structure
@@ -31,3 +307,38 @@ type ErrorResponse struct {
 	Message string `json:"message"`
 	Details any    `json:"details,omitempty"`
 }
+
+// CreateWebhookSubscriptionRequest subscribes an endpoint to events. The secret is required
+// when creating a subscription; updates without one keep the current secret.
+type CreateWebhookSubscriptionRequest struct {
+	URL        string   `json:"url" binding:"required,http_url,max=2048"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,unique,dive,oneof=order.created order.status_changed product.created product.updated product.deleted"`
+	Secret     string   `json:"secret" binding:"omitempty,min=16,max=255"`
+	IsActive   *bool    `json:"is_active"`
+}
+
+// WebhookDeliveryFilter selects the deliveries of a subscription, newest first
+type WebhookDeliveryFilter struct {
+	Status WebhookDeliveryStatus `form:"status" binding:"omitempty,oneof=pending succeeded dead"`
+	Limit  int                   `form:"limit" binding:"omitempty,gte=1,lte=100"`
+}
+
+// NotificationFilter pages a user's notifications, newest first. Cursor is the next_cursor
+// of the previous page.
+type NotificationFilter struct {
+	Unread bool   `form:"unread"`
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit" binding:"omitempty,gte=1,lte=100"`
+}
+
+// UpdateNotificationPreferencesRequest sets the channels of some event types. Event types
+// that aren't listed keep their current channels.
+type UpdateNotificationPreferencesRequest struct {
+	Preferences []NotificationPreferenceRequest `json:"preferences" binding:"required,min=1,dive"`
+}
+
+type NotificationPreferenceRequest struct {
+	EventType string `json:"event_type" binding:"required,oneof=order.placed order.shipped order.cancelled product.back_in_stock product.low_stock product.stock_replenished"`
+	InApp     *bool  `json:"in_app" binding:"required"`
+	Email     *bool  `json:"email" binding:"required"`
+}
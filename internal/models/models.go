@@ -1,21 +1,95 @@
 package models
 
 import (
+	"encoding/json"
+	"slices"
 	"time"
 )
 
 type UserRole string
 type OrderStatus string
+type ProductStatus string
+type DiscountType string
+type DiscountSource string
+type PromotionType string
+type TaxClass string
+type ShippingRateType string
+type ReturnStatus string
+type ReturnReason string
+type RefundStatus string
+type PaymentStatus string
+type InvoiceKind string
+type WebhookDeliveryStatus string
+type JobStatus string
 
 const (
 	RoleCustomer UserRole = "customer"
 	RoleAdmin    UserRole = "admin"
 
-	StatusPending   OrderStatus = "pending"
-	StatusConfirmed OrderStatus = "confirmed"
-	StatusShipped   OrderStatus = "shipped"
-	StatusDelivered OrderStatus = "delivered"
-	StatusCancelled OrderStatus = "cancelled"
+	StatusPending           OrderStatus = "pending"
+	StatusConfirmed         OrderStatus = "confirmed"
+	StatusPartiallyShipped  OrderStatus = "partially_shipped"
+	StatusShipped           OrderStatus = "shipped"
+	StatusDelivered         OrderStatus = "delivered"
+	StatusPartiallyReturned OrderStatus = "partially_returned"
+	StatusReturned          OrderStatus = "returned"
+	StatusCancelled         OrderStatus = "cancelled"
+
+	ProductDraft        ProductStatus = "draft"
+	ProductScheduled    ProductStatus = "scheduled"
+	ProductActive       ProductStatus = "active"
+	ProductDiscontinued ProductStatus = "discontinued"
+
+	DiscountPercentage DiscountType = "percentage"
+	DiscountFixed      DiscountType = "fixed"
+
+	DiscountSourceCoupon    DiscountSource = "coupon"
+	DiscountSourcePromotion DiscountSource = "promotion"
+
+	PromotionBuyXGetY       PromotionType = "buy_x_get_y"
+	PromotionBundle         PromotionType = "bundle"
+	PromotionTieredPrice    PromotionType = "tiered_price"
+	PromotionOrderThreshold PromotionType = "order_threshold"
+
+	TaxClassStandard TaxClass = "standard"
+
+	ShippingFlat        ShippingRateType = "flat"
+	ShippingWeightBased ShippingRateType = "weight_based"
+
+	ReturnRequested ReturnStatus = "requested"
+	ReturnApproved  ReturnStatus = "approved"
+	ReturnRejected  ReturnStatus = "rejected"
+	ReturnReceived  ReturnStatus = "received"
+
+	ReasonDamaged        ReturnReason = "damaged"
+	ReasonDefective      ReturnReason = "defective"
+	ReasonWrongItem      ReturnReason = "wrong_item"
+	ReasonNotAsDescribed ReturnReason = "not_as_described"
+	ReasonNoLongerNeeded ReturnReason = "no_longer_needed"
+	ReasonOther          ReturnReason = "other"
+
+	RefundPending   RefundStatus = "pending"
+	RefundSucceeded RefundStatus = "succeeded"
+	RefundFailed    RefundStatus = "failed"
+
+	PaymentPending    PaymentStatus = "pending"
+	PaymentAuthorized PaymentStatus = "authorized"
+	PaymentCaptured   PaymentStatus = "captured"
+	PaymentVoided     PaymentStatus = "voided"
+	PaymentFailed     PaymentStatus = "failed"
+	PaymentRefunded   PaymentStatus = "refunded"
+
+	InvoiceKindInvoice    InvoiceKind = "invoice"
+	InvoiceKindCreditNote InvoiceKind = "credit_note"
+
+	DeliveryPending   WebhookDeliveryStatus = "pending"
+	DeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	DeliveryDead      WebhookDeliveryStatus = "dead"
+
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
 )
 
 type User struct {
@@ -28,35 +102,447 @@ type User struct {
 }
 
 type Product struct {
-	ID            string    `json:"id" db:"id"`
-	Name          string    `json:"name" db:"name"`
-	Description   string    `json:"description" db:"description"`
-	Price         float64   `json:"price" db:"price"`
-	StockQuantity int       `json:"stock_quantity" db:"stock_quantity"`
-	CreatedAt     time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+	ID               string        `json:"id" db:"id"`
+	Name             string        `json:"name" db:"name"`
+	Description      string        `json:"description" db:"description"`
+	Price            float64       `json:"price" db:"price"`
+	Category         string        `json:"category" db:"category"`
+	TaxClass         TaxClass      `json:"tax_class" db:"tax_class"`
+	WeightKg         float64       `json:"weight_kg" db:"weight_kg"`
+	LengthCm         float64       `json:"length_cm" db:"length_cm"`
+	WidthCm          float64       `json:"width_cm" db:"width_cm"`
+	HeightCm         float64       `json:"height_cm" db:"height_cm"`
+	StockQuantity    int           `json:"stock_quantity" db:"stock_quantity"`
+	ReorderThreshold int           `json:"reorder_threshold" db:"reorder_threshold"`
+	Version          int           `json:"version" db:"version"`
+	Status           ProductStatus `json:"status" db:"status"`
+	PublishAt        *time.Time    `json:"publish_at,omitempty" db:"publish_at"`
+	UnpublishAt      *time.Time    `json:"unpublish_at,omitempty" db:"unpublish_at"`
+	ArchivedAt       *time.Time    `json:"archived_at,omitempty" db:"archived_at"`
+	CreatedAt        time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+// IsArchived reports whether the product has been archived
+func (p *Product) IsArchived() bool {
+	return p.ArchivedAt != nil
+}
+
+// IsAvailable reports whether customers can see and order the product
+func (p *Product) IsAvailable() bool {
+	return p.Status == ProductActive && !p.IsArchived()
+}
+
+// IsLowStock reports whether the product's stock is at or below its reorder threshold
+func (p *Product) IsLowStock() bool {
+	return p.StockQuantity <= p.ReorderThreshold
+}
+
+// ProductPrice is an entry in a product's price history. The price applies from EffectiveFrom
+// until EffectiveTo, or until a later entry takes over if EffectiveTo is not set.
+type ProductPrice struct {
+	ID            string     `json:"id" db:"id"`
+	ProductID     string     `json:"product_id" db:"product_id"`
+	Price         float64    `json:"price" db:"price"`
+	EffectiveFrom time.Time  `json:"effective_from" db:"effective_from"`
+	EffectiveTo   *time.Time `json:"effective_to,omitempty" db:"effective_to"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
+
+type Coupon struct {
+	ID             string       `json:"id" db:"id"`
+	Code           string       `json:"code" db:"code"`
+	Description    string       `json:"description" db:"description"`
+	DiscountType   DiscountType `json:"discount_type" db:"discount_type"`
+	Value          float64      `json:"value" db:"value"`
+	MinOrderValue  float64      `json:"min_order_value" db:"min_order_value"`
+	MaxUses        *int         `json:"max_uses,omitempty" db:"max_uses"`
+	MaxUsesPerUser *int         `json:"max_uses_per_user,omitempty" db:"max_uses_per_user"`
+	ProductIDs     []string     `json:"product_ids" db:"product_ids"`
+	Categories     []string     `json:"categories" db:"categories"`
+	StartsAt       *time.Time   `json:"starts_at,omitempty" db:"starts_at"`
+	EndsAt         *time.Time   `json:"ends_at,omitempty" db:"ends_at"`
+	IsActive       bool         `json:"is_active" db:"is_active"`
+	CreatedAt      time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+// Promotion is a discount applied automatically to orders that match its rule.
+// Promotions are applied in priority order, lowest value first.
+type Promotion struct {
+	ID          string        `json:"id" db:"id"`
+	Name        string        `json:"name" db:"name"`
+	Description string        `json:"description" db:"description"`
+	Type        PromotionType `json:"type" db:"type"`
+	Rule        PromotionRule `json:"rule" db:"rule"`
+	ProductIDs  []string      `json:"product_ids" db:"product_ids"`
+	Categories  []string      `json:"categories" db:"categories"`
+	Priority    int           `json:"priority" db:"priority"`
+	Exclusive   bool          `json:"exclusive" db:"exclusive"`
+	StartsAt    *time.Time    `json:"starts_at,omitempty" db:"starts_at"`
+	EndsAt      *time.Time    `json:"ends_at,omitempty" db:"ends_at"`
+	IsActive    bool          `json:"is_active" db:"is_active"`
+	CreatedAt   time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+// PromotionRule holds the parameters of a promotion. Which fields are used depends on the promotion type:
+//   - buy_x_get_y: for every buy_quantity units bought, get_quantity more units get get_percent_off (default 100)
+//   - bundle: every set of one of each product_ids costs bundle_price, which must be below the products' total
+//   - tiered_price: lines take the percent_off of the highest tier their quantity reaches
+//   - order_threshold: orders of at least min_order_value get value off, as a percentage or a fixed amount
+type PromotionRule struct {
+	BuyQuantity   int             `json:"buy_quantity,omitempty"`
+	GetQuantity   int             `json:"get_quantity,omitempty"`
+	GetPercentOff float64         `json:"get_percent_off,omitempty"`
+	BundlePrice   float64         `json:"bundle_price,omitempty"`
+	Tiers         []PromotionTier `json:"tiers,omitempty"`
+	MinOrderValue float64         `json:"min_order_value,omitempty"`
+	DiscountType  DiscountType    `json:"discount_type,omitempty"`
+	Value         float64         `json:"value,omitempty"`
+}
+
+type PromotionTier struct {
+	MinQuantity int     `json:"min_quantity"`
+	PercentOff  float64 `json:"percent_off"`
+}
+
+// CouponRedemption records the use of a coupon by an order
+type CouponRedemption struct {
+	ID        string    `json:"id" db:"id"`
+	CouponID  string    `json:"coupon_id" db:"coupon_id"`
+	UserID    string    `json:"user_id" db:"user_id"`
+	OrderID   string    `json:"order_id" db:"order_id"`
+	Amount    float64   `json:"amount" db:"amount"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// StockSubscription is a customer's request to be notified when a product is back in stock
+type StockSubscription struct {
+	ID         string     `json:"id" db:"id"`
+	UserID     string     `json:"user_id" db:"user_id"`
+	UserEmail  string     `json:"-" db:"user_email"`
+	ProductID  string     `json:"product_id" db:"product_id"`
+	NotifiedAt *time.Time `json:"notified_at,omitempty" db:"notified_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
 }
 
 type Order struct {
-	ID          string      `json:"id" db:"id"`
-	UserID      string      `json:"user_id" db:"user_id"`
-	Status      OrderStatus `json:"status" db:"status"`
-	TotalAmount float64     `json:"total_amount" db:"total_amount"`
-	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time   `json:"updated_at" db:"updated_at"`
-	Items       []OrderItem `json:"items,omitempty" db:"-"`
+	ID              string          `json:"id" db:"id"`
+	UserID          string          `json:"user_id" db:"user_id"`
+	Status          OrderStatus     `json:"status" db:"status"`
+	Subtotal        float64         `json:"subtotal" db:"subtotal"`
+	DiscountTotal   float64         `json:"discount_total" db:"discount_total"`
+	TaxTotal        float64         `json:"tax_total" db:"tax_total"`
+	TaxRegion       string          `json:"tax_region" db:"tax_region"`
+	TaxInclusive    bool            `json:"tax_inclusive" db:"tax_inclusive"`
+	ShippingMethod  *string         `json:"shipping_method,omitempty" db:"shipping_method"`
+	ShippingTotal   float64         `json:"shipping_total" db:"shipping_total"`
+	TotalAmount     float64         `json:"total_amount" db:"total_amount"`
+	CouponCode      *string         `json:"coupon_code,omitempty" db:"coupon_code"`
+	ShippingAddress *OrderAddress   `json:"shipping_address,omitempty" db:"shipping_address"`
+	BillingAddress  *OrderAddress   `json:"billing_address,omitempty" db:"billing_address"`
+	CreatedAt       time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at" db:"updated_at"`
+	Items           []OrderItem     `json:"items,omitempty" db:"-"`
+	Discounts       []OrderDiscount `json:"discounts,omitempty" db:"-"`
+	Shipments       []Shipment      `json:"shipments,omitempty" db:"-"`
+	Payments        []Payment       `json:"payments,omitempty" db:"-"`
+}
+
+// CapturedPayment returns the payment the order was paid with, or nil if it hasn't been paid
+func (o *Order) CapturedPayment() *Payment {
+	for i := range o.Payments {
+		if o.Payments[i].Status == PaymentCaptured || o.Payments[i].Status == PaymentRefunded {
+			return &o.Payments[i]
+		}
+	}
+	return nil
+}
+
+// manualTransitions lists the statuses an order can be moved to by hand from each status.
+// Orders are confirmed by paying for them and the shipping statuses follow from the
+// order's shipments instead.
+var manualTransitions = map[OrderStatus][]OrderStatus{
+	StatusPending:   {StatusCancelled},
+	StatusConfirmed: {StatusCancelled},
+}
+
+// CanTransitionTo reports whether an order in status s can be moved to next by hand
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	return slices.Contains(manualTransitions[s], next)
+}
+
+// IsShippable reports whether shipments can be recorded for an order in status s. Orders
+// are only shipped once they have been paid for, which confirms them.
+func (s OrderStatus) IsShippable() bool {
+	return s == StatusConfirmed || s == StatusPartiallyShipped
+}
+
+// ShippedQuantities sums up how much of each order item has been shipped, by order item ID
+func (o *Order) ShippedQuantities() map[string]int {
+	shipped := make(map[string]int)
+	for _, shipment := range o.Shipments {
+		for _, item := range shipment.Items {
+			shipped[item.OrderItemID] += item.Quantity
+		}
+	}
+	return shipped
+}
+
+// ShipmentStatus derives the order status from its shipments: partially shipped until every
+// item has shipped, then shipped, and delivered once all shipments have been delivered.
+// Orders without shipments keep their status.
+func (o *Order) ShipmentStatus() OrderStatus {
+	if len(o.Shipments) == 0 {
+		return o.Status
+	}
+
+	shipped := o.ShippedQuantities()
+	for _, item := range o.Items {
+		if shipped[item.ID] < item.Quantity {
+			return StatusPartiallyShipped
+		}
+	}
+	for _, shipment := range o.Shipments {
+		if shipment.DeliveredAt == nil {
+			return StatusShipped
+		}
+	}
+	return StatusDelivered
+}
+
+// ReturnStatus derives the order status from the quantities that came back: partially
+// returned until every item has been returned in full, then returned
+func (o *Order) ReturnStatus() OrderStatus {
+	returned := false
+	full := true
+	for _, item := range o.Items {
+		returned = returned || item.ReturnedQuantity > 0
+		full = full && item.ReturnedQuantity >= item.Quantity
+	}
+
+	switch {
+	case returned && full:
+		return StatusReturned
+	case returned:
+		return StatusPartiallyReturned
+	}
+	return o.Status
+}
+
+// IsInvoiceable reports whether an order in status s has been paid for and gets an invoice
+func (s OrderStatus) IsInvoiceable() bool {
+	return s != StatusPending && s != StatusCancelled
+}
+
+// IsReturnable reports whether returns can be requested for an order in status s
+func (s OrderStatus) IsReturnable() bool {
+	return s == StatusDelivered || s == StatusPartiallyReturned
+}
+
+// Shipment is a parcel with some or all of an order's items
+type Shipment struct {
+	ID             string         `json:"id" db:"id"`
+	OrderID        string         `json:"order_id" db:"order_id"`
+	Carrier        string         `json:"carrier" db:"carrier"`
+	TrackingNumber string         `json:"tracking_number" db:"tracking_number"`
+	ShippedAt      time.Time      `json:"shipped_at" db:"shipped_at"`
+	DeliveredAt    *time.Time     `json:"delivered_at,omitempty" db:"delivered_at"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at" db:"updated_at"`
+	Items          []ShipmentItem `json:"items" db:"-"`
+}
+
+type ShipmentItem struct {
+	ID          string    `json:"id" db:"id"`
+	ShipmentID  string    `json:"shipment_id" db:"shipment_id"`
+	OrderItemID string    `json:"order_item_id" db:"order_item_id"`
+	Quantity    int       `json:"quantity" db:"quantity"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// Address is an entry in a customer's address book
+type Address struct {
+	ID         string    `json:"id" db:"id"`
+	UserID     string    `json:"user_id" db:"user_id"`
+	Label      string    `json:"label" db:"label"`
+	FullName   string    `json:"full_name" db:"full_name"`
+	Line1      string    `json:"line1" db:"line1"`
+	Line2      string    `json:"line2" db:"line2"`
+	City       string    `json:"city" db:"city"`
+	Region     string    `json:"region" db:"region"`
+	PostalCode string    `json:"postal_code" db:"postal_code"`
+	Country    string    `json:"country" db:"country"`
+	Phone      string    `json:"phone" db:"phone"`
+	IsDefault  bool      `json:"is_default" db:"is_default"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Snapshot copies the address for an order, so later edits to the address book don't change the order
+func (a *Address) Snapshot() *OrderAddress {
+	return &OrderAddress{
+		FullName:   a.FullName,
+		Line1:      a.Line1,
+		Line2:      a.Line2,
+		City:       a.City,
+		Region:     a.Region,
+		PostalCode: a.PostalCode,
+		Country:    a.Country,
+		Phone:      a.Phone,
+	}
+}
+
+// OrderAddress is the shipping or billing address an order was placed with
+type OrderAddress struct {
+	FullName   string `json:"full_name"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2,omitempty"`
+	City       string `json:"city"`
+	Region     string `json:"region,omitempty"`
+	PostalCode string `json:"postal_code,omitempty"`
+	Country    string `json:"country"`
+	Phone      string `json:"phone,omitempty"`
+}
+
+// TaxRegion is the region whose tax rates apply to deliveries to the address,
+// e.g. "US-CA", or just the country when the address has no region
+func (a *OrderAddress) TaxRegion() string {
+	if a.Region == "" {
+		return a.Country
+	}
+	return a.Country + "-" + a.Region
+}
+
+// OrderQuote is the price an order would be placed at right now
+type OrderQuote struct {
+	Subtotal       float64         `json:"subtotal"`
+	DiscountTotal  float64         `json:"discount_total"`
+	TaxTotal       float64         `json:"tax_total"`
+	TaxRegion      string          `json:"tax_region"`
+	TaxInclusive   bool            `json:"tax_inclusive"`
+	ShippingMethod *string         `json:"shipping_method,omitempty"`
+	ShippingTotal  float64         `json:"shipping_total"`
+	TotalAmount    float64         `json:"total_amount"`
+	CouponCode     *string         `json:"coupon_code,omitempty"`
+	Items          []QuoteItem     `json:"items"`
+	Discounts      []OrderDiscount `json:"discounts"`
+}
+
+type QuoteItem struct {
+	ProductID      string  `json:"product_id"`
+	Quantity       int     `json:"quantity"`
+	UnitPrice      float64 `json:"unit_price"`
+	SubTotal       float64 `json:"sub_total"`
+	DiscountAmount float64 `json:"discount_amount"`
+	TaxRate        float64 `json:"tax_rate"`
+	TaxAmount      float64 `json:"tax_amount"`
+}
+
+// OrderDiscount is a discount applied to an order, spread over the items'
+// discount amounts. The discounts of an order add up to its DiscountTotal.
+type OrderDiscount struct {
+	ID          string         `json:"id" db:"id"`
+	OrderID     string         `json:"order_id" db:"order_id"`
+	Source      DiscountSource `json:"source" db:"source"`
+	ReferenceID string         `json:"reference_id" db:"reference_id"`
+	Code        string         `json:"code,omitempty" db:"code"`
+	Description string         `json:"description,omitempty" db:"description"`
+	Amount      float64        `json:"amount" db:"amount"`
+	CreatedAt   time.Time      `json:"created_at" db:"created_at"`
 }
 
 type OrderItem struct {
+	ID               string                `json:"id" db:"id"`
+	OrderID          string                `json:"order_id" db:"order_id"`
+	ProductID        string                `json:"product_id" db:"product_id"`
+	Quantity         int                   `json:"quantity" db:"quantity"`
+	UnitPrice        float64               `json:"unit_price" db:"unit_price"`
+	SubTotal         float64               `json:"sub_total" db:"subtotal"`
+	DiscountAmount   float64               `json:"discount_amount" db:"discount_amount"`
+	TaxRate          float64               `json:"tax_rate" db:"tax_rate"`
+	TaxAmount        float64               `json:"tax_amount" db:"tax_amount"`
+	ReturnedQuantity int                   `json:"returned_quantity" db:"returned_quantity"`
+	CreatedAt        time.Time             `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time             `json:"updated_at" db:"updated_at"`
+	Product          *Product              `json:"product,omitempty" db:"-"`
+	Allocations      []OrderItemAllocation `json:"allocations,omitempty" db:"-"`
+}
+
+// OrderItemAllocation records how much of an order item is fulfilled from a warehouse
+type OrderItemAllocation struct {
+	ID            string    `json:"id" db:"id"`
+	OrderItemID   string    `json:"order_item_id" db:"order_item_id"`
+	WarehouseID   string    `json:"warehouse_id" db:"warehouse_id"`
+	WarehouseCode string    `json:"warehouse_code" db:"warehouse_code"`
+	Quantity      int       `json:"quantity" db:"quantity"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// ShippingMethod is a shipping option offered at checkout. Its carrier works out the
+// charge: flat methods charge the base rate, weight based ones add the per kg rate for
+// every started kg of billable weight. Orders worth at least FreeOver ship for free.
+type ShippingMethod struct {
+	ID            string           `json:"id" db:"id"`
+	Code          string           `json:"code" db:"code"`
+	Name          string           `json:"name" db:"name"`
+	Carrier       string           `json:"carrier" db:"carrier"`
+	RateType      ShippingRateType `json:"rate_type" db:"rate_type"`
+	BaseRate      float64          `json:"base_rate" db:"base_rate"`
+	PerKgRate     float64          `json:"per_kg_rate" db:"per_kg_rate"`
+	FreeOver      *float64         `json:"free_over,omitempty" db:"free_over"`
+	Countries     []string         `json:"countries" db:"countries"`
+	EstimatedDays int              `json:"estimated_days" db:"estimated_days"`
+	IsActive      bool             `json:"is_active" db:"is_active"`
+	CreatedAt     time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// ShipsTo reports whether the method delivers to the country. Methods without countries deliver everywhere.
+func (m *ShippingMethod) ShipsTo(country string) bool {
+	return len(m.Countries) == 0 || slices.Contains(m.Countries, country)
+}
+
+// ShippingOption is the price of shipping a cart with a shipping method
+type ShippingOption struct {
+	Method        string  `json:"method"`
+	Name          string  `json:"name"`
+	Carrier       string  `json:"carrier"`
+	Amount        float64 `json:"amount"`
+	EstimatedDays int     `json:"estimated_days"`
+}
+
+// TaxRate is the tax percentage charged on a tax class in a region. Regions are
+// country codes such as "DE" or country subdivisions such as "US-CA".
+type TaxRate struct {
 	ID        string    `json:"id" db:"id"`
-	OrderID   string    `json:"order_id" db:"order_id"`
-	ProductID string    `json:"product_id" db:"product_id"`
-	Quantity  int       `json:"quantity" db:"quantity"`
-	UnitPrice float64   `json:"unit_price" db:"unit_price"`
-	SubTotal  float64   `json:"sub_total" db:"sub_total"`
+	Region    string    `json:"region" db:"region"`
+	TaxClass  TaxClass  `json:"tax_class" db:"tax_class"`
+	Name      string    `json:"name" db:"name"`
+	Rate      float64   `json:"rate" db:"rate"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type Warehouse struct {
+	ID        string    `json:"id" db:"id"`
+	Code      string    `json:"code" db:"code"`
+	Name      string    `json:"name" db:"name"`
+	Priority  int       `json:"priority" db:"priority"`
+	IsActive  bool      `json:"is_active" db:"is_active"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
-	Product   *Product  `json:"product,omitempty" db:"-"`
+}
+
+// WarehouseStock is the stock level of a product in a single warehouse
+type WarehouseStock struct {
+	WarehouseID       string    `json:"warehouse_id" db:"warehouse_id"`
+	WarehouseCode     string    `json:"warehouse_code" db:"warehouse_code"`
+	WarehousePriority int       `json:"-" db:"warehouse_priority"`
+	ProductID         string    `json:"product_id" db:"product_id"`
+	Quantity          int       `json:"quantity" db:"quantity"`
+	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
 }
 
 type Session struct {
@@ -67,3 +553,227 @@ type Session struct {
 	ExpiresAt    time.Time `json:"expires_at" db:"expires_at"`
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 }
+
+// PasswordReset is a single-use code that lets a user choose a new password
+type PasswordReset struct {
+	ID        string     `json:"id" db:"id"`
+	UserID    string     `json:"user_id" db:"user_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Return is a customer's request to send back some of an order's items. Approved returns
+// are refunded once the goods have been received.
+type Return struct {
+	ID             string       `json:"id" db:"id"`
+	OrderID        string       `json:"order_id" db:"order_id"`
+	UserID         string       `json:"user_id" db:"user_id"`
+	Status         ReturnStatus `json:"status" db:"status"`
+	Note           string       `json:"note" db:"note"`
+	ResolutionNote string       `json:"resolution_note" db:"resolution_note"`
+	ReceivedAt     *time.Time   `json:"received_at,omitempty" db:"received_at"`
+	CreatedAt      time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at" db:"updated_at"`
+	Items          []ReturnItem `json:"items" db:"-"`
+	Refund         *Refund      `json:"refund,omitempty" db:"-"`
+}
+
+type ReturnItem struct {
+	ID          string       `json:"id" db:"id"`
+	ReturnID    string       `json:"return_id" db:"return_id"`
+	OrderItemID string       `json:"order_item_id" db:"order_item_id"`
+	Quantity    int          `json:"quantity" db:"quantity"`
+	Reason      ReturnReason `json:"reason" db:"reason"`
+	CreatedAt   time.Time    `json:"created_at" db:"created_at"`
+}
+
+// Refund records money paid back on an order, for a return or otherwise. Refunds through the
+// payment provider are pending until the provider has paid them back, which is only asked
+// once the transaction recording them has committed.
+type Refund struct {
+	ID                string       `json:"id" db:"id"`
+	OrderID           string       `json:"order_id" db:"order_id"`
+	ReturnID          *string      `json:"return_id,omitempty" db:"return_id"`
+	PaymentID         *string      `json:"payment_id,omitempty" db:"payment_id"`
+	ProviderReference string       `json:"provider_reference,omitempty" db:"provider_reference"`
+	Status            RefundStatus `json:"status" db:"status"`
+	Amount            float64      `json:"amount" db:"amount"`
+	Reason            string       `json:"reason" db:"reason"`
+	FailureReason     string       `json:"failure_reason,omitempty" db:"failure_reason"`
+	CreatedAt         time.Time    `json:"created_at" db:"created_at"`
+}
+
+// RefundJob is the payload of the job that pays a pending refund back through the provider
+type RefundJob struct {
+	RefundID string `json:"refund_id"`
+}
+
+// Payment is an attempt to pay for an order through the payment provider. Declined
+// attempts are kept as failed payments. Payments are pending while the provider is
+// being asked, and stay pending until reconciled if its answer is lost.
+type Payment struct {
+	ID             string        `json:"id" db:"id"`
+	OrderID        string        `json:"order_id" db:"order_id"`
+	Provider       string        `json:"provider" db:"provider"`
+	Reference      string        `json:"reference" db:"reference"`
+	Status         PaymentStatus `json:"status" db:"status"`
+	Amount         float64       `json:"amount" db:"amount"`
+	RefundedAmount float64       `json:"refunded_amount" db:"refunded_amount"`
+	FailureReason  string        `json:"failure_reason,omitempty" db:"failure_reason"`
+	CreatedAt      time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+// InProgress reports whether the outcome of a payment in status s hasn't been settled yet
+func (s PaymentStatus) InProgress() bool {
+	return s == PaymentPending || s == PaymentAuthorized
+}
+
+// PaymentEvent is a webhook event received from the payment provider, stored as it was sent
+type PaymentEvent struct {
+	ID         string          `json:"id" db:"id"`
+	Provider   string          `json:"provider" db:"provider"`
+	EventID    string          `json:"event_id" db:"event_id"`
+	Type       string          `json:"type" db:"type"`
+	Payload    json.RawMessage `json:"payload" db:"payload"`
+	ReceivedAt time.Time       `json:"received_at" db:"received_at"`
+}
+
+// Invoice is an invoice or credit note. Lines, totals and addresses are copied from the order
+// when it is issued, so later changes don't alter issued documents. Credit notes refer to the
+// invoice they correct by its number.
+type Invoice struct {
+	ID              string        `json:"id" db:"id"`
+	OrderID         string        `json:"order_id" db:"order_id"`
+	Kind            InvoiceKind   `json:"kind" db:"kind"`
+	Number          string        `json:"number" db:"number"`
+	RefundID        *string       `json:"refund_id,omitempty" db:"refund_id"`
+	OriginalNumber  *string       `json:"original_number,omitempty" db:"original_number"`
+	BillingAddress  *OrderAddress `json:"billing_address,omitempty" db:"billing_address"`
+	ShippingAddress *OrderAddress `json:"shipping_address,omitempty" db:"shipping_address"`
+	Lines           []InvoiceLine `json:"lines" db:"lines"`
+	Subtotal        float64       `json:"subtotal" db:"subtotal"`
+	DiscountTotal   float64       `json:"discount_total" db:"discount_total"`
+	ShippingTotal   float64       `json:"shipping_total" db:"shipping_total"`
+	TaxTotal        float64       `json:"tax_total" db:"tax_total"`
+	TaxInclusive    bool          `json:"tax_inclusive" db:"tax_inclusive"`
+	Total           float64       `json:"total" db:"total"`
+	IssuedAt        time.Time     `json:"issued_at" db:"issued_at"`
+}
+
+type InvoiceLine struct {
+	ProductID   string  `json:"product_id,omitempty"`
+	Description string  `json:"description"`
+	Quantity    int     `json:"quantity"`
+	UnitPrice   float64 `json:"unit_price"`
+	Discount    float64 `json:"discount"`
+	TaxRate     float64 `json:"tax_rate"`
+	TaxAmount   float64 `json:"tax_amount"`
+	Total       float64 `json:"total"`
+}
+
+// OutboxEvent is a domain event waiting in the outbox to be published, or published already.
+// Position orders events in the sequence they were written.
+type OutboxEvent struct {
+	Position      int64           `json:"position" db:"position"`
+	ID            string          `json:"id" db:"id"`
+	AggregateType string          `json:"aggregate_type" db:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id" db:"aggregate_id"`
+	Type          string          `json:"type" db:"type"`
+	Payload       json.RawMessage `json:"payload" db:"payload"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	PublishedAt   *time.Time      `json:"published_at,omitempty" db:"published_at"`
+	Attempts      int             `json:"attempts" db:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at" db:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty" db:"last_error"`
+}
+
+// WebhookSubscription is a partner endpoint that is called with the events of the given types.
+// Requests are signed with the secret, which is never shown again.
+type WebhookSubscription struct {
+	ID         string    `json:"id" db:"id"`
+	URL        string    `json:"url" db:"url"`
+	EventTypes []string  `json:"event_types" db:"event_types"`
+	Secret     string    `json:"-" db:"secret"`
+	IsActive   bool      `json:"is_active" db:"is_active"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// WebhookDelivery is an event on its way to a subscription. Payload is the request body,
+// which stays the same across attempts.
+type WebhookDelivery struct {
+	ID             string                   `json:"id" db:"id"`
+	SubscriptionID string                   `json:"subscription_id" db:"subscription_id"`
+	EventID        string                   `json:"event_id" db:"event_id"`
+	EventType      string                   `json:"event_type" db:"event_type"`
+	Payload        json.RawMessage          `json:"payload" db:"payload" swaggertype:"object"`
+	Status         WebhookDeliveryStatus    `json:"status" db:"status"`
+	Attempts       int                      `json:"attempts" db:"attempts"`
+	NextAttemptAt  time.Time                `json:"next_attempt_at" db:"next_attempt_at"`
+	LastStatusCode *int                     `json:"last_status_code,omitempty" db:"last_status_code"`
+	LastError      string                   `json:"last_error,omitempty" db:"last_error"`
+	DeliveredAt    *time.Time               `json:"delivered_at,omitempty" db:"delivered_at"`
+	History        []WebhookDeliveryAttempt `json:"history,omitempty" db:"-"`
+	CreatedAt      time.Time                `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time                `json:"updated_at" db:"updated_at"`
+}
+
+// WebhookDeliveryAttempt records one request of a delivery. StatusCode is missing when
+// the endpoint couldn't be reached.
+type WebhookDeliveryAttempt struct {
+	ID          string    `json:"id" db:"id"`
+	DeliveryID  string    `json:"delivery_id" db:"delivery_id"`
+	StatusCode  *int      `json:"status_code,omitempty" db:"status_code"`
+	Error       string    `json:"error,omitempty" db:"error"`
+	DurationMs  int       `json:"duration_ms" db:"duration_ms"`
+	AttemptedAt time.Time `json:"attempted_at" db:"attempted_at"`
+}
+
+// Job is a unit of background work of a registered kind. Failed jobs are retried until they
+// have been attempted MaxAttempts times.
+type Job struct {
+	ID          string          `json:"id" db:"id"`
+	Kind        string          `json:"kind" db:"kind"`
+	Payload     json.RawMessage `json:"payload" db:"payload" swaggertype:"object"`
+	Status      JobStatus       `json:"status" db:"status"`
+	Attempts    int             `json:"attempts" db:"attempts"`
+	MaxAttempts int             `json:"max_attempts" db:"max_attempts"`
+	RunAt       time.Time       `json:"run_at" db:"run_at"`
+	LockedUntil *time.Time      `json:"locked_until,omitempty" db:"locked_until"`
+	LockedBy    *string         `json:"locked_by,omitempty" db:"locked_by"`
+	LastError   string          `json:"last_error,omitempty" db:"last_error"`
+	UniqueKey   *string         `json:"unique_key,omitempty" db:"unique_key"`
+	FinishedAt  *time.Time      `json:"finished_at,omitempty" db:"finished_at"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// Notification is a message in a user's in-app inbox
+type Notification struct {
+	ID        string          `json:"id" db:"id"`
+	UserID    string          `json:"user_id" db:"user_id"`
+	Type      string          `json:"type" db:"type"`
+	Title     string          `json:"title" db:"title"`
+	Body      string          `json:"body" db:"body"`
+	Data      json.RawMessage `json:"data" db:"data" swaggertype:"object"`
+	ReadAt    *time.Time      `json:"read_at,omitempty" db:"read_at"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
+
+// NotificationInbox is a page of a user's notifications, newest first. NextCursor is set
+// when there are older notifications.
+type NotificationInbox struct {
+	Notifications []Notification `json:"notifications"`
+	UnreadCount   int            `json:"unread_count"`
+	NextCursor    string         `json:"next_cursor,omitempty"`
+}
+
+// NotificationPreference is the channels a user gets notifications of an event type through
+type NotificationPreference struct {
+	EventType string `json:"event_type" db:"event_type"`
+	InApp     bool   `json:"in_app" db:"in_app"`
+	Email     bool   `json:"email" db:"email"`
+}
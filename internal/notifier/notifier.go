@@ -0,0 +1,69 @@
+package notifier
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Event names of the notifications sent by the service
+const (
+	EventLowStock         = "product.low_stock"
+	EventStockReplenished = "product.stock_replenished"
+	EventBackInStock      = "product.back_in_stock"
+	EventOrderPlaced      = "order.placed"
+	EventOrderShipped     = "order.shipped"
+	EventOrderCancelled   = "order.cancelled"
+)
+
+// Events lists the events users can choose the channels of
+var Events = []string{
+	EventOrderPlaced, EventOrderShipped, EventOrderCancelled, EventBackInStock, EventLowStock, EventStockReplenished,
+}
+
+// DefaultChannels reports whether users who haven't chosen otherwise get an event in the
+// app and by email. Stock level alerts for administrators only show in the app.
+func DefaultChannels(event string) (inApp, email bool) {
+	switch event {
+	case EventLowStock, EventStockReplenished:
+		return true, false
+	default:
+		return true, true
+	}
+}
+
+// Notification is a message about an event, addressed to a user or, when
+// UserID is empty, to the shop's administrators.
+type Notification struct {
+	Event  string
+	UserID string
+	Email  string
+	Data   map[string]any
+}
+
+// Notifier delivers notifications. Implementations must be safe for concurrent use.
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+type logNotifier struct {
+	logger *slog.Logger
+}
+
+// NewLogNotifier returns a Notifier that writes notifications to the given logger.
+// A nil logger uses slog.Default().
+func NewLogNotifier(logger *slog.Logger) Notifier {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &logNotifier{logger: logger}
+}
+
+func (n *logNotifier) Notify(ctx context.Context, notification Notification) error {
+	n.logger.InfoContext(ctx, "notification",
+		slog.String("event", notification.Event),
+		slog.String("user_id", notification.UserID),
+		slog.String("email", notification.Email),
+		slog.Any("data", notification.Data),
+	)
+	return nil
+}
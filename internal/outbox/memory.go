@@ -0,0 +1,41 @@
+package outbox
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/zde37/instashop-task/internal/models"
+)
+
+// MemoryPublisher keeps published events in memory, in the order they were published.
+// Events published again are kept once.
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	seen   map[string]bool
+	events []models.OutboxEvent
+}
+
+// NewMemoryPublisher returns an empty MemoryPublisher
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{seen: make(map[string]bool)}
+}
+
+func (p *MemoryPublisher) Publish(_ context.Context, event *models.OutboxEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.seen[event.ID] {
+		return nil
+	}
+	p.seen[event.ID] = true
+	p.events = append(p.events, *event)
+	return nil
+}
+
+// Events returns a copy of the events published so far
+func (p *MemoryPublisher) Events() []models.OutboxEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.events)
+}
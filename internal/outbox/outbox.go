@@ -0,0 +1,94 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/zde37/instashop-task/internal/models"
+)
+
+// Names of the publishers that can be selected
+const (
+	Log    = "log"
+	Memory = "memory"
+)
+
+// Aggregate types. Events of the same aggregate are published in the order they were written.
+const (
+	AggregateOrder   = "order"
+	AggregateProduct = "product"
+	AggregateUser    = "user"
+)
+
+// Event types written to the outbox
+const (
+	// EventOrderCreated carries the order as placed
+	EventOrderCreated = "order.created"
+	// EventOrderStatusChanged carries an OrderStatusChange
+	EventOrderStatusChanged = "order.status_changed"
+	// EventProductCreated and EventProductUpdated carry the product as it is after the change
+	EventProductCreated = "product.created"
+	EventProductUpdated = "product.updated"
+	// EventProductDeleted carries the product as it was before it was deleted
+	EventProductDeleted = "product.deleted"
+	// EventUserRegistered carries a UserRegistered
+	EventUserRegistered = "user.registered"
+)
+
+// OrderStatusChange is the payload of EventOrderStatusChanged
+type OrderStatusChange struct {
+	OrderID string             `json:"order_id"`
+	UserID  string             `json:"user_id"`
+	From    models.OrderStatus `json:"from"`
+	To      models.OrderStatus `json:"to"`
+}
+
+// UserRegistered is the payload of EventUserRegistered
+type UserRegistered struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+// Publisher hands outbox events on to their consumers. Delivery is at least once: an event
+// whose publication failed, or whose success couldn't be recorded, is published again, so
+// consumers should skip event IDs they have seen. Implementations must be safe for concurrent use.
+type Publisher interface {
+	Publish(ctx context.Context, event *models.OutboxEvent) error
+}
+
+// NewPublisher returns the publisher registered under name. An empty name selects Log.
+func NewPublisher(name string) (Publisher, error) {
+	switch name {
+	case "", Log:
+		return NewLogPublisher(nil), nil
+	case Memory:
+		return NewMemoryPublisher(), nil
+	default:
+		return nil, fmt.Errorf("unknown event publisher %q", name)
+	}
+}
+
+type logPublisher struct {
+	logger *slog.Logger
+}
+
+// NewLogPublisher returns a Publisher that writes events to the given logger.
+// A nil logger uses slog.Default().
+func NewLogPublisher(logger *slog.Logger) Publisher {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &logPublisher{logger: logger}
+}
+
+func (p *logPublisher) Publish(ctx context.Context, event *models.OutboxEvent) error {
+	p.logger.InfoContext(ctx, "event",
+		slog.String("id", event.ID),
+		slog.String("type", event.Type),
+		slog.String("aggregate_type", event.AggregateType),
+		slog.String("aggregate_id", event.AggregateID),
+		slog.String("payload", string(event.Payload)),
+	)
+	return nil
+}
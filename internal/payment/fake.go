@@ -0,0 +1,166 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// Tokens that make the fake provider fail. Any other token is approved.
+const (
+	TokenDecline        = "tok_decline"
+	TokenTimeout        = "tok_timeout"
+	TokenCaptureDecline = "tok_capture_decline"
+	TokenCaptureTimeout = "tok_capture_timeout"
+)
+
+type fakePayment struct {
+	token      string
+	authorized float64
+	captured   float64
+	refunded   float64
+	voided     bool
+}
+
+// FakeProvider is an in-process provider for development and tests. Its outcome only depends
+// on the token: TokenDecline fails the authorization and TokenTimeout times out before the
+// authorization reaches the provider. TokenCaptureDecline fails the capture, and with
+// TokenCaptureTimeout the amount is captured but the answer times out. Everything else
+// succeeds. Payments are only kept in memory.
+type FakeProvider struct {
+	mu       sync.Mutex
+	payments map[string]*fakePayment
+	// byKey holds the references of the payments by idempotency key
+	byKey map[string]string
+	// refundsByKey holds the references of the refunds by idempotency key
+	refundsByKey map[string]string
+}
+
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{
+		payments:     make(map[string]*fakePayment),
+		byKey:        make(map[string]string),
+		refundsByKey: make(map[string]string),
+	}
+}
+
+func (p *FakeProvider) Name() string {
+	return Fake
+}
+
+func (p *FakeProvider) Authorize(ctx context.Context, req AuthorizeRequest) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrTimeout, err)
+	}
+	switch req.Token {
+	case TokenDecline:
+		return "", fmt.Errorf("%w: insufficient funds", ErrDeclined)
+	case TokenTimeout:
+		return "", ErrTimeout
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if reference, ok := p.byKey[req.IdempotencyKey]; ok && req.IdempotencyKey != "" {
+		return reference, nil
+	}
+	reference := "fake_" + pkg.GenerateID()
+	p.payments[reference] = &fakePayment{token: req.Token, authorized: req.Amount}
+	if req.IdempotencyKey != "" {
+		p.byKey[req.IdempotencyKey] = reference
+	}
+	return reference, nil
+}
+
+func (p *FakeProvider) Capture(ctx context.Context, reference string, amount float64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, err := p.lookup(reference)
+	if err != nil {
+		return err
+	}
+	switch {
+	case payment.token == TokenCaptureDecline:
+		return fmt.Errorf("%w: capture refused", ErrDeclined)
+	case payment.voided:
+		return fmt.Errorf("%w: authorization was voided", ErrDeclined)
+	case payment.captured > 0 && payment.captured == amount:
+		return nil
+	case payment.captured+amount > payment.authorized:
+		return fmt.Errorf("%w: capture exceeds the authorized amount", ErrDeclined)
+	}
+
+	payment.captured += amount
+	if payment.token == TokenCaptureTimeout {
+		return ErrTimeout
+	}
+	return nil
+}
+
+func (p *FakeProvider) Void(ctx context.Context, reference string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, err := p.lookup(reference)
+	if err != nil {
+		return err
+	}
+	if payment.captured > 0 {
+		return fmt.Errorf("%w: payment has been captured", ErrDeclined)
+	}
+
+	payment.voided = true
+	return nil
+}
+
+func (p *FakeProvider) Refund(ctx context.Context, req RefundRequest) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if refundReference, ok := p.refundsByKey[req.IdempotencyKey]; ok && req.IdempotencyKey != "" {
+		return refundReference, nil
+	}
+	refundReference := "fake_refund_" + pkg.GenerateID()
+
+	// payments from before a restart are unknown, their refunds are accepted
+	if payment, ok := p.payments[req.Reference]; ok {
+		if payment.refunded+req.Amount > payment.captured {
+			return "", fmt.Errorf("%w: refund exceeds the captured amount", ErrDeclined)
+		}
+		payment.refunded += req.Amount
+	}
+
+	if req.IdempotencyKey != "" {
+		p.refundsByKey[req.IdempotencyKey] = refundReference
+	}
+	return refundReference, nil
+}
+
+func (p *FakeProvider) Lookup(ctx context.Context, idempotencyKey string) (Transaction, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	reference, ok := p.byKey[idempotencyKey]
+	if !ok {
+		return Transaction{}, ErrNotFound
+	}
+	payment := p.payments[reference]
+	return Transaction{
+		Reference: reference,
+		Captured:  payment.captured > 0,
+		Voided:    payment.voided,
+		Refunded:  payment.captured > 0 && payment.refunded >= payment.captured,
+	}, nil
+}
+
+func (p *FakeProvider) lookup(reference string) (*fakePayment, error) {
+	payment, ok := p.payments[reference]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown reference %s", ErrDeclined, reference)
+	}
+	return payment, nil
+}
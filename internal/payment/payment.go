@@ -0,0 +1,82 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Fake is the name of the in-process fake provider
+const Fake = "fake"
+
+var (
+	// ErrDeclined is returned when the provider refuses a payment operation
+	ErrDeclined = errors.New("payment declined")
+	// ErrTimeout is returned when the provider doesn't answer in time. The outcome of the
+	// operation is unknown.
+	ErrTimeout = errors.New("payment provider timed out")
+	// ErrNotFound is returned by Lookup for payments the provider has no record of
+	ErrNotFound = errors.New("payment not found")
+)
+
+// AuthorizeRequest asks the provider to reserve an amount on the customer's payment method
+type AuthorizeRequest struct {
+	OrderID string
+	Amount  float64
+	// Token identifies the payment method, as collected by the provider's client side integration
+	Token string
+	// IdempotencyKey identifies the payment. Authorizing again with the same key returns the
+	// first authorization instead of reserving the amount twice.
+	IdempotencyKey string
+}
+
+// RefundRequest asks the provider to pay back part of a captured amount
+type RefundRequest struct {
+	// Reference is the provider's reference of the captured payment
+	Reference string
+	Amount    float64
+	// IdempotencyKey identifies the refund. Refunding again with the same key returns the
+	// first refund instead of paying the amount back twice.
+	IdempotencyKey string
+}
+
+// Transaction is the provider's record of a payment
+type Transaction struct {
+	Reference string
+	Captured  bool
+	Voided    bool
+	// Refunded is set once the whole captured amount has been refunded
+	Refunded bool
+}
+
+// Provider moves money through a payment gateway. Authorizations reserve an amount that is
+// then captured or voided; captured amounts can be refunded in parts. Operations are
+// identified by the reference returned from Authorize. Providers that settle asynchronously
+// report the outcome through webhooks.
+//
+// Errors other than ErrDeclined leave the outcome of an operation unknown. Authorize and
+// Capture can be repeated safely, Refund can with the same idempotency key, and Lookup
+// tells what became of a payment.
+type Provider interface {
+	Name() string
+	Authorize(ctx context.Context, req AuthorizeRequest) (reference string, err error)
+	// Capture takes the authorized amount. Capturing a captured authorization again succeeds
+	// without taking the amount twice.
+	Capture(ctx context.Context, reference string, amount float64) error
+	Void(ctx context.Context, reference string) error
+	// Refund pays back part of a captured amount and returns the provider's reference of the refund
+	Refund(ctx context.Context, req RefundRequest) (refundReference string, err error)
+	// Lookup returns the payment authorized with the idempotency key, or ErrNotFound if the
+	// authorization never reached the provider
+	Lookup(ctx context.Context, idempotencyKey string) (Transaction, error)
+}
+
+// NewProvider returns the provider registered under name. An empty name selects Fake.
+func NewProvider(name string) (Provider, error) {
+	switch name {
+	case "", Fake:
+		return NewFakeProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", name)
+	}
+}
@@ -0,0 +1,125 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// SignatureHeader carries the signature of webhook requests, formatted as described at
+// pkg.SignatureHeader
+const SignatureHeader = "X-Payment-Signature"
+
+// SignatureTolerance is how far a webhook's timestamp may be off, which bounds replays
+const SignatureTolerance = 5 * time.Minute
+
+// Event types sent by providers
+const (
+	EventCaptured = "payment.captured"
+	EventFailed   = "payment.failed"
+	EventRefunded = "payment.refunded"
+)
+
+// ErrInvalidSignature is returned for webhooks that aren't signed with the shared secret
+// or whose timestamp is outside the tolerance
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Event is a payment state change reported by the provider. IDs are unique per provider,
+// and providers may deliver the same event more than once.
+type Event struct {
+	ID   string    `json:"id"`
+	Type string    `json:"type"`
+	Data EventData `json:"data"`
+}
+
+type EventData struct {
+	// Reference is the payment reference returned by Authorize
+	Reference       string  `json:"reference"`
+	Amount          float64 `json:"amount"`
+	RefundReference string  `json:"refund_reference,omitempty"`
+	FailureReason   string  `json:"failure_reason,omitempty"`
+}
+
+// ParseEvent decodes a webhook body
+func ParseEvent(payload []byte) (Event, error) {
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return Event{}, fmt.Errorf("decoding event: %w", err)
+	}
+	if event.ID == "" || event.Type == "" {
+		return Event{}, errors.New("event id and type are required")
+	}
+	return event, nil
+}
+
+// Signer signs webhook bodies the way providers do. It is used by local providers and to
+// send test webhooks.
+type Signer struct {
+	secret []byte
+}
+
+func NewSigner(secret string) Signer {
+	return Signer{secret: []byte(secret)}
+}
+
+// Sign returns the signature header value for the payload sent at the given time
+func (s Signer) Sign(payload []byte, at time.Time) string {
+	return pkg.SignatureHeader(s.secret, payload, at)
+}
+
+// Verifier checks webhook signatures against the shared secret
+type Verifier struct {
+	signer    Signer
+	tolerance time.Duration
+	now       func() time.Time
+}
+
+// NewVerifier returns a verifier for the secret. Without a secret every webhook is rejected.
+func NewVerifier(secret string) *Verifier {
+	return &Verifier{signer: NewSigner(secret), tolerance: SignatureTolerance, now: time.Now}
+}
+
+// Verify checks that the signature header matches the payload and was created recently
+func (v *Verifier) Verify(header string, payload []byte) error {
+	if len(v.signer.secret) == 0 {
+		return fmt.Errorf("%w: no webhook secret configured", ErrInvalidSignature)
+	}
+
+	var timestamp string
+	var signatures [][]byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			// providers send several signatures while rotating secrets
+			if signature, err := hex.DecodeString(value); err == nil {
+				signatures = append(signatures, signature)
+			}
+		}
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: missing timestamp", ErrInvalidSignature)
+	}
+	if age := v.now().Sub(time.Unix(unix, 0)); age > v.tolerance || age < -v.tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+
+	expected := pkg.SignatureMAC(v.signer.secret, timestamp, payload)
+	for _, signature := range signatures {
+		if hmac.Equal(signature, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
@@ -0,0 +1,110 @@
+package payment
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifierVerify(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	payload := []byte(`{"id":"evt_1","type":"payment.captured","data":{"reference":"pay_1","amount":10}}`)
+	signer := NewSigner("secret")
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		payload []byte
+		wantErr bool
+	}{
+		{
+			name:   "valid",
+			secret: "secret",
+			header: signer.Sign(payload, now),
+		},
+		{
+			name:   "within tolerance",
+			secret: "secret",
+			header: signer.Sign(payload, now.Add(-SignatureTolerance+time.Second)),
+		},
+		{
+			name:   "one of several signatures matches",
+			secret: "secret",
+			header: NewSigner("old secret").Sign(payload, now) + ",v1=" + signatureOf(signer.Sign(payload, now)),
+		},
+		{
+			name:    "signed with another secret",
+			secret:  "secret",
+			header:  NewSigner("other").Sign(payload, now),
+			wantErr: true,
+		},
+		{
+			name:    "body changed",
+			secret:  "secret",
+			header:  signer.Sign(payload, now),
+			payload: []byte(`{"id":"evt_1","type":"payment.captured","data":{"reference":"pay_1","amount":1000}}`),
+			wantErr: true,
+		},
+		{
+			name:    "replayed after the tolerance",
+			secret:  "secret",
+			header:  signer.Sign(payload, now.Add(-SignatureTolerance-time.Second)),
+			wantErr: true,
+		},
+		{
+			name:    "timestamp in the future",
+			secret:  "secret",
+			header:  signer.Sign(payload, now.Add(SignatureTolerance+time.Second)),
+			wantErr: true,
+		},
+		{
+			name:    "timestamp changed",
+			secret:  "secret",
+			header:  "t=1700000001,v1=" + signatureOf(signer.Sign(payload, now)),
+			wantErr: true,
+		},
+		{
+			name:    "no timestamp",
+			secret:  "secret",
+			header:  "v1=" + signatureOf(signer.Sign(payload, now)),
+			wantErr: true,
+		},
+		{
+			name:    "no signature",
+			secret:  "secret",
+			wantErr: true,
+		},
+		{
+			name:    "no secret configured",
+			header:  NewSigner("").Sign(payload, now),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVerifier(tt.secret)
+			v.now = func() time.Time { return now }
+
+			body := payload
+			if tt.payload != nil {
+				body = tt.payload
+			}
+			err := v.Verify(tt.header, body)
+			if tt.wantErr && !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Verify() error = %v, want %v", err, ErrInvalidSignature)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Verify() error = %v", err)
+			}
+		})
+	}
+}
+
+// signatureOf returns the v1 value of a signature header
+func signatureOf(header string) string {
+	_, signature, _ := strings.Cut(header, "v1=")
+	return signature
+}
@@ -0,0 +1,36 @@
+package pricing
+
+import (
+	"time"
+
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// EvaluateCoupon works out the discount a coupon gives on the lines at the given time.
+// Usage limits are not checked here since they depend on the coupon's redemptions.
+func EvaluateCoupon(coupon *models.Coupon, lines []Line, now time.Time) (Discount, error) {
+	switch {
+	case !coupon.IsActive:
+		return Discount{}, pkg.ErrInvalidCoupon
+	case coupon.StartsAt != nil && now.Before(*coupon.StartsAt):
+		return Discount{}, pkg.ErrCouponExpired
+	case coupon.EndsAt != nil && !now.Before(*coupon.EndsAt):
+		return Discount{}, pkg.ErrCouponExpired
+	case Net(lines) < coupon.MinOrderValue:
+		return Discount{}, pkg.ErrCouponMinimumNotMet
+	}
+
+	eligible := eligibleLines(lines, coupon.ProductIDs, coupon.Categories)
+	amounts := amountOff(lines, eligible, coupon.DiscountType, coupon.Value)
+	if amounts == nil {
+		return Discount{}, pkg.ErrCouponNotApplicable
+	}
+
+	return newDiscount(models.OrderDiscount{
+		Source:      models.DiscountSourceCoupon,
+		ReferenceID: coupon.ID,
+		Code:        coupon.Code,
+		Description: coupon.Description,
+	}, amounts), nil
+}
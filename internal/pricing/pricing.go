@@ -0,0 +1,148 @@
+package pricing
+
+import (
+	"slices"
+
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// Line is an order line being priced
+type Line struct {
+	ProductID string
+	Category  string
+	Quantity  int
+	UnitPrice float64
+	// Discount is the amount already taken off the line by earlier discounts
+	Discount float64
+}
+
+// Subtotal is the line's price before discounts
+func (l Line) Subtotal() float64 {
+	return pkg.RoundMoney(l.UnitPrice * float64(l.Quantity))
+}
+
+// Net is the line's price after the discounts applied so far
+func (l Line) Net() float64 {
+	return pkg.RoundMoney(l.Subtotal() - l.Discount)
+}
+
+// unitNet is the price of one unit after the discounts applied so far
+func (l Line) unitNet() float64 {
+	if l.Quantity == 0 {
+		return 0
+	}
+	return l.Net() / float64(l.Quantity)
+}
+
+// Discount is the outcome of a coupon or promotion: the order discount record and
+// how its amount is split over the lines
+type Discount struct {
+	models.OrderDiscount
+	// LineAmounts holds the share of Amount taken off each line, in the order of the lines
+	LineAmounts []float64
+}
+
+func newDiscount(discount models.OrderDiscount, amounts []float64) Discount {
+	var total float64
+	for _, amount := range amounts {
+		total += amount
+	}
+	discount.Amount = pkg.RoundMoney(total)
+	return Discount{OrderDiscount: discount, LineAmounts: amounts}
+}
+
+// Apply takes the discount off the lines
+func (d Discount) Apply(lines []Line) {
+	for i, amount := range d.LineAmounts {
+		lines[i].Discount = pkg.RoundMoney(lines[i].Discount + amount)
+	}
+}
+
+// Subtotal adds up the lines' prices before discounts
+func Subtotal(lines []Line) float64 {
+	var total float64
+	for _, line := range lines {
+		total += line.Subtotal()
+	}
+	return pkg.RoundMoney(total)
+}
+
+// Net adds up the lines' prices after discounts
+func Net(lines []Line) float64 {
+	var total float64
+	for _, line := range lines {
+		total += line.Net()
+	}
+	return pkg.RoundMoney(total)
+}
+
+// split divides amount over the lines selected by eligible in proportion to their net
+// prices. Rounding leftovers go to the last eligible line so the shares add up exactly.
+func split(amount float64, lines []Line, eligible []bool) []float64 {
+	shares := make([]float64, len(lines))
+
+	var base float64
+	last := -1
+	for i, line := range lines {
+		if eligible[i] {
+			base += line.Net()
+			last = i
+		}
+	}
+	if last < 0 || base <= 0 {
+		return shares
+	}
+
+	remaining := amount
+	for i, line := range lines {
+		if !eligible[i] {
+			continue
+		}
+		if i == last {
+			shares[i] = pkg.RoundMoney(remaining)
+			break
+		}
+		shares[i] = pkg.RoundMoney(amount * line.Net() / base)
+		remaining -= shares[i]
+	}
+	return shares
+}
+
+// eligibleLines reports which lines match the product and category restrictions.
+// Without restrictions every line is eligible.
+func eligibleLines(lines []Line, productIDs, categories []string) []bool {
+	eligible := make([]bool, len(lines))
+	for i, line := range lines {
+		eligible[i] = (len(productIDs) == 0 && len(categories) == 0) ||
+			slices.Contains(productIDs, line.ProductID) ||
+			(line.Category != "" && slices.Contains(categories, line.Category))
+	}
+	return eligible
+}
+
+// amountOff takes a percentage or a fixed amount off the eligible lines. It returns
+// nil if there is nothing eligible to discount.
+func amountOff(lines []Line, eligible []bool, discountType models.DiscountType, value float64) []float64 {
+	var base float64
+	for i, line := range lines {
+		if eligible[i] {
+			base += line.Net()
+		}
+	}
+	if base <= 0 {
+		return nil
+	}
+
+	var amount float64
+	switch discountType {
+	case models.DiscountPercentage:
+		amount = pkg.RoundMoney(base * value / 100)
+	case models.DiscountFixed:
+		amount = pkg.RoundMoney(min(value, base))
+	}
+	if amount <= 0 {
+		return nil
+	}
+	return split(amount, lines, eligible)
+}
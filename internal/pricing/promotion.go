@@ -0,0 +1,175 @@
+package pricing
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// ApplyPromotions runs the promotions over the lines in priority order and takes the
+// discounts of the ones that fire off the lines. Each promotion sees the lines as left by
+// the promotions before it. An exclusive promotion only fires if none has fired before it
+// and stops any others from firing after it.
+func ApplyPromotions(promotions []models.Promotion, lines []Line) []Discount {
+	promotions = slices.Clone(promotions)
+	slices.SortStableFunc(promotions, func(a, b models.Promotion) int {
+		return cmp.Compare(a.Priority, b.Priority)
+	})
+
+	var discounts []Discount
+	for i := range promotions {
+		promotion := &promotions[i]
+		if promotion.Exclusive && len(discounts) > 0 {
+			continue
+		}
+
+		amounts := evaluatePromotion(promotion, lines)
+		if amounts == nil {
+			continue
+		}
+
+		discount := newDiscount(models.OrderDiscount{
+			Source:      models.DiscountSourcePromotion,
+			ReferenceID: promotion.ID,
+			Description: promotion.Name,
+		}, amounts)
+		discount.Apply(lines)
+		discounts = append(discounts, discount)
+
+		if promotion.Exclusive {
+			break
+		}
+	}
+	return discounts
+}
+
+// evaluatePromotion returns the amount the promotion takes off each line, or nil if it doesn't fire
+func evaluatePromotion(promotion *models.Promotion, lines []Line) []float64 {
+	rule := promotion.Rule
+	eligible := eligibleLines(lines, promotion.ProductIDs, promotion.Categories)
+
+	switch promotion.Type {
+	case models.PromotionBuyXGetY:
+		return buyXGetY(lines, eligible, rule)
+	case models.PromotionBundle:
+		return bundle(lines, promotion.ProductIDs, rule.BundlePrice)
+	case models.PromotionTieredPrice:
+		return tieredPrice(lines, eligible, rule.Tiers)
+	case models.PromotionOrderThreshold:
+		if Net(lines) < rule.MinOrderValue {
+			return nil
+		}
+		return amountOff(lines, eligible, rule.DiscountType, rule.Value)
+	}
+	return nil
+}
+
+// buyXGetY discounts the cheapest eligible units, get_quantity of them for every
+// buy_quantity + get_quantity units in the order
+func buyXGetY(lines []Line, eligible []bool, rule models.PromotionRule) []float64 {
+	if rule.BuyQuantity <= 0 || rule.GetQuantity <= 0 {
+		return nil
+	}
+	percentOff := rule.GetPercentOff
+	if percentOff == 0 {
+		percentOff = 100
+	}
+
+	var units int
+	var candidates []int
+	for i, line := range lines {
+		if eligible[i] && line.Net() > 0 {
+			units += line.Quantity
+			candidates = append(candidates, i)
+		}
+	}
+	discounted := units / (rule.BuyQuantity + rule.GetQuantity) * rule.GetQuantity
+	if discounted == 0 {
+		return nil
+	}
+
+	slices.SortStableFunc(candidates, func(a, b int) int {
+		return cmp.Compare(lines[a].unitNet(), lines[b].unitNet())
+	})
+
+	amounts := make([]float64, len(lines))
+	for _, i := range candidates {
+		quantity := min(discounted, lines[i].Quantity)
+		amounts[i] = pkg.RoundMoney(lines[i].unitNet() * float64(quantity) * percentOff / 100)
+		discounted -= quantity
+		if discounted == 0 {
+			break
+		}
+	}
+	return amounts
+}
+
+// bundle sells every set of one unit of each component for the bundle price
+func bundle(lines []Line, components []string, price float64) []float64 {
+	if len(components) == 0 {
+		return nil
+	}
+
+	quantities := make(map[string]int)
+	unitPrices := make(map[string]float64)
+	for _, line := range lines {
+		if line.Quantity > quantities[line.ProductID] {
+			quantities[line.ProductID] = line.Quantity
+			unitPrices[line.ProductID] = line.unitNet()
+		}
+	}
+
+	sets := -1
+	var setPrice float64
+	for _, productID := range components {
+		if sets < 0 || quantities[productID] < sets {
+			sets = quantities[productID]
+		}
+		setPrice += unitPrices[productID]
+	}
+	saving := pkg.RoundMoney(float64(sets) * (setPrice - price))
+	if sets <= 0 || saving <= 0 {
+		return nil
+	}
+
+	// the saving is shared by the lines holding the components
+	eligible := make([]bool, len(lines))
+	seen := make(map[string]bool)
+	for i, line := range lines {
+		if slices.Contains(components, line.ProductID) && !seen[line.ProductID] && line.Quantity == quantities[line.ProductID] {
+			eligible[i] = true
+			seen[line.ProductID] = true
+		}
+	}
+	return split(saving, lines, eligible)
+}
+
+// tieredPrice takes the percentage of the highest tier reached by each eligible line's quantity off the line
+func tieredPrice(lines []Line, eligible []bool, tiers []models.PromotionTier) []float64 {
+	amounts := make([]float64, len(lines))
+	fired := false
+	for i, line := range lines {
+		if !eligible[i] {
+			continue
+		}
+
+		var percentOff float64
+		reached := 0
+		for _, tier := range tiers {
+			if line.Quantity >= tier.MinQuantity && tier.MinQuantity > reached {
+				percentOff = tier.PercentOff
+				reached = tier.MinQuantity
+			}
+		}
+		if percentOff > 0 && line.Net() > 0 {
+			amounts[i] = pkg.RoundMoney(line.Net() * percentOff / 100)
+			fired = true
+		}
+	}
+	if !fired {
+		return nil
+	}
+	return amounts
+}
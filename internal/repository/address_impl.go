@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// addressColumns is the column list scanned into models.Address
+const addressColumns = `id, user_id, label, full_name, line1, line2, city, region, postal_code, country, phone, is_default, created_at, updated_at`
+
+func (r *repositoryImpl) CreateAddress(ctx context.Context, tx pgx.Tx, address *models.Address) error {
+	query := `
+        INSERT INTO addresses (id, user_id, label, full_name, line1, line2, city, region, postal_code, country, phone, is_default)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+        RETURNING created_at, updated_at`
+
+	err := pgxscan.Get(ctx, tx, address, query, address.ID, address.UserID, address.Label, address.FullName, address.Line1, address.Line2,
+		address.City, address.Region, address.PostalCode, address.Country, address.Phone, address.IsDefault)
+	if err != nil {
+		return fmt.Errorf("create address: %w", err)
+	}
+	return nil
+}
+
+// GetAddress returns an address of the user. Addresses of other users are reported as not found.
+func (r *repositoryImpl) GetAddress(ctx context.Context, id string) (*models.Address, error) {
+	var address models.Address
+	query := `SELECT ` + addressColumns + ` FROM addresses WHERE id = $1`
+
+	err := pgxscan.Get(ctx, r.db, &address, query, id)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, pkg.ErrNotFound
+		}
+		return nil, fmt.Errorf("get address: %w", err)
+	}
+	return &address, nil
+}
+
+func (r *repositoryImpl) GetDefaultAddress(ctx context.Context, userID string) (*models.Address, error) {
+	var address models.Address
+	query := `SELECT ` + addressColumns + ` FROM addresses WHERE user_id = $1 AND is_default`
+
+	err := pgxscan.Get(ctx, r.db, &address, query, userID)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, pkg.ErrNotFound
+		}
+		return nil, fmt.Errorf("get default address: %w", err)
+	}
+	return &address, nil
+}
+
+// LockAddressBook locks the user's address book until tx ends, so changes to the default
+// address are serialised, and reports whether the user has a default address
+func (r *repositoryImpl) LockAddressBook(ctx context.Context, tx pgx.Tx, userID string) (hasDefault bool, err error) {
+	// NO KEY UPDATE doesn't hold up rows referencing the user being inserted meanwhile
+	var lockedID string
+	err = pgxscan.Get(ctx, tx, &lockedID, `SELECT id FROM users WHERE id = $1 FOR NO KEY UPDATE`, userID)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return false, pkg.ErrNotFound
+		}
+		return false, fmt.Errorf("lock address book: %w", err)
+	}
+
+	query := `SELECT EXISTS (SELECT 1 FROM addresses WHERE user_id = $1 AND is_default)`
+	if err := pgxscan.Get(ctx, tx, &hasDefault, query, userID); err != nil {
+		return false, fmt.Errorf("check default address: %w", err)
+	}
+	return hasDefault, nil
+}
+
+func (r *repositoryImpl) ListAddresses(ctx context.Context, userID string) ([]models.Address, error) {
+	var addresses []models.Address
+	query := `SELECT ` + addressColumns + ` FROM addresses WHERE user_id = $1 ORDER BY is_default DESC, created_at`
+
+	err := pgxscan.Select(ctx, r.db, &addresses, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list addresses: %w", err)
+	}
+	return addresses, nil
+}
+
+func (r *repositoryImpl) UpdateAddress(ctx context.Context, tx pgx.Tx, address *models.Address) error {
+	query := `
+        UPDATE addresses
+        SET label = $1, full_name = $2, line1 = $3, line2 = $4, city = $5, region = $6, postal_code = $7, country = $8, phone = $9,
+            is_default = $10, updated_at = $11
+        WHERE id = $12 AND user_id = $13
+        RETURNING created_at, updated_at`
+	now := time.Now()
+
+	err := pgxscan.Get(ctx, tx, address, query, address.Label, address.FullName, address.Line1, address.Line2, address.City, address.Region,
+		address.PostalCode, address.Country, address.Phone, address.IsDefault, now, address.ID, address.UserID)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return pkg.ErrNotFound
+		}
+		return fmt.Errorf("update address: %w", err)
+	}
+	return nil
+}
+
+// ClearDefaultAddress unsets the user's default address, if any
+func (r *repositoryImpl) ClearDefaultAddress(ctx context.Context, tx pgx.Tx, userID string) error {
+	query := `UPDATE addresses SET is_default = false, updated_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND is_default`
+
+	if _, err := tx.Exec(ctx, query, userID); err != nil {
+		return fmt.Errorf("clear default address: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) DeleteAddress(ctx context.Context, id string) error {
+	query := `DELETE FROM addresses WHERE id = $1`
+
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("delete address: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return pkg.ErrNotFound
+	}
+	return nil
+}
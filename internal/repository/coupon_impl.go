@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// couponColumns is the column list scanned into models.Coupon
+const couponColumns = `id, code, description, discount_type, value, min_order_value, max_uses, max_uses_per_user,
+        product_ids, categories, starts_at, ends_at, is_active, created_at, updated_at`
+
+func (r *repositoryImpl) CreateCoupon(ctx context.Context, coupon *models.Coupon) error {
+	query := `
+        INSERT INTO coupons (id, code, description, discount_type, value, min_order_value, max_uses, max_uses_per_user,
+                             product_ids, categories, starts_at, ends_at, is_active)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+        RETURNING created_at, updated_at`
+
+	err := pgxscan.Get(ctx, r.db, coupon, query, coupon.ID, coupon.Code, coupon.Description, coupon.DiscountType, coupon.Value,
+		coupon.MinOrderValue, coupon.MaxUses, coupon.MaxUsesPerUser, coupon.ProductIDs, coupon.Categories, coupon.StartsAt,
+		coupon.EndsAt, coupon.IsActive)
+	if err != nil {
+		if isPgError(err, pgUniqueViolation) {
+			return pkg.ErrCouponCodeTaken
+		}
+		return fmt.Errorf("create coupon: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) GetCouponByID(ctx context.Context, id string) (*models.Coupon, error) {
+	var coupon models.Coupon
+	query := `SELECT ` + couponColumns + ` FROM coupons WHERE id = $1`
+
+	err := pgxscan.Get(ctx, r.db, &coupon, query, id)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, pkg.ErrNotFound
+		}
+		return nil, fmt.Errorf("get coupon by id: %w", err)
+	}
+	return &coupon, nil
+}
+
+// LockCouponByCode returns the coupon with the given code and locks it until tx ends,
+// so concurrent orders can't redeem it past its usage limits.
+func (r *repositoryImpl) LockCouponByCode(ctx context.Context, tx pgx.Tx, code string) (*models.Coupon, error) {
+	var coupon models.Coupon
+	query := `SELECT ` + couponColumns + ` FROM coupons WHERE code = $1 FOR UPDATE`
+
+	err := pgxscan.Get(ctx, tx, &coupon, query, code)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, pkg.ErrInvalidCoupon
+		}
+		return nil, fmt.Errorf("lock coupon by code: %w", err)
+	}
+	return &coupon, nil
+}
+
+func (r *repositoryImpl) ListCoupons(ctx context.Context) ([]models.Coupon, error) {
+	var coupons []models.Coupon
+	query := `SELECT ` + couponColumns + ` FROM coupons ORDER BY created_at DESC`
+
+	err := pgxscan.Select(ctx, r.db, &coupons, query)
+	if err != nil {
+		return nil, fmt.Errorf("list coupons: %w", err)
+	}
+	return coupons, nil
+}
+
+func (r *repositoryImpl) UpdateCoupon(ctx context.Context, coupon *models.Coupon) error {
+	query := `
+        UPDATE coupons
+        SET code = $1, description = $2, discount_type = $3, value = $4, min_order_value = $5, max_uses = $6,
+            max_uses_per_user = $7, product_ids = $8, categories = $9, starts_at = $10, ends_at = $11, is_active = $12,
+            updated_at = $13
+        WHERE id = $14
+        RETURNING created_at, updated_at`
+	now := time.Now()
+
+	err := pgxscan.Get(ctx, r.db, coupon, query, coupon.Code, coupon.Description, coupon.DiscountType, coupon.Value,
+		coupon.MinOrderValue, coupon.MaxUses, coupon.MaxUsesPerUser, coupon.ProductIDs, coupon.Categories, coupon.StartsAt,
+		coupon.EndsAt, coupon.IsActive, now, coupon.ID)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return pkg.ErrNotFound
+		}
+		if isPgError(err, pgUniqueViolation) {
+			return pkg.ErrCouponCodeTaken
+		}
+		return fmt.Errorf("update coupon: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) DeleteCoupon(ctx context.Context, id string) error {
+	query := `DELETE FROM coupons WHERE id = $1`
+
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		if isPgError(err, pgForeignKeyViolation) {
+			return pkg.ErrCouponInUse
+		}
+		return fmt.Errorf("delete coupon: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return pkg.ErrNotFound
+	}
+	return nil
+}
+
+// CountCouponRedemptions returns how often the coupon has been redeemed in total and by the user
+func (r *repositoryImpl) CountCouponRedemptions(ctx context.Context, tx pgx.Tx, couponID, userID string) (total, byUser int, err error) {
+	query := `SELECT COUNT(*), COUNT(*) FILTER (WHERE user_id = $2) FROM coupon_redemptions WHERE coupon_id = $1`
+
+	if err := tx.QueryRow(ctx, query, couponID, userID).Scan(&total, &byUser); err != nil {
+		return 0, 0, fmt.Errorf("count coupon redemptions: %w", err)
+	}
+	return total, byUser, nil
+}
+
+func (r *repositoryImpl) CreateCouponRedemption(ctx context.Context, tx pgx.Tx, redemption *models.CouponRedemption) error {
+	query := `INSERT INTO coupon_redemptions (id, coupon_id, user_id, order_id, amount) VALUES ($1, $2, $3, $4, $5) RETURNING created_at`
+
+	err := pgxscan.Get(ctx, tx, redemption, query, redemption.ID, redemption.CouponID, redemption.UserID, redemption.OrderID, redemption.Amount)
+	if err != nil {
+		return fmt.Errorf("create coupon redemption: %w", err)
+	}
+	return nil
+}
+
+// DeleteOrderCouponRedemptions gives the coupon uses of an order back, e.g. when it is cancelled
+func (r *repositoryImpl) DeleteOrderCouponRedemptions(ctx context.Context, tx pgx.Tx, orderID string) error {
+	query := `DELETE FROM coupon_redemptions WHERE order_id = $1`
+
+	if _, err := tx.Exec(ctx, query, orderID); err != nil {
+		return fmt.Errorf("delete order coupon redemptions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// invoiceColumns is the column list scanned into models.Invoice
+const invoiceColumns = `id, order_id, kind, number, refund_id, original_number, billing_address, shipping_address, lines,
+        subtotal, discount_total, shipping_total, tax_total, tax_inclusive, total, issued_at`
+
+// NextInvoiceNumber allocates the next number of the kind in the year. The sequence row stays
+// locked until tx ends, so concurrent documents are numbered one after the other and a rolled
+// back document gives its number back.
+func (r *repositoryImpl) NextInvoiceNumber(ctx context.Context, tx pgx.Tx, kind models.InvoiceKind, year int) (int, error) {
+	query := `
+        INSERT INTO invoice_sequences (kind, year, last_number) VALUES ($1, $2, 1)
+        ON CONFLICT (kind, year) DO UPDATE SET last_number = invoice_sequences.last_number + 1
+        RETURNING last_number`
+
+	var number int
+	err := pgxscan.Get(ctx, tx, &number, query, kind, year)
+	if err != nil {
+		return 0, fmt.Errorf("next invoice number: %w", err)
+	}
+	return number, nil
+}
+
+func (r *repositoryImpl) CreateInvoice(ctx context.Context, tx pgx.Tx, invoice *models.Invoice) error {
+	query := `
+        INSERT INTO invoices (id, order_id, kind, number, refund_id, original_number, billing_address, shipping_address, lines,
+                              subtotal, discount_total, shipping_total, tax_total, tax_inclusive, total)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+        RETURNING issued_at`
+
+	err := pgxscan.Get(ctx, tx, invoice, query, invoice.ID, invoice.OrderID, invoice.Kind, invoice.Number, invoice.RefundID,
+		invoice.OriginalNumber, invoice.BillingAddress, invoice.ShippingAddress, invoice.Lines, invoice.Subtotal,
+		invoice.DiscountTotal, invoice.ShippingTotal, invoice.TaxTotal, invoice.TaxInclusive, invoice.Total)
+	if err != nil {
+		return fmt.Errorf("create invoice: %w", err)
+	}
+	return nil
+}
+
+// GetOrderInvoice returns the invoice of an order, not its credit notes
+func (r *repositoryImpl) GetOrderInvoice(ctx context.Context, orderID string) (*models.Invoice, error) {
+	var invoice models.Invoice
+	query := `SELECT ` + invoiceColumns + ` FROM invoices WHERE order_id = $1 AND kind = $2`
+
+	err := pgxscan.Get(ctx, r.db, &invoice, query, orderID, models.InvoiceKindInvoice)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, pkg.ErrNotFound
+		}
+		return nil, fmt.Errorf("get order invoice: %w", err)
+	}
+	return &invoice, nil
+}
+
+func (r *repositoryImpl) GetOrderCreditNote(ctx context.Context, orderID, id string) (*models.Invoice, error) {
+	var invoice models.Invoice
+	query := `SELECT ` + invoiceColumns + ` FROM invoices WHERE order_id = $1 AND id = $2 AND kind = $3`
+
+	err := pgxscan.Get(ctx, r.db, &invoice, query, orderID, id, models.InvoiceKindCreditNote)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, pkg.ErrNotFound
+		}
+		return nil, fmt.Errorf("get order credit note: %w", err)
+	}
+	return &invoice, nil
+}
+
+func (r *repositoryImpl) ListOrderCreditNotes(ctx context.Context, orderID string) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	query := `SELECT ` + invoiceColumns + ` FROM invoices WHERE order_id = $1 AND kind = $2 ORDER BY issued_at, number`
+
+	err := pgxscan.Select(ctx, r.db, &invoices, query, orderID, models.InvoiceKindCreditNote)
+	if err != nil {
+		return nil, fmt.Errorf("list order credit notes: %w", err)
+	}
+	return invoices, nil
+}
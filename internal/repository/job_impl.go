@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// jobColumns is the column list scanned into models.Job
+const jobColumns = `id, kind, payload, status, attempts, max_attempts, run_at, locked_until, locked_by, last_error, unique_key,
+        finished_at, created_at, updated_at`
+
+// EnqueueJob adds a job to the queue. It returns false without adding anything if a job
+// with the same unique key exists.
+func (r *repositoryImpl) EnqueueJob(ctx context.Context, job *models.Job) (bool, error) {
+	query := `
+        INSERT INTO jobs (id, kind, payload, max_attempts, run_at, unique_key)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (unique_key) DO NOTHING
+        RETURNING status, created_at, updated_at`
+
+	err := pgxscan.Get(ctx, r.db, job, query, job.ID, job.Kind, job.Payload, job.MaxAttempts, job.RunAt, job.UniqueKey)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("enqueue job: %w", err)
+	}
+	return true, nil
+}
+
+// CreateJob adds a job to the queue as part of tx, so it only runs if tx commits
+func (r *repositoryImpl) CreateJob(ctx context.Context, tx pgx.Tx, job *models.Job) error {
+	query := `
+        INSERT INTO jobs (id, kind, payload, max_attempts, run_at, unique_key)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING status, created_at, updated_at`
+
+	err := pgxscan.Get(ctx, tx, job, query, job.ID, job.Kind, job.Payload, job.MaxAttempts, job.RunAt, job.UniqueKey)
+	if err != nil {
+		return fmt.Errorf("create job: %w", err)
+	}
+	return nil
+}
+
+// ClaimJobs marks up to limit due jobs of the kind as running for the runner until lockedUntil
+// and counts the attempt. Running jobs whose lock has expired are due again. Jobs locked by
+// another transaction are skipped, so concurrent workers never claim the same job.
+func (r *repositoryImpl) ClaimJobs(ctx context.Context, kind, runner string, now, lockedUntil time.Time, limit int) ([]models.Job, error) {
+	var jobs []models.Job
+	query := `
+        UPDATE jobs SET status = 'running', attempts = attempts + 1, locked_until = $3, locked_by = $4, updated_at = $2
+        WHERE id IN (
+            SELECT id FROM jobs
+            WHERE kind = $1 AND ((status = 'queued' AND run_at <= $2) OR (status = 'running' AND locked_until <= $2))
+            ORDER BY run_at
+            LIMIT $5
+            FOR UPDATE SKIP LOCKED)
+        RETURNING ` + jobColumns
+
+	err := pgxscan.Select(ctx, r.db, &jobs, query, kind, now, lockedUntil, runner, limit)
+	if err != nil {
+		return nil, fmt.Errorf("claim jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// CompleteJob records that a job succeeded. Like the other outcomes it is only recorded while
+// the runner holds the job's lease; pkg.ErrNotFound is returned otherwise.
+func (r *repositoryImpl) CompleteJob(ctx context.Context, id, runner string, at time.Time) error {
+	query := `
+        UPDATE jobs SET status = 'succeeded', locked_until = NULL, locked_by = NULL, last_error = '', finished_at = $1,
+            updated_at = $1
+        WHERE id = $2 AND locked_by = $3 AND locked_until > now()`
+
+	return r.updateJob(ctx, "complete job", query, at, id, runner)
+}
+
+// RetryJob puts a failed job back into the queue, due at runAt
+func (r *repositoryImpl) RetryJob(ctx context.Context, id, runner string, runAt time.Time, reason string) error {
+	query := `
+        UPDATE jobs SET status = 'queued', locked_until = NULL, locked_by = NULL, run_at = $1, last_error = $2, updated_at = $3
+        WHERE id = $4 AND locked_by = $5 AND locked_until > now()`
+
+	return r.updateJob(ctx, "retry job", query, runAt, reason, time.Now(), id, runner)
+}
+
+// FailJob gives up on a job
+func (r *repositoryImpl) FailJob(ctx context.Context, id, runner string, at time.Time, reason string) error {
+	query := `
+        UPDATE jobs SET status = 'failed', locked_until = NULL, locked_by = NULL, last_error = $1, finished_at = $2,
+            updated_at = $2
+        WHERE id = $3 AND locked_by = $4 AND locked_until > now()`
+
+	return r.updateJob(ctx, "fail job", query, reason, at, id, runner)
+}
+
+// ReleaseJob puts an interrupted job back into the queue without counting the attempt
+func (r *repositoryImpl) ReleaseJob(ctx context.Context, id, runner string) error {
+	query := `
+        UPDATE jobs SET status = 'queued', locked_until = NULL, locked_by = NULL, attempts = attempts - 1, updated_at = $1
+        WHERE id = $2 AND locked_by = $3 AND locked_until > now()`
+
+	return r.updateJob(ctx, "release job", query, time.Now(), id, runner)
+}
+
+// DeleteFinishedJobs deletes the jobs with the status that finished before the given time
+func (r *repositoryImpl) DeleteFinishedJobs(ctx context.Context, status models.JobStatus, before time.Time) (int64, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM jobs WHERE status = $1 AND finished_at < $2`, status, before)
+	if err != nil {
+		return 0, fmt.Errorf("delete finished jobs: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+func (r *repositoryImpl) updateJob(ctx context.Context, op, query string, args ...any) error {
+	result, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return pkg.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// notificationColumns is the column list scanned into models.Notification
+const notificationColumns = `id, user_id, type, title, body, data, read_at, created_at`
+
+func (r *repositoryImpl) CreateNotification(ctx context.Context, tx pgx.Tx, notification *models.Notification) error {
+	query := `
+        INSERT INTO notifications (id, user_id, type, title, body, data)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING created_at`
+
+	err := pgxscan.Get(ctx, tx, notification, query, notification.ID, notification.UserID, notification.Type,
+		notification.Title, notification.Body, notification.Data)
+	if err != nil {
+		return fmt.Errorf("create notification: %w", err)
+	}
+	return nil
+}
+
+// ListNotifications returns up to limit of the user's notifications older than the one with
+// the ID before, or the newest ones if before is empty, newest first
+func (r *repositoryImpl) ListNotifications(ctx context.Context, userID string, unread bool, before string, limit int) ([]models.Notification, error) {
+	var notifications []models.Notification
+	query := `
+        SELECT ` + notificationColumns + `
+        FROM notifications
+        WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL) AND ($3 = '' OR id < $3)
+        ORDER BY id DESC
+        LIMIT $4`
+
+	err := pgxscan.Select(ctx, r.db, &notifications, query, userID, unread, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list notifications: %w", err)
+	}
+	return notifications, nil
+}
+
+func (r *repositoryImpl) CountUnreadNotifications(ctx context.Context, userID string) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`
+
+	if err := pgxscan.Get(ctx, r.db, &count, query, userID); err != nil {
+		return 0, fmt.Errorf("count unread notifications: %w", err)
+	}
+	return count, nil
+}
+
+// MarkNotificationRead marks one of the user's notifications read at the given time, unless
+// it has been read before
+func (r *repositoryImpl) MarkNotificationRead(ctx context.Context, userID, id string, at time.Time) (*models.Notification, error) {
+	var notification models.Notification
+	query := `
+        UPDATE notifications SET read_at = COALESCE(read_at, $1)
+        WHERE id = $2 AND user_id = $3
+        RETURNING ` + notificationColumns
+
+	err := pgxscan.Get(ctx, r.db, &notification, query, at, id, userID)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, pkg.ErrNotFound
+		}
+		return nil, fmt.Errorf("mark notification read: %w", err)
+	}
+	return &notification, nil
+}
+
+// MarkAllNotificationsRead marks the user's unread notifications read and returns how many there were
+func (r *repositoryImpl) MarkAllNotificationsRead(ctx context.Context, userID string, at time.Time) (int64, error) {
+	query := `UPDATE notifications SET read_at = $1 WHERE user_id = $2 AND read_at IS NULL`
+
+	result, err := r.db.Exec(ctx, query, at, userID)
+	if err != nil {
+		return 0, fmt.Errorf("mark all notifications read: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// ListNotificationPreferences returns the preferences the user has stored. Event types
+// without one use the defaults.
+func (r *repositoryImpl) ListNotificationPreferences(ctx context.Context, userID string) ([]models.NotificationPreference, error) {
+	var preferences []models.NotificationPreference
+	query := `SELECT event_type, in_app, email FROM notification_preferences WHERE user_id = $1`
+
+	err := pgxscan.Select(ctx, r.db, &preferences, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list notification preferences: %w", err)
+	}
+	return preferences, nil
+}
+
+func (r *repositoryImpl) SaveNotificationPreference(ctx context.Context, tx pgx.Tx, userID string, preference *models.NotificationPreference) error {
+	query := `
+        INSERT INTO notification_preferences (user_id, event_type, in_app, email, updated_at)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (user_id, event_type) DO UPDATE
+        SET in_app = EXCLUDED.in_app, email = EXCLUDED.email, updated_at = EXCLUDED.updated_at`
+
+	_, err := tx.Exec(ctx, query, userID, preference.EventType, preference.InApp, preference.Email, time.Now())
+	if err != nil {
+		return fmt.Errorf("save notification preference: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/models"
+)
+
+// outboxEventColumns is the column list scanned into models.OutboxEvent
+const outboxEventColumns = `position, id, aggregate_type, aggregate_id, type, payload, created_at, published_at, attempts,
+        next_attempt_at, last_error`
+
+// outboxRelayLock is the advisory lock key held by the outbox relay
+const outboxRelayLock = 7301
+
+// CreateOutboxEvent writes an event to the outbox. It is published only if tx commits.
+func (r *repositoryImpl) CreateOutboxEvent(ctx context.Context, tx pgx.Tx, event *models.OutboxEvent) error {
+	query := `
+        INSERT INTO outbox_events (id, aggregate_type, aggregate_id, type, payload)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING position, created_at, next_attempt_at`
+
+	err := pgxscan.Get(ctx, tx, event, query, event.ID, event.AggregateType, event.AggregateID, event.Type, event.Payload)
+	if err != nil {
+		return fmt.Errorf("create outbox event: %w", err)
+	}
+	return nil
+}
+
+// Notify sends a Postgres notification on the channel when tx commits
+func (r *repositoryImpl) Notify(ctx context.Context, tx pgx.Tx, channel, payload string) error {
+	if _, err := tx.Exec(ctx, `SELECT pg_notify($1, $2)`, channel, payload); err != nil {
+		return fmt.Errorf("notify %s: %w", channel, err)
+	}
+	return nil
+}
+
+// ListOrderStatusChanges returns up to limit order status change events after the given
+// position, oldest first. With a user ID only the changes of that user's orders are listed.
+func (r *repositoryImpl) ListOrderStatusChanges(ctx context.Context, after int64, userID string, limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	query := `
+        SELECT ` + outboxEventColumns + `
+        FROM outbox_events
+        WHERE type = 'order.status_changed' AND position > $1 AND ($2 = '' OR payload->>'user_id' = $2)
+        ORDER BY position
+        LIMIT $3`
+
+	err := pgxscan.Select(ctx, r.db, &events, query, after, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list order status changes: %w", err)
+	}
+	return events, nil
+}
+
+// OrderStatusReplayStart returns the position to list order status changes after to resume
+// from the change at lastPosition. It goes back to the first change of a transaction that
+// started within lookback of the one that recorded the last change: positions are taken on
+// insert, so such a change can have an earlier position yet commit later.
+func (r *repositoryImpl) OrderStatusReplayStart(ctx context.Context, lastPosition int64, lookback time.Duration) (int64, error) {
+	var start int64
+	query := `
+        SELECT LEAST($1::bigint, COALESCE(MIN(e.position) - 1, $1::bigint))
+        FROM outbox_events last
+        JOIN outbox_events e ON e.type = 'order.status_changed'
+            AND e.created_at > last.created_at - $2 * interval '1 microsecond'
+        WHERE last.position = $1::bigint`
+
+	if err := r.db.QueryRow(ctx, query, lastPosition, lookback.Microseconds()).Scan(&start); err != nil {
+		return 0, fmt.Errorf("order status replay start: %w", err)
+	}
+	return start, nil
+}
+
+// TryLockOutboxRelay takes the relay's lock until tx ends. It returns false if another
+// relay holds it.
+func (r *repositoryImpl) TryLockOutboxRelay(ctx context.Context, tx pgx.Tx) (bool, error) {
+	var locked bool
+	if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1)`, outboxRelayLock).Scan(&locked); err != nil {
+		return false, fmt.Errorf("lock outbox relay: %w", err)
+	}
+	return locked, nil
+}
+
+// ListPendingOutboxEvents returns the oldest unpublished events that may be published at now.
+// Events queue behind the earlier unpublished events of their aggregate, so an aggregate
+// whose oldest event is waiting for a retry is left out altogether.
+func (r *repositoryImpl) ListPendingOutboxEvents(ctx context.Context, tx pgx.Tx, now time.Time, limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	query := `
+        SELECT ` + outboxEventColumns + `
+        FROM outbox_events e
+        WHERE e.published_at IS NULL
+          AND NOT EXISTS (
+              SELECT 1 FROM outbox_events w
+              WHERE w.aggregate_type = e.aggregate_type AND w.aggregate_id = e.aggregate_id
+                AND w.position <= e.position AND w.published_at IS NULL AND w.next_attempt_at > $1)
+        ORDER BY e.position
+        LIMIT $2`
+
+	err := pgxscan.Select(ctx, tx, &events, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list pending outbox events: %w", err)
+	}
+	return events, nil
+}
+
+func (r *repositoryImpl) MarkOutboxEventPublished(ctx context.Context, tx pgx.Tx, position int64, at time.Time) error {
+	query := `UPDATE outbox_events SET published_at = $1, attempts = attempts + 1, last_error = '' WHERE position = $2`
+
+	if _, err := tx.Exec(ctx, query, at, position); err != nil {
+		return fmt.Errorf("mark outbox event published: %w", err)
+	}
+	return nil
+}
+
+// RecordOutboxEventFailure counts a failed attempt to publish an event and holds the event
+// back until retryAt
+func (r *repositoryImpl) RecordOutboxEventFailure(ctx context.Context, tx pgx.Tx, position int64, retryAt time.Time, reason string) error {
+	query := `UPDATE outbox_events SET attempts = attempts + 1, next_attempt_at = $1, last_error = $2 WHERE position = $3`
+
+	if _, err := tx.Exec(ctx, query, retryAt, reason, position); err != nil {
+		return fmt.Errorf("record outbox event failure: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// CreatePasswordReset stores the password reset. Resets are created without a token hash
+// when their code is only issued once the email with it is sent.
+func (r *repositoryImpl) CreatePasswordReset(ctx context.Context, tx pgx.Tx, reset *models.PasswordReset) error {
+	query := `
+        INSERT INTO password_resets (id, user_id, token_hash, expires_at)
+        VALUES ($1, $2, NULLIF($3, ''), $4)
+        RETURNING created_at`
+
+	err := pgxscan.Get(ctx, tx, reset, query, reset.ID, reset.UserID, reset.TokenHash, reset.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("create password reset: %w", err)
+	}
+	return nil
+}
+
+// LockPasswordReset returns the password reset with the token hash, locked until tx ends
+func (r *repositoryImpl) LockPasswordReset(ctx context.Context, tx pgx.Tx, tokenHash string) (*models.PasswordReset, error) {
+	var reset models.PasswordReset
+	query := `
+        SELECT id, user_id, token_hash, expires_at, used_at, created_at
+        FROM password_resets WHERE token_hash = $1
+        FOR UPDATE`
+
+	err := pgxscan.Get(ctx, tx, &reset, query, tokenHash)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, pkg.ErrNotFound
+		}
+		return nil, fmt.Errorf("lock password reset: %w", err)
+	}
+	return &reset, nil
+}
+
+// IssuePasswordResetToken sets the token hash of the password reset, replacing the code
+// issued before. Resets that were used or have expired at now aren't found.
+func (r *repositoryImpl) IssuePasswordResetToken(ctx context.Context, id, tokenHash string, now time.Time) (*models.PasswordReset, error) {
+	var reset models.PasswordReset
+	query := `
+        UPDATE password_resets SET token_hash = $1
+        WHERE id = $2 AND used_at IS NULL AND expires_at > $3
+        RETURNING id, user_id, token_hash, expires_at, used_at, created_at`
+
+	err := pgxscan.Get(ctx, r.db, &reset, query, tokenHash, id, now)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, pkg.ErrNotFound
+		}
+		return nil, fmt.Errorf("issue password reset token: %w", err)
+	}
+	return &reset, nil
+}
+
+// UsePasswordResets marks all of the user's unused password resets as used, so none of the
+// codes sent before works anymore
+func (r *repositoryImpl) UsePasswordResets(ctx context.Context, tx pgx.Tx, userID string, at time.Time) error {
+	query := `UPDATE password_resets SET used_at = $1 WHERE user_id = $2 AND used_at IS NULL`
+
+	if _, err := tx.Exec(ctx, query, at, userID); err != nil {
+		return fmt.Errorf("use password resets: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) UpdateUserPassword(ctx context.Context, tx pgx.Tx, userID, passwordHash string) error {
+	query := `UPDATE users SET password_hash = $1, updated_at = $2 WHERE id = $3`
+
+	result, err := tx.Exec(ctx, query, passwordHash, time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("update user password: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return pkg.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// paymentColumns is the column list scanned into models.Payment
+const paymentColumns = `id, order_id, provider, reference, status, amount, refunded_amount, failure_reason, created_at, updated_at`
+
+// refundColumns is the column list scanned into models.Refund
+const refundColumns = `id, order_id, return_id, payment_id, provider_reference, status, amount, reason, failure_reason, created_at`
+
+func (r *repositoryImpl) CreatePayment(ctx context.Context, tx pgx.Tx, payment *models.Payment) error {
+	query := `
+        INSERT INTO payments (id, order_id, provider, reference, status, amount, refunded_amount, failure_reason)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING created_at, updated_at`
+
+	err := pgxscan.Get(ctx, tx, payment, query, payment.ID, payment.OrderID, payment.Provider, payment.Reference, payment.Status,
+		payment.Amount, payment.RefundedAmount, payment.FailureReason)
+	if err != nil {
+		return fmt.Errorf("create payment: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) UpdatePayment(ctx context.Context, tx pgx.Tx, payment *models.Payment) error {
+	query := `
+        UPDATE payments SET reference = $1, status = $2, refunded_amount = $3, failure_reason = $4, updated_at = $5
+        WHERE id = $6
+        RETURNING updated_at`
+	now := time.Now()
+
+	err := pgxscan.Get(ctx, tx, payment, query, payment.Reference, payment.Status, payment.RefundedAmount, payment.FailureReason, now, payment.ID)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return pkg.ErrNotFound
+		}
+		return fmt.Errorf("update payment: %w", err)
+	}
+	return nil
+}
+
+// LockPayment loads a payment and locks it until tx ends
+func (r *repositoryImpl) LockPayment(ctx context.Context, tx pgx.Tx, id string) (*models.Payment, error) {
+	var payment models.Payment
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE id = $1 FOR UPDATE`
+
+	err := pgxscan.Get(ctx, tx, &payment, query, id)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, pkg.ErrNotFound
+		}
+		return nil, fmt.Errorf("lock payment: %w", err)
+	}
+	return &payment, nil
+}
+
+// ListPendingPayments returns up to limit payments that have been pending since before the given time, oldest first
+func (r *repositoryImpl) ListPendingPayments(ctx context.Context, before time.Time, limit int) ([]models.Payment, error) {
+	var payments []models.Payment
+	query := `
+        SELECT ` + paymentColumns + `
+        FROM payments WHERE status = 'pending' AND created_at < $1
+        ORDER BY created_at, id LIMIT $2`
+
+	err := pgxscan.Select(ctx, r.db, &payments, query, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list pending payments: %w", err)
+	}
+	return payments, nil
+}
+
+// LockPaymentByReference loads the payment with the provider's reference and locks it until tx ends
+func (r *repositoryImpl) LockPaymentByReference(ctx context.Context, tx pgx.Tx, provider, reference string) (*models.Payment, error) {
+	var payment models.Payment
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE provider = $1 AND reference = $2 FOR UPDATE`
+
+	err := pgxscan.Get(ctx, tx, &payment, query, provider, reference)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, pkg.ErrNotFound
+		}
+		return nil, fmt.Errorf("lock payment by reference: %w", err)
+	}
+	return &payment, nil
+}
+
+// RefundRecorded reports whether a refund with the provider's reference has been recorded
+func (r *repositoryImpl) RefundRecorded(ctx context.Context, tx pgx.Tx, providerReference string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM refunds WHERE provider_reference = $1)`
+
+	var exists bool
+	err := pgxscan.Get(ctx, tx, &exists, query, providerReference)
+	if err != nil {
+		return false, fmt.Errorf("check refund recorded: %w", err)
+	}
+	return exists, nil
+}
+
+// LockRefund loads the refund and locks it until tx ends
+func (r *repositoryImpl) LockRefund(ctx context.Context, tx pgx.Tx, id string) (*models.Refund, error) {
+	var refund models.Refund
+	query := `SELECT ` + refundColumns + ` FROM refunds WHERE id = $1 FOR UPDATE`
+
+	err := pgxscan.Get(ctx, tx, &refund, query, id)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, pkg.ErrNotFound
+		}
+		return nil, fmt.Errorf("lock refund: %w", err)
+	}
+	return &refund, nil
+}
+
+// UpdateRefund stores the outcome of a refund
+func (r *repositoryImpl) UpdateRefund(ctx context.Context, tx pgx.Tx, refund *models.Refund) error {
+	query := `UPDATE refunds SET status = $1, provider_reference = $2, failure_reason = $3 WHERE id = $4`
+
+	result, err := tx.Exec(ctx, query, refund.Status, refund.ProviderReference, refund.FailureReason, refund.ID)
+	if err != nil {
+		return fmt.Errorf("update refund: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return pkg.ErrNotFound
+	}
+	return nil
+}
+
+// ListPendingRefunds returns up to limit refunds that have been pending since before the given time, oldest first
+func (r *repositoryImpl) ListPendingRefunds(ctx context.Context, before time.Time, limit int) ([]models.Refund, error) {
+	var refunds []models.Refund
+	query := `
+        SELECT ` + refundColumns + `
+        FROM refunds WHERE status = 'pending' AND created_at < $1
+        ORDER BY created_at, id LIMIT $2`
+
+	err := pgxscan.Select(ctx, r.db, &refunds, query, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list pending refunds: %w", err)
+	}
+	return refunds, nil
+}
+
+// CreatePaymentEvent stores a webhook event. It returns false without storing anything if
+// the provider's event has been stored before.
+func (r *repositoryImpl) CreatePaymentEvent(ctx context.Context, tx pgx.Tx, event *models.PaymentEvent) (bool, error) {
+	query := `
+        INSERT INTO payment_events (id, provider, event_id, type, payload)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (provider, event_id) DO NOTHING
+        RETURNING received_at`
+
+	err := pgxscan.Get(ctx, tx, event, query, event.ID, event.Provider, event.EventID, event.Type, event.Payload)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("create payment event: %w", err)
+	}
+	return true, nil
+}
+
+// listOrderPayments returns the payments of an order, oldest first
+func (r *repositoryImpl) listOrderPayments(ctx context.Context, orderID string) ([]models.Payment, error) {
+	var payments []models.Payment
+	query := `
+        SELECT ` + paymentColumns + `
+        FROM payments WHERE order_id = $1 ORDER BY created_at, id`
+
+	err := pgxscan.Select(ctx, r.db, &payments, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("list order payments: %w", err)
+	}
+	return payments, nil
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// effectivePricesQuery selects the price in effect at $1 for every product that has one:
+// of the entries whose window contains $1, the one that started last wins
+const effectivePricesQuery = `
+        SELECT DISTINCT ON (product_id) product_id, price
+        FROM product_prices
+        WHERE effective_from <= $1 AND (effective_to IS NULL OR effective_to > $1)
+        ORDER BY product_id, effective_from DESC, created_at DESC`
+
+func (r *repositoryImpl) CreateProductPrice(ctx context.Context, tx pgx.Tx, price *models.ProductPrice) error {
+	query := `INSERT INTO product_prices (id, product_id, price, effective_from, effective_to) VALUES ($1, $2, $3, $4, $5) RETURNING created_at`
+
+	err := pgxscan.Get(ctx, tx, price, query, price.ID, price.ProductID, price.Price, price.EffectiveFrom, price.EffectiveTo)
+	if err != nil {
+		return fmt.Errorf("create product price: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) ListProductPrices(ctx context.Context, productID string) ([]models.ProductPrice, error) {
+	var prices []models.ProductPrice
+	query := `
+        SELECT id, product_id, price, effective_from, effective_to, created_at
+        FROM product_prices
+        WHERE product_id = $1
+        ORDER BY effective_from DESC, created_at DESC`
+
+	err := pgxscan.Select(ctx, r.db, &prices, query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("list product prices: %w", err)
+	}
+	return prices, nil
+}
+
+// DeleteScheduledProductPrice removes a price change that hasn't taken effect yet
+func (r *repositoryImpl) DeleteScheduledProductPrice(ctx context.Context, productID, priceID string, now time.Time) error {
+	var effectiveFrom time.Time
+	query := `SELECT effective_from FROM product_prices WHERE id = $1 AND product_id = $2`
+
+	err := r.db.QueryRow(ctx, query, priceID, productID).Scan(&effectiveFrom)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return pkg.ErrNotFound
+		}
+		return fmt.Errorf("get product price: %w", err)
+	}
+	if !effectiveFrom.After(now) {
+		return pkg.ErrPriceInEffect
+	}
+
+	query = `DELETE FROM product_prices WHERE id = $1 AND effective_from > $2`
+
+	result, err := r.db.Exec(ctx, query, priceID, now)
+	if err != nil {
+		return fmt.Errorf("delete product price: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return pkg.ErrPriceInEffect
+	}
+	return nil
+}
+
+// GetEffectivePrices returns the price in effect at the given time for each of the products.
+// Products without price history are left out.
+func (r *repositoryImpl) GetEffectivePrices(ctx context.Context, productIDs []string, at time.Time) (map[string]float64, error) {
+	var rows []struct {
+		ProductID string  `db:"product_id"`
+		Price     float64 `db:"price"`
+	}
+	query := `SELECT product_id, price FROM (` + effectivePricesQuery + `) e WHERE product_id = ANY($2)`
+
+	err := pgxscan.Select(ctx, r.db, &rows, query, at, productIDs)
+	if err != nil {
+		return nil, fmt.Errorf("get effective prices: %w", err)
+	}
+
+	prices := make(map[string]float64, len(rows))
+	for _, row := range rows {
+		prices[row.ProductID] = row.Price
+	}
+	return prices, nil
+}
+
+// SyncProductPrices copies the price in effect at now into products.price where they differ
+// and returns the updated products
+func (r *repositoryImpl) SyncProductPrices(ctx context.Context, tx pgx.Tx, now time.Time) ([]models.Product, error) {
+	var products []models.Product
+	query := `
+        UPDATE products p SET price = e.price, version = p.version + 1, updated_at = $1
+        FROM (` + effectivePricesQuery + `) e
+        WHERE p.id = e.product_id AND p.price <> e.price
+        RETURNING p.id, p.name, p.description, p.price, p.category, p.tax_class, p.weight_kg, p.length_cm, p.width_cm, p.height_cm,
+                  p.stock_quantity, p.reorder_threshold, p.version, p.status, p.publish_at, p.unpublish_at, p.archived_at,
+                  p.created_at, p.updated_at`
+
+	err := pgxscan.Select(ctx, tx, &products, query, now)
+	if err != nil {
+		return nil, fmt.Errorf("sync product prices: %w", err)
+	}
+	return products, nil
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// promotionColumns is the column list scanned into models.Promotion
+const promotionColumns = `id, name, description, type, rule, product_ids, categories, priority, exclusive,
+        starts_at, ends_at, is_active, created_at, updated_at`
+
+func (r *repositoryImpl) CreatePromotion(ctx context.Context, promotion *models.Promotion) error {
+	query := `
+        INSERT INTO promotions (id, name, description, type, rule, product_ids, categories, priority, exclusive, starts_at, ends_at, is_active)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+        RETURNING created_at, updated_at`
+
+	err := pgxscan.Get(ctx, r.db, promotion, query, promotion.ID, promotion.Name, promotion.Description, promotion.Type, promotion.Rule,
+		promotion.ProductIDs, promotion.Categories, promotion.Priority, promotion.Exclusive, promotion.StartsAt, promotion.EndsAt,
+		promotion.IsActive)
+	if err != nil {
+		return fmt.Errorf("create promotion: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) GetPromotionByID(ctx context.Context, id string) (*models.Promotion, error) {
+	var promotion models.Promotion
+	query := `SELECT ` + promotionColumns + ` FROM promotions WHERE id = $1`
+
+	err := pgxscan.Get(ctx, r.db, &promotion, query, id)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, pkg.ErrNotFound
+		}
+		return nil, fmt.Errorf("get promotion by id: %w", err)
+	}
+	return &promotion, nil
+}
+
+func (r *repositoryImpl) ListPromotions(ctx context.Context) ([]models.Promotion, error) {
+	var promotions []models.Promotion
+	query := `SELECT ` + promotionColumns + ` FROM promotions ORDER BY priority, created_at`
+
+	err := pgxscan.Select(ctx, r.db, &promotions, query)
+	if err != nil {
+		return nil, fmt.Errorf("list promotions: %w", err)
+	}
+	return promotions, nil
+}
+
+// ListActivePromotions returns the promotions that are enabled and running at the given time, in priority order
+func (r *repositoryImpl) ListActivePromotions(ctx context.Context, at time.Time) ([]models.Promotion, error) {
+	var promotions []models.Promotion
+	query := `
+        SELECT ` + promotionColumns + ` FROM promotions
+        WHERE is_active AND (starts_at IS NULL OR starts_at <= $1) AND (ends_at IS NULL OR ends_at > $1)
+        ORDER BY priority, created_at`
+
+	err := pgxscan.Select(ctx, r.db, &promotions, query, at)
+	if err != nil {
+		return nil, fmt.Errorf("list active promotions: %w", err)
+	}
+	return promotions, nil
+}
+
+func (r *repositoryImpl) UpdatePromotion(ctx context.Context, promotion *models.Promotion) error {
+	query := `
+        UPDATE promotions
+        SET name = $1, description = $2, type = $3, rule = $4, product_ids = $5, categories = $6, priority = $7,
+            exclusive = $8, starts_at = $9, ends_at = $10, is_active = $11, updated_at = $12
+        WHERE id = $13
+        RETURNING created_at, updated_at`
+	now := time.Now()
+
+	err := pgxscan.Get(ctx, r.db, promotion, query, promotion.Name, promotion.Description, promotion.Type, promotion.Rule,
+		promotion.ProductIDs, promotion.Categories, promotion.Priority, promotion.Exclusive, promotion.StartsAt, promotion.EndsAt,
+		promotion.IsActive, now, promotion.ID)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return pkg.ErrNotFound
+		}
+		return fmt.Errorf("update promotion: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) DeletePromotion(ctx context.Context, id string) error {
+	query := `DELETE FROM promotions WHERE id = $1`
+
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("delete promotion: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return pkg.ErrNotFound
+	}
+	return nil
+}
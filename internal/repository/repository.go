@@ -2,29 +2,173 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/zde37/instashop-task/internal/models"
 )
 
 type Repository interface {
-	CreateUser(ctx context.Context, user *models.User) error
+	CreateUser(ctx context.Context, tx pgx.Tx, user *models.User) error
 	GetUser(ctx context.Context, identifier, data string) (*models.User, error)
+	ListUsersByRole(ctx context.Context, role models.UserRole) ([]models.User, error)
+	UpdateUserPassword(ctx context.Context, tx pgx.Tx, userID, passwordHash string) error
+	CreatePasswordReset(ctx context.Context, tx pgx.Tx, reset *models.PasswordReset) error
+	LockPasswordReset(ctx context.Context, tx pgx.Tx, tokenHash string) (*models.PasswordReset, error)
+	IssuePasswordResetToken(ctx context.Context, id, tokenHash string, now time.Time) (*models.PasswordReset, error)
+	UsePasswordResets(ctx context.Context, tx pgx.Tx, userID string, at time.Time) error
 
 	WithTransaction(ctx context.Context, fn func(pgx.Tx) error) error
-	CreateProduct(ctx context.Context, product *models.Product) error
+	CreateProduct(ctx context.Context, tx pgx.Tx, product *models.Product) error
 	GetProductByID(ctx context.Context, id string) (*models.Product, error)
-	ListProducts(ctx context.Context) ([]models.Product, error)
-	UpdateProduct(ctx context.Context, product *models.Product) error
-	DeleteProduct(ctx context.Context, id string) error
+	ListProducts(ctx context.Context, filter models.ProductFilter) ([]models.Product, error)
+	UpdateProduct(ctx context.Context, tx pgx.Tx, product *models.Product) error
+	DeleteProduct(ctx context.Context, tx pgx.Tx, id string) (*models.Product, error)
+	SetProductArchived(ctx context.Context, tx pgx.Tx, id string, archived bool) (*models.Product, error)
+	UpdateProductStatus(ctx context.Context, tx pgx.Tx, product *models.Product) error
+	ApplyProductSchedules(ctx context.Context, tx pgx.Tx, now time.Time) (published, unpublished []models.Product, err error)
+
+	CreateProductPrice(ctx context.Context, tx pgx.Tx, price *models.ProductPrice) error
+	ListProductPrices(ctx context.Context, productID string) ([]models.ProductPrice, error)
+	DeleteScheduledProductPrice(ctx context.Context, productID, priceID string, now time.Time) error
+	GetEffectivePrices(ctx context.Context, productIDs []string, at time.Time) (map[string]float64, error)
+	SyncProductPrices(ctx context.Context, tx pgx.Tx, now time.Time) ([]models.Product, error)
+
+	CreateCoupon(ctx context.Context, coupon *models.Coupon) error
+	GetCouponByID(ctx context.Context, id string) (*models.Coupon, error)
+	LockCouponByCode(ctx context.Context, tx pgx.Tx, code string) (*models.Coupon, error)
+	ListCoupons(ctx context.Context) ([]models.Coupon, error)
+	UpdateCoupon(ctx context.Context, coupon *models.Coupon) error
+	DeleteCoupon(ctx context.Context, id string) error
+	CountCouponRedemptions(ctx context.Context, tx pgx.Tx, couponID, userID string) (total, byUser int, err error)
+	CreateCouponRedemption(ctx context.Context, tx pgx.Tx, redemption *models.CouponRedemption) error
+	DeleteOrderCouponRedemptions(ctx context.Context, tx pgx.Tx, orderID string) error
+
+	CreatePromotion(ctx context.Context, promotion *models.Promotion) error
+	GetPromotionByID(ctx context.Context, id string) (*models.Promotion, error)
+	ListPromotions(ctx context.Context) ([]models.Promotion, error)
+	ListActivePromotions(ctx context.Context, at time.Time) ([]models.Promotion, error)
+	UpdatePromotion(ctx context.Context, promotion *models.Promotion) error
+	DeletePromotion(ctx context.Context, id string) error
+
+	CreateTaxRate(ctx context.Context, rate *models.TaxRate) error
+	ListTaxRates(ctx context.Context) ([]models.TaxRate, error)
+	ListTaxRatesByRegion(ctx context.Context, regions []string) ([]models.TaxRate, error)
+	UpdateTaxRate(ctx context.Context, rate *models.TaxRate) error
+	DeleteTaxRate(ctx context.Context, id string) error
+
+	CreateShippingMethod(ctx context.Context, method *models.ShippingMethod) error
+	GetShippingMethodByID(ctx context.Context, id string) (*models.ShippingMethod, error)
+	GetShippingMethodByCode(ctx context.Context, code string) (*models.ShippingMethod, error)
+	ListShippingMethods(ctx context.Context, activeOnly bool) ([]models.ShippingMethod, error)
+	UpdateShippingMethod(ctx context.Context, method *models.ShippingMethod) error
+	DeleteShippingMethod(ctx context.Context, id string) error
 
 	CreateOrder(ctx context.Context, tx pgx.Tx, order *models.Order) error
 	GetOrderByID(ctx context.Context, id string) (*models.Order, error)
-	GetOrderByUserID(ctx context.Context, userID string) ([]models.Order, error)
-	UpdateOrderStatus(ctx context.Context, id string, status models.OrderStatus) error
+	ListUserOrders(ctx context.Context, userID string, filter models.UserOrderFilter, after *models.OrderCursor, limit int) ([]models.Order, error)
+	UserOrderSummary(ctx context.Context, userID string, filter models.UserOrderFilter) (*models.OrderSummary, error)
+	ListOrderItems(ctx context.Context, orderIDs []string) (map[string][]models.OrderItem, error)
+	ListOrders(ctx context.Context, filter models.OrderFilter) ([]models.Order, int, error)
+	ListStalePendingOrders(ctx context.Context, before time.Time, limit int) ([]string, error)
+	LockOrder(ctx context.Context, tx pgx.Tx, id string) error
+	UpdateOrderStatus(ctx context.Context, tx pgx.Tx, id string, status models.OrderStatus) error
+	CreateShipment(ctx context.Context, tx pgx.Tx, shipment *models.Shipment) error
+	MarkShipmentDelivered(ctx context.Context, tx pgx.Tx, id string, at time.Time) error
+
+	CreateReturn(ctx context.Context, tx pgx.Tx, ret *models.Return) error
+	GetReturn(ctx context.Context, id string) (*models.Return, error)
+	ListReturns(ctx context.Context, status models.ReturnStatus) ([]models.Return, error)
+	ListOrderReturns(ctx context.Context, orderID string) ([]models.Return, error)
+	UpdateReturn(ctx context.Context, tx pgx.Tx, ret *models.Return) error
+	AddReturnedQuantity(ctx context.Context, tx pgx.Tx, orderItemID string, quantity int) error
+	CreateRefund(ctx context.Context, tx pgx.Tx, refund *models.Refund) error
+
+	CreatePayment(ctx context.Context, tx pgx.Tx, payment *models.Payment) error
+	LockPayment(ctx context.Context, tx pgx.Tx, id string) (*models.Payment, error)
+	LockPaymentByReference(ctx context.Context, tx pgx.Tx, provider, reference string) (*models.Payment, error)
+	ListPendingPayments(ctx context.Context, before time.Time, limit int) ([]models.Payment, error)
+	UpdatePayment(ctx context.Context, tx pgx.Tx, payment *models.Payment) error
+	RefundRecorded(ctx context.Context, tx pgx.Tx, providerReference string) (bool, error)
+	LockRefund(ctx context.Context, tx pgx.Tx, id string) (*models.Refund, error)
+	UpdateRefund(ctx context.Context, tx pgx.Tx, refund *models.Refund) error
+	ListPendingRefunds(ctx context.Context, before time.Time, limit int) ([]models.Refund, error)
+	CreatePaymentEvent(ctx context.Context, tx pgx.Tx, event *models.PaymentEvent) (bool, error)
+
+	NextInvoiceNumber(ctx context.Context, tx pgx.Tx, kind models.InvoiceKind, year int) (int, error)
+	CreateInvoice(ctx context.Context, tx pgx.Tx, invoice *models.Invoice) error
+	GetOrderInvoice(ctx context.Context, orderID string) (*models.Invoice, error)
+	GetOrderCreditNote(ctx context.Context, orderID, id string) (*models.Invoice, error)
+	ListOrderCreditNotes(ctx context.Context, orderID string) ([]models.Invoice, error)
+
+	CreateOutboxEvent(ctx context.Context, tx pgx.Tx, event *models.OutboxEvent) error
+	Notify(ctx context.Context, tx pgx.Tx, channel, payload string) error
+	ListOrderStatusChanges(ctx context.Context, after int64, userID string, limit int) ([]models.OutboxEvent, error)
+	OrderStatusReplayStart(ctx context.Context, lastPosition int64, lookback time.Duration) (int64, error)
+	TryLockOutboxRelay(ctx context.Context, tx pgx.Tx) (bool, error)
+	ListPendingOutboxEvents(ctx context.Context, tx pgx.Tx, now time.Time, limit int) ([]models.OutboxEvent, error)
+	MarkOutboxEventPublished(ctx context.Context, tx pgx.Tx, position int64, at time.Time) error
+	RecordOutboxEventFailure(ctx context.Context, tx pgx.Tx, position int64, retryAt time.Time, reason string) error
+
+	CreateWebhookSubscription(ctx context.Context, subscription *models.WebhookSubscription) error
+	GetWebhookSubscription(ctx context.Context, id string) (*models.WebhookSubscription, error)
+	ListWebhookSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	ListEventSubscriptions(ctx context.Context, tx pgx.Tx, eventType string) ([]models.WebhookSubscription, error)
+	UpdateWebhookSubscription(ctx context.Context, subscription *models.WebhookSubscription) error
+	DeleteWebhookSubscription(ctx context.Context, id string) error
+	CreateWebhookDelivery(ctx context.Context, tx pgx.Tx, delivery *models.WebhookDelivery) (bool, error)
+	ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error)
+	RecordWebhookAttempt(ctx context.Context, tx pgx.Tx, delivery *models.WebhookDelivery, attempt *models.WebhookDeliveryAttempt) error
+	ListWebhookDeliveries(ctx context.Context, subscriptionID string, status models.WebhookDeliveryStatus, limit int) ([]models.WebhookDelivery, error)
+	GetWebhookDelivery(ctx context.Context, subscriptionID, id string) (*models.WebhookDelivery, error)
+	ResetWebhookDelivery(ctx context.Context, subscriptionID, id string, now time.Time) error
+
+	EnqueueJob(ctx context.Context, job *models.Job) (bool, error)
+	CreateJob(ctx context.Context, tx pgx.Tx, job *models.Job) error
+	ClaimJobs(ctx context.Context, kind, runner string, now, lockedUntil time.Time, limit int) ([]models.Job, error)
+	CompleteJob(ctx context.Context, id, runner string, at time.Time) error
+	RetryJob(ctx context.Context, id, runner string, runAt time.Time, reason string) error
+	FailJob(ctx context.Context, id, runner string, at time.Time, reason string) error
+	ReleaseJob(ctx context.Context, id, runner string) error
+	DeleteFinishedJobs(ctx context.Context, status models.JobStatus, before time.Time) (int64, error)
+
+	CreateWarehouse(ctx context.Context, warehouse *models.Warehouse) error
+	GetWarehouseByID(ctx context.Context, id string) (*models.Warehouse, error)
+	GetDefaultWarehouse(ctx context.Context) (*models.Warehouse, error)
+	ListWarehouses(ctx context.Context) ([]models.Warehouse, error)
+	UpdateWarehouse(ctx context.Context, warehouse *models.Warehouse) error
+	ListProductStock(ctx context.Context, productID string) ([]models.WarehouseStock, error)
+	LockWarehouseStock(ctx context.Context, tx pgx.Tx, productIDs []string) ([]models.WarehouseStock, error)
+	LockProductStock(ctx context.Context, tx pgx.Tx, productID string) (int, error)
+	SetWarehouseStock(ctx context.Context, tx pgx.Tx, warehouseID, productID string, quantity int) error
+	AdjustWarehouseStock(ctx context.Context, tx pgx.Tx, warehouseID, productID string, delta int) error
+
+	ListLowStockProducts(ctx context.Context) ([]models.Product, error)
+	CreateStockSubscription(ctx context.Context, subscription *models.StockSubscription) error
+	DeleteStockSubscription(ctx context.Context, userID, productID string) error
+	ListPendingStockSubscriptions(ctx context.Context, productID string) ([]models.StockSubscription, error)
+	MarkStockSubscriptionNotified(ctx context.Context, id string) error
+
+	CreateAddress(ctx context.Context, tx pgx.Tx, address *models.Address) error
+	GetAddress(ctx context.Context, id string) (*models.Address, error)
+	GetDefaultAddress(ctx context.Context, userID string) (*models.Address, error)
+	LockAddressBook(ctx context.Context, tx pgx.Tx, userID string) (hasDefault bool, err error)
+	ListAddresses(ctx context.Context, userID string) ([]models.Address, error)
+	UpdateAddress(ctx context.Context, tx pgx.Tx, address *models.Address) error
+	ClearDefaultAddress(ctx context.Context, tx pgx.Tx, userID string) error
+	DeleteAddress(ctx context.Context, id string) error
 
 	CreateSession(ctx context.Context, session *models.Session) error
 	GetSessionByID(ctx context.Context, id string) (*models.Session, error)
 	DeleteSessionByUserID(ctx context.Context, userID string) error
 	BlockSession(ctx context.Context, id string) error
+	DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error)
+
+	CreateNotification(ctx context.Context, tx pgx.Tx, notification *models.Notification) error
+	ListNotifications(ctx context.Context, userID string, unread bool, before string, limit int) ([]models.Notification, error)
+	CountUnreadNotifications(ctx context.Context, userID string) (int, error)
+	MarkNotificationRead(ctx context.Context, userID, id string, at time.Time) (*models.Notification, error)
+	MarkAllNotificationsRead(ctx context.Context, userID string, at time.Time) (int64, error)
+	ListNotificationPreferences(ctx context.Context, userID string) ([]models.NotificationPreference, error)
+	SaveNotificationPreference(ctx context.Context, tx pgx.Tx, userID string, preference *models.NotificationPreference) error
 }
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/georgysavva/scany/v2/pgxscan"
@@ -12,6 +13,13 @@ import (
 	"github.com/zde37/instashop-task/pkg"
 )
 
+// productColumns is the column list scanned into models.Product
+const productColumns = `id, name, description, price, category, tax_class, weight_kg, length_cm, width_cm, height_cm, stock_quantity, reorder_threshold, version, status, publish_at, unpublish_at, archived_at, created_at, updated_at`
+
+// orderColumns is the column list scanned into models.Order
+const orderColumns = `id, user_id, status, subtotal, discount_total, tax_total, tax_region, tax_inclusive, shipping_method, shipping_total,
+        total_amount, coupon_code, shipping_address, billing_address, created_at, updated_at`
+
 type repositoryImpl struct {
 	db *pgxpool.Pool
 }
@@ -20,10 +28,10 @@ func New(db *pgxpool.Pool) Repository {
 	return &repositoryImpl{db: db}
 }
 
-func (r *repositoryImpl) CreateUser(ctx context.Context, user *models.User) error {
+func (r *repositoryImpl) CreateUser(ctx context.Context, tx pgx.Tx, user *models.User) error {
 	query := `INSERT INTO users (id, email, password_hash, role) VALUES ($1, $2, $3, $4) RETURNING created_at, updated_at`
 
-	err := pgxscan.Get(ctx, r.db, user, query, user.ID, user.Email, user.PasswordHash, user.Role)
+	err := pgxscan.Get(ctx, tx, user, query, user.ID, user.Email, user.PasswordHash, user.Role)
 	if err != nil {
 		return fmt.Errorf("create user: %w", err)
 	}
@@ -44,10 +52,28 @@ func (r *repositoryImpl) GetUser(ctx context.Context, identifier, data string) (
 	return &user, nil
 }
 
-func (r *repositoryImpl) CreateProduct(ctx context.Context, product *models.Product) error {
-	query := `INSERT INTO products (id, name, description, price, stock_quantity) VALUES ($1, $2, $3, $4, $5) RETURNING created_at, updated_at`
+func (r *repositoryImpl) ListUsersByRole(ctx context.Context, role models.UserRole) ([]models.User, error) {
+	var users []models.User
+	query := `SELECT id, email, password_hash, role, created_at, updated_at FROM users WHERE role = $1 ORDER BY created_at`
 
-	err := pgxscan.Get(ctx, r.db, product, query, product.ID, product.Name, product.Description, product.Price, product.StockQuantity)
+	err := pgxscan.Select(ctx, r.db, &users, query, role)
+	if err != nil {
+		return nil, fmt.Errorf("list users by role: %w", err)
+	}
+	return users, nil
+}
+
+func (r *repositoryImpl) CreateProduct(ctx context.Context, tx pgx.Tx, product *models.Product) error {
+	// stock_quantity is maintained from warehouse_stock, so it starts at zero
+	query := `
+        INSERT INTO products (id, name, description, price, category, tax_class, weight_kg, length_cm, width_cm, height_cm,
+                              reorder_threshold, status, publish_at, unpublish_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+        RETURNING version, created_at, updated_at`
+
+	err := pgxscan.Get(ctx, tx, product, query, product.ID, product.Name, product.Description, product.Price, product.Category,
+		product.TaxClass, product.WeightKg, product.LengthCm, product.WidthCm, product.HeightCm, product.ReorderThreshold,
+		product.Status, product.PublishAt, product.UnpublishAt)
 	if err != nil {
 		return fmt.Errorf("create product: %w", err)
 	}
@@ -56,7 +82,7 @@ func (r *repositoryImpl) CreateProduct(ctx context.Context, product *models.Prod
 
 func (r *repositoryImpl) GetProductByID(ctx context.Context, id string) (*models.Product, error) {
 	var product models.Product
-	query := `SELECT id, name, description, price, stock_quantity, created_at, updated_at FROM products WHERE id = $1`
+	query := `SELECT ` + productColumns + ` FROM products WHERE id = $1`
 
 	err := pgxscan.Get(ctx, r.db, &product, query, id)
 	if err != nil {
@@ -68,49 +94,157 @@ func (r *repositoryImpl) GetProductByID(ctx context.Context, id string) (*models
 	return &product, nil
 }
 
-func (r *repositoryImpl) ListProducts(ctx context.Context) ([]models.Product, error) {
+func (r *repositoryImpl) ListProducts(ctx context.Context, filter models.ProductFilter) ([]models.Product, error) {
 	var products []models.Product
-	query := `SELECT id, name, description, price, stock_quantity, created_at, updated_at FROM products ORDER BY created_at DESC`
+	query := `
+        SELECT ` + productColumns + ` FROM products
+        WHERE ($1 OR archived_at IS NULL) AND (cardinality($2::text[]) = 0 OR status = ANY($2))
+        ORDER BY created_at DESC`
 
-	err := pgxscan.Select(ctx, r.db, &products, query)
+	err := pgxscan.Select(ctx, r.db, &products, query, filter.IncludeArchived, productStatuses(filter.Statuses))
 	if err != nil {
 		return nil, fmt.Errorf("list products: %w", err)
 	}
 	return products, nil
 }
 
-func (r *repositoryImpl) UpdateProduct(ctx context.Context, product *models.Product) error {
-	query := `UPDATE products SET name = $1, description = $2, price = $3, stock_quantity = $4, updated_at = $5 WHERE id = $6 RETURNING updated_at`
+// UpdateProduct updates the product if its version still matches product.Version and
+// increments the version. It returns pkg.ErrVersionMismatch if the product was changed in between.
+func (r *repositoryImpl) UpdateProduct(ctx context.Context, tx pgx.Tx, product *models.Product) error {
+	query := `
+        UPDATE products
+        SET name = $1, description = $2, price = $3, category = $4, tax_class = $5, weight_kg = $6, length_cm = $7, width_cm = $8,
+            height_cm = $9, reorder_threshold = $10, version = version + 1, updated_at = $11
+        WHERE id = $12 AND version = $13
+        RETURNING version, updated_at`
 	now := time.Now()
 
-	err := pgxscan.Get(ctx, r.db, &product.UpdatedAt, query, product.Name, product.Description, product.Price, product.StockQuantity, now, product.ID)
+	err := pgxscan.Get(ctx, tx, product, query, product.Name, product.Description, product.Price, product.Category, product.TaxClass,
+		product.WeightKg, product.LengthCm, product.WidthCm, product.HeightCm, product.ReorderThreshold, now, product.ID, product.Version)
 	if err != nil {
 		if pgxscan.NotFound(err) {
-			return pkg.ErrNotFound
+			return r.productUpdateMiss(ctx, tx, product.ID)
 		}
 		return fmt.Errorf("update product: %w", err)
 	}
 	return nil
 }
 
-func (r *repositoryImpl) DeleteProduct(ctx context.Context, id string) error {
-	query := `DELETE FROM products WHERE id = $1`
+// UpdateProductStatus changes the lifecycle fields of the product if its version still matches product.Version
+func (r *repositoryImpl) UpdateProductStatus(ctx context.Context, tx pgx.Tx, product *models.Product) error {
+	query := `
+        UPDATE products SET status = $1, publish_at = $2, unpublish_at = $3, version = version + 1, updated_at = $4
+        WHERE id = $5 AND version = $6
+        RETURNING version, updated_at`
+	now := time.Now()
 
-	result, err := r.db.Exec(ctx, query, id)
+	err := pgxscan.Get(ctx, tx, product, query, product.Status, product.PublishAt, product.UnpublishAt, now, product.ID, product.Version)
 	if err != nil {
-		return fmt.Errorf("delete product: %w", err)
+		if pgxscan.NotFound(err) {
+			return r.productUpdateMiss(ctx, tx, product.ID)
+		}
+		return fmt.Errorf("update product status: %w", err)
 	}
+	return nil
+}
 
-	if result.RowsAffected() == 0 {
+// ApplyProductSchedules publishes scheduled products whose publish time has come and
+// discontinues active products whose unpublish time has passed. It returns the changed products.
+func (r *repositoryImpl) ApplyProductSchedules(ctx context.Context, tx pgx.Tx, now time.Time) (published, unpublished []models.Product, err error) {
+	query := `
+        UPDATE products SET status = 'active', version = version + 1, updated_at = $1
+        WHERE status = 'scheduled' AND publish_at <= $1
+        RETURNING ` + productColumns
+
+	err = pgxscan.Select(ctx, tx, &published, query, now)
+	if err != nil {
+		return nil, nil, fmt.Errorf("publish scheduled products: %w", err)
+	}
+
+	query = `
+        UPDATE products SET status = 'discontinued', version = version + 1, updated_at = $1
+        WHERE status = 'active' AND unpublish_at <= $1
+        RETURNING ` + productColumns
+
+	err = pgxscan.Select(ctx, tx, &unpublished, query, now)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unpublish products: %w", err)
+	}
+	return published, unpublished, nil
+}
+
+func productStatuses(statuses []models.ProductStatus) []string {
+	values := make([]string, len(statuses))
+	for i, status := range statuses {
+		values[i] = string(status)
+	}
+	return values
+}
+
+// queryRower is implemented by both the pool and transactions
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// productUpdateMiss explains why a versioned product update matched no rows
+func (r *repositoryImpl) productUpdateMiss(ctx context.Context, db queryRower, id string) error {
+	var exists bool
+	if err := db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM products WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return fmt.Errorf("check product exists: %w", err)
+	}
+	if !exists {
 		return pkg.ErrNotFound
 	}
-	return nil
+	return pkg.ErrVersionMismatch
 }
 
-func (r *repositoryImpl) CreateOrder(ctx context.Context, tx pgx.Tx, order *models.Order) error {
-	query := `INSERT INTO orders (id, user_id, status, total_amount) VALUES ($1, $2, $3, $4) RETURNING created_at, updated_at`
+// DeleteProduct deletes a product and returns it as it was
+func (r *repositoryImpl) DeleteProduct(ctx context.Context, tx pgx.Tx, id string) (*models.Product, error) {
+	var product models.Product
+	query := `DELETE FROM products WHERE id = $1 RETURNING ` + productColumns
 
-	err := pgxscan.Get(ctx, tx, order, query, order.ID, order.UserID, order.Status, order.TotalAmount)
+	err := pgxscan.Get(ctx, tx, &product, query, id)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, pkg.ErrNotFound
+		}
+		if isPgError(err, pgForeignKeyViolation) {
+			return nil, pkg.ErrProductReferenced
+		}
+		return nil, fmt.Errorf("delete product: %w", err)
+	}
+	return &product, nil
+}
+
+func (r *repositoryImpl) SetProductArchived(ctx context.Context, tx pgx.Tx, id string, archived bool) (*models.Product, error) {
+	var product models.Product
+	query := `
+        UPDATE products
+        SET archived_at = CASE WHEN $1 THEN COALESCE(archived_at, CURRENT_TIMESTAMP) END,
+            version = version + 1, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $2
+        RETURNING ` + productColumns
+
+	err := pgxscan.Get(ctx, tx, &product, query, archived, id)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, pkg.ErrNotFound
+		}
+		return nil, fmt.Errorf("set product archived: %w", err)
+	}
+	return &product, nil
+}
+
+func (r *repositoryImpl) CreateOrder(ctx context.Context, tx pgx.Tx, order *models.Order) error {
+	query := `
+        INSERT INTO orders (id, user_id, status, subtotal, discount_total, tax_total, tax_region, tax_inclusive, shipping_method,
+                            shipping_total, total_amount, coupon_code, shipping_address, billing_address)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+        RETURNING created_at, updated_at`
+
+	err := pgxscan.Get(ctx, tx, order, query, order.ID, order.UserID, order.Status, order.Subtotal, order.DiscountTotal, order.TaxTotal,
+		order.TaxRegion, order.TaxInclusive, order.ShippingMethod, order.ShippingTotal, order.TotalAmount, order.CouponCode,
+		order.ShippingAddress, order.BillingAddress)
 	if err != nil {
 		return fmt.Errorf("create order: %w", err)
 	}
@@ -121,12 +255,48 @@ func (r *repositoryImpl) CreateOrder(ctx context.Context, tx pgx.Tx, order *mode
 		item.ID = pkg.GenerateID()
 		item.OrderID = order.ID
 
-		query = `INSERT INTO order_items (id, order_id, product_id, quantity, unit_price) VALUES ($1, $2, $3, $4, $5) RETURNING created_at, updated_at, subtotal`
+		query = `
+            INSERT INTO order_items (id, order_id, product_id, quantity, unit_price, discount_amount, tax_rate, tax_amount)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+            RETURNING created_at, updated_at, subtotal`
 
-		err = pgxscan.Get(ctx, tx, item, query, item.ID, item.OrderID, item.ProductID, item.Quantity, item.UnitPrice)
+		err = pgxscan.Get(ctx, tx, item, query, item.ID, item.OrderID, item.ProductID, item.Quantity, item.UnitPrice, item.DiscountAmount,
+			item.TaxRate, item.TaxAmount)
 		if err != nil {
 			return fmt.Errorf("create order item: %w", err)
 		}
+
+		// insert warehouse allocations
+		for j := range item.Allocations {
+			allocation := &item.Allocations[j]
+			allocation.ID = pkg.GenerateID()
+			allocation.OrderItemID = item.ID
+
+			query = `INSERT INTO order_item_allocations (id, order_item_id, warehouse_id, quantity) VALUES ($1, $2, $3, $4) RETURNING created_at`
+
+			err = pgxscan.Get(ctx, tx, allocation, query, allocation.ID, allocation.OrderItemID, allocation.WarehouseID, allocation.Quantity)
+			if err != nil {
+				return fmt.Errorf("create order item allocation: %w", err)
+			}
+		}
+	}
+
+	// insert the discounts that make up the discount total
+	for i := range order.Discounts {
+		discount := &order.Discounts[i]
+		discount.ID = pkg.GenerateID()
+		discount.OrderID = order.ID
+
+		query = `
+            INSERT INTO order_discounts (id, order_id, source, reference_id, code, description, amount)
+            VALUES ($1, $2, $3, $4, $5, $6, $7)
+            RETURNING created_at`
+
+		err = pgxscan.Get(ctx, tx, discount, query, discount.ID, discount.OrderID, discount.Source, discount.ReferenceID,
+			discount.Code, discount.Description, discount.Amount)
+		if err != nil {
+			return fmt.Errorf("create order discount: %w", err)
+		}
 	}
 	return err
 }
@@ -150,7 +320,7 @@ func (r *repositoryImpl) WithTransaction(ctx context.Context, fn func(pgx.Tx) er
 
 func (r *repositoryImpl) GetOrderByID(ctx context.Context, id string) (*models.Order, error) {
 	var order models.Order
-	query := `SELECT id, user_id, status, total_amount, created_at, updated_at FROM orders WHERE id = $1`
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE id = $1`
 
 	err := pgxscan.Get(ctx, r.db, &order, query, id)
 	if err != nil {
@@ -160,49 +330,253 @@ func (r *repositoryImpl) GetOrderByID(ctx context.Context, id string) (*models.O
 		return nil, fmt.Errorf("get order by id: %w", err)
 	}
 
-	// get order items with products
+	items, err := r.ListOrderItems(ctx, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	order.Items = items[id]
+
 	query = `
-        SELECT i.*, p.*
+        SELECT id, order_id, source, reference_id, code, description, amount, created_at
+        FROM order_discounts WHERE order_id = $1 ORDER BY created_at, id`
+
+	err = pgxscan.Select(ctx, r.db, &order.Discounts, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("get order discounts: %w", err)
+	}
+
+	order.Shipments, err = r.listOrderShipments(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	order.Payments, err = r.listOrderPayments(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+// userOrderCondition matches a user's orders selected by a models.UserOrderFilter, with the
+// orders table aliased as o
+const userOrderCondition = `
+        o.user_id = $1
+        AND (cardinality($2::text[]) = 0 OR o.status = ANY($2))
+        AND ($3::timestamptz IS NULL OR o.created_at >= $3)
+        AND ($4::timestamptz IS NULL OR o.created_at < $4)`
+
+// ListUserOrders returns up to limit of the user's orders matching the filter, newest first,
+// starting after the order marked by the cursor if there is one
+func (r *repositoryImpl) ListUserOrders(ctx context.Context, userID string, filter models.UserOrderFilter, after *models.OrderCursor,
+	limit int) ([]models.Order, error) {
+	var afterCreatedAt *time.Time
+	var afterID string
+	if after != nil {
+		afterCreatedAt, afterID = &after.CreatedAt, after.ID
+	}
+
+	query := `
+        SELECT ` + orderColumns + ` FROM orders o
+        WHERE ` + userOrderCondition + `
+        AND ($5::timestamptz IS NULL OR (o.created_at, o.id) < ($5, $6))
+        ORDER BY o.created_at DESC, o.id DESC
+        LIMIT $7`
+
+	var orders []models.Order
+	err := pgxscan.Select(ctx, r.db, &orders, query, userID, orderStatuses(filter.Statuses), filter.CreatedFrom, filter.CreatedTo,
+		afterCreatedAt, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list user orders: %w", err)
+	}
+	return orders, nil
+}
+
+// UserOrderSummary counts the user's orders matching the filter and what was spent on them.
+// Orders that were never paid for don't add to the amount spent, and refunds that weren't
+// declined are taken off.
+func (r *repositoryImpl) UserOrderSummary(ctx context.Context, userID string, filter models.UserOrderFilter) (*models.OrderSummary, error) {
+	query := `
+        SELECT count(*) AS order_count,
+               COALESCE(SUM(o.total_amount - COALESCE(r.refunded, 0)) FILTER (WHERE o.status NOT IN ('pending', 'cancelled')), 0) AS total_spent
+        FROM orders o
+        LEFT JOIN (SELECT order_id, SUM(amount) AS refunded FROM refunds WHERE status <> 'failed' GROUP BY order_id) r ON r.order_id = o.id
+        WHERE ` + userOrderCondition
+
+	var summary models.OrderSummary
+	err := pgxscan.Get(ctx, r.db, &summary, query, userID, orderStatuses(filter.Statuses), filter.CreatedFrom, filter.CreatedTo)
+	if err != nil {
+		return nil, fmt.Errorf("user order summary: %w", err)
+	}
+	return &summary, nil
+}
+
+// ListOrderItems returns the items of the orders with their products and warehouse
+// allocations, by order ID
+func (r *repositoryImpl) ListOrderItems(ctx context.Context, orderIDs []string) (map[string][]models.OrderItem, error) {
+	query := `
+        SELECT i.id, i.order_id, i.product_id, i.quantity, i.unit_price, i.subtotal, i.discount_amount, i.tax_rate, i.tax_amount,
+               i.returned_quantity, i.created_at, i.updated_at,
+               p.id AS "product.id", p.name AS "product.name", p.description AS "product.description",
+               p.price AS "product.price", p.category AS "product.category", p.tax_class AS "product.tax_class",
+               p.weight_kg AS "product.weight_kg", p.length_cm AS "product.length_cm", p.width_cm AS "product.width_cm",
+               p.height_cm AS "product.height_cm",
+               p.stock_quantity AS "product.stock_quantity", p.reorder_threshold AS "product.reorder_threshold",
+               p.version AS "product.version", p.status AS "product.status", p.archived_at AS "product.archived_at",
+               p.created_at AS "product.created_at", p.updated_at AS "product.updated_at"
         FROM order_items i
         JOIN products p ON p.id = i.product_id
-        WHERE i.order_id = $1`
+        WHERE i.order_id = ANY($1)
+        ORDER BY i.created_at, i.id`
 
 	var items []struct {
 		models.OrderItem
 		Product models.Product `db:"product"`
 	}
 
-	err = pgxscan.Select(ctx, r.db, &items, query, id)
+	err := pgxscan.Select(ctx, r.db, &items, query, orderIDs)
 	if err != nil {
 		return nil, fmt.Errorf("get order items: %w", err)
 	}
 
-	order.Items = make([]models.OrderItem, len(items))
-	for i, item := range items {
-		order.Items[i] = item.OrderItem
-		order.Items[i].Product = &item.Product
+	// get warehouse allocations of the items
+	query = `
+        SELECT a.id, a.order_item_id, a.warehouse_id, w.code AS warehouse_code, a.quantity, a.created_at
+        FROM order_item_allocations a
+        JOIN order_items i ON i.id = a.order_item_id
+        JOIN warehouses w ON w.id = a.warehouse_id
+        WHERE i.order_id = ANY($1)
+        ORDER BY w.priority, w.code`
+
+	var allocations []models.OrderItemAllocation
+	err = pgxscan.Select(ctx, r.db, &allocations, query, orderIDs)
+	if err != nil {
+		return nil, fmt.Errorf("get order item allocations: %w", err)
 	}
 
-	return &order, nil
+	allocationsByItem := make(map[string][]models.OrderItemAllocation)
+	for _, allocation := range allocations {
+		allocationsByItem[allocation.OrderItemID] = append(allocationsByItem[allocation.OrderItemID], allocation)
+	}
+
+	byOrder := make(map[string][]models.OrderItem, len(orderIDs))
+	for _, item := range items {
+		item.OrderItem.Product = &item.Product
+		item.OrderItem.Allocations = allocationsByItem[item.ID]
+		byOrder[item.OrderID] = append(byOrder[item.OrderID], item.OrderItem)
+	}
+	return byOrder, nil
 }
 
-func (r *repositoryImpl) GetOrderByUserID(ctx context.Context, userID string) ([]models.Order, error) {
-	query := `SELECT id, user_id, status, total_amount, created_at, updated_at FROM orders WHERE user_id = $1 ORDER BY created_at DESC`
+// orderFilterCondition matches the orders selected by a models.OrderFilter, see orderFilterArgs
+const orderFilterCondition = `
+        (cardinality($1::text[]) = 0 OR status = ANY($1))
+        AND ($2::timestamptz IS NULL OR created_at >= $2)
+        AND ($3::timestamptz IS NULL OR created_at < $3)
+        AND ($4 = '' OR user_id IN (SELECT id FROM users WHERE email ILIKE '%' || $4 || '%'))
+        AND ($5::numeric IS NULL OR total_amount >= $5)
+        AND ($6::numeric IS NULL OR total_amount <= $6)
+        AND ($7 = '' OR id IN (SELECT order_id FROM order_items WHERE product_id = $7))`
+
+// orderSortColumns are the columns orders can be sorted by
+var orderSortColumns = map[string]string{
+	"created_at":   "created_at",
+	"updated_at":   "updated_at",
+	"total_amount": "total_amount",
+	"status":       "status",
+}
+
+// ListOrders returns a page of all orders matching the filter, and how many orders match in total
+func (r *repositoryImpl) ListOrders(ctx context.Context, filter models.OrderFilter) ([]models.Order, int, error) {
+	sort, direction := strings.TrimPrefix(filter.Sort, "-"), "ASC"
+	if strings.HasPrefix(filter.Sort, "-") {
+		direction = "DESC"
+	}
+	column, ok := orderSortColumns[sort]
+	if !ok {
+		column, direction = "created_at", "DESC"
+	}
+
+	args := orderFilterArgs(filter)
+
+	var total int
+	query := `SELECT count(*) FROM orders WHERE ` + orderFilterCondition
+	if err := pgxscan.Get(ctx, r.db, &total, query, args...); err != nil {
+		return nil, 0, fmt.Errorf("count orders: %w", err)
+	}
+
+	query = `
+        SELECT ` + orderColumns + ` FROM orders WHERE ` + orderFilterCondition + `
+        ORDER BY ` + column + ` ` + direction + `, id ` + direction + `
+        LIMIT $8 OFFSET $9`
 
 	var orders []models.Order
-	err := pgxscan.Select(ctx, r.db, &orders, query, userID)
+	err := pgxscan.Select(ctx, r.db, &orders, query, append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)...)
 	if err != nil {
-		return nil, fmt.Errorf("get orders by user id: %w", err)
+		return nil, 0, fmt.Errorf("list orders: %w", err)
 	}
-	return orders, nil
+	return orders, total, nil
+}
+
+// orderFilterArgs returns the arguments of orderFilterCondition for the filter
+func orderFilterArgs(filter models.OrderFilter) []any {
+	return []any{orderStatuses(filter.Statuses), filter.CreatedFrom, filter.CreatedTo, escapeLike(filter.CustomerEmail),
+		filter.MinTotal, filter.MaxTotal, filter.ProductID}
+}
+
+func orderStatuses(statuses []models.OrderStatus) []string {
+	values := make([]string, len(statuses))
+	for i, status := range statuses {
+		values[i] = string(status)
+	}
+	return values
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern so text only matches itself
+func escapeLike(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(text)
+}
+
+// LockOrder locks the order row until tx ends, so changes to the order and its
+// shipments are serialised
+func (r *repositoryImpl) LockOrder(ctx context.Context, tx pgx.Tx, id string) error {
+	query := `SELECT id FROM orders WHERE id = $1 FOR UPDATE`
+
+	var lockedID string
+	err := pgxscan.Get(ctx, tx, &lockedID, query, id)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return pkg.ErrNotFound
+		}
+		return fmt.Errorf("lock order: %w", err)
+	}
+	return nil
 }
 
-func (r *repositoryImpl) UpdateOrderStatus(ctx context.Context, id string, status models.OrderStatus) error {
+// ListStalePendingOrders returns the IDs of up to limit orders that have been pending since
+// before the given time, oldest first. Orders with a payment in progress are left out, so
+// they don't hold up the orders behind them while their payment settles.
+func (r *repositoryImpl) ListStalePendingOrders(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	var ids []string
+	query := `
+        SELECT id FROM orders
+        WHERE status = 'pending' AND created_at < $1
+            AND NOT EXISTS (SELECT 1 FROM payments WHERE order_id = orders.id AND status IN ('pending', 'authorized'))
+        ORDER BY created_at LIMIT $2`
+
+	err := pgxscan.Select(ctx, r.db, &ids, query, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list stale pending orders: %w", err)
+	}
+	return ids, nil
+}
+
+func (r *repositoryImpl) UpdateOrderStatus(ctx context.Context, tx pgx.Tx, id string, status models.OrderStatus) error {
 	query := `UPDATE orders SET status = $1, updated_at = $2 WHERE id = $3 RETURNING updated_at`
 	now := time.Now()
 
 	var updatedAt time.Time
-	err := pgxscan.Get(ctx, r.db, &updatedAt, query, status, now, id)
+	err := pgxscan.Get(ctx, tx, &updatedAt, query, status, now, id)
 	if err != nil {
 		if pgxscan.NotFound(err) {
 			return pkg.ErrNotFound
@@ -264,3 +638,12 @@ func (r *repositoryImpl) BlockSession(ctx context.Context, id string) error {
 	}
 	return nil
 }
+
+// DeleteExpiredSessions deletes the sessions that expired before now
+func (r *repositoryImpl) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.db.Exec(ctx, `DELETE FROM sessions WHERE expires_at < $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("delete expired sessions: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// returnColumns is the column list scanned into models.Return
+const returnColumns = `id, order_id, user_id, status, note, resolution_note, received_at, created_at, updated_at`
+
+func (r *repositoryImpl) CreateReturn(ctx context.Context, tx pgx.Tx, ret *models.Return) error {
+	query := `
+        INSERT INTO returns (id, order_id, user_id, status, note)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING created_at, updated_at`
+
+	err := pgxscan.Get(ctx, tx, ret, query, ret.ID, ret.OrderID, ret.UserID, ret.Status, ret.Note)
+	if err != nil {
+		return fmt.Errorf("create return: %w", err)
+	}
+
+	for i := range ret.Items {
+		item := &ret.Items[i]
+		item.ID = pkg.GenerateID()
+		item.ReturnID = ret.ID
+
+		query = `
+            INSERT INTO return_items (id, return_id, order_item_id, quantity, reason)
+            VALUES ($1, $2, $3, $4, $5)
+            RETURNING created_at`
+
+		err = pgxscan.Get(ctx, tx, item, query, item.ID, item.ReturnID, item.OrderItemID, item.Quantity, item.Reason)
+		if err != nil {
+			return fmt.Errorf("create return item: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *repositoryImpl) GetReturn(ctx context.Context, id string) (*models.Return, error) {
+	var ret models.Return
+	query := `SELECT ` + returnColumns + ` FROM returns WHERE id = $1`
+
+	err := pgxscan.Get(ctx, r.db, &ret, query, id)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, pkg.ErrNotFound
+		}
+		return nil, fmt.Errorf("get return: %w", err)
+	}
+
+	returns := []models.Return{ret}
+	if err := r.loadReturnDetails(ctx, returns); err != nil {
+		return nil, err
+	}
+	return &returns[0], nil
+}
+
+// ListReturns returns the returns in the given status, or all returns if status is empty, newest first
+func (r *repositoryImpl) ListReturns(ctx context.Context, status models.ReturnStatus) ([]models.Return, error) {
+	var returns []models.Return
+	query := `SELECT ` + returnColumns + ` FROM returns WHERE ($1 = '' OR status = $1) ORDER BY created_at DESC`
+
+	err := pgxscan.Select(ctx, r.db, &returns, query, status)
+	if err != nil {
+		return nil, fmt.Errorf("list returns: %w", err)
+	}
+
+	if err := r.loadReturnDetails(ctx, returns); err != nil {
+		return nil, err
+	}
+	return returns, nil
+}
+
+func (r *repositoryImpl) ListOrderReturns(ctx context.Context, orderID string) ([]models.Return, error) {
+	var returns []models.Return
+	query := `SELECT ` + returnColumns + ` FROM returns WHERE order_id = $1 ORDER BY created_at DESC`
+
+	err := pgxscan.Select(ctx, r.db, &returns, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("list order returns: %w", err)
+	}
+
+	if err := r.loadReturnDetails(ctx, returns); err != nil {
+		return nil, err
+	}
+	return returns, nil
+}
+
+func (r *repositoryImpl) UpdateReturn(ctx context.Context, tx pgx.Tx, ret *models.Return) error {
+	query := `
+        UPDATE returns SET status = $1, resolution_note = $2, received_at = $3, updated_at = $4
+        WHERE id = $5
+        RETURNING updated_at`
+	now := time.Now()
+
+	err := pgxscan.Get(ctx, tx, ret, query, ret.Status, ret.ResolutionNote, ret.ReceivedAt, now, ret.ID)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return pkg.ErrNotFound
+		}
+		return fmt.Errorf("update return: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) AddReturnedQuantity(ctx context.Context, tx pgx.Tx, orderItemID string, quantity int) error {
+	query := `UPDATE order_items SET returned_quantity = returned_quantity + $1, updated_at = $2 WHERE id = $3`
+
+	result, err := tx.Exec(ctx, query, quantity, time.Now(), orderItemID)
+	if err != nil {
+		return fmt.Errorf("add returned quantity: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return pkg.ErrNotFound
+	}
+	return nil
+}
+
+func (r *repositoryImpl) CreateRefund(ctx context.Context, tx pgx.Tx, refund *models.Refund) error {
+	query := `
+        INSERT INTO refunds (id, order_id, return_id, payment_id, provider_reference, status, amount, reason)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING created_at`
+
+	err := pgxscan.Get(ctx, tx, refund, query, refund.ID, refund.OrderID, refund.ReturnID, refund.PaymentID,
+		refund.ProviderReference, refund.Status, refund.Amount, refund.Reason)
+	if err != nil {
+		return fmt.Errorf("create refund: %w", err)
+	}
+	return nil
+}
+
+// loadReturnDetails fills in the items and refunds of the returns
+func (r *repositoryImpl) loadReturnDetails(ctx context.Context, returns []models.Return) error {
+	if len(returns) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(returns))
+	for i, ret := range returns {
+		ids[i] = ret.ID
+	}
+
+	var items []models.ReturnItem
+	query := `
+        SELECT id, return_id, order_item_id, quantity, reason, created_at
+        FROM return_items WHERE return_id = ANY($1) ORDER BY created_at, id`
+
+	err := pgxscan.Select(ctx, r.db, &items, query, ids)
+	if err != nil {
+		return fmt.Errorf("list return items: %w", err)
+	}
+
+	var refunds []models.Refund
+	query = `SELECT ` + refundColumns + ` FROM refunds WHERE return_id = ANY($1)`
+
+	err = pgxscan.Select(ctx, r.db, &refunds, query, ids)
+	if err != nil {
+		return fmt.Errorf("list return refunds: %w", err)
+	}
+
+	itemsByReturn := make(map[string][]models.ReturnItem)
+	for _, item := range items {
+		itemsByReturn[item.ReturnID] = append(itemsByReturn[item.ReturnID], item)
+	}
+	refundsByReturn := make(map[string]*models.Refund)
+	for i := range refunds {
+		refundsByReturn[*refunds[i].ReturnID] = &refunds[i]
+	}
+
+	for i := range returns {
+		returns[i].Items = itemsByReturn[returns[i].ID]
+		returns[i].Refund = refundsByReturn[returns[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+func (r *repositoryImpl) CreateShipment(ctx context.Context, tx pgx.Tx, shipment *models.Shipment) error {
+	query := `
+        INSERT INTO shipments (id, order_id, carrier, tracking_number, shipped_at)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING created_at, updated_at`
+
+	err := pgxscan.Get(ctx, tx, shipment, query, shipment.ID, shipment.OrderID, shipment.Carrier, shipment.TrackingNumber,
+		shipment.ShippedAt)
+	if err != nil {
+		return fmt.Errorf("create shipment: %w", err)
+	}
+
+	for i := range shipment.Items {
+		item := &shipment.Items[i]
+		item.ID = pkg.GenerateID()
+		item.ShipmentID = shipment.ID
+
+		query = `
+            INSERT INTO shipment_items (id, shipment_id, order_item_id, quantity)
+            VALUES ($1, $2, $3, $4)
+            RETURNING created_at`
+
+		err = pgxscan.Get(ctx, tx, item, query, item.ID, item.ShipmentID, item.OrderItemID, item.Quantity)
+		if err != nil {
+			return fmt.Errorf("create shipment item: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *repositoryImpl) MarkShipmentDelivered(ctx context.Context, tx pgx.Tx, id string, at time.Time) error {
+	query := `UPDATE shipments SET delivered_at = $1, updated_at = $2 WHERE id = $3`
+
+	result, err := tx.Exec(ctx, query, at, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("mark shipment delivered: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return pkg.ErrNotFound
+	}
+	return nil
+}
+
+// listOrderShipments returns the shipments of an order with their items, oldest first
+func (r *repositoryImpl) listOrderShipments(ctx context.Context, orderID string) ([]models.Shipment, error) {
+	var shipments []models.Shipment
+	query := `
+        SELECT id, order_id, carrier, tracking_number, shipped_at, delivered_at, created_at, updated_at
+        FROM shipments WHERE order_id = $1 ORDER BY shipped_at, id`
+
+	err := pgxscan.Select(ctx, r.db, &shipments, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("list order shipments: %w", err)
+	}
+
+	query = `
+        SELECT si.id, si.shipment_id, si.order_item_id, si.quantity, si.created_at
+        FROM shipment_items si
+        JOIN shipments s ON s.id = si.shipment_id
+        WHERE s.order_id = $1`
+
+	var items []models.ShipmentItem
+	err = pgxscan.Select(ctx, r.db, &items, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("list shipment items: %w", err)
+	}
+
+	itemsByShipment := make(map[string][]models.ShipmentItem)
+	for _, item := range items {
+		itemsByShipment[item.ShipmentID] = append(itemsByShipment[item.ShipmentID], item)
+	}
+	for i := range shipments {
+		shipments[i].Items = itemsByShipment[shipments[i].ID]
+	}
+	return shipments, nil
+}
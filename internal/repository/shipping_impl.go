@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// shippingMethodColumns is the column list scanned into models.ShippingMethod
+const shippingMethodColumns = `id, code, name, carrier, rate_type, base_rate, per_kg_rate, free_over, countries, estimated_days,
+        is_active, created_at, updated_at`
+
+func (r *repositoryImpl) CreateShippingMethod(ctx context.Context, method *models.ShippingMethod) error {
+	query := `
+        INSERT INTO shipping_methods (id, code, name, carrier, rate_type, base_rate, per_kg_rate, free_over, countries,
+                                      estimated_days, is_active)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+        RETURNING created_at, updated_at`
+
+	err := pgxscan.Get(ctx, r.db, method, query, method.ID, method.Code, method.Name, method.Carrier, method.RateType,
+		method.BaseRate, method.PerKgRate, method.FreeOver, method.Countries, method.EstimatedDays, method.IsActive)
+	if err != nil {
+		if isPgError(err, pgUniqueViolation) {
+			return pkg.ErrShippingMethodCodeTaken
+		}
+		return fmt.Errorf("create shipping method: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) GetShippingMethodByID(ctx context.Context, id string) (*models.ShippingMethod, error) {
+	var method models.ShippingMethod
+	query := `SELECT ` + shippingMethodColumns + ` FROM shipping_methods WHERE id = $1`
+
+	err := pgxscan.Get(ctx, r.db, &method, query, id)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, pkg.ErrNotFound
+		}
+		return nil, fmt.Errorf("get shipping method by id: %w", err)
+	}
+	return &method, nil
+}
+
+func (r *repositoryImpl) GetShippingMethodByCode(ctx context.Context, code string) (*models.ShippingMethod, error) {
+	var method models.ShippingMethod
+	query := `SELECT ` + shippingMethodColumns + ` FROM shipping_methods WHERE code = $1`
+
+	err := pgxscan.Get(ctx, r.db, &method, query, code)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, pkg.ErrNotFound
+		}
+		return nil, fmt.Errorf("get shipping method by code: %w", err)
+	}
+	return &method, nil
+}
+
+func (r *repositoryImpl) ListShippingMethods(ctx context.Context, activeOnly bool) ([]models.ShippingMethod, error) {
+	var methods []models.ShippingMethod
+	query := `SELECT ` + shippingMethodColumns + ` FROM shipping_methods WHERE (NOT $1 OR is_active) ORDER BY base_rate, code`
+
+	err := pgxscan.Select(ctx, r.db, &methods, query, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("list shipping methods: %w", err)
+	}
+	return methods, nil
+}
+
+func (r *repositoryImpl) UpdateShippingMethod(ctx context.Context, method *models.ShippingMethod) error {
+	query := `
+        UPDATE shipping_methods
+        SET code = $1, name = $2, carrier = $3, rate_type = $4, base_rate = $5, per_kg_rate = $6, free_over = $7, countries = $8,
+            estimated_days = $9, is_active = $10, updated_at = $11
+        WHERE id = $12
+        RETURNING created_at, updated_at`
+	now := time.Now()
+
+	err := pgxscan.Get(ctx, r.db, method, query, method.Code, method.Name, method.Carrier, method.RateType, method.BaseRate,
+		method.PerKgRate, method.FreeOver, method.Countries, method.EstimatedDays, method.IsActive, now, method.ID)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return pkg.ErrNotFound
+		}
+		if isPgError(err, pgUniqueViolation) {
+			return pkg.ErrShippingMethodCodeTaken
+		}
+		return fmt.Errorf("update shipping method: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) DeleteShippingMethod(ctx context.Context, id string) error {
+	query := `DELETE FROM shipping_methods WHERE id = $1`
+
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("delete shipping method: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return pkg.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+func (r *repositoryImpl) ListLowStockProducts(ctx context.Context) ([]models.Product, error) {
+	var products []models.Product
+	query := `SELECT ` + productColumns + ` FROM products WHERE archived_at IS NULL AND status <> 'discontinued' AND stock_quantity <= reorder_threshold ORDER BY stock_quantity, name`
+
+	err := pgxscan.Select(ctx, r.db, &products, query)
+	if err != nil {
+		return nil, fmt.Errorf("list low stock products: %w", err)
+	}
+	return products, nil
+}
+
+func (r *repositoryImpl) CreateStockSubscription(ctx context.Context, subscription *models.StockSubscription) error {
+	query := `INSERT INTO stock_subscriptions (id, user_id, product_id) VALUES ($1, $2, $3) RETURNING created_at`
+
+	err := pgxscan.Get(ctx, r.db, subscription, query, subscription.ID, subscription.UserID, subscription.ProductID)
+	if err != nil {
+		if isPgError(err, pgUniqueViolation) {
+			return pkg.ErrAlreadySubscribed
+		}
+		return fmt.Errorf("create stock subscription: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) DeleteStockSubscription(ctx context.Context, userID, productID string) error {
+	query := `DELETE FROM stock_subscriptions WHERE user_id = $1 AND product_id = $2 AND notified_at IS NULL`
+
+	result, err := r.db.Exec(ctx, query, userID, productID)
+	if err != nil {
+		return fmt.Errorf("delete stock subscription: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return pkg.ErrNotFound
+	}
+	return nil
+}
+
+func (r *repositoryImpl) ListPendingStockSubscriptions(ctx context.Context, productID string) ([]models.StockSubscription, error) {
+	var subscriptions []models.StockSubscription
+	query := `
+        SELECT s.id, s.user_id, u.email AS user_email, s.product_id, s.notified_at, s.created_at
+        FROM stock_subscriptions s
+        JOIN users u ON u.id = s.user_id
+        WHERE s.product_id = $1 AND s.notified_at IS NULL
+        ORDER BY s.created_at`
+
+	err := pgxscan.Select(ctx, r.db, &subscriptions, query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("list pending stock subscriptions: %w", err)
+	}
+	return subscriptions, nil
+}
+
+func (r *repositoryImpl) MarkStockSubscriptionNotified(ctx context.Context, id string) error {
+	query := `UPDATE stock_subscriptions SET notified_at = $1 WHERE id = $2`
+
+	result, err := r.db.Exec(ctx, query, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("mark stock subscription notified: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return pkg.ErrNotFound
+	}
+	return nil
+}
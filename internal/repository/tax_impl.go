@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+func (r *repositoryImpl) CreateTaxRate(ctx context.Context, rate *models.TaxRate) error {
+	query := `INSERT INTO tax_rates (id, region, tax_class, name, rate) VALUES ($1, $2, $3, $4, $5) RETURNING created_at, updated_at`
+
+	err := pgxscan.Get(ctx, r.db, rate, query, rate.ID, rate.Region, rate.TaxClass, rate.Name, rate.Rate)
+	if err != nil {
+		if isPgError(err, pgUniqueViolation) {
+			return pkg.ErrTaxRateExists
+		}
+		return fmt.Errorf("create tax rate: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) ListTaxRates(ctx context.Context) ([]models.TaxRate, error) {
+	var rates []models.TaxRate
+	query := `SELECT id, region, tax_class, name, rate, created_at, updated_at FROM tax_rates ORDER BY region, tax_class`
+
+	err := pgxscan.Select(ctx, r.db, &rates, query)
+	if err != nil {
+		return nil, fmt.Errorf("list tax rates: %w", err)
+	}
+	return rates, nil
+}
+
+func (r *repositoryImpl) ListTaxRatesByRegion(ctx context.Context, regions []string) ([]models.TaxRate, error) {
+	var rates []models.TaxRate
+	query := `SELECT id, region, tax_class, name, rate, created_at, updated_at FROM tax_rates WHERE region = ANY($1)`
+
+	err := pgxscan.Select(ctx, r.db, &rates, query, regions)
+	if err != nil {
+		return nil, fmt.Errorf("list tax rates by region: %w", err)
+	}
+	return rates, nil
+}
+
+func (r *repositoryImpl) UpdateTaxRate(ctx context.Context, rate *models.TaxRate) error {
+	query := `
+        UPDATE tax_rates SET region = $1, tax_class = $2, name = $3, rate = $4, updated_at = $5
+        WHERE id = $6
+        RETURNING created_at, updated_at`
+	now := time.Now()
+
+	err := pgxscan.Get(ctx, r.db, rate, query, rate.Region, rate.TaxClass, rate.Name, rate.Rate, now, rate.ID)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return pkg.ErrNotFound
+		}
+		if isPgError(err, pgUniqueViolation) {
+			return pkg.ErrTaxRateExists
+		}
+		return fmt.Errorf("update tax rate: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) DeleteTaxRate(ctx context.Context, id string) error {
+	query := `DELETE FROM tax_rates WHERE id = $1`
+
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("delete tax rate: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return pkg.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+const (
+	pgCheckViolation      = "23514"
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
+// isPgError reports whether err is a postgres error with the given SQLSTATE code
+func isPgError(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
+
+func (r *repositoryImpl) CreateWarehouse(ctx context.Context, warehouse *models.Warehouse) error {
+	query := `INSERT INTO warehouses (id, code, name, priority, is_active) VALUES ($1, $2, $3, $4, $5) RETURNING created_at, updated_at`
+
+	err := pgxscan.Get(ctx, r.db, warehouse, query, warehouse.ID, warehouse.Code, warehouse.Name, warehouse.Priority, warehouse.IsActive)
+	if err != nil {
+		if isPgError(err, pgUniqueViolation) {
+			return pkg.ErrWarehouseCodeTaken
+		}
+		return fmt.Errorf("create warehouse: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) GetWarehouseByID(ctx context.Context, id string) (*models.Warehouse, error) {
+	var warehouse models.Warehouse
+	query := `SELECT id, code, name, priority, is_active, created_at, updated_at FROM warehouses WHERE id = $1`
+
+	err := pgxscan.Get(ctx, r.db, &warehouse, query, id)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, pkg.ErrNotFound
+		}
+		return nil, fmt.Errorf("get warehouse by id: %w", err)
+	}
+	return &warehouse, nil
+}
+
+func (r *repositoryImpl) GetDefaultWarehouse(ctx context.Context) (*models.Warehouse, error) {
+	var warehouse models.Warehouse
+	query := `SELECT id, code, name, priority, is_active, created_at, updated_at FROM warehouses WHERE is_active ORDER BY priority, code LIMIT 1`
+
+	err := pgxscan.Get(ctx, r.db, &warehouse, query)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, pkg.ErrNoActiveWarehouse
+		}
+		return nil, fmt.Errorf("get default warehouse: %w", err)
+	}
+	return &warehouse, nil
+}
+
+func (r *repositoryImpl) ListWarehouses(ctx context.Context) ([]models.Warehouse, error) {
+	var warehouses []models.Warehouse
+	query := `SELECT id, code, name, priority, is_active, created_at, updated_at FROM warehouses ORDER BY priority, code`
+
+	err := pgxscan.Select(ctx, r.db, &warehouses, query)
+	if err != nil {
+		return nil, fmt.Errorf("list warehouses: %w", err)
+	}
+	return warehouses, nil
+}
+
+func (r *repositoryImpl) UpdateWarehouse(ctx context.Context, warehouse *models.Warehouse) error {
+	query := `UPDATE warehouses SET code = $1, name = $2, priority = $3, is_active = $4, updated_at = $5 WHERE id = $6 RETURNING created_at, updated_at`
+	now := time.Now()
+
+	err := pgxscan.Get(ctx, r.db, warehouse, query, warehouse.Code, warehouse.Name, warehouse.Priority, warehouse.IsActive, now, warehouse.ID)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return pkg.ErrNotFound
+		}
+		if isPgError(err, pgUniqueViolation) {
+			return pkg.ErrWarehouseCodeTaken
+		}
+		return fmt.Errorf("update warehouse: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) ListProductStock(ctx context.Context, productID string) ([]models.WarehouseStock, error) {
+	var levels []models.WarehouseStock
+	query := `
+        SELECT s.warehouse_id, w.code AS warehouse_code, w.priority AS warehouse_priority, s.product_id, s.quantity, s.updated_at
+        FROM warehouse_stock s
+        JOIN warehouses w ON w.id = s.warehouse_id
+        WHERE s.product_id = $1
+        ORDER BY w.priority, w.code`
+
+	err := pgxscan.Select(ctx, r.db, &levels, query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("list product stock: %w", err)
+	}
+	return levels, nil
+}
+
+// LockWarehouseStock returns the stock of the products in active warehouses and locks
+// the rows until tx ends, so concurrent orders can't allocate the same units.
+func (r *repositoryImpl) LockWarehouseStock(ctx context.Context, tx pgx.Tx, productIDs []string) ([]models.WarehouseStock, error) {
+	var levels []models.WarehouseStock
+	query := `
+        SELECT s.warehouse_id, w.code AS warehouse_code, w.priority AS warehouse_priority, s.product_id, s.quantity, s.updated_at
+        FROM warehouse_stock s
+        JOIN warehouses w ON w.id = s.warehouse_id
+        WHERE s.product_id = ANY($1) AND w.is_active
+        ORDER BY s.warehouse_id, s.product_id
+        FOR UPDATE OF s`
+
+	err := pgxscan.Select(ctx, tx, &levels, query, productIDs)
+	if err != nil {
+		return nil, fmt.Errorf("lock warehouse stock: %w", err)
+	}
+	return levels, nil
+}
+
+// LockProductStock locks the product's stock in every warehouse until tx ends and returns
+// its total, which products.stock_quantity is kept at
+func (r *repositoryImpl) LockProductStock(ctx context.Context, tx pgx.Tx, productID string) (int, error) {
+	query := `
+        SELECT COALESCE(SUM(quantity), 0) FROM (
+            SELECT quantity FROM warehouse_stock WHERE product_id = $1
+            ORDER BY warehouse_id
+            FOR UPDATE
+        ) s`
+
+	var total int
+	if err := tx.QueryRow(ctx, query, productID).Scan(&total); err != nil {
+		return 0, fmt.Errorf("lock product stock: %w", err)
+	}
+	return total, nil
+}
+
+func (r *repositoryImpl) SetWarehouseStock(ctx context.Context, tx pgx.Tx, warehouseID, productID string, quantity int) error {
+	query := `
+        INSERT INTO warehouse_stock (warehouse_id, product_id, quantity) VALUES ($1, $2, $3)
+        ON CONFLICT (warehouse_id, product_id) DO UPDATE SET quantity = EXCLUDED.quantity, updated_at = CURRENT_TIMESTAMP`
+
+	if _, err := tx.Exec(ctx, query, warehouseID, productID, quantity); err != nil {
+		return fmt.Errorf("set warehouse stock: %w", err)
+	}
+	return nil
+}
+
+// AdjustWarehouseStock adds delta (which may be negative) to the stock of a product in a warehouse.
+// It returns pkg.ErrInsufficientStock if the stock would drop below zero.
+func (r *repositoryImpl) AdjustWarehouseStock(ctx context.Context, tx pgx.Tx, warehouseID, productID string, delta int) error {
+	// check constraints are evaluated on the proposed row before ON CONFLICT applies,
+	// so removing stock can't go through the upsert
+	if delta < 0 {
+		query := `UPDATE warehouse_stock SET quantity = quantity + $3, updated_at = CURRENT_TIMESTAMP WHERE warehouse_id = $1 AND product_id = $2`
+
+		result, err := tx.Exec(ctx, query, warehouseID, productID, delta)
+		if err != nil {
+			if isPgError(err, pgCheckViolation) {
+				return pkg.ErrInsufficientStock
+			}
+			return fmt.Errorf("adjust warehouse stock: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return pkg.ErrInsufficientStock
+		}
+		return nil
+	}
+
+	query := `
+        INSERT INTO warehouse_stock (warehouse_id, product_id, quantity) VALUES ($1, $2, $3)
+        ON CONFLICT (warehouse_id, product_id) DO UPDATE SET quantity = warehouse_stock.quantity + EXCLUDED.quantity, updated_at = CURRENT_TIMESTAMP`
+
+	if _, err := tx.Exec(ctx, query, warehouseID, productID, delta); err != nil {
+		return fmt.Errorf("adjust warehouse stock: %w", err)
+	}
+	return nil
+}
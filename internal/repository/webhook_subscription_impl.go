@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// webhookSubscriptionColumns is the column list scanned into models.WebhookSubscription
+const webhookSubscriptionColumns = `id, url, event_types, secret, is_active, created_at, updated_at`
+
+// webhookDeliveryColumns is the column list scanned into models.WebhookDelivery
+const webhookDeliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+        last_status_code, last_error, delivered_at, created_at, updated_at`
+
+func (r *repositoryImpl) CreateWebhookSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	query := `
+        INSERT INTO webhook_subscriptions (id, url, event_types, secret, is_active)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING created_at, updated_at`
+
+	err := pgxscan.Get(ctx, r.db, subscription, query, subscription.ID, subscription.URL, subscription.EventTypes,
+		subscription.Secret, subscription.IsActive)
+	if err != nil {
+		return fmt.Errorf("create webhook subscription: %w", err)
+	}
+	return nil
+}
+
+func (r *repositoryImpl) GetWebhookSubscription(ctx context.Context, id string) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	query := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions WHERE id = $1`
+
+	err := pgxscan.Get(ctx, r.db, &subscription, query, id)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, pkg.ErrNotFound
+		}
+		return nil, fmt.Errorf("get webhook subscription: %w", err)
+	}
+	return &subscription, nil
+}
+
+func (r *repositoryImpl) ListWebhookSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	query := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions ORDER BY created_at, id`
+
+	err := pgxscan.Select(ctx, r.db, &subscriptions, query)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook subscriptions: %w", err)
+	}
+	return subscriptions, nil
+}
+
+// ListEventSubscriptions returns the active subscriptions to an event type
+func (r *repositoryImpl) ListEventSubscriptions(ctx context.Context, tx pgx.Tx, eventType string) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	query := `SELECT ` + webhookSubscriptionColumns + ` FROM webhook_subscriptions WHERE is_active AND $1 = ANY(event_types)`
+
+	err := pgxscan.Select(ctx, tx, &subscriptions, query, eventType)
+	if err != nil {
+		return nil, fmt.Errorf("list event subscriptions: %w", err)
+	}
+	return subscriptions, nil
+}
+
+func (r *repositoryImpl) UpdateWebhookSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	query := `
+        UPDATE webhook_subscriptions SET url = $1, event_types = $2, secret = $3, is_active = $4, updated_at = $5
+        WHERE id = $6
+        RETURNING created_at, updated_at`
+	now := time.Now()
+
+	err := pgxscan.Get(ctx, r.db, subscription, query, subscription.URL, subscription.EventTypes, subscription.Secret,
+		subscription.IsActive, now, subscription.ID)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return pkg.ErrNotFound
+		}
+		return fmt.Errorf("update webhook subscription: %w", err)
+	}
+	return nil
+}
+
+// DeleteWebhookSubscription deletes a subscription together with its deliveries
+func (r *repositoryImpl) DeleteWebhookSubscription(ctx context.Context, id string) error {
+	query := `DELETE FROM webhook_subscriptions WHERE id = $1`
+
+	result, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("delete webhook subscription: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return pkg.ErrNotFound
+	}
+	return nil
+}
+
+// CreateWebhookDelivery queues a delivery. It returns false without queueing anything if
+// the event has been queued for the subscription before.
+func (r *repositoryImpl) CreateWebhookDelivery(ctx context.Context, tx pgx.Tx, delivery *models.WebhookDelivery) (bool, error) {
+	query := `
+        INSERT INTO webhook_deliveries (id, subscription_id, event_id, event_type, payload)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (subscription_id, event_id) DO NOTHING
+        RETURNING ` + webhookDeliveryColumns
+
+	err := pgxscan.Get(ctx, tx, delivery, query, delivery.ID, delivery.SubscriptionID, delivery.EventID, delivery.EventType,
+		delivery.Payload)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("create webhook delivery: %w", err)
+	}
+	return true, nil
+}
+
+// ClaimWebhookDeliveries returns the pending deliveries of active subscriptions that are due
+// at now and holds them back until now plus lease, so no one else sends them in the meantime
+func (r *repositoryImpl) ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	query := `
+        UPDATE webhook_deliveries SET next_attempt_at = $2
+        WHERE id IN (
+            SELECT d.id FROM webhook_deliveries d
+            JOIN webhook_subscriptions s ON s.id = d.subscription_id
+            WHERE d.status = 'pending' AND d.next_attempt_at <= $1 AND s.is_active
+            ORDER BY d.next_attempt_at
+            LIMIT $3
+            FOR UPDATE OF d SKIP LOCKED)
+        RETURNING ` + webhookDeliveryColumns
+
+	err := pgxscan.Select(ctx, r.db, &deliveries, query, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// RecordWebhookAttempt stores an attempt together with the delivery's state after it
+func (r *repositoryImpl) RecordWebhookAttempt(ctx context.Context, tx pgx.Tx, delivery *models.WebhookDelivery, attempt *models.WebhookDeliveryAttempt) error {
+	query := `
+        INSERT INTO webhook_delivery_attempts (id, delivery_id, status_code, error, duration_ms, attempted_at)
+        VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := tx.Exec(ctx, query, attempt.ID, attempt.DeliveryID, attempt.StatusCode, attempt.Error, attempt.DurationMs,
+		attempt.AttemptedAt)
+	if err != nil {
+		return fmt.Errorf("create webhook delivery attempt: %w", err)
+	}
+
+	query = `
+        UPDATE webhook_deliveries
+        SET status = $1, attempts = $2, next_attempt_at = $3, last_status_code = $4, last_error = $5, delivered_at = $6,
+            updated_at = $7
+        WHERE id = $8
+        RETURNING updated_at`
+	now := time.Now()
+
+	err = pgxscan.Get(ctx, tx, delivery, query, delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.LastStatusCode,
+		delivery.LastError, delivery.DeliveredAt, now, delivery.ID)
+	if err != nil {
+		return fmt.Errorf("update webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// ListWebhookDeliveries returns the deliveries of a subscription, newest first
+func (r *repositoryImpl) ListWebhookDeliveries(ctx context.Context, subscriptionID string, status models.WebhookDeliveryStatus, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	query := `
+        SELECT ` + webhookDeliveryColumns + `
+        FROM webhook_deliveries
+        WHERE subscription_id = $1 AND ($2 = '' OR status = $2)
+        ORDER BY created_at DESC, id DESC
+        LIMIT $3`
+
+	err := pgxscan.Select(ctx, r.db, &deliveries, query, subscriptionID, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// GetWebhookDelivery returns a delivery of a subscription with its attempts, oldest first
+func (r *repositoryImpl) GetWebhookDelivery(ctx context.Context, subscriptionID, id string) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE id = $1 AND subscription_id = $2`
+
+	err := pgxscan.Get(ctx, r.db, &delivery, query, id, subscriptionID)
+	if err != nil {
+		if pgxscan.NotFound(err) {
+			return nil, pkg.ErrNotFound
+		}
+		return nil, fmt.Errorf("get webhook delivery: %w", err)
+	}
+
+	query = `
+        SELECT id, delivery_id, status_code, error, duration_ms, attempted_at
+        FROM webhook_delivery_attempts WHERE delivery_id = $1 ORDER BY attempted_at, id`
+
+	err = pgxscan.Select(ctx, r.db, &delivery.History, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook delivery attempts: %w", err)
+	}
+	return &delivery, nil
+}
+
+// ResetWebhookDelivery makes a delivery pending again with a fresh set of attempts, due at now
+func (r *repositoryImpl) ResetWebhookDelivery(ctx context.Context, subscriptionID, id string, now time.Time) error {
+	query := `
+        UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = $1, updated_at = $1
+        WHERE id = $2 AND subscription_id = $3`
+
+	result, err := r.db.Exec(ctx, query, now, id, subscriptionID)
+	if err != nil {
+		return fmt.Errorf("reset webhook delivery: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return pkg.ErrNotFound
+	}
+	return nil
+}
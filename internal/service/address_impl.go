@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/auth"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// CreateAddress adds an address to the user's address book. The first address
+// becomes the default one.
+func (s *serviceImpl) CreateAddress(ctx context.Context, req *models.CreateAddressRequest) (*models.Address, error) {
+	actor, err := auth.ActorFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID := actor.UserID
+
+	address := &models.Address{
+		ID:        pkg.GenerateID(),
+		UserID:    userID,
+		IsDefault: req.IsDefault,
+	}
+	if err := applyAddressRequest(address, &req.AddressRequest); err != nil {
+		return nil, err
+	}
+
+	err = s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		// the first address becomes the default
+		hasDefault, err := s.repo.LockAddressBook(ctx, tx, userID)
+		if err != nil {
+			return fmt.Errorf("locking address book: %w", err)
+		}
+		if !hasDefault {
+			address.IsDefault = true
+		}
+
+		if address.IsDefault {
+			if err := s.repo.ClearDefaultAddress(ctx, tx, userID); err != nil {
+				return fmt.Errorf("clearing default address: %w", err)
+			}
+		}
+		if err := s.repo.CreateAddress(ctx, tx, address); err != nil {
+			return fmt.Errorf("creating address: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return address, nil
+}
+
+func (s *serviceImpl) GetAddress(ctx context.Context, id string) (*models.Address, error) {
+	return s.authorizedAddress(ctx, auth.View, id)
+}
+
+// ListAddresses lists the address book of the user making the request
+func (s *serviceImpl) ListAddresses(ctx context.Context) ([]models.Address, error) {
+	actor, err := auth.ActorFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses, err := s.repo.ListAddresses(ctx, actor.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("listing addresses: %w", err)
+	}
+	return addresses, nil
+}
+
+// UpdateAddress replaces an address in the user's address book. Orders placed with
+// the address keep the copy they were placed with.
+func (s *serviceImpl) UpdateAddress(ctx context.Context, id string, req *models.CreateAddressRequest) (*models.Address, error) {
+	address, err := s.authorizedAddress(ctx, auth.Manage, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyAddressRequest(address, &req.AddressRequest); err != nil {
+		return nil, err
+	}
+	address.IsDefault = req.IsDefault
+
+	if err := s.saveAddress(ctx, address); err != nil {
+		return nil, err
+	}
+	return address, nil
+}
+
+// SetDefaultAddress makes an address the one orders ship to when they don't name one
+func (s *serviceImpl) SetDefaultAddress(ctx context.Context, id string) (*models.Address, error) {
+	address, err := s.authorizedAddress(ctx, auth.Manage, id)
+	if err != nil {
+		return nil, err
+	}
+	address.IsDefault = true
+
+	if err := s.saveAddress(ctx, address); err != nil {
+		return nil, err
+	}
+	return address, nil
+}
+
+func (s *serviceImpl) DeleteAddress(ctx context.Context, id string) error {
+	if _, err := s.authorizedAddress(ctx, auth.Manage, id); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteAddress(ctx, id); err != nil {
+		return fmt.Errorf("deleting address: %w", err)
+	}
+	return nil
+}
+
+// authorizedAddress returns the address if the user making the request may perform the action on it
+func (s *serviceImpl) authorizedAddress(ctx context.Context, action auth.Action, id string) (*models.Address, error) {
+	address, err := s.repo.GetAddress(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting address: %w", err)
+	}
+	if err := auth.Authorize(ctx, action, address.UserID); err != nil {
+		return nil, err
+	}
+	return address, nil
+}
+
+// saveAddress updates an address, taking the default flag away from the user's
+// other address first if it becomes the default
+func (s *serviceImpl) saveAddress(ctx context.Context, address *models.Address) error {
+	return s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		if address.IsDefault {
+			if _, err := s.repo.LockAddressBook(ctx, tx, address.UserID); err != nil {
+				return fmt.Errorf("locking address book: %w", err)
+			}
+			if err := s.repo.ClearDefaultAddress(ctx, tx, address.UserID); err != nil {
+				return fmt.Errorf("clearing default address: %w", err)
+			}
+		}
+		if err := s.repo.UpdateAddress(ctx, tx, address); err != nil {
+			return fmt.Errorf("updating address: %w", err)
+		}
+		return nil
+	})
+}
+
+// orderAddresses resolves the shipping and billing addresses of an order request into
+// snapshots. Without a shipping address in the request the default address is used; if
+// there is none either, shipping is nil and it is up to the caller whether that's allowed.
+func (s *serviceImpl) orderAddresses(ctx context.Context, userID string, req *models.CreateOrderRequest) (shipping, billing *models.OrderAddress, err error) {
+	shipping, err = s.orderAddress(ctx, req.ShippingAddressID, req.ShippingAddress)
+	if err != nil {
+		return nil, nil, fmt.Errorf("shipping address: %w", err)
+	}
+	if shipping == nil {
+		address, err := s.repo.GetDefaultAddress(ctx, userID)
+		if err != nil && !errors.Is(err, pkg.ErrNotFound) {
+			return nil, nil, fmt.Errorf("getting default address: %w", err)
+		}
+		if address != nil {
+			shipping = address.Snapshot()
+		}
+	}
+
+	billing, err = s.orderAddress(ctx, req.BillingAddressID, req.BillingAddress)
+	if err != nil {
+		return nil, nil, fmt.Errorf("billing address: %w", err)
+	}
+	if billing == nil {
+		billing = shipping
+	}
+	return shipping, billing, nil
+}
+
+// orderAddress returns a snapshot of the address book entry with the given ID or of the
+// inline address, or nil if neither is given
+func (s *serviceImpl) orderAddress(ctx context.Context, id string, inline *models.AddressRequest) (*models.OrderAddress, error) {
+	switch {
+	case id != "" && inline != nil:
+		return nil, fmt.Errorf("%w: give either an address ID or an address, not both", pkg.ErrInvalidInput)
+	case id != "":
+		// orders can only be sent to the customer's own addresses
+		address, err := s.authorizedAddress(ctx, auth.Manage, id)
+		if err != nil {
+			return nil, err
+		}
+		return address.Snapshot(), nil
+	case inline != nil:
+		var address models.Address
+		if err := applyAddressRequest(&address, inline); err != nil {
+			return nil, err
+		}
+		return address.Snapshot(), nil
+	}
+	return nil, nil
+}
+
+func applyAddressRequest(address *models.Address, req *models.AddressRequest) error {
+	country := strings.ToUpper(req.Country)
+	postalCode := strings.ToUpper(strings.TrimSpace(req.PostalCode))
+	if err := pkg.ValidatePostalCode(country, postalCode); err != nil {
+		return err
+	}
+
+	address.Label = req.Label
+	address.FullName = req.FullName
+	address.Line1 = req.Line1
+	address.Line2 = req.Line2
+	address.City = req.City
+	address.Region = strings.TrimSpace(req.Region)
+	address.PostalCode = postalCode
+	address.Country = country
+	address.Phone = req.Phone
+	return nil
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/internal/payment"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+func testAddress(id, userID string) *models.Address {
+	return &models.Address{
+		ID:         id,
+		UserID:     userID,
+		FullName:   "Ada Lovelace",
+		Line1:      "1 Main Street",
+		City:       "Berlin",
+		PostalCode: "10115",
+		Country:    "DE",
+		IsDefault:  true,
+	}
+}
+
+var addressRequest = &models.CreateAddressRequest{AddressRequest: models.AddressRequest{
+	FullName:   "Ada Lovelace",
+	Line1:      "2 Side Street",
+	City:       "Berlin",
+	PostalCode: "10117",
+	Country:    "DE",
+}}
+
+func TestAddressAuthorization(t *testing.T) {
+	operations := []struct {
+		name string
+		// view operations are open to admins, everything else only to the owner
+		view bool
+		call func(s *serviceImpl, ctx context.Context) error
+	}{
+		{
+			name: "get",
+			view: true,
+			call: func(s *serviceImpl, ctx context.Context) error {
+				_, err := s.GetAddress(ctx, "address-1")
+				return err
+			},
+		},
+		{
+			name: "update",
+			call: func(s *serviceImpl, ctx context.Context) error {
+				_, err := s.UpdateAddress(ctx, "address-1", addressRequest)
+				return err
+			},
+		},
+		{
+			name: "set default",
+			call: func(s *serviceImpl, ctx context.Context) error {
+				_, err := s.SetDefaultAddress(ctx, "address-1")
+				return err
+			},
+		},
+		{
+			name: "delete",
+			call: func(s *serviceImpl, ctx context.Context) error {
+				return s.DeleteAddress(ctx, "address-1")
+			},
+		},
+	}
+	actors := []struct {
+		name string
+		ctx  context.Context
+		// allowed reports whether the actor may perform an operation that is a view or not
+		allowed func(view bool) bool
+		// wantErr is the error the actor gets for operations it may not perform
+		wantErr error
+	}{
+		{name: "owner", ctx: customer("user-1"), allowed: func(bool) bool { return true }},
+		{name: "other customer", ctx: customer("user-2"), allowed: func(bool) bool { return false }, wantErr: pkg.ErrNotFound},
+		{name: "admin", ctx: admin("admin-1"), allowed: func(view bool) bool { return view }, wantErr: pkg.ErrUnauthorized},
+		{name: "anonymous", ctx: context.Background(), allowed: func(bool) bool { return false }, wantErr: pkg.ErrUnauthorized},
+	}
+
+	for _, op := range operations {
+		for _, actor := range actors {
+			t.Run(op.name+" by "+actor.name, func(t *testing.T) {
+				repo := newMemRepo()
+				repo.addAddress(testAddress("address-1", "user-1"))
+				s := newTestService(repo, payment.NewFakeProvider())
+
+				err := op.call(s, actor.ctx)
+				if actor.allowed(op.view) {
+					if err != nil {
+						t.Fatalf("error = %v, want none", err)
+					}
+					return
+				}
+				if !errors.Is(err, actor.wantErr) {
+					t.Fatalf("error = %v, want %v", err, actor.wantErr)
+				}
+				if stored, _ := repo.GetAddress(context.Background(), "address-1"); stored == nil || *stored != *testAddress("address-1", "user-1") {
+					t.Errorf("address changed to %+v", stored)
+				}
+			})
+		}
+	}
+}
+
+func TestOrderingWithAnotherUsersAddress(t *testing.T) {
+	items := []models.CreateOrderItemRequest{{ProductID: "product-1", Quantity: 1}}
+	tests := []struct {
+		name string
+		call func(s *serviceImpl, ctx context.Context) error
+	}{
+		{
+			name: "quote order shipping to it",
+			call: func(s *serviceImpl, ctx context.Context) error {
+				_, err := s.QuoteOrder(ctx, &models.CreateOrderRequest{Items: items, ShippingAddressID: "address-1"})
+				return err
+			},
+		},
+		{
+			name: "quote order billed to it",
+			call: func(s *serviceImpl, ctx context.Context) error {
+				_, err := s.QuoteOrder(ctx, &models.CreateOrderRequest{Items: items, BillingAddressID: "address-1"})
+				return err
+			},
+		},
+		{
+			name: "quote shipping to it",
+			call: func(s *serviceImpl, ctx context.Context) error {
+				_, err := s.QuoteShipping(ctx, &models.ShippingQuoteRequest{Items: items, AddressID: "address-1"})
+				return err
+			},
+		},
+		{
+			name: "order shipped to it",
+			call: func(s *serviceImpl, ctx context.Context) error {
+				return s.CreateOrder(ctx, &models.CreateOrderRequest{Items: items, ShippingAddressID: "address-1"})
+			},
+		},
+	}
+
+	actors := []struct {
+		name    string
+		ctx     context.Context
+		wantErr error
+	}{
+		{name: "customer", ctx: customer("user-2"), wantErr: pkg.ErrNotFound},
+		{name: "admin", ctx: admin("admin-1"), wantErr: pkg.ErrUnauthorized},
+	}
+
+	for _, tt := range tests {
+		for _, actor := range actors {
+			t.Run(tt.name+" as "+actor.name, func(t *testing.T) {
+				repo := newMemRepo()
+				repo.addAddress(testAddress("address-1", "user-1"))
+				s := newTestService(repo, payment.NewFakeProvider())
+
+				if err := tt.call(s, actor.ctx); !errors.Is(err, actor.wantErr) {
+					t.Fatalf("error = %v, want %v", err, actor.wantErr)
+				}
+			})
+		}
+	}
+}
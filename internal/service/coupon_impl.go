@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/internal/pricing"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+func (s *serviceImpl) CreateCoupon(ctx context.Context, req *models.CreateCouponRequest) (*models.Coupon, error) {
+	coupon := &models.Coupon{ID: pkg.GenerateID()}
+	if err := applyCouponRequest(coupon, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateCoupon(ctx, coupon); err != nil {
+		return nil, fmt.Errorf("creating coupon: %w", err)
+	}
+	return coupon, nil
+}
+
+func (s *serviceImpl) GetCouponByID(ctx context.Context, id string) (*models.Coupon, error) {
+	coupon, err := s.repo.GetCouponByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting coupon: %w", err)
+	}
+	return coupon, nil
+}
+
+func (s *serviceImpl) ListCoupons(ctx context.Context) ([]models.Coupon, error) {
+	coupons, err := s.repo.ListCoupons(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing coupons: %w", err)
+	}
+	return coupons, nil
+}
+
+func (s *serviceImpl) UpdateCoupon(ctx context.Context, id string, req *models.CreateCouponRequest) (*models.Coupon, error) {
+	coupon := &models.Coupon{ID: id}
+	if err := applyCouponRequest(coupon, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateCoupon(ctx, coupon); err != nil {
+		return nil, fmt.Errorf("updating coupon: %w", err)
+	}
+	return coupon, nil
+}
+
+// DeleteCoupon removes a coupon that has never been redeemed. Redeemed coupons
+// should be deactivated instead so the orders keep their audit trail.
+func (s *serviceImpl) DeleteCoupon(ctx context.Context, id string) error {
+	if err := s.repo.DeleteCoupon(ctx, id); err != nil {
+		return fmt.Errorf("deleting coupon: %w", err)
+	}
+	return nil
+}
+
+// applyCoupon works out the discount of the coupon with the given code on the lines.
+// The coupon stays locked until tx ends, so its usage limits hold under concurrent orders.
+func (s *serviceImpl) applyCoupon(ctx context.Context, tx pgx.Tx, userID, code string, lines []pricing.Line) (*models.Coupon, pricing.Discount, error) {
+	coupon, err := s.repo.LockCouponByCode(ctx, tx, normalizeCouponCode(code))
+	if err != nil {
+		return nil, pricing.Discount{}, fmt.Errorf("getting coupon: %w", err)
+	}
+
+	discount, err := pricing.EvaluateCoupon(coupon, lines, time.Now())
+	if err != nil {
+		return nil, pricing.Discount{}, err
+	}
+
+	total, byUser, err := s.repo.CountCouponRedemptions(ctx, tx, coupon.ID, userID)
+	if err != nil {
+		return nil, pricing.Discount{}, fmt.Errorf("counting coupon redemptions: %w", err)
+	}
+	if (coupon.MaxUses != nil && total >= *coupon.MaxUses) || (coupon.MaxUsesPerUser != nil && byUser >= *coupon.MaxUsesPerUser) {
+		return nil, pricing.Discount{}, pkg.ErrCouponUsageLimit
+	}
+	return coupon, discount, nil
+}
+
+func applyCouponRequest(coupon *models.Coupon, req *models.CreateCouponRequest) error {
+	if req.DiscountType == models.DiscountPercentage && req.Value > 100 {
+		return fmt.Errorf("%w: percentage discounts can't exceed 100", pkg.ErrInvalidInput)
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", pkg.ErrInvalidInput)
+	}
+
+	coupon.Code = normalizeCouponCode(req.Code)
+	if coupon.Code == "" {
+		return fmt.Errorf("%w: code is required", pkg.ErrInvalidInput)
+	}
+	coupon.Description = req.Description
+	coupon.DiscountType = req.DiscountType
+	coupon.Value = req.Value
+	coupon.MinOrderValue = req.MinOrderValue
+	coupon.MaxUses = req.MaxUses
+	coupon.MaxUsesPerUser = req.MaxUsesPerUser
+	coupon.ProductIDs = nonNil(req.ProductIDs)
+	coupon.Categories = nonNil(req.Categories)
+	coupon.StartsAt = req.StartsAt
+	coupon.EndsAt = req.EndsAt
+	coupon.IsActive = req.IsActive == nil || *req.IsActive
+	return nil
+}
+
+// normalizeCouponCode makes coupon codes case insensitive
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// nonNil returns an empty slice for nil so it is stored as an empty array
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
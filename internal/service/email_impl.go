@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/jobs"
+	"github.com/zde37/instashop-task/internal/mail"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// passwordResetTTL is how long a password reset code can be used
+const passwordResetTTL = time.Hour
+
+// RequestPasswordReset emails a password reset code to the user with the email address.
+// Unknown addresses are ignored without an error, so the response doesn't tell whether
+// an account exists.
+func (s *serviceImpl) RequestPasswordReset(ctx context.Context, req *models.ForgotPasswordRequest) error {
+	user, err := s.repo.GetUser(ctx, pkg.EmailIdentifier, req.Email)
+	if err != nil {
+		if errors.Is(err, pkg.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("getting user: %w", err)
+	}
+
+	// the code is issued when the email is sent, so it isn't stored with the queued email
+	reset := &models.PasswordReset{
+		ID:        pkg.GenerateID(),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	}
+
+	return s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		if err := s.repo.CreatePasswordReset(ctx, tx, reset); err != nil {
+			return fmt.Errorf("creating password reset: %w", err)
+		}
+		data := mail.PasswordResetData{Email: user.Email, ResetID: reset.ID, ExpiresAt: reset.ExpiresAt}
+		return s.sendEmail(ctx, tx, user.Email, mail.TemplatePasswordReset, data)
+	})
+}
+
+// ResetPassword sets a new password with a password reset code. The code and any other
+// outstanding codes of the user stop working, and the user is signed out everywhere.
+func (s *serviceImpl) ResetPassword(ctx context.Context, req *models.ResetPasswordRequest) error {
+	hashedPassword, err := pkg.HashPassword(req.Password)
+	if err != nil {
+		return fmt.Errorf("hashing password: %w", err)
+	}
+
+	var userID string
+	err = s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		reset, err := s.repo.LockPasswordReset(ctx, tx, hashResetToken(req.Token))
+		if err != nil {
+			if errors.Is(err, pkg.ErrNotFound) {
+				return pkg.ErrInvalidResetToken
+			}
+			return fmt.Errorf("getting password reset: %w", err)
+		}
+		now := time.Now()
+		if reset.UsedAt != nil || !now.Before(reset.ExpiresAt) {
+			return pkg.ErrInvalidResetToken
+		}
+
+		userID = reset.UserID
+		if err := s.repo.UpdateUserPassword(ctx, tx, userID, hashedPassword); err != nil {
+			return fmt.Errorf("updating password: %w", err)
+		}
+		if err := s.repo.UsePasswordResets(ctx, tx, userID, now); err != nil {
+			return fmt.Errorf("using password resets: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteSessionByUserID(ctx, userID); err != nil && !errors.Is(err, pkg.ErrNotFound) {
+		return fmt.Errorf("deleting sessions: %w", err)
+	}
+	return nil
+}
+
+// sendEmail queues an email to be sent once tx commits. The email is rendered when it is
+// sent, so the data is stored with the job and must not hold secrets.
+func (s *serviceImpl) sendEmail(ctx context.Context, tx pgx.Tx, to string, template mail.Template, data any) error {
+	email, err := mail.NewJob(template, to, data)
+	if err != nil {
+		return err
+	}
+	job, err := jobs.New(jobs.KindSendEmail, email)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.CreateJob(ctx, tx, job); err != nil {
+		return fmt.Errorf("queueing %s email: %w", template, err)
+	}
+	return nil
+}
+
+// SendEmail renders a queued email and sends it. Password reset emails get a new code for
+// their reset here; emails of resets that were used or have expired are dropped.
+func (s *serviceImpl) SendEmail(ctx context.Context, email *mail.Job) error {
+	data, err := email.Decode()
+	if err != nil {
+		return err
+	}
+
+	if reset, ok := data.(*mail.PasswordResetData); ok {
+		token, err := resetToken()
+		if err != nil {
+			return err
+		}
+		if _, err := s.repo.IssuePasswordResetToken(ctx, reset.ResetID, hashResetToken(token), time.Now()); err != nil {
+			if errors.Is(err, pkg.ErrNotFound) {
+				slog.Info("password reset used or expired, not sending its email", slog.String("reset_id", reset.ResetID))
+				return nil
+			}
+			return fmt.Errorf("issuing password reset code: %w", err)
+		}
+		reset.Token = token
+	}
+
+	msg, err := mail.Render(email.Template, email.To, data)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, msg)
+}
+
+// resetToken returns a random password reset code
+func resetToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating reset token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/mail"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/internal/payment"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// resetRepo stores users and password resets on top of memRepo
+type resetRepo struct {
+	*memRepo
+
+	user   *models.User
+	resets map[string]*models.PasswordReset
+}
+
+func (r *resetRepo) GetUser(ctx context.Context, identifier, value string) (*models.User, error) {
+	if r.user.Email != value {
+		return nil, pkg.ErrNotFound
+	}
+	return r.user, nil
+}
+
+func (r *resetRepo) CreatePasswordReset(ctx context.Context, tx pgx.Tx, reset *models.PasswordReset) error {
+	stored := *reset
+	r.resets[reset.ID] = &stored
+	return nil
+}
+
+func (r *resetRepo) IssuePasswordResetToken(ctx context.Context, id, tokenHash string, now time.Time) (*models.PasswordReset, error) {
+	reset, ok := r.resets[id]
+	if !ok || reset.UsedAt != nil || !now.Before(reset.ExpiresAt) {
+		return nil, pkg.ErrNotFound
+	}
+	reset.TokenHash = tokenHash
+	return reset, nil
+}
+
+// sentMailer keeps the emails it is given
+type sentMailer struct {
+	sent []*mail.Message
+}
+
+func (m *sentMailer) Send(ctx context.Context, msg *mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// requestReset requests a password reset and returns the queued email
+func requestReset(t *testing.T, s *serviceImpl, repo *resetRepo) *mail.Job {
+	t.Helper()
+	if err := s.RequestPasswordReset(context.Background(), &models.ForgotPasswordRequest{Email: repo.user.Email}); err != nil {
+		t.Fatalf("RequestPasswordReset() error = %v", err)
+	}
+	if len(repo.jobs) != 1 {
+		t.Fatalf("queued %d jobs, want 1", len(repo.jobs))
+	}
+	var email mail.Job
+	if err := json.Unmarshal(repo.jobs[0].Payload, &email); err != nil {
+		t.Fatal(err)
+	}
+	return &email
+}
+
+func newResetTest() (*serviceImpl, *resetRepo, *sentMailer) {
+	repo := &resetRepo{
+		memRepo: newMemRepo(),
+		user:    &models.User{ID: "user-1", Email: "ada@example.com"},
+		resets:  make(map[string]*models.PasswordReset),
+	}
+	mailer := &sentMailer{}
+	s := newTestService(repo, payment.NewFakeProvider())
+	s.mailer = mailer
+	return s, repo, mailer
+}
+
+func TestPasswordResetEmail(t *testing.T) {
+	s, repo, mailer := newResetTest()
+	email := requestReset(t, s, repo)
+
+	for _, reset := range repo.resets {
+		if reset.TokenHash != "" {
+			t.Errorf("reset stored with a code before its email was sent")
+		}
+	}
+
+	if err := s.SendEmail(context.Background(), email); err != nil {
+		t.Fatalf("SendEmail() error = %v", err)
+	}
+	if len(mailer.sent) != 1 {
+		t.Fatalf("sent %d emails, want 1", len(mailer.sent))
+	}
+	msg := mailer.sent[0]
+	if msg.To != repo.user.Email {
+		t.Errorf("email sent to %s, want %s", msg.To, repo.user.Email)
+	}
+
+	// the code in the email is the one the reset is looked up by, and it was never queued
+	var issued *models.PasswordReset
+	for _, reset := range repo.resets {
+		issued = reset
+	}
+	var token string
+	for _, field := range strings.Fields(msg.Text) {
+		if hashResetToken(field) == issued.TokenHash {
+			token = field
+		}
+	}
+	if token == "" {
+		t.Fatalf("email doesn't hold the issued code:\n%s", msg.Text)
+	}
+	if strings.Contains(string(repo.jobs[0].Payload), token) {
+		t.Errorf("queued email holds the code: %s", repo.jobs[0].Payload)
+	}
+}
+
+func TestPasswordResetEmailOfUsedReset(t *testing.T) {
+	s, repo, mailer := newResetTest()
+	email := requestReset(t, s, repo)
+
+	now := time.Now()
+	for _, reset := range repo.resets {
+		reset.UsedAt = &now
+	}
+
+	if err := s.SendEmail(context.Background(), email); err != nil {
+		t.Fatalf("SendEmail() error = %v", err)
+	}
+	if len(mailer.sent) != 0 {
+		t.Errorf("sent the email of a used reset")
+	}
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/auth"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// GetOrderInvoice returns the invoice of an order that has been paid for. Orders paid before
+// invoicing existed get their invoice on first request.
+func (s *serviceImpl) GetOrderInvoice(ctx context.Context, orderID string) (*models.Invoice, error) {
+	order, err := s.orderForInvoices(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	invoice, err := s.repo.GetOrderInvoice(ctx, order.ID)
+	if err == nil {
+		return invoice, nil
+	}
+	if !errors.Is(err, pkg.ErrNotFound) {
+		return nil, fmt.Errorf("getting invoice: %w", err)
+	}
+	if !order.Status.IsInvoiceable() {
+		return nil, fmt.Errorf("%w: order is %s", pkg.ErrNotFound, order.Status)
+	}
+
+	err = s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		order, err := s.lockOrder(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+		invoice, err = s.issueInvoice(ctx, tx, order)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+func (s *serviceImpl) ListOrderCreditNotes(ctx context.Context, orderID string) ([]models.Invoice, error) {
+	order, err := s.orderForInvoices(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	creditNotes, err := s.repo.ListOrderCreditNotes(ctx, order.ID)
+	if err != nil {
+		return nil, fmt.Errorf("listing credit notes: %w", err)
+	}
+	return creditNotes, nil
+}
+
+func (s *serviceImpl) GetOrderCreditNote(ctx context.Context, orderID, id string) (*models.Invoice, error) {
+	order, err := s.orderForInvoices(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	creditNote, err := s.repo.GetOrderCreditNote(ctx, order.ID, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting credit note: %w", err)
+	}
+	return creditNote, nil
+}
+
+// orderForInvoices returns the order whose documents are requested, if the user may see them
+func (s *serviceImpl) orderForInvoices(ctx context.Context, orderID string) (*models.Order, error) {
+	order, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("getting order: %w", err)
+	}
+	if err := auth.Authorize(ctx, auth.View, order.UserID); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// issueInvoice issues the invoice of a locked order, unless it has one already. The invoice
+// freezes the order's items, totals and addresses as they are now.
+func (s *serviceImpl) issueInvoice(ctx context.Context, tx pgx.Tx, order *models.Order) (*models.Invoice, error) {
+	existing, err := s.repo.GetOrderInvoice(ctx, order.ID)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, pkg.ErrNotFound) {
+		return nil, fmt.Errorf("getting invoice: %w", err)
+	}
+
+	lines := make([]models.InvoiceLine, len(order.Items))
+	for i, item := range order.Items {
+		total := item.SubTotal - item.DiscountAmount
+		if !order.TaxInclusive {
+			total += item.TaxAmount
+		}
+		lines[i] = models.InvoiceLine{
+			ProductID:   item.ProductID,
+			Description: itemDescription(&item),
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Discount:    item.DiscountAmount,
+			TaxRate:     item.TaxRate,
+			TaxAmount:   item.TaxAmount,
+			Total:       pkg.RoundMoney(total),
+		}
+	}
+
+	invoice := &models.Invoice{
+		ID:              pkg.GenerateID(),
+		OrderID:         order.ID,
+		Kind:            models.InvoiceKindInvoice,
+		BillingAddress:  order.BillingAddress,
+		ShippingAddress: order.ShippingAddress,
+		Lines:           lines,
+		Subtotal:        order.Subtotal,
+		DiscountTotal:   order.DiscountTotal,
+		ShippingTotal:   order.ShippingTotal,
+		TaxTotal:        order.TaxTotal,
+		TaxInclusive:    order.TaxInclusive,
+		Total:           order.TotalAmount,
+	}
+	if err := s.createInvoice(ctx, tx, invoice); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// issueCreditNote issues the credit note for a refund on a locked order. Refunds for returns
+// credit the returned items; any other refund is credited as a single line.
+func (s *serviceImpl) issueCreditNote(ctx context.Context, tx pgx.Tx, order *models.Order, refund *models.Refund, returned []models.ReturnItem) error {
+	original, err := s.issueInvoice(ctx, tx, order)
+	if err != nil {
+		return err
+	}
+
+	creditNote := &models.Invoice{
+		ID:              pkg.GenerateID(),
+		OrderID:         order.ID,
+		Kind:            models.InvoiceKindCreditNote,
+		RefundID:        &refund.ID,
+		OriginalNumber:  &original.Number,
+		BillingAddress:  original.BillingAddress,
+		ShippingAddress: original.ShippingAddress,
+		TaxInclusive:    order.TaxInclusive,
+		Total:           refund.Amount,
+	}
+
+	items := make(map[string]*models.OrderItem, len(order.Items))
+	for i := range order.Items {
+		items[order.Items[i].ID] = &order.Items[i]
+	}
+	for _, returnItem := range returned {
+		item, ok := items[returnItem.OrderItemID]
+		if !ok {
+			continue
+		}
+
+		share := float64(returnItem.Quantity) / float64(item.Quantity)
+		line := models.InvoiceLine{
+			ProductID:   item.ProductID,
+			Description: itemDescription(item),
+			Quantity:    returnItem.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Discount:    pkg.RoundMoney(item.DiscountAmount * share),
+			TaxRate:     item.TaxRate,
+			TaxAmount:   pkg.RoundMoney(item.TaxAmount * share),
+			Total:       pkg.RoundMoney(paidPerUnit(order, item) * float64(returnItem.Quantity)),
+		}
+		creditNote.Lines = append(creditNote.Lines, line)
+		creditNote.Subtotal += item.UnitPrice * float64(returnItem.Quantity)
+		creditNote.DiscountTotal += line.Discount
+		creditNote.TaxTotal += line.TaxAmount
+	}
+
+	if len(creditNote.Lines) == 0 {
+		// the refund includes tax in the proportion the order did
+		var tax float64
+		if order.TotalAmount > 0 {
+			tax = pkg.RoundMoney(refund.Amount * order.TaxTotal / order.TotalAmount)
+		}
+		net := refund.Amount
+		if !order.TaxInclusive {
+			net -= tax
+		}
+		creditNote.Lines = []models.InvoiceLine{{
+			Description: "Refund: " + refund.Reason,
+			Quantity:    1,
+			UnitPrice:   pkg.RoundMoney(net),
+			TaxAmount:   tax,
+			Total:       refund.Amount,
+		}}
+		creditNote.Subtotal = net
+		creditNote.TaxTotal = tax
+	}
+	creditNote.Subtotal = pkg.RoundMoney(creditNote.Subtotal)
+	creditNote.DiscountTotal = pkg.RoundMoney(creditNote.DiscountTotal)
+	creditNote.TaxTotal = pkg.RoundMoney(creditNote.TaxTotal)
+
+	return s.createInvoice(ctx, tx, creditNote)
+}
+
+// createInvoice numbers the document and stores it. Numbers run per kind and year without
+// gaps, e.g. INV-2024-000001 and CN-2024-000001.
+func (s *serviceImpl) createInvoice(ctx context.Context, tx pgx.Tx, invoice *models.Invoice) error {
+	year := time.Now().UTC().Year()
+	number, err := s.repo.NextInvoiceNumber(ctx, tx, invoice.Kind, year)
+	if err != nil {
+		return fmt.Errorf("numbering %s: %w", invoice.Kind, err)
+	}
+
+	prefix := "INV"
+	if invoice.Kind == models.InvoiceKindCreditNote {
+		prefix = "CN"
+	}
+	invoice.Number = fmt.Sprintf("%s-%d-%06d", prefix, year, number)
+
+	if err := s.repo.CreateInvoice(ctx, tx, invoice); err != nil {
+		return fmt.Errorf("creating %s: %w", invoice.Kind, err)
+	}
+	return nil
+}
+
+// recordRefund stores a refund together with its credit note
+func (s *serviceImpl) recordRefund(ctx context.Context, tx pgx.Tx, order *models.Order, refund *models.Refund, returned []models.ReturnItem) error {
+	if err := s.repo.CreateRefund(ctx, tx, refund); err != nil {
+		return fmt.Errorf("creating refund: %w", err)
+	}
+	return s.issueCreditNote(ctx, tx, order, refund, returned)
+}
+
+func itemDescription(item *models.OrderItem) string {
+	if item.Product != nil {
+		return item.Product.Name
+	}
+	return item.ProductID
+}
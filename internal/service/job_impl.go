@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/models"
+)
+
+const (
+	expiryBatchSize = 100
+	// succeededJobRetention and failedJobRetention are how long finished jobs are kept;
+	// failed jobs are kept longer for looking into what went wrong
+	succeededJobRetention = 24 * time.Hour
+	failedJobRetention    = 30 * 24 * time.Hour
+)
+
+// CleanupSessions deletes the sessions whose refresh token has expired
+func (s *serviceImpl) CleanupSessions(ctx context.Context) error {
+	deleted, err := s.repo.DeleteExpiredSessions(ctx, time.Now())
+	if err != nil {
+		return fmt.Errorf("deleting expired sessions: %w", err)
+	}
+	if deleted > 0 {
+		slog.Info("expired sessions deleted", slog.Int64("count", deleted))
+	}
+	return nil
+}
+
+// PruneJobs deletes the jobs that finished longer ago than their retention
+func (s *serviceImpl) PruneJobs(ctx context.Context) error {
+	now := time.Now()
+	var deleted int64
+	for status, retention := range map[models.JobStatus]time.Duration{
+		models.JobSucceeded: succeededJobRetention,
+		models.JobFailed:    failedJobRetention,
+	} {
+		count, err := s.repo.DeleteFinishedJobs(ctx, status, now.Add(-retention))
+		if err != nil {
+			return fmt.Errorf("deleting %s jobs: %w", status, err)
+		}
+		deleted += count
+	}
+	if deleted > 0 {
+		slog.Info("finished jobs deleted", slog.Int64("count", deleted))
+	}
+	return nil
+}
+
+// ExpirePendingOrders cancels the orders that have been pending for longer than maxAge,
+// giving back their stock and coupon use. Orders with a payment in progress or waiting to
+// be captured are left for the payment to settle.
+func (s *serviceImpl) ExpirePendingOrders(ctx context.Context, maxAge time.Duration) error {
+	cutoff := time.Now().Add(-maxAge)
+	var expired int
+	for {
+		ids, err := s.repo.ListStalePendingOrders(ctx, cutoff, expiryBatchSize)
+		if err != nil {
+			return fmt.Errorf("listing stale pending orders: %w", err)
+		}
+
+		var batch int
+		for _, id := range ids {
+			ok, err := s.expirePendingOrder(ctx, id, cutoff)
+			if err != nil {
+				return fmt.Errorf("expiring order %s: %w", id, err)
+			}
+			if ok {
+				batch++
+			}
+		}
+		expired += batch
+
+		// orders skipped because they changed meanwhile could be listed again, so stop once
+		// a batch is short or makes no progress
+		if len(ids) < expiryBatchSize || batch == 0 {
+			break
+		}
+	}
+
+	if expired > 0 {
+		slog.Info("stale pending orders expired", slog.Int("count", expired))
+	}
+	return nil
+}
+
+// expirePendingOrder cancels an order if it is still pending and unpaid after taking its lock
+func (s *serviceImpl) expirePendingOrder(ctx context.Context, id string, cutoff time.Time) (bool, error) {
+	var expired bool
+	before := make(stockLevels)
+	err := s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		order, err := s.lockOrder(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if order.Status != models.StatusPending || order.CreatedAt.After(cutoff) {
+			return nil
+		}
+		for _, p := range order.Payments {
+			if p.Status.InProgress() {
+				return nil
+			}
+		}
+
+		expired = true
+		return s.cancelOrder(ctx, tx, order, before)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	s.notifyStockChanges(ctx, before)
+	return expired, nil
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/auth"
+	"github.com/zde37/instashop-task/internal/mail"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/internal/notifier"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+const defaultNotificationLimit = 50
+
+// ListNotifications returns a page of the user's notifications with the number of unread ones
+func (s *serviceImpl) ListNotifications(ctx context.Context, filter models.NotificationFilter) (*models.NotificationInbox, error) {
+	actor, err := auth.ActorFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultNotificationLimit
+	}
+
+	// one more than asked for tells whether there is a next page
+	notifications, err := s.repo.ListNotifications(ctx, actor.UserID, filter.Unread, filter.Cursor, filter.Limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("listing notifications: %w", err)
+	}
+	unread, err := s.repo.CountUnreadNotifications(ctx, actor.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("counting unread notifications: %w", err)
+	}
+
+	inbox := &models.NotificationInbox{Notifications: notifications, UnreadCount: unread}
+	if len(notifications) > filter.Limit {
+		inbox.Notifications = notifications[:filter.Limit]
+		inbox.NextCursor = inbox.Notifications[filter.Limit-1].ID
+	}
+	if inbox.Notifications == nil {
+		inbox.Notifications = []models.Notification{}
+	}
+	return inbox, nil
+}
+
+func (s *serviceImpl) MarkNotificationRead(ctx context.Context, id string) (*models.Notification, error) {
+	actor, err := auth.ActorFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	notification, err := s.repo.MarkNotificationRead(ctx, actor.UserID, id, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("marking notification read: %w", err)
+	}
+	return notification, nil
+}
+
+// MarkAllNotificationsRead marks the user's unread notifications read and returns how many there were
+func (s *serviceImpl) MarkAllNotificationsRead(ctx context.Context) (int64, error) {
+	actor, err := auth.ActorFrom(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	marked, err := s.repo.MarkAllNotificationsRead(ctx, actor.UserID, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("marking notifications read: %w", err)
+	}
+	return marked, nil
+}
+
+// GetNotificationPreferences returns the user's channels for every event type, with the
+// defaults filled in for event types the user hasn't chosen channels for
+func (s *serviceImpl) GetNotificationPreferences(ctx context.Context) ([]models.NotificationPreference, error) {
+	actor, err := auth.ActorFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.repo.ListNotificationPreferences(ctx, actor.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("listing notification preferences: %w", err)
+	}
+	chosen := make(map[string]models.NotificationPreference, len(stored))
+	for _, preference := range stored {
+		chosen[preference.EventType] = preference
+	}
+
+	preferences := make([]models.NotificationPreference, len(notifier.Events))
+	for i, event := range notifier.Events {
+		preference, ok := chosen[event]
+		if !ok {
+			preference.EventType = event
+			preference.InApp, preference.Email = notifier.DefaultChannels(event)
+		}
+		preferences[i] = preference
+	}
+	return preferences, nil
+}
+
+func (s *serviceImpl) UpdateNotificationPreferences(ctx context.Context, req *models.UpdateNotificationPreferencesRequest) ([]models.NotificationPreference, error) {
+	actor, err := auth.ActorFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		for _, r := range req.Preferences {
+			preference := &models.NotificationPreference{EventType: r.EventType, InApp: *r.InApp, Email: *r.Email}
+			if err := s.repo.SaveNotificationPreference(ctx, tx, actor.UserID, preference); err != nil {
+				return fmt.Errorf("saving notification preference: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetNotificationPreferences(ctx)
+}
+
+// userNotification is an event to tell a user about through the channels they chose for it
+type userNotification struct {
+	event string
+	title string
+	body  string
+	// data is stored with the in-app notification
+	data     any
+	template mail.Template
+	mailData any
+}
+
+// fanOut delivers a notification to a user in the app and by email, as the user's
+// preferences for the event say. Both are written as part of tx.
+func (s *serviceImpl) fanOut(ctx context.Context, tx pgx.Tx, userID, email string, n userNotification) error {
+	inApp, byEmail, err := s.notificationChannels(ctx, userID, n.event)
+	if err != nil {
+		return err
+	}
+
+	if inApp {
+		data, err := json.Marshal(n.data)
+		if err != nil {
+			return fmt.Errorf("encoding %s notification: %w", n.event, err)
+		}
+		notification := &models.Notification{
+			ID:     pkg.GenerateID(),
+			UserID: userID,
+			Type:   n.event,
+			Title:  n.title,
+			Body:   n.body,
+			Data:   data,
+		}
+		if err := s.repo.CreateNotification(ctx, tx, notification); err != nil {
+			return fmt.Errorf("creating %s notification: %w", n.event, err)
+		}
+	}
+	if byEmail {
+		return s.sendEmail(ctx, tx, email, n.template, n.mailData)
+	}
+	return nil
+}
+
+// notificationChannels reports the channels the user gets notifications of the event through
+func (s *serviceImpl) notificationChannels(ctx context.Context, userID, event string) (inApp, email bool, err error) {
+	preferences, err := s.repo.ListNotificationPreferences(ctx, userID)
+	if err != nil {
+		return false, false, fmt.Errorf("listing notification preferences: %w", err)
+	}
+	for _, preference := range preferences {
+		if preference.EventType == event {
+			return preference.InApp, preference.Email, nil
+		}
+	}
+	inApp, email = notifier.DefaultChannels(event)
+	return inApp, email, nil
+}
+
+// notifyCustomer fans a notification out to the customer who placed the order
+func (s *serviceImpl) notifyCustomer(ctx context.Context, tx pgx.Tx, order *models.Order, n userNotification) error {
+	user, err := s.repo.GetUser(ctx, pkg.IDIdentifier, order.UserID)
+	if err != nil {
+		return fmt.Errorf("getting order customer: %w", err)
+	}
+	return s.fanOut(ctx, tx, user.ID, user.Email, n)
+}
+
+// notifyAdmins fans a notification out to every administrator
+func (s *serviceImpl) notifyAdmins(ctx context.Context, tx pgx.Tx, n userNotification) error {
+	admins, err := s.repo.ListUsersByRole(ctx, models.RoleAdmin)
+	if err != nil {
+		return fmt.Errorf("listing admins: %w", err)
+	}
+	for _, admin := range admins {
+		if err := s.fanOut(ctx, tx, admin.ID, admin.Email, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *serviceImpl) notifyOrderPlaced(ctx context.Context, tx pgx.Tx, order *models.Order) error {
+	return s.notifyCustomer(ctx, tx, order, userNotification{
+		event:    notifier.EventOrderPlaced,
+		title:    "Order received",
+		body:     fmt.Sprintf("We received your order %s of %.2f.", order.ID, order.TotalAmount),
+		data:     orderNotificationData(order),
+		template: mail.TemplateOrderConfirmation,
+		mailData: mail.OrderData{Order: order},
+	})
+}
+
+// notifyOrderShipped tells the customer what a new shipment of their order contains
+func (s *serviceImpl) notifyOrderShipped(ctx context.Context, tx pgx.Tx, order *models.Order, shipment *models.Shipment) error {
+	names := make(map[string]string, len(order.Items))
+	for i := range order.Items {
+		names[order.Items[i].ID] = itemDescription(&order.Items[i])
+	}
+	mailData := mail.ShipmentData{Order: order, Shipment: shipment, Items: make([]mail.ShippedItem, len(shipment.Items))}
+	for i, item := range shipment.Items {
+		mailData.Items[i] = mail.ShippedItem{Name: names[item.OrderItemID], Quantity: item.Quantity}
+	}
+
+	body := fmt.Sprintf("Your order %s is on its way with %s.", order.ID, shipment.Carrier)
+	if shipment.TrackingNumber != "" {
+		body = fmt.Sprintf("Your order %s is on its way with %s, tracking number %s.", order.ID, shipment.Carrier, shipment.TrackingNumber)
+	}
+	data := orderNotificationData(order)
+	data["shipment_id"] = shipment.ID
+	data["tracking_number"] = shipment.TrackingNumber
+
+	return s.notifyCustomer(ctx, tx, order, userNotification{
+		event:    notifier.EventOrderShipped,
+		title:    "Order shipped",
+		body:     body,
+		data:     data,
+		template: mail.TemplateOrderShipped,
+		mailData: mailData,
+	})
+}
+
+func (s *serviceImpl) notifyOrderCancelled(ctx context.Context, tx pgx.Tx, order *models.Order) error {
+	return s.notifyCustomer(ctx, tx, order, userNotification{
+		event:    notifier.EventOrderCancelled,
+		title:    "Order cancelled",
+		body:     fmt.Sprintf("Your order %s has been cancelled.", order.ID),
+		data:     orderNotificationData(order),
+		template: mail.TemplateOrderCancelled,
+		mailData: mail.OrderData{Order: order},
+	})
+}
+
+// deliver runs a fan-out in a transaction of its own, for events that happen after the
+// change that caused them has committed. Failures are logged rather than returned.
+func (s *serviceImpl) deliver(ctx context.Context, event string, fanOut func(tx pgx.Tx) error) bool {
+	if err := s.repo.WithTransaction(ctx, fanOut); err != nil {
+		slog.Error("failed to deliver notification", slog.String("event", event), slog.String("err", err.Error()))
+		return false
+	}
+	return true
+}
+
+func orderNotificationData(order *models.Order) map[string]any {
+	return map[string]any{
+		"order_id": order.ID,
+		"status":   order.Status,
+	}
+}
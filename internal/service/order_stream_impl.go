@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/auth"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/internal/outbox"
+	"github.com/zde37/instashop-task/internal/stream"
+)
+
+const (
+	// replayBatchSize is the number of missed status changes loaded at a time on resume
+	replayBatchSize = 500
+	// replayLookback is how long before the last change a client saw resumed streams replay
+	// changes from. Event IDs are taken when a change is recorded, not when it commits, so a
+	// transaction still open when the client got its last event can commit a change with a
+	// lower ID later. Transactions running longer than this can still be missed.
+	replayLookback = time.Minute
+)
+
+// SubscribeOrderUpdates subscribes to the status changes of the user's orders, or of all
+// orders for admins. With the ID of the last event the client saw, the changes since then
+// are returned to be sent first, along with the changes from replayLookback before it that
+// the client may have seen already. Live events may repeat some of them too.
+func (s *serviceImpl) SubscribeOrderUpdates(ctx context.Context, lastEventID int64) (*stream.Subscription, []stream.OrderStatusEvent, error) {
+	actor, err := auth.ActorFrom(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userID := actor.UserID
+	if actor.IsAdmin() {
+		userID = ""
+	}
+
+	// subscribe before catching up, so no change falls between the two
+	sub := s.updates.Subscribe(actor.UserID, actor.IsAdmin())
+	if lastEventID <= 0 {
+		return sub, nil, nil
+	}
+
+	after, err := s.repo.OrderStatusReplayStart(ctx, lastEventID, replayLookback)
+	if err != nil {
+		sub.Close()
+		return nil, nil, fmt.Errorf("finding order status replay start: %w", err)
+	}
+
+	var missed []stream.OrderStatusEvent
+	for {
+		events, err := s.repo.ListOrderStatusChanges(ctx, after, userID, replayBatchSize)
+		if err != nil {
+			sub.Close()
+			return nil, nil, fmt.Errorf("listing order status changes: %w", err)
+		}
+
+		for i := range events {
+			event, err := orderStatusEvent(&events[i])
+			if err != nil {
+				sub.Close()
+				return nil, nil, err
+			}
+			missed = append(missed, event)
+		}
+		if len(events) < replayBatchSize {
+			break
+		}
+		after = events[len(events)-1].Position
+	}
+	return sub, missed, nil
+}
+
+// announceStatusChange tells the order status streams of all instances about a change
+// recorded in event, once tx commits
+func (s *serviceImpl) announceStatusChange(ctx context.Context, tx pgx.Tx, event *models.OutboxEvent, change outbox.OrderStatusChange) error {
+	payload, err := json.Marshal(newOrderStatusEvent(event, change))
+	if err != nil {
+		return fmt.Errorf("encoding order status change: %w", err)
+	}
+	if err := s.repo.Notify(ctx, tx, stream.Channel, string(payload)); err != nil {
+		return fmt.Errorf("announcing order status change: %w", err)
+	}
+	return nil
+}
+
+func orderStatusEvent(event *models.OutboxEvent) (stream.OrderStatusEvent, error) {
+	var change outbox.OrderStatusChange
+	if err := json.Unmarshal(event.Payload, &change); err != nil {
+		return stream.OrderStatusEvent{}, fmt.Errorf("decoding order status change %d: %w", event.Position, err)
+	}
+	return newOrderStatusEvent(event, change), nil
+}
+
+func newOrderStatusEvent(event *models.OutboxEvent, change outbox.OrderStatusChange) stream.OrderStatusEvent {
+	return stream.OrderStatusEvent{
+		ID:        event.Position,
+		OrderID:   change.OrderID,
+		UserID:    change.UserID,
+		From:      change.From,
+		To:        change.To,
+		ChangedAt: event.CreatedAt,
+	}
+}
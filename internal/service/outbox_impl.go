@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/internal/outbox"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+const (
+	// outboxBatchSize is the number of events the relay publishes per run
+	outboxBatchSize = 100
+	// outboxMaxBackoff caps the wait before an event that failed to publish is tried again
+	outboxMaxBackoff = 10 * time.Minute
+)
+
+// RelayOutboxEvents publishes the events written to the outbox and queues their webhook
+// deliveries. Events of an aggregate are published one after the other in the order they
+// were written; an event that fails holds back the aggregate's later events until it has been
+// retried successfully. Events are marked published after the publisher has accepted them, so
+// an event can be published more than once but is never lost. It is run periodically by the
+// job runner.
+func (s *serviceImpl) RelayOutboxEvents(ctx context.Context) error {
+	var published, failed int
+	err := s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		// a second relay would race the first one for the same events
+		locked, err := s.repo.TryLockOutboxRelay(ctx, tx)
+		if err != nil || !locked {
+			return err
+		}
+
+		now := time.Now()
+		events, err := s.repo.ListPendingOutboxEvents(ctx, tx, now, outboxBatchSize)
+		if err != nil {
+			return err
+		}
+
+		held := make(map[string]bool)
+		for i := range events {
+			event := &events[i]
+			aggregate := event.AggregateType + "/" + event.AggregateID
+			if held[aggregate] {
+				continue
+			}
+
+			if err := s.queueWebhookDeliveries(ctx, tx, event); err != nil {
+				return err
+			}
+			if err := s.events.Publish(ctx, event); err != nil {
+				held[aggregate] = true
+				failed++
+				retryAt := now.Add(pkg.Backoff(event.Attempts+1, time.Second, outboxMaxBackoff))
+				if err := s.repo.RecordOutboxEventFailure(ctx, tx, event.Position, retryAt, err.Error()); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := s.repo.MarkOutboxEventPublished(ctx, tx, event.Position, time.Now()); err != nil {
+				return err
+			}
+			published++
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("relaying outbox events: %w", err)
+	}
+
+	if failed > 0 {
+		slog.Warn("relayed outbox events", slog.Int("published", published), slog.Int("failed", failed))
+	}
+	return nil
+}
+
+// recordEvent writes an event about an aggregate to the outbox. The event is published
+// by the relay once tx has committed, and not at all if tx rolls back.
+func (s *serviceImpl) recordEvent(ctx context.Context, tx pgx.Tx, aggregateType, aggregateID, eventType string, data any) (*models.OutboxEvent, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s event: %w", eventType, err)
+	}
+
+	event := &models.OutboxEvent{
+		ID:            pkg.GenerateID(),
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Type:          eventType,
+		Payload:       payload,
+	}
+	if err := s.repo.CreateOutboxEvent(ctx, tx, event); err != nil {
+		return nil, fmt.Errorf("recording %s event: %w", eventType, err)
+	}
+	return event, nil
+}
+
+// updateOrderStatus moves a locked order to status and records the change. Customers are
+// notified when their order is cancelled.
+func (s *serviceImpl) updateOrderStatus(ctx context.Context, tx pgx.Tx, order *models.Order, status models.OrderStatus) error {
+	if err := s.repo.UpdateOrderStatus(ctx, tx, order.ID, status); err != nil {
+		return fmt.Errorf("updating order status: %w", err)
+	}
+
+	change := outbox.OrderStatusChange{OrderID: order.ID, UserID: order.UserID, From: order.Status, To: status}
+	order.Status = status
+	event, err := s.recordEvent(ctx, tx, outbox.AggregateOrder, order.ID, outbox.EventOrderStatusChanged, change)
+	if err != nil {
+		return err
+	}
+	if err := s.announceStatusChange(ctx, tx, event, change); err != nil {
+		return err
+	}
+
+	if status == models.StatusCancelled {
+		return s.notifyOrderCancelled(ctx, tx, order)
+	}
+	return nil
+}
+
+// recordProductEvents records an event of the given type for each product
+func (s *serviceImpl) recordProductEvents(ctx context.Context, tx pgx.Tx, eventType string, products ...models.Product) error {
+	for i := range products {
+		if _, err := s.recordEvent(ctx, tx, outbox.AggregateProduct, products[i].ID, eventType, &products[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,407 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/auth"
+	"github.com/zde37/instashop-task/internal/jobs"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/internal/payment"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+const (
+	// paymentTimeout bounds each call to the payment provider
+	paymentTimeout = 30 * time.Second
+	// paymentSettleTime is how long a payment may stay pending before it is reconciled,
+	// well past the time PayOrder can take
+	paymentSettleTime  = 5 * time.Minute
+	reconcileBatchSize = 100
+)
+
+// PayOrder pays for a customer's pending order. The amount is authorized and captured right
+// away, and only a successful capture confirms the order. Declined attempts are recorded as
+// failed payments, and the order stays pending so the customer can try again.
+//
+// The payment is recorded as pending before the provider is called, and the provider is
+// called outside any transaction with the payment's ID as idempotency key. If the provider's
+// answer is lost the payment is returned still pending, and ReconcilePayments settles it.
+func (s *serviceImpl) PayOrder(ctx context.Context, orderID string, req *models.PayOrderRequest) (*models.Payment, error) {
+	p, err := s.startPayment(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	// once money may move, the payment is seen through even if the client goes away
+	ctx = context.WithoutCancel(ctx)
+
+	paymentErr := s.authorizeAndCapture(ctx, p, req.PaymentToken)
+	switch {
+	case paymentErr == nil:
+		if err := s.completePayment(ctx, p); err != nil {
+			return nil, err
+		}
+		return p, nil
+
+	case errors.Is(paymentErr, payment.ErrDeclined):
+		p.FailureReason = paymentErr.Error()
+		if err := s.savePayment(ctx, p); err != nil {
+			return nil, err
+		}
+		return nil, paymentError(paymentErr)
+
+	default:
+		slog.Warn("payment outcome unknown, leaving it to be reconciled", slog.String("payment_id", p.ID), slog.String("err", paymentErr.Error()))
+		p.Status = models.PaymentPending
+		if err := s.savePayment(ctx, p); err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+}
+
+// ReconcilePayments settles the payments whose outcome PayOrder didn't learn, by asking the
+// provider what became of them. Captured payments confirm their order as PayOrder would,
+// and are refunded if the order can no longer be confirmed. Refunds still pending are sent
+// again.
+func (s *serviceImpl) ReconcilePayments(ctx context.Context) error {
+	settled := time.Now().Add(-paymentSettleTime)
+	payments, err := s.repo.ListPendingPayments(ctx, settled, reconcileBatchSize)
+	if err != nil {
+		return fmt.Errorf("listing pending payments: %w", err)
+	}
+	refunds, err := s.repo.ListPendingRefunds(ctx, settled, reconcileBatchSize)
+	if err != nil {
+		return fmt.Errorf("listing pending refunds: %w", err)
+	}
+
+	var failed int
+	for i := range payments {
+		if err := s.reconcilePayment(ctx, &payments[i]); err != nil {
+			// the payment stays pending and is tried again next time
+			slog.Error("failed to reconcile payment", slog.String("payment_id", payments[i].ID), slog.String("err", err.Error()))
+			failed++
+		}
+	}
+	for _, refund := range refunds {
+		if err := s.sendRefund(ctx, refund.ID); err != nil {
+			slog.Error("failed to reconcile refund", slog.String("refund_id", refund.ID), slog.String("err", err.Error()))
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d pending payments and refunds couldn't be reconciled", failed, len(payments)+len(refunds))
+	}
+	return nil
+}
+
+func (s *serviceImpl) reconcilePayment(ctx context.Context, p *models.Payment) error {
+	lookupCtx, cancel := context.WithTimeout(ctx, paymentTimeout)
+	defer cancel()
+
+	transaction, err := s.payments.Lookup(lookupCtx, p.ID)
+	switch {
+	case errors.Is(err, payment.ErrNotFound):
+		p.Status = models.PaymentFailed
+		p.FailureReason = "payment never reached the provider"
+		return s.savePayment(ctx, p)
+	case err != nil:
+		return fmt.Errorf("looking up payment: %w", err)
+	}
+
+	p.Reference = transaction.Reference
+	switch {
+	case transaction.Refunded:
+		p.Status = models.PaymentRefunded
+		p.RefundedAmount = p.Amount
+
+	case transaction.Captured:
+		p.Status = models.PaymentCaptured
+		// orders that can't be confirmed any more have been refunded
+		if err := s.completePayment(ctx, p); err != nil && p.Status != models.PaymentRefunded {
+			return err
+		}
+		return nil
+
+	case transaction.Voided:
+		p.Status = models.PaymentVoided
+
+	default:
+		// nothing has been taken, so the authorization is let go
+		voidCtx, cancel := context.WithTimeout(ctx, paymentTimeout)
+		defer cancel()
+
+		if err := s.payments.Void(voidCtx, p.Reference); err != nil {
+			return fmt.Errorf("voiding payment: %w", err)
+		}
+		p.Status = models.PaymentVoided
+	}
+	p.FailureReason = "payment outcome unknown"
+	return s.savePayment(ctx, p)
+}
+
+// startPayment records a pending payment of the order's total. Orders can only be paid
+// for while they are pending and no other payment of them is in progress.
+func (s *serviceImpl) startPayment(ctx context.Context, orderID string) (*models.Payment, error) {
+	var p *models.Payment
+	err := s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		order, err := s.lockOrder(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+		if err := auth.Authorize(ctx, auth.Manage, order.UserID); err != nil {
+			return err
+		}
+		if order.Status != models.StatusPending {
+			return pkg.ErrOrderNotPending
+		}
+		for _, existing := range order.Payments {
+			if existing.Status.InProgress() {
+				return pkg.ErrPaymentInProgress
+			}
+		}
+
+		p = &models.Payment{
+			ID:       pkg.GenerateID(),
+			OrderID:  order.ID,
+			Provider: s.payments.Name(),
+			Status:   models.PaymentPending,
+			Amount:   order.TotalAmount,
+		}
+		if err := s.repo.CreatePayment(ctx, tx, p); err != nil {
+			return fmt.Errorf("creating payment: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// completePayment records a captured payment and confirms its order. If that fails the
+// money is refunded, so customers aren't charged for orders that didn't go through.
+func (s *serviceImpl) completePayment(ctx context.Context, p *models.Payment) error {
+	err := s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		order, err := s.lockOrder(ctx, tx, p.OrderID)
+		if err != nil {
+			return err
+		}
+		current, err := s.repo.LockPayment(ctx, tx, p.ID)
+		if err != nil {
+			return fmt.Errorf("locking payment: %w", err)
+		}
+		if current.Status != models.PaymentPending {
+			// settled by a webhook or reconciliation meanwhile
+			*p = *current
+			return nil
+		}
+		if order.Status != models.StatusPending {
+			return pkg.ErrOrderNotPending
+		}
+
+		if err := s.repo.UpdatePayment(ctx, tx, p); err != nil {
+			return fmt.Errorf("updating payment: %w", err)
+		}
+		if err := s.updateOrderStatus(ctx, tx, order, models.StatusConfirmed); err != nil {
+			return err
+		}
+		_, err = s.issueInvoice(ctx, tx, order)
+		return err
+	})
+	if err == nil {
+		return nil
+	}
+
+	slog.Error("failed to confirm paid order, refunding payment", slog.String("payment_id", p.ID), slog.String("err", err.Error()))
+	refundCtx, cancel := context.WithTimeout(ctx, paymentTimeout)
+	defer cancel()
+
+	req := payment.RefundRequest{Reference: p.Reference, Amount: p.Amount, IdempotencyKey: p.ID}
+	if _, refundErr := s.payments.Refund(refundCtx, req); refundErr != nil {
+		// the payment stays pending, so reconciliation tries again
+		return errors.Join(err, fmt.Errorf("refunding payment: %w", refundErr))
+	}
+	p.Status = models.PaymentRefunded
+	p.RefundedAmount = p.Amount
+	p.FailureReason = "order couldn't be confirmed: " + err.Error()
+	if saveErr := s.savePayment(ctx, p); saveErr != nil {
+		return errors.Join(err, saveErr)
+	}
+	return err
+}
+
+// savePayment stores the state of a payment in a transaction of its own
+func (s *serviceImpl) savePayment(ctx context.Context, p *models.Payment) error {
+	return s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		if err := s.repo.UpdatePayment(ctx, tx, p); err != nil {
+			return fmt.Errorf("updating payment: %w", err)
+		}
+		return nil
+	})
+}
+
+// authorizeAndCapture takes the payment's amount with the token. An authorization whose
+// capture is declined is voided again; if that fails the payment stays pending, so
+// reconciliation voids it later. Errors other than payment.ErrDeclined leave the outcome
+// unknown.
+func (s *serviceImpl) authorizeAndCapture(ctx context.Context, p *models.Payment, token string) error {
+	authCtx, cancel := context.WithTimeout(ctx, paymentTimeout)
+	defer cancel()
+
+	req := payment.AuthorizeRequest{OrderID: p.OrderID, Amount: p.Amount, Token: token, IdempotencyKey: p.ID}
+	reference, err := s.payments.Authorize(authCtx, req)
+	if err != nil {
+		if errors.Is(err, payment.ErrDeclined) {
+			p.Status = models.PaymentFailed
+		}
+		return err
+	}
+	p.Reference = reference
+
+	captureCtx, cancel := context.WithTimeout(ctx, paymentTimeout)
+	defer cancel()
+
+	if err := s.payments.Capture(captureCtx, reference, p.Amount); err != nil {
+		if !errors.Is(err, payment.ErrDeclined) {
+			return err
+		}
+
+		voidCtx, cancel := context.WithTimeout(ctx, paymentTimeout)
+		defer cancel()
+
+		if voidErr := s.payments.Void(voidCtx, reference); voidErr != nil {
+			slog.Error("failed to void payment authorization, leaving it to be reconciled", slog.String("payment_id", p.ID), slog.String("err", voidErr.Error()))
+		} else {
+			p.Status = models.PaymentVoided
+		}
+		return err
+	}
+	p.Status = models.PaymentCaptured
+	return nil
+}
+
+// refundPayment records the refund with a credit note for the returned items, if any, and
+// queues paying it back through the payment the order was paid with. The provider is only
+// asked once tx has committed, so a rolled back transaction never moves money; until then
+// the refund is pending. Orders that were never paid through the provider only get the
+// records.
+func (s *serviceImpl) refundPayment(ctx context.Context, tx pgx.Tx, order *models.Order, refund *models.Refund, returned []models.ReturnItem) error {
+	captured := order.CapturedPayment()
+	if captured == nil || refund.Amount <= 0 {
+		refund.Status = models.RefundSucceeded
+		return s.recordRefund(ctx, tx, order, refund, returned)
+	}
+
+	captured.RefundedAmount = pkg.RoundMoney(captured.RefundedAmount + refund.Amount)
+	if captured.RefundedAmount >= captured.Amount {
+		captured.Status = models.PaymentRefunded
+	}
+	if err := s.repo.UpdatePayment(ctx, tx, captured); err != nil {
+		return fmt.Errorf("updating payment: %w", err)
+	}
+	refund.PaymentID = &captured.ID
+	refund.Status = models.RefundPending
+	if err := s.recordRefund(ctx, tx, order, refund, returned); err != nil {
+		return err
+	}
+
+	job, err := jobs.New(jobs.KindSendRefund, models.RefundJob{RefundID: refund.ID})
+	if err != nil {
+		return err
+	}
+	if err := s.repo.CreateJob(ctx, tx, job); err != nil {
+		return fmt.Errorf("queueing refund: %w", err)
+	}
+	return nil
+}
+
+// SendRefund pays a pending refund back through the provider, with the refund's ID as
+// idempotency key so it is paid once however often it is sent. Refunds whose outcome is
+// unknown stay pending; the job is retried and ReconcilePayments sends them again.
+func (s *serviceImpl) SendRefund(ctx context.Context, job *models.RefundJob) error {
+	return s.sendRefund(ctx, job.RefundID)
+}
+
+func (s *serviceImpl) sendRefund(ctx context.Context, refundID string) error {
+	var refund *models.Refund
+	var reference string
+	err := s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		refund, err = s.repo.LockRefund(ctx, tx, refundID)
+		if err != nil {
+			return fmt.Errorf("getting refund: %w", err)
+		}
+		if refund.Status != models.RefundPending {
+			return nil
+		}
+		p, err := s.repo.LockPayment(ctx, tx, *refund.PaymentID)
+		if err != nil {
+			return fmt.Errorf("getting payment: %w", err)
+		}
+		reference = p.Reference
+		return nil
+	})
+	if err != nil || refund.Status != models.RefundPending {
+		return err
+	}
+
+	refundCtx, cancel := context.WithTimeout(ctx, paymentTimeout)
+	defer cancel()
+
+	req := payment.RefundRequest{Reference: reference, Amount: refund.Amount, IdempotencyKey: refund.ID}
+	providerReference, refundErr := s.payments.Refund(refundCtx, req)
+	if refundErr != nil && !errors.Is(refundErr, payment.ErrDeclined) {
+		return fmt.Errorf("refunding payment: %w", refundErr)
+	}
+
+	return s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		current, err := s.repo.LockRefund(ctx, tx, refundID)
+		if err != nil {
+			return fmt.Errorf("getting refund: %w", err)
+		}
+		if current.Status != models.RefundPending {
+			return nil
+		}
+
+		if refundErr != nil {
+			// the amount is owed to the customer still, and has to be paid back by hand
+			slog.Error("payment provider declined refund", slog.String("refund_id", current.ID), slog.String("err", refundErr.Error()))
+			current.Status = models.RefundFailed
+			current.FailureReason = refundErr.Error()
+
+			p, err := s.repo.LockPayment(ctx, tx, *current.PaymentID)
+			if err != nil {
+				return fmt.Errorf("getting payment: %w", err)
+			}
+			p.RefundedAmount = pkg.RoundMoney(p.RefundedAmount - current.Amount)
+			if p.Status == models.PaymentRefunded && p.RefundedAmount < p.Amount {
+				p.Status = models.PaymentCaptured
+			}
+			if err := s.repo.UpdatePayment(ctx, tx, p); err != nil {
+				return fmt.Errorf("updating payment: %w", err)
+			}
+		} else {
+			current.Status = models.RefundSucceeded
+			current.ProviderReference = providerReference
+		}
+
+		if err := s.repo.UpdateRefund(ctx, tx, current); err != nil {
+			return fmt.Errorf("updating refund: %w", err)
+		}
+		return nil
+	})
+}
+
+// paymentError maps a provider error to the error reported to the client
+func paymentError(err error) error {
+	if errors.Is(err, payment.ErrDeclined) {
+		return fmt.Errorf("%w: %v", pkg.ErrPaymentDeclined, err)
+	}
+	return fmt.Errorf("%w: %v", pkg.ErrPaymentFailed, err)
+}
@@ -0,0 +1,416 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/jobs"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/internal/payment"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+func TestPayOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		token       string
+		wantErr     error
+		wantPayment models.PaymentStatus
+		wantOrder   models.OrderStatus
+	}{
+		{
+			name:        "captured",
+			token:       "tok_visa",
+			wantPayment: models.PaymentCaptured,
+			wantOrder:   models.StatusConfirmed,
+		},
+		{
+			name:        "authorization declined",
+			token:       payment.TokenDecline,
+			wantErr:     pkg.ErrPaymentDeclined,
+			wantPayment: models.PaymentFailed,
+			wantOrder:   models.StatusPending,
+		},
+		{
+			name:        "capture declined",
+			token:       payment.TokenCaptureDecline,
+			wantErr:     pkg.ErrPaymentDeclined,
+			wantPayment: models.PaymentVoided,
+			wantOrder:   models.StatusPending,
+		},
+		{
+			name:        "authorization timed out",
+			token:       payment.TokenTimeout,
+			wantPayment: models.PaymentPending,
+			wantOrder:   models.StatusPending,
+		},
+		{
+			name:        "capture timed out",
+			token:       payment.TokenCaptureTimeout,
+			wantPayment: models.PaymentPending,
+			wantOrder:   models.StatusPending,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMemRepo()
+			repo.addOrder(pendingOrder("order-1", "user-1", 42.50))
+			s := newTestService(repo, payment.NewFakeProvider())
+
+			p, err := s.PayOrder(customer("user-1"), "order-1", &models.PayOrderRequest{PaymentToken: tt.token})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PayOrder() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && p.Status != tt.wantPayment {
+				t.Errorf("returned payment status = %s, want %s", p.Status, tt.wantPayment)
+			}
+
+			statuses := paymentStatuses(t, repo, "order-1")
+			if !slices.Equal(statuses, []models.PaymentStatus{tt.wantPayment}) {
+				t.Errorf("stored payments = %v, want [%s]", statuses, tt.wantPayment)
+			}
+			if got := repo.orderStatus(t, "order-1"); got != tt.wantOrder {
+				t.Errorf("order status = %s, want %s", got, tt.wantOrder)
+			}
+			if got, want := repo.hasInvoice("order-1"), tt.wantOrder == models.StatusConfirmed; got != want {
+				t.Errorf("invoice issued = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestPayOrderUsesPaymentIDAsIdempotencyKey(t *testing.T) {
+	repo := newMemRepo()
+	repo.addOrder(pendingOrder("order-1", "user-1", 10))
+	provider := payment.NewFakeProvider()
+	s := newTestService(repo, provider)
+
+	p, err := s.PayOrder(customer("user-1"), "order-1", &models.PayOrderRequest{PaymentToken: "tok_visa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transaction, err := provider.Lookup(context.Background(), p.ID)
+	if err != nil {
+		t.Fatalf("Lookup(payment ID) error = %v", err)
+	}
+	if transaction.Reference != p.Reference || !transaction.Captured {
+		t.Errorf("provider has %+v, want reference %s captured", transaction, p.Reference)
+	}
+}
+
+func TestPayOrderRetryAfterDecline(t *testing.T) {
+	repo := newMemRepo()
+	repo.addOrder(pendingOrder("order-1", "user-1", 10))
+	s := newTestService(repo, payment.NewFakeProvider())
+	ctx := customer("user-1")
+
+	if _, err := s.PayOrder(ctx, "order-1", &models.PayOrderRequest{PaymentToken: payment.TokenDecline}); !errors.Is(err, pkg.ErrPaymentDeclined) {
+		t.Fatalf("first PayOrder() error = %v, want %v", err, pkg.ErrPaymentDeclined)
+	}
+	if _, err := s.PayOrder(ctx, "order-1", &models.PayOrderRequest{PaymentToken: "tok_visa"}); err != nil {
+		t.Fatalf("second PayOrder() error = %v", err)
+	}
+
+	want := []models.PaymentStatus{models.PaymentFailed, models.PaymentCaptured}
+	if got := paymentStatuses(t, repo, "order-1"); !slices.Equal(got, want) {
+		t.Errorf("payments = %v, want %v", got, want)
+	}
+}
+
+func TestPayOrderWhilePaymentPending(t *testing.T) {
+	repo := newMemRepo()
+	repo.addOrder(pendingOrder("order-1", "user-1", 10))
+	s := newTestService(repo, payment.NewFakeProvider())
+	ctx := customer("user-1")
+
+	if _, err := s.PayOrder(ctx, "order-1", &models.PayOrderRequest{PaymentToken: payment.TokenCaptureTimeout}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the first payment may have gone through, so paying again could charge twice
+	_, err := s.PayOrder(ctx, "order-1", &models.PayOrderRequest{PaymentToken: "tok_visa"})
+	if !errors.Is(err, pkg.ErrPaymentInProgress) {
+		t.Fatalf("PayOrder() error = %v, want %v", err, pkg.ErrPaymentInProgress)
+	}
+}
+
+// failingProvider is a fake provider whose voids and refunds can be made to fail
+type failingProvider struct {
+	*payment.FakeProvider
+	voidErr   error
+	refundErr error
+	// loseRefunds makes refunds go through while their answer times out
+	loseRefunds bool
+}
+
+func (p *failingProvider) Void(ctx context.Context, reference string) error {
+	if p.voidErr != nil {
+		return p.voidErr
+	}
+	return p.FakeProvider.Void(ctx, reference)
+}
+
+func (p *failingProvider) Refund(ctx context.Context, req payment.RefundRequest) (string, error) {
+	if p.refundErr != nil {
+		return "", p.refundErr
+	}
+	reference, err := p.FakeProvider.Refund(ctx, req)
+	if err == nil && p.loseRefunds {
+		return "", payment.ErrTimeout
+	}
+	return reference, err
+}
+
+func TestPayOrderCaptureDeclinedAndVoidFailed(t *testing.T) {
+	repo := newMemRepo()
+	repo.addOrder(pendingOrder("order-1", "user-1", 10))
+	provider := &failingProvider{FakeProvider: payment.NewFakeProvider(), voidErr: payment.ErrTimeout}
+	s := newTestService(repo, provider)
+	ctx := customer("user-1")
+
+	_, err := s.PayOrder(ctx, "order-1", &models.PayOrderRequest{PaymentToken: payment.TokenCaptureDecline})
+	if !errors.Is(err, pkg.ErrPaymentDeclined) {
+		t.Fatalf("PayOrder() error = %v, want %v", err, pkg.ErrPaymentDeclined)
+	}
+	// the authorization still holds the customer's funds, so it is left to reconciliation
+	if statuses := paymentStatuses(t, repo, "order-1"); !slices.Equal(statuses, []models.PaymentStatus{models.PaymentPending}) {
+		t.Fatalf("payments = %v, want [pending]", statuses)
+	}
+
+	provider.voidErr = nil
+	repo.age(paymentSettleTime + time.Minute)
+	if err := s.ReconcilePayments(context.Background()); err != nil {
+		t.Fatalf("ReconcilePayments() error = %v", err)
+	}
+	if statuses := paymentStatuses(t, repo, "order-1"); !slices.Equal(statuses, []models.PaymentStatus{models.PaymentVoided}) {
+		t.Fatalf("payments after reconciling = %v, want [voided]", statuses)
+	}
+
+	// with the authorization let go the customer can pay again
+	if _, err := s.PayOrder(ctx, "order-1", &models.PayOrderRequest{PaymentToken: "tok_visa"}); err != nil {
+		t.Fatalf("PayOrder() after reconciling error = %v", err)
+	}
+}
+
+func TestPayOrderRefundsWhenOrderCantBeConfirmed(t *testing.T) {
+	repo := newMemRepo()
+	repo.addOrder(pendingOrder("order-1", "user-1", 10))
+	repo.invoiceErr = errors.New("database unavailable")
+	provider := payment.NewFakeProvider()
+	s := newTestService(repo, provider)
+
+	_, err := s.PayOrder(customer("user-1"), "order-1", &models.PayOrderRequest{PaymentToken: "tok_visa"})
+	if err == nil {
+		t.Fatal("PayOrder() succeeded, want an error")
+	}
+
+	statuses := paymentStatuses(t, repo, "order-1")
+	if !slices.Equal(statuses, []models.PaymentStatus{models.PaymentRefunded}) {
+		t.Fatalf("payments = %v, want [refunded]", statuses)
+	}
+	order, _ := repo.GetOrderByID(context.Background(), "order-1")
+	transaction, err := provider.Lookup(context.Background(), order.Payments[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !transaction.Refunded {
+		t.Errorf("provider has %+v, want the payment refunded", transaction)
+	}
+}
+
+func TestReconcilePayments(t *testing.T) {
+	tests := []struct {
+		name        string
+		token       string
+		wantPayment models.PaymentStatus
+		wantOrder   models.OrderStatus
+	}{
+		{
+			name:        "authorization never arrived",
+			token:       payment.TokenTimeout,
+			wantPayment: models.PaymentFailed,
+			wantOrder:   models.StatusPending,
+		},
+		{
+			name:        "captured without an answer",
+			token:       payment.TokenCaptureTimeout,
+			wantPayment: models.PaymentCaptured,
+			wantOrder:   models.StatusConfirmed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMemRepo()
+			repo.addOrder(pendingOrder("order-1", "user-1", 10))
+			s := newTestService(repo, payment.NewFakeProvider())
+
+			p, err := s.PayOrder(customer("user-1"), "order-1", &models.PayOrderRequest{PaymentToken: tt.token})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// payments are only reconciled once PayOrder can't be working on them any more
+			if err := s.ReconcilePayments(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if got := repo.payment(t, p.ID).Status; got != models.PaymentPending {
+				t.Fatalf("fresh payment reconciled to %s", got)
+			}
+
+			repo.age(paymentSettleTime)
+			if err := s.ReconcilePayments(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if got := repo.payment(t, p.ID).Status; got != tt.wantPayment {
+				t.Errorf("payment status = %s, want %s", got, tt.wantPayment)
+			}
+			if got := repo.orderStatus(t, "order-1"); got != tt.wantOrder {
+				t.Errorf("order status = %s, want %s", got, tt.wantOrder)
+			}
+		})
+	}
+}
+
+func TestReconcileRefundsPaymentOfCancelledOrder(t *testing.T) {
+	repo := newMemRepo()
+	repo.addOrder(pendingOrder("order-1", "user-1", 10))
+	provider := payment.NewFakeProvider()
+	s := newTestService(repo, provider)
+
+	p, err := s.PayOrder(customer("user-1"), "order-1", &models.PayOrderRequest{PaymentToken: payment.TokenCaptureTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdateOrderStatus(context.Background(), nil, "order-1", models.StatusCancelled); err != nil {
+		t.Fatal(err)
+	}
+
+	repo.age(paymentSettleTime)
+	if err := s.ReconcilePayments(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := repo.payment(t, p.ID).Status; got != models.PaymentRefunded {
+		t.Errorf("payment status = %s, want %s", got, models.PaymentRefunded)
+	}
+	transaction, err := provider.Lookup(context.Background(), p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !transaction.Refunded {
+		t.Errorf("provider has %+v, want the payment refunded", transaction)
+	}
+}
+
+// refundOrder refunds the amount of a paid order the way cancellations and returns do, and
+// returns the refund as it was stored
+func refundOrder(t *testing.T, s *serviceImpl, repo *memRepo, orderID string, amount float64) *models.Refund {
+	t.Helper()
+	ctx := context.Background()
+	refund := &models.Refund{ID: pkg.GenerateID(), OrderID: orderID, Amount: amount, Reason: "order cancelled"}
+	err := repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		order, err := s.lockOrder(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+		return s.refundPayment(ctx, tx, order, refund, nil)
+	})
+	if err != nil {
+		t.Fatalf("refundPayment() error = %v", err)
+	}
+	return refund
+}
+
+func (r *memRepo) refund(t *testing.T, id string) models.Refund {
+	t.Helper()
+	refund, err := r.LockRefund(context.Background(), nil, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *refund
+}
+
+func TestRefundPaymentPaysBackOnceAfterCommit(t *testing.T) {
+	repo := newMemRepo()
+	repo.addOrder(pendingOrder("order-1", "user-1", 10))
+	provider := &failingProvider{FakeProvider: payment.NewFakeProvider()}
+	s := newTestService(repo, provider)
+
+	p, err := s.PayOrder(customer("user-1"), "order-1", &models.PayOrderRequest{PaymentToken: "tok_visa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	refund := refundOrder(t, s, repo, "order-1", 10)
+
+	// the transaction only records the refund, and queues paying it back
+	if got := repo.refund(t, refund.ID).Status; got != models.RefundPending {
+		t.Fatalf("refund status = %s, want %s", got, models.RefundPending)
+	}
+	if transaction, _ := provider.Lookup(context.Background(), p.ID); transaction.Refunded {
+		t.Fatal("provider refunded the payment before the transaction committed")
+	}
+	if len(repo.jobs) != 1 || repo.jobs[0].Kind != jobs.KindSendRefund {
+		t.Fatalf("queued jobs %v, want a refund", repo.jobs)
+	}
+	if len(repo.creditNotes) != 1 {
+		t.Errorf("issued %d credit notes, want 1", len(repo.creditNotes))
+	}
+
+	// the provider refunds, but its answer is lost
+	provider.loseRefunds = true
+	if err := s.SendRefund(context.Background(), &models.RefundJob{RefundID: refund.ID}); err == nil {
+		t.Fatal("SendRefund() succeeded without the provider's answer")
+	}
+	if got := repo.refund(t, refund.ID).Status; got != models.RefundPending {
+		t.Fatalf("refund status = %s, want %s", got, models.RefundPending)
+	}
+
+	// reconciliation sends it again under the same idempotency key, which doesn't pay twice
+	provider.loseRefunds = false
+	repo.age(paymentSettleTime + time.Minute)
+	if err := s.ReconcilePayments(context.Background()); err != nil {
+		t.Fatalf("ReconcilePayments() error = %v", err)
+	}
+	stored := repo.refund(t, refund.ID)
+	if stored.Status != models.RefundSucceeded || stored.ProviderReference == "" {
+		t.Errorf("refund is %s with reference %q, want succeeded with a reference", stored.Status, stored.ProviderReference)
+	}
+	if transaction, _ := provider.Lookup(context.Background(), p.ID); !transaction.Refunded {
+		t.Errorf("provider has %+v, want the payment refunded", transaction)
+	}
+	if got := repo.payment(t, p.ID); got.Status != models.PaymentRefunded || got.RefundedAmount != 10 {
+		t.Errorf("payment is %s with %.2f refunded, want refunded with 10.00", got.Status, got.RefundedAmount)
+	}
+}
+
+func TestRefundPaymentDeclined(t *testing.T) {
+	repo := newMemRepo()
+	repo.addOrder(pendingOrder("order-1", "user-1", 10))
+	provider := &failingProvider{FakeProvider: payment.NewFakeProvider()}
+	s := newTestService(repo, provider)
+
+	p, err := s.PayOrder(customer("user-1"), "order-1", &models.PayOrderRequest{PaymentToken: "tok_visa"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	refund := refundOrder(t, s, repo, "order-1", 10)
+
+	provider.refundErr = fmt.Errorf("%w: account closed", payment.ErrDeclined)
+	if err := s.SendRefund(context.Background(), &models.RefundJob{RefundID: refund.ID}); err != nil {
+		t.Fatalf("SendRefund() error = %v", err)
+	}
+	if got := repo.refund(t, refund.ID); got.Status != models.RefundFailed || got.FailureReason == "" {
+		t.Errorf("refund is %s with reason %q, want failed with a reason", got.Status, got.FailureReason)
+	}
+	// nothing was paid back, so the payment is captured in full again
+	if got := repo.payment(t, p.ID); got.Status != models.PaymentCaptured || got.RefundedAmount != 0 {
+		t.Errorf("payment is %s with %.2f refunded, want captured with nothing refunded", got.Status, got.RefundedAmount)
+	}
+}
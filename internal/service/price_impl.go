@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/internal/outbox"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// SchedulePrice adds a price change to a product's price history. Changes that start
+// now are applied to the product straight away, later ones by the job runner.
+func (s *serviceImpl) SchedulePrice(ctx context.Context, productID string, req *models.SchedulePriceRequest) (*models.ProductPrice, error) {
+	if _, err := s.repo.GetProductByID(ctx, productID); err != nil {
+		return nil, fmt.Errorf("getting product: %w", err)
+	}
+
+	now := time.Now()
+	price := &models.ProductPrice{
+		ID:            pkg.GenerateID(),
+		ProductID:     productID,
+		Price:         req.Price,
+		EffectiveFrom: now,
+		EffectiveTo:   req.EffectiveTo,
+	}
+	if req.EffectiveFrom != nil {
+		if req.EffectiveFrom.Before(now.Add(-time.Minute)) {
+			return nil, fmt.Errorf("%w: effective_from can't be in the past", pkg.ErrInvalidInput)
+		}
+		price.EffectiveFrom = *req.EffectiveFrom
+	}
+	if price.EffectiveTo != nil && !price.EffectiveTo.After(price.EffectiveFrom) {
+		return nil, fmt.Errorf("%w: effective_to must be after effective_from", pkg.ErrInvalidInput)
+	}
+
+	err := s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		if err := s.repo.CreateProductPrice(ctx, tx, price); err != nil {
+			return fmt.Errorf("creating product price: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !price.EffectiveFrom.After(now) {
+		if err := s.SyncProductPrices(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return price, nil
+}
+
+func (s *serviceImpl) ListProductPrices(ctx context.Context, productID string) ([]models.ProductPrice, error) {
+	if _, err := s.repo.GetProductByID(ctx, productID); err != nil {
+		return nil, fmt.Errorf("getting product: %w", err)
+	}
+
+	prices, err := s.repo.ListProductPrices(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("listing product prices: %w", err)
+	}
+	return prices, nil
+}
+
+// CancelScheduledPrice removes a price change that hasn't taken effect yet
+func (s *serviceImpl) CancelScheduledPrice(ctx context.Context, productID, priceID string) error {
+	if err := s.repo.DeleteScheduledProductPrice(ctx, productID, priceID, time.Now()); err != nil {
+		return fmt.Errorf("cancelling scheduled price: %w", err)
+	}
+	return nil
+}
+
+// SyncProductPrices updates the listed price of products whose price history has moved on.
+// It is run periodically by the job runner.
+func (s *serviceImpl) SyncProductPrices(ctx context.Context) error {
+	var updated []models.Product
+	err := s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		updated, err = s.repo.SyncProductPrices(ctx, tx, time.Now())
+		if err != nil {
+			return err
+		}
+		return s.recordProductEvents(ctx, tx, outbox.EventProductUpdated, updated...)
+	})
+	if err != nil {
+		return fmt.Errorf("syncing product prices: %w", err)
+	}
+
+	if len(updated) > 0 {
+		slog.Info("synced product prices", slog.Int("updated", len(updated)))
+	}
+	return nil
+}
+
+// recordPrice appends an open-ended price history entry for a product starting at from
+func (s *serviceImpl) recordPrice(ctx context.Context, tx pgx.Tx, productID string, price float64, from time.Time) error {
+	return s.repo.CreateProductPrice(ctx, tx, &models.ProductPrice{
+		ID:            pkg.GenerateID(),
+		ProductID:     productID,
+		Price:         price,
+		EffectiveFrom: from,
+	})
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/auth"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/internal/pricing"
+	"github.com/zde37/instashop-task/internal/tax"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// QuoteOrder prices a cart the way CreateOrder would, without checking or reserving stock
+func (s *serviceImpl) QuoteOrder(ctx context.Context, req *models.CreateOrderRequest) (*models.OrderQuote, error) {
+	actor, err := auth.ActorFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	shipping, _, err := s.orderAddresses(ctx, actor.UserID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := s.pricedOrder(ctx, actor.UserID, req, shipping)
+	if err != nil {
+		return nil, err
+	}
+	if req.ShippingMethod != "" {
+		charge, err := s.rateShipping(ctx, order, req.ShippingMethod, shipping)
+		if err != nil {
+			return nil, err
+		}
+		if err := charge.apply(order); err != nil {
+			return nil, err
+		}
+	}
+
+	quote := &models.OrderQuote{
+		Subtotal:       order.Subtotal,
+		DiscountTotal:  order.DiscountTotal,
+		TaxTotal:       order.TaxTotal,
+		TaxRegion:      order.TaxRegion,
+		TaxInclusive:   order.TaxInclusive,
+		ShippingMethod: order.ShippingMethod,
+		ShippingTotal:  order.ShippingTotal,
+		TotalAmount:    order.TotalAmount,
+		CouponCode:     order.CouponCode,
+		Items:          make([]models.QuoteItem, len(order.Items)),
+		Discounts:      order.Discounts,
+	}
+	if quote.Discounts == nil {
+		quote.Discounts = []models.OrderDiscount{}
+	}
+	for i, item := range order.Items {
+		quote.Items[i] = models.QuoteItem{
+			ProductID:      item.ProductID,
+			Quantity:       item.Quantity,
+			UnitPrice:      item.UnitPrice,
+			SubTotal:       item.SubTotal,
+			DiscountAmount: item.DiscountAmount,
+			TaxRate:        item.TaxRate,
+			TaxAmount:      item.TaxAmount,
+		}
+	}
+	return quote, nil
+}
+
+// pricedOrder prices the goods of an order request in a transaction of its own, to be
+// shown to the customer or to rate shipping with before the order is placed
+func (s *serviceImpl) pricedOrder(ctx context.Context, userID string, req *models.CreateOrderRequest, shipping *models.OrderAddress) (*models.Order, error) {
+	var order *models.Order
+	err := s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		order, _, err = s.priceOrder(ctx, tx, userID, req, shipping)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// priceOrder builds the items of an order at the prices in effect now and applies the
+// running promotions and then the coupon, if any. Tax is charged at the rates of the
+// shipping address, or of the default tax region without one. Shipping is charged on top
+// by shippingCharge.apply and isn't taxed. It returns the coupon redemption to record when
+// the order is placed, or nil without a coupon.
+func (s *serviceImpl) priceOrder(ctx context.Context, tx pgx.Tx, userID string, req *models.CreateOrderRequest,
+	shipping *models.OrderAddress) (*models.Order, *models.CouponRedemption, error) {
+	now := time.Now()
+	order := &models.Order{Items: make([]models.OrderItem, len(req.Items))}
+
+	productIDs := make([]string, len(req.Items))
+	for i, item := range req.Items {
+		productIDs[i] = item.ProductID
+	}
+
+	// items are charged the price in effect when the order is placed
+	prices, err := s.repo.GetEffectivePrices(ctx, productIDs, now)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting effective prices: %w", err)
+	}
+
+	lines := make([]pricing.Line, len(req.Items))
+	for i, item := range req.Items {
+		product, err := s.repo.GetProductByID(ctx, item.ProductID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("getting product %s: %w", item.ProductID, err)
+		}
+		if !product.IsAvailable() {
+			return nil, nil, fmt.Errorf("product %s: %w", item.ProductID, pkg.ErrProductUnavailable)
+		}
+
+		unitPrice, ok := prices[product.ID]
+		if !ok {
+			unitPrice = product.Price
+		}
+
+		order.Items[i] = models.OrderItem{
+			ProductID: product.ID,
+			Quantity:  item.Quantity,
+			UnitPrice: unitPrice,
+			Product:   product,
+		}
+		lines[i] = pricing.Line{
+			ProductID: product.ID,
+			Category:  product.Category,
+			Quantity:  item.Quantity,
+			UnitPrice: unitPrice,
+		}
+	}
+
+	// automatic promotions come first, the coupon applies to what is left
+	promotions, err := s.repo.ListActivePromotions(ctx, now)
+	if err != nil {
+		return nil, nil, fmt.Errorf("listing promotions: %w", err)
+	}
+	for _, discount := range pricing.ApplyPromotions(promotions, lines) {
+		order.Discounts = append(order.Discounts, discount.OrderDiscount)
+	}
+
+	var redemption *models.CouponRedemption
+	if req.CouponCode != "" {
+		coupon, discount, err := s.applyCoupon(ctx, tx, userID, req.CouponCode, lines)
+		if err != nil {
+			return nil, nil, err
+		}
+		discount.Apply(lines)
+		order.Discounts = append(order.Discounts, discount.OrderDiscount)
+		order.CouponCode = &coupon.Code
+		redemption = &models.CouponRedemption{
+			CouponID: coupon.ID,
+			UserID:   userID,
+			Amount:   discount.Amount,
+		}
+	}
+
+	// tax is charged on the discounted prices
+	taxLines := make([]tax.Line, len(lines))
+	for i, line := range lines {
+		taxLines[i] = tax.Line{ProductID: line.ProductID, TaxClass: order.Items[i].Product.TaxClass, Amount: line.Net()}
+	}
+	taxRequest := tax.Request{Lines: taxLines}
+	if shipping != nil {
+		taxRequest.Region = shipping.TaxRegion()
+	}
+	taxes, err := s.tax.Calculate(ctx, taxRequest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("calculating tax: %w", err)
+	}
+
+	for i, line := range lines {
+		order.Items[i].SubTotal = line.Subtotal()
+		order.Items[i].DiscountAmount = line.Discount
+		order.Items[i].TaxRate = taxes.Lines[i].Rate
+		order.Items[i].TaxAmount = taxes.Lines[i].Amount
+	}
+	order.Subtotal = pricing.Subtotal(lines)
+	order.DiscountTotal = pkg.RoundMoney(order.Subtotal - pricing.Net(lines))
+	order.TaxTotal = taxes.Total()
+	order.TaxRegion = taxes.Region
+	order.TaxInclusive = taxes.Inclusive
+	order.TotalAmount = pricing.Net(lines)
+	if !taxes.Inclusive {
+		order.TotalAmount = pkg.RoundMoney(order.TotalAmount + order.TaxTotal)
+	}
+	return order, redemption, nil
+}
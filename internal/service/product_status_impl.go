@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/internal/outbox"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// UpdateProductStatus moves a product through its publishing lifecycle. A non-zero
+// expectedVersion makes the update fail unless the product is at that version.
+func (s *serviceImpl) UpdateProductStatus(ctx context.Context, id string, req *models.UpdateProductStatusRequest, expectedVersion int) (*models.Product, error) {
+	product, err := s.getProductVersion(ctx, id, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateProductSchedule(req.Status, req.PublishAt, req.UnpublishAt, time.Now()); err != nil {
+		return nil, err
+	}
+
+	product.Status = req.Status
+	product.PublishAt = req.PublishAt
+	product.UnpublishAt = req.UnpublishAt
+	if product.Status == models.ProductActive && product.PublishAt == nil {
+		now := time.Now()
+		product.PublishAt = &now
+	}
+
+	err = s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		if err := s.repo.UpdateProductStatus(ctx, tx, product); err != nil {
+			return fmt.Errorf("updating product status: %w", err)
+		}
+		return s.recordProductEvents(ctx, tx, outbox.EventProductUpdated, *product)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// ApplyProductSchedules publishes and unpublishes the products whose scheduled times have come.
+// It is run periodically by the job runner.
+func (s *serviceImpl) ApplyProductSchedules(ctx context.Context) error {
+	var published, unpublished []models.Product
+	err := s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		published, unpublished, err = s.repo.ApplyProductSchedules(ctx, tx, time.Now())
+		if err != nil {
+			return err
+		}
+		if err := s.recordProductEvents(ctx, tx, outbox.EventProductUpdated, published...); err != nil {
+			return err
+		}
+		return s.recordProductEvents(ctx, tx, outbox.EventProductUpdated, unpublished...)
+	})
+	if err != nil {
+		return fmt.Errorf("applying product schedules: %w", err)
+	}
+
+	if len(published) > 0 || len(unpublished) > 0 {
+		slog.Info("applied product schedules", slog.Int("published", len(published)), slog.Int("unpublished", len(unpublished)))
+	}
+	return nil
+}
+
+// newProductStatus returns the initial status of a product created from req
+func newProductStatus(req *models.CreateProductRequest) models.ProductStatus {
+	switch {
+	case req.Status != "":
+		return req.Status
+	case req.PublishAt != nil:
+		return models.ProductScheduled
+	default:
+		return models.ProductDraft
+	}
+}
+
+// validateProductSchedule checks that the publish and unpublish times make sense for status
+func validateProductSchedule(status models.ProductStatus, publishAt, unpublishAt *time.Time, now time.Time) error {
+	if status == models.ProductScheduled && (publishAt == nil || !publishAt.After(now)) {
+		return fmt.Errorf("%w: scheduled products need a publish_at in the future", pkg.ErrInvalidInput)
+	}
+	if unpublishAt == nil {
+		return nil
+	}
+	if !unpublishAt.After(now) {
+		return fmt.Errorf("%w: unpublish_at must be in the future", pkg.ErrInvalidInput)
+	}
+	if publishAt != nil && !unpublishAt.After(*publishAt) {
+		return fmt.Errorf("%w: unpublish_at must be after publish_at", pkg.ErrInvalidInput)
+	}
+	return nil
+}
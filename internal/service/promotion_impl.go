@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+func (s *serviceImpl) CreatePromotion(ctx context.Context, req *models.CreatePromotionRequest) (*models.Promotion, error) {
+	promotion := &models.Promotion{ID: pkg.GenerateID()}
+	if err := s.applyPromotionRequest(ctx, promotion, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreatePromotion(ctx, promotion); err != nil {
+		return nil, fmt.Errorf("creating promotion: %w", err)
+	}
+	return promotion, nil
+}
+
+func (s *serviceImpl) GetPromotionByID(ctx context.Context, id string) (*models.Promotion, error) {
+	promotion, err := s.repo.GetPromotionByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting promotion: %w", err)
+	}
+	return promotion, nil
+}
+
+func (s *serviceImpl) ListPromotions(ctx context.Context) ([]models.Promotion, error) {
+	promotions, err := s.repo.ListPromotions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing promotions: %w", err)
+	}
+	return promotions, nil
+}
+
+func (s *serviceImpl) UpdatePromotion(ctx context.Context, id string, req *models.CreatePromotionRequest) (*models.Promotion, error) {
+	promotion := &models.Promotion{ID: id}
+	if err := s.applyPromotionRequest(ctx, promotion, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdatePromotion(ctx, promotion); err != nil {
+		return nil, fmt.Errorf("updating promotion: %w", err)
+	}
+	return promotion, nil
+}
+
+// DeletePromotion removes a promotion. Orders it was applied to keep its name and saving.
+func (s *serviceImpl) DeletePromotion(ctx context.Context, id string) error {
+	if err := s.repo.DeletePromotion(ctx, id); err != nil {
+		return fmt.Errorf("deleting promotion: %w", err)
+	}
+	return nil
+}
+
+func (s *serviceImpl) applyPromotionRequest(ctx context.Context, promotion *models.Promotion, req *models.CreatePromotionRequest) error {
+	if err := validatePromotionRule(req); err != nil {
+		return fmt.Errorf("%w: %v", pkg.ErrInvalidInput, err)
+	}
+	if req.Type == models.PromotionBundle {
+		if err := s.validateBundlePrice(ctx, req); err != nil {
+			return err
+		}
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", pkg.ErrInvalidInput)
+	}
+
+	promotion.Name = req.Name
+	promotion.Description = req.Description
+	promotion.Type = req.Type
+	promotion.Rule = req.Rule
+	promotion.ProductIDs = nonNil(req.ProductIDs)
+	promotion.Categories = nonNil(req.Categories)
+	promotion.Priority = req.Priority
+	promotion.Exclusive = req.Exclusive
+	promotion.StartsAt = req.StartsAt
+	promotion.EndsAt = req.EndsAt
+	promotion.IsActive = req.IsActive == nil || *req.IsActive
+	return nil
+}
+
+// validatePromotionRule checks that the rule has the parameters its promotion type needs
+func validatePromotionRule(req *models.CreatePromotionRequest) error {
+	rule := req.Rule
+	switch req.Type {
+	case models.PromotionBuyXGetY:
+		if rule.BuyQuantity <= 0 || rule.GetQuantity <= 0 {
+			return fmt.Errorf("buy_quantity and get_quantity must be positive")
+		}
+		if rule.GetPercentOff < 0 || rule.GetPercentOff > 100 {
+			return fmt.Errorf("get_percent_off must be between 0 and 100")
+		}
+	case models.PromotionBundle:
+		if len(req.ProductIDs) < 2 {
+			return fmt.Errorf("bundles need at least two product_ids")
+		}
+		if len(req.Categories) > 0 {
+			return fmt.Errorf("bundles can't be restricted to categories")
+		}
+		if rule.BundlePrice <= 0 {
+			return fmt.Errorf("bundle_price must be positive")
+		}
+	case models.PromotionTieredPrice:
+		if len(rule.Tiers) == 0 {
+			return fmt.Errorf("tiers are required")
+		}
+		for _, tier := range rule.Tiers {
+			if tier.MinQuantity <= 0 || tier.PercentOff <= 0 || tier.PercentOff > 100 {
+				return fmt.Errorf("tiers need a positive min_quantity and a percent_off between 0 and 100")
+			}
+		}
+	case models.PromotionOrderThreshold:
+		if rule.MinOrderValue < 0 || rule.Value <= 0 {
+			return fmt.Errorf("min_order_value can't be negative and value must be positive")
+		}
+		switch rule.DiscountType {
+		case models.DiscountPercentage:
+			if rule.Value > 100 {
+				return fmt.Errorf("percentage discounts can't exceed 100")
+			}
+		case models.DiscountFixed:
+		default:
+			return fmt.Errorf("discount_type must be percentage or fixed")
+		}
+	}
+	return nil
+}
+
+// validateBundlePrice checks that a bundle costs less than its products bought separately
+func (s *serviceImpl) validateBundlePrice(ctx context.Context, req *models.CreatePromotionRequest) error {
+	var total float64
+	for _, productID := range req.ProductIDs {
+		product, err := s.repo.GetProductByID(ctx, productID)
+		if err != nil {
+			if errors.Is(err, pkg.ErrNotFound) {
+				return fmt.Errorf("%w: product %s doesn't exist", pkg.ErrInvalidInput, productID)
+			}
+			return fmt.Errorf("getting bundle product: %w", err)
+		}
+		total += product.Price
+	}
+
+	if req.Rule.BundlePrice >= pkg.RoundMoney(total) {
+		return fmt.Errorf("%w: bundle_price must be below the %.2f the products cost separately", pkg.ErrInvalidInput, total)
+	}
+	return nil
+}
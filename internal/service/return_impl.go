@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/auth"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// RequestReturn opens a return for some of the items of a customer's delivered order.
+// Items can't be returned more often than they were ordered, counting all returns that
+// haven't been rejected.
+func (s *serviceImpl) RequestReturn(ctx context.Context, orderID string, req *models.CreateReturnRequest) (*models.Return, error) {
+	ret := &models.Return{
+		ID:      pkg.GenerateID(),
+		OrderID: orderID,
+		Status:  models.ReturnRequested,
+		Note:    req.Note,
+	}
+
+	err := s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		order, err := s.lockOrder(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+		if err := auth.Authorize(ctx, auth.Manage, order.UserID); err != nil {
+			return err
+		}
+		if !order.Status.IsReturnable() {
+			return fmt.Errorf("%w: order is %s", pkg.ErrOrderNotReturnable, order.Status)
+		}
+
+		previous, err := s.repo.ListOrderReturns(ctx, orderID)
+		if err != nil {
+			return fmt.Errorf("listing order returns: %w", err)
+		}
+
+		ret.UserID = order.UserID
+		ret.Items, err = returnItems(order, previous, req.Items)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.CreateReturn(ctx, tx, ret); err != nil {
+			return fmt.Errorf("creating return: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (s *serviceImpl) ListOrderReturns(ctx context.Context, orderID string) ([]models.Return, error) {
+	order, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("getting order: %w", err)
+	}
+	if err := auth.Authorize(ctx, auth.View, order.UserID); err != nil {
+		return nil, err
+	}
+
+	returns, err := s.repo.ListOrderReturns(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("listing order returns: %w", err)
+	}
+	return returns, nil
+}
+
+func (s *serviceImpl) GetReturn(ctx context.Context, id string) (*models.Return, error) {
+	ret, err := s.repo.GetReturn(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting return: %w", err)
+	}
+	return ret, nil
+}
+
+// ListReturns returns the returns in the given status, or all returns if status is empty
+func (s *serviceImpl) ListReturns(ctx context.Context, status models.ReturnStatus) ([]models.Return, error) {
+	returns, err := s.repo.ListReturns(ctx, status)
+	if err != nil {
+		return nil, fmt.Errorf("listing returns: %w", err)
+	}
+	return returns, nil
+}
+
+func (s *serviceImpl) ApproveReturn(ctx context.Context, id string, req *models.ResolveReturnRequest) (*models.Return, error) {
+	return s.resolveReturn(ctx, id, models.ReturnApproved, req.Note)
+}
+
+func (s *serviceImpl) RejectReturn(ctx context.Context, id string, req *models.ResolveReturnRequest) (*models.Return, error) {
+	return s.resolveReturn(ctx, id, models.ReturnRejected, req.Note)
+}
+
+// ReceiveReturn records the goods of an approved return as received. The items count as
+// returned on the order, which becomes partially returned or returned, and the amount paid
+// for them is refunded through the order's payment. Shipping isn't refunded.
+func (s *serviceImpl) ReceiveReturn(ctx context.Context, id string, req *models.ReceiveReturnRequest) (*models.Return, error) {
+	var ret *models.Return
+	before := make(stockLevels)
+	err := s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		var order *models.Order
+		var err error
+		ret, order, err = s.lockReturn(ctx, tx, id, models.ReturnApproved)
+		if err != nil {
+			return err
+		}
+
+		items := make(map[string]*models.OrderItem, len(order.Items))
+		for i := range order.Items {
+			items[order.Items[i].ID] = &order.Items[i]
+		}
+
+		refund := &models.Refund{
+			ID:       pkg.GenerateID(),
+			OrderID:  order.ID,
+			ReturnID: &ret.ID,
+			Reason:   "return " + ret.ID,
+		}
+		for _, returned := range ret.Items {
+			item := items[returned.OrderItemID]
+			if err := s.repo.AddReturnedQuantity(ctx, tx, item.ID, returned.Quantity); err != nil {
+				return fmt.Errorf("updating returned quantity: %w", err)
+			}
+			item.ReturnedQuantity += returned.Quantity
+			refund.Amount += paidPerUnit(order, item) * float64(returned.Quantity)
+
+			if req.Restock {
+				if err := s.restock(ctx, tx, item, returned.Quantity); err != nil {
+					return err
+				}
+				before[item.ProductID] = item.Product.StockQuantity
+			}
+		}
+
+		refund.Amount = pkg.RoundMoney(refund.Amount)
+		if err := s.refundPayment(ctx, tx, order, refund, ret.Items); err != nil {
+			return err
+		}
+		ret.Refund = refund
+
+		now := time.Now()
+		ret.Status = models.ReturnReceived
+		ret.ReceivedAt = &now
+		if req.Note != "" {
+			ret.ResolutionNote = req.Note
+		}
+		if err := s.repo.UpdateReturn(ctx, tx, ret); err != nil {
+			return fmt.Errorf("updating return: %w", err)
+		}
+
+		if status := order.ReturnStatus(); status != order.Status {
+			if err := s.updateOrderStatus(ctx, tx, order, status); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyStockChanges(ctx, before)
+	return ret, nil
+}
+
+// resolveReturn approves or rejects a requested return
+func (s *serviceImpl) resolveReturn(ctx context.Context, id string, status models.ReturnStatus, note string) (*models.Return, error) {
+	var ret *models.Return
+	err := s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		ret, _, err = s.lockReturn(ctx, tx, id, models.ReturnRequested)
+		if err != nil {
+			return err
+		}
+
+		ret.Status = status
+		ret.ResolutionNote = note
+		if err := s.repo.UpdateReturn(ctx, tx, ret); err != nil {
+			return fmt.Errorf("updating return: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// lockReturn locks the order of a return until tx ends and loads both. The return must
+// be in the expected status.
+func (s *serviceImpl) lockReturn(ctx context.Context, tx pgx.Tx, id string, expected models.ReturnStatus) (*models.Return, *models.Order, error) {
+	ret, err := s.repo.GetReturn(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting return: %w", err)
+	}
+
+	order, err := s.lockOrder(ctx, tx, ret.OrderID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the return may have moved on while waiting for the lock
+	ret, err = s.repo.GetReturn(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting return: %w", err)
+	}
+	if ret.Status != expected {
+		return nil, nil, fmt.Errorf("%w: return is %s", pkg.ErrInvalidReturnStatus, ret.Status)
+	}
+	return ret, order, nil
+}
+
+// restock puts returned units of an order item back into the warehouse they were shipped from
+func (s *serviceImpl) restock(ctx context.Context, tx pgx.Tx, item *models.OrderItem, quantity int) error {
+	var warehouseID string
+	if len(item.Allocations) > 0 {
+		warehouseID = item.Allocations[0].WarehouseID
+	} else {
+		warehouse, err := s.repo.GetDefaultWarehouse(ctx)
+		if err != nil {
+			return fmt.Errorf("getting default warehouse: %w", err)
+		}
+		warehouseID = warehouse.ID
+	}
+
+	if err := s.repo.AdjustWarehouseStock(ctx, tx, warehouseID, item.ProductID, quantity); err != nil {
+		return fmt.Errorf("restocking returned items: %w", err)
+	}
+	return nil
+}
+
+// paidPerUnit is what the customer paid for one unit of an order item, after discounts and with tax
+func paidPerUnit(order *models.Order, item *models.OrderItem) float64 {
+	paid := item.SubTotal - item.DiscountAmount
+	if !order.TaxInclusive {
+		paid += item.TaxAmount
+	}
+	return paid / float64(item.Quantity)
+}
+
+// returnItems checks the requested quantities against what is left to return of each order item
+func returnItems(order *models.Order, previous []models.Return, req []models.CreateReturnItemRequest) ([]models.ReturnItem, error) {
+	remaining := make(map[string]int, len(order.Items))
+	for _, item := range order.Items {
+		remaining[item.ID] = item.Quantity
+	}
+	for _, ret := range previous {
+		if ret.Status == models.ReturnRejected {
+			continue
+		}
+		for _, item := range ret.Items {
+			remaining[item.OrderItemID] -= item.Quantity
+		}
+	}
+
+	items := make([]models.ReturnItem, len(req))
+	for i, item := range req {
+		left, ok := remaining[item.OrderItemID]
+		if !ok {
+			return nil, fmt.Errorf("%w: order item %s is not part of the order", pkg.ErrInvalidInput, item.OrderItemID)
+		}
+		if item.Quantity > left {
+			return nil, fmt.Errorf("%w: only %d of order item %s left to return", pkg.ErrInvalidInput, left, item.OrderItemID)
+		}
+		remaining[item.OrderItemID] -= item.Quantity
+		items[i] = models.ReturnItem{OrderItemID: item.OrderItemID, Quantity: item.Quantity, Reason: item.Reason}
+	}
+	return items, nil
+}
@@ -2,24 +2,124 @@ package service
 
 import (
 	"context"
+	"time"
 
+	"github.com/zde37/instashop-task/internal/mail"
 	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/internal/stream"
 )
 
 type Service interface {
 	Register(ctx context.Context, req *models.AuthRequest) (*models.User, error)
 	Login(ctx context.Context, req *models.AuthRequest) (accessToken, refreshToken string, err error)
 	Logout(ctx context.Context, userID string) error
+	RequestPasswordReset(ctx context.Context, req *models.ForgotPasswordRequest) error
+	ResetPassword(ctx context.Context, req *models.ResetPasswordRequest) error
+	SendEmail(ctx context.Context, email *mail.Job) error
 
 	CreateProduct(ctx context.Context, req *models.CreateProductRequest) (*models.Product, error)
 	GetProductByID(ctx context.Context, id string) (*models.Product, error)
-	ListProducts(ctx context.Context) ([]models.Product, error)
-	UpdateProduct(ctx context.Context, id string, req *models.CreateProductRequest) (*models.Product, error)
+	ListProducts(ctx context.Context, filter models.ProductFilter) ([]models.Product, error)
+	UpdateProduct(ctx context.Context, id string, req *models.CreateProductRequest, expectedVersion int) (*models.Product, error)
+	PatchProduct(ctx context.Context, id string, patch []byte, expectedVersion int) (*models.Product, error)
 	DeleteProduct(ctx context.Context, id string) error
+	ArchiveProduct(ctx context.Context, id string) (*models.Product, error)
+	UnarchiveProduct(ctx context.Context, id string) (*models.Product, error)
+	UpdateProductStatus(ctx context.Context, id string, req *models.UpdateProductStatusRequest, expectedVersion int) (*models.Product, error)
+	ApplyProductSchedules(ctx context.Context) error
 
-	CreateOrder(ctx context.Context, userID string, req *models.CreateOrderRequest) error
+	SchedulePrice(ctx context.Context, productID string, req *models.SchedulePriceRequest) (*models.ProductPrice, error)
+	ListProductPrices(ctx context.Context, productID string) ([]models.ProductPrice, error)
+	CancelScheduledPrice(ctx context.Context, productID, priceID string) error
+	SyncProductPrices(ctx context.Context) error
+
+	CreateCoupon(ctx context.Context, req *models.CreateCouponRequest) (*models.Coupon, error)
+	GetCouponByID(ctx context.Context, id string) (*models.Coupon, error)
+	ListCoupons(ctx context.Context) ([]models.Coupon, error)
+	UpdateCoupon(ctx context.Context, id string, req *models.CreateCouponRequest) (*models.Coupon, error)
+	DeleteCoupon(ctx context.Context, id string) error
+
+	CreatePromotion(ctx context.Context, req *models.CreatePromotionRequest) (*models.Promotion, error)
+	GetPromotionByID(ctx context.Context, id string) (*models.Promotion, error)
+	ListPromotions(ctx context.Context) ([]models.Promotion, error)
+	UpdatePromotion(ctx context.Context, id string, req *models.CreatePromotionRequest) (*models.Promotion, error)
+	DeletePromotion(ctx context.Context, id string) error
+
+	CreateTaxRate(ctx context.Context, req *models.CreateTaxRateRequest) (*models.TaxRate, error)
+	ListTaxRates(ctx context.Context) ([]models.TaxRate, error)
+	UpdateTaxRate(ctx context.Context, id string, req *models.CreateTaxRateRequest) (*models.TaxRate, error)
+	DeleteTaxRate(ctx context.Context, id string) error
+
+	CreateAddress(ctx context.Context, req *models.CreateAddressRequest) (*models.Address, error)
+	GetAddress(ctx context.Context, id string) (*models.Address, error)
+	ListAddresses(ctx context.Context) ([]models.Address, error)
+	UpdateAddress(ctx context.Context, id string, req *models.CreateAddressRequest) (*models.Address, error)
+	SetDefaultAddress(ctx context.Context, id string) (*models.Address, error)
+	DeleteAddress(ctx context.Context, id string) error
+
+	CreateShippingMethod(ctx context.Context, req *models.CreateShippingMethodRequest) (*models.ShippingMethod, error)
+	ListShippingMethods(ctx context.Context) ([]models.ShippingMethod, error)
+	UpdateShippingMethod(ctx context.Context, id string, req *models.CreateShippingMethodRequest) (*models.ShippingMethod, error)
+	DeleteShippingMethod(ctx context.Context, id string) error
+	QuoteShipping(ctx context.Context, req *models.ShippingQuoteRequest) ([]models.ShippingOption, error)
+
+	CreateOrder(ctx context.Context, req *models.CreateOrderRequest) error
+	QuoteOrder(ctx context.Context, req *models.CreateOrderRequest) (*models.OrderQuote, error)
 	GetOrderByID(ctx context.Context, id string) (*models.Order, error)
-	GetUserOrders(ctx context.Context, userID string) ([]models.Order, error)
+	GetUserOrders(ctx context.Context, filter models.UserOrderFilter) (*models.UserOrderPage, error)
+	ListOrders(ctx context.Context, filter models.OrderFilter) (*models.OrderPage, error)
 	UpdateStatus(ctx context.Context, id string, status models.OrderStatus) error
-	CancelOrder(ctx context.Context, id string, userID string) error
+	BulkUpdateStatus(ctx context.Context, ids []string, status models.OrderStatus) []error
+	CancelOrder(ctx context.Context, id string) error
+	PayOrder(ctx context.Context, orderID string, req *models.PayOrderRequest) (*models.Payment, error)
+	ReconcilePayments(ctx context.Context) error
+	SendRefund(ctx context.Context, job *models.RefundJob) error
+	HandlePaymentWebhook(ctx context.Context, payload []byte, signature string) error
+	CreateShipment(ctx context.Context, orderID string, req *models.CreateShipmentRequest) (*models.Shipment, error)
+	MarkShipmentDelivered(ctx context.Context, orderID, shipmentID string) (*models.Shipment, error)
+	GetOrderInvoice(ctx context.Context, orderID string) (*models.Invoice, error)
+	ListOrderCreditNotes(ctx context.Context, orderID string) ([]models.Invoice, error)
+	GetOrderCreditNote(ctx context.Context, orderID, id string) (*models.Invoice, error)
+
+	RequestReturn(ctx context.Context, orderID string, req *models.CreateReturnRequest) (*models.Return, error)
+	ListOrderReturns(ctx context.Context, orderID string) ([]models.Return, error)
+	GetReturn(ctx context.Context, id string) (*models.Return, error)
+	ListReturns(ctx context.Context, status models.ReturnStatus) ([]models.Return, error)
+	ApproveReturn(ctx context.Context, id string, req *models.ResolveReturnRequest) (*models.Return, error)
+	RejectReturn(ctx context.Context, id string, req *models.ResolveReturnRequest) (*models.Return, error)
+	ReceiveReturn(ctx context.Context, id string, req *models.ReceiveReturnRequest) (*models.Return, error)
+
+	CreateWarehouse(ctx context.Context, req *models.CreateWarehouseRequest) (*models.Warehouse, error)
+	ListWarehouses(ctx context.Context) ([]models.Warehouse, error)
+	UpdateWarehouse(ctx context.Context, id string, req *models.CreateWarehouseRequest) (*models.Warehouse, error)
+	GetProductStock(ctx context.Context, productID string) ([]models.WarehouseStock, error)
+	SetWarehouseStock(ctx context.Context, warehouseID, productID string, quantity int) error
+
+	ListLowStockProducts(ctx context.Context) ([]models.Product, error)
+	SubscribeBackInStock(ctx context.Context, userID, productID string) (*models.StockSubscription, error)
+	UnsubscribeBackInStock(ctx context.Context, userID, productID string) error
+
+	RelayOutboxEvents(ctx context.Context) error
+
+	CreateWebhookSubscription(ctx context.Context, req *models.CreateWebhookSubscriptionRequest) (*models.WebhookSubscription, error)
+	GetWebhookSubscription(ctx context.Context, id string) (*models.WebhookSubscription, error)
+	ListWebhookSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	UpdateWebhookSubscription(ctx context.Context, id string, req *models.CreateWebhookSubscriptionRequest) (*models.WebhookSubscription, error)
+	DeleteWebhookSubscription(ctx context.Context, id string) error
+	ListWebhookDeliveries(ctx context.Context, subscriptionID string, filter models.WebhookDeliveryFilter) ([]models.WebhookDelivery, error)
+	GetWebhookDelivery(ctx context.Context, subscriptionID, id string) (*models.WebhookDelivery, error)
+	RedeliverWebhook(ctx context.Context, subscriptionID, id string) (*models.WebhookDelivery, error)
+	DeliverWebhooks(ctx context.Context) error
+
+	ListNotifications(ctx context.Context, filter models.NotificationFilter) (*models.NotificationInbox, error)
+	MarkNotificationRead(ctx context.Context, id string) (*models.Notification, error)
+	MarkAllNotificationsRead(ctx context.Context) (int64, error)
+	GetNotificationPreferences(ctx context.Context) ([]models.NotificationPreference, error)
+	UpdateNotificationPreferences(ctx context.Context, req *models.UpdateNotificationPreferencesRequest) ([]models.NotificationPreference, error)
+
+	SubscribeOrderUpdates(ctx context.Context, lastEventID int64) (*stream.Subscription, []stream.OrderStatusEvent, error)
+
+	CleanupSessions(ctx context.Context) error
+	PruneJobs(ctx context.Context) error
+	ExpirePendingOrders(ctx context.Context, maxAge time.Duration) error
 }
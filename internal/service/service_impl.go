@@ -1,26 +1,63 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/auth"
+	"github.com/zde37/instashop-task/internal/inventory"
+	"github.com/zde37/instashop-task/internal/mail"
 	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/internal/notifier"
+	"github.com/zde37/instashop-task/internal/outbox"
+	"github.com/zde37/instashop-task/internal/payment"
 	"github.com/zde37/instashop-task/internal/repository"
+	"github.com/zde37/instashop-task/internal/shipping"
+	"github.com/zde37/instashop-task/internal/stream"
+	"github.com/zde37/instashop-task/internal/tax"
+	"github.com/zde37/instashop-task/internal/webhook"
 	"github.com/zde37/instashop-task/pkg"
 )
 
 type serviceImpl struct {
-	repo     repository.Repository
-	jwtMaker *pkg.JWTMaker
+	repo       repository.Repository
+	jwtMaker   *pkg.JWTMaker
+	fulfilment inventory.Strategy
+	notifier   notifier.Notifier
+	tax        tax.Calculator
+	carriers   shipping.Carriers
+	payments   payment.Provider
+	webhooks   *payment.Verifier
+	events     outbox.Publisher
+	sender     webhook.Sender
+	updates    *stream.Broker
+	mailer     mail.Mailer
 }
 
-func New(repo repository.Repository, jwtMaker *pkg.JWTMaker) Service {
+func New(repo repository.Repository, jwtMaker *pkg.JWTMaker, fulfilment inventory.Strategy, notifier notifier.Notifier, tax tax.Calculator,
+	carriers shipping.Carriers, payments payment.Provider, webhooks *payment.Verifier, events outbox.Publisher,
+	sender webhook.Sender, updates *stream.Broker, mailer mail.Mailer) Service {
 	return &serviceImpl{
-		repo:     repo,
-		jwtMaker: jwtMaker,
+		repo:       repo,
+		jwtMaker:   jwtMaker,
+		fulfilment: fulfilment,
+		notifier:   notifier,
+		tax:        tax,
+		carriers:   carriers,
+		payments:   payments,
+		webhooks:   webhooks,
+		events:     events,
+		sender:     sender,
+		updates:    updates,
+		mailer:     mailer,
 	}
 }
 
@@ -45,8 +82,18 @@ func (s *serviceImpl) Register(ctx context.Context, req *models.AuthRequest) (*m
 		Role:         models.RoleCustomer,
 	}
 
-	if err := s.repo.CreateUser(ctx, user); err != nil {
-		return nil, fmt.Errorf("creating user: %w", err)
+	err = s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		if err := s.repo.CreateUser(ctx, tx, user); err != nil {
+			return fmt.Errorf("creating user: %w", err)
+		}
+		registered := outbox.UserRegistered{UserID: user.ID, Email: user.Email}
+		if _, err := s.recordEvent(ctx, tx, outbox.AggregateUser, user.ID, outbox.EventUserRegistered, registered); err != nil {
+			return err
+		}
+		return s.sendEmail(ctx, tx, user.Email, mail.TemplateWelcome, mail.WelcomeData{Email: user.Email})
+	})
+	if err != nil {
+		return nil, err
 	}
 	return user, nil
 }
@@ -95,16 +142,55 @@ func (s *serviceImpl) Logout(ctx context.Context, userID string) error {
 }
 
 func (s *serviceImpl) CreateProduct(ctx context.Context, req *models.CreateProductRequest) (*models.Product, error) {
+	status := newProductStatus(req)
+	if err := validateProductSchedule(status, req.PublishAt, req.UnpublishAt, time.Now()); err != nil {
+		return nil, err
+	}
+
 	product := &models.Product{
-		ID:            pkg.GenerateID(),
-		Name:          req.Name,
-		Description:   req.Description,
-		Price:         req.Price,
-		StockQuantity: req.StockQuantity,
+		ID:               pkg.GenerateID(),
+		Name:             req.Name,
+		Description:      req.Description,
+		Price:            req.Price,
+		Category:         req.Category,
+		TaxClass:         taxClassOrDefault(req.TaxClass),
+		WeightKg:         req.WeightKg,
+		LengthCm:         req.LengthCm,
+		WidthCm:          req.WidthCm,
+		HeightCm:         req.HeightCm,
+		ReorderThreshold: req.ReorderThreshold,
+		Status:           status,
+		PublishAt:        req.PublishAt,
+		UnpublishAt:      req.UnpublishAt,
 	}
+	if status == models.ProductActive && product.PublishAt == nil {
+		now := time.Now()
+		product.PublishAt = &now
+	}
+
+	err := s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		if err := s.repo.CreateProduct(ctx, tx, product); err != nil {
+			return fmt.Errorf("creating product: %w", err)
+		}
+		if err := s.recordPrice(ctx, tx, product.ID, product.Price, product.CreatedAt); err != nil {
+			return fmt.Errorf("recording product price: %w", err)
+		}
 
-	if err := s.repo.CreateProduct(ctx, product); err != nil {
-		return nil, fmt.Errorf("creating product: %w", err)
+		// initial stock goes into the default warehouse
+		if req.StockQuantity != 0 {
+			warehouse, err := s.repo.GetDefaultWarehouse(ctx)
+			if err != nil {
+				return fmt.Errorf("getting default warehouse: %w", err)
+			}
+			if err := s.repo.SetWarehouseStock(ctx, tx, warehouse.ID, product.ID, req.StockQuantity); err != nil {
+				return fmt.Errorf("setting initial stock: %w", err)
+			}
+			product.StockQuantity = req.StockQuantity
+		}
+		return s.recordProductEvents(ctx, tx, outbox.EventProductCreated, *product)
+	})
+	if err != nil {
+		return nil, err
 	}
 	return product, nil
 }
@@ -117,8 +203,8 @@ func (s *serviceImpl) GetProductByID(ctx context.Context, id string) (*models.Pr
 	return product, nil
 }
 
-func (s *serviceImpl) ListProducts(ctx context.Context) ([]models.Product, error) {
-	products, err := s.repo.ListProducts(ctx)
+func (s *serviceImpl) ListProducts(ctx context.Context, filter models.ProductFilter) ([]models.Product, error) {
+	products, err := s.repo.ListProducts(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("listing products: %w", err)
 	}
@@ -126,77 +212,320 @@ func (s *serviceImpl) ListProducts(ctx context.Context) ([]models.Product, error
 	return products, nil
 }
 
-func (s *serviceImpl) UpdateProduct(ctx context.Context, id string, req *models.CreateProductRequest) (*models.Product, error) {
-	product := &models.Product{
-		ID:            id,
-		Name:          req.Name,
-		Description:   req.Description,
-		Price:         req.Price,
-		StockQuantity: req.StockQuantity,
+// UpdateProduct replaces the editable fields of a product. A non-zero expectedVersion
+// makes the update fail with pkg.ErrVersionMismatch unless the product is at that version.
+func (s *serviceImpl) UpdateProduct(ctx context.Context, id string, req *models.CreateProductRequest, expectedVersion int) (*models.Product, error) {
+	product, err := s.getProductVersion(ctx, id, expectedVersion)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := s.repo.UpdateProduct(ctx, product); err != nil {
-		return nil, fmt.Errorf("updating product: %w", err)
+	return s.saveProduct(ctx, product, models.ProductDocument{
+		Name:             req.Name,
+		Description:      req.Description,
+		Price:            req.Price,
+		Category:         req.Category,
+		TaxClass:         req.TaxClass,
+		WeightKg:         req.WeightKg,
+		LengthCm:         req.LengthCm,
+		WidthCm:          req.WidthCm,
+		HeightCm:         req.HeightCm,
+		StockQuantity:    req.StockQuantity,
+		ReorderThreshold: req.ReorderThreshold,
+	})
+}
+
+// PatchProduct applies a JSON Merge Patch to the editable fields of a product
+func (s *serviceImpl) PatchProduct(ctx context.Context, id string, patch []byte, expectedVersion int) (*models.Product, error) {
+	product, err := s.getProductVersion(ctx, id, expectedVersion)
+	if err != nil {
+		return nil, err
 	}
 
-	return product, nil
+	current, err := json.Marshal(models.ProductDocument{
+		Name:             product.Name,
+		Description:      product.Description,
+		Price:            product.Price,
+		Category:         product.Category,
+		TaxClass:         product.TaxClass,
+		WeightKg:         product.WeightKg,
+		LengthCm:         product.LengthCm,
+		WidthCm:          product.WidthCm,
+		HeightCm:         product.HeightCm,
+		StockQuantity:    product.StockQuantity,
+		ReorderThreshold: product.ReorderThreshold,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encoding product: %w", err)
+	}
+
+	patched, err := pkg.MergePatch(current, patch)
+	if err != nil {
+		return nil, fmt.Errorf("applying merge patch: %w", err)
+	}
+
+	// a null stock_quantity would remove it and empty the default warehouse, so it must
+	// keep a value
+	var stock struct {
+		StockQuantity *int `json:"stock_quantity"`
+	}
+	if err := json.Unmarshal(patched, &stock); err == nil && stock.StockQuantity == nil {
+		return nil, fmt.Errorf("%w: stock_quantity can't be null", pkg.ErrInvalidInput)
+	}
+
+	var doc models.ProductDocument
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", pkg.ErrInvalidInput, err)
+	}
+	if err := pkg.ValidateStruct(&doc); err != nil {
+		return nil, err
+	}
+
+	return s.saveProduct(ctx, product, doc)
 }
 
-func (s *serviceImpl) DeleteProduct(ctx context.Context, id string) error {
-	if err := s.repo.DeleteProduct(ctx, id); err != nil {
-		return fmt.Errorf("deleting product: %w", err)
+// getProductVersion loads a product and checks it against the version the caller expects, if any
+func (s *serviceImpl) getProductVersion(ctx context.Context, id string, expectedVersion int) (*models.Product, error) {
+	product, err := s.repo.GetProductByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting product: %w", err)
 	}
-	return nil
+	if expectedVersion != 0 && product.Version != expectedVersion {
+		return nil, pkg.ErrVersionMismatch
+	}
+	return product, nil
 }
 
-func (s *serviceImpl) CreateOrder(ctx context.Context, userID string, req *models.CreateOrderRequest) error {
+// saveProduct writes doc to product. The update only succeeds if the product is still
+// at the version it was loaded with, so concurrent edits can't overwrite each other.
+func (s *serviceImpl) saveProduct(ctx context.Context, product *models.Product, doc models.ProductDocument) (*models.Product, error) {
+	// orders change stock without a new product version, so stock is only set if the
+	// caller changed it, and then to the quantity asked for
+	stockChanged := doc.StockQuantity != product.StockQuantity
+	before := make(stockLevels)
+	var delta int
+	priceChanged := doc.Price != product.Price
+
+	product.Name = doc.Name
+	product.Description = doc.Description
+	product.Price = doc.Price
+	product.Category = doc.Category
+	product.TaxClass = taxClassOrDefault(doc.TaxClass)
+	product.WeightKg = doc.WeightKg
+	product.LengthCm = doc.LengthCm
+	product.WidthCm = doc.WidthCm
+	product.HeightCm = doc.HeightCm
+	product.ReorderThreshold = doc.ReorderThreshold
+
 	err := s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
-		order := &models.Order{
-			UserID: userID,
-			Status: models.StatusPending,
-			Items:  make([]models.OrderItem, len(req.Items)),
+		// stock_quantity is an aggregate of the warehouses, so a change to it is applied to
+		// the default warehouse, measured against the stock held once it is locked. The
+		// stock is locked before the product, in the order placing orders takes the locks.
+		if stockChanged {
+			current, err := s.repo.LockProductStock(ctx, tx, product.ID)
+			if err != nil {
+				return fmt.Errorf("locking product stock: %w", err)
+			}
+			before[product.ID] = current
+			delta = doc.StockQuantity - current
+		}
+
+		if err := s.repo.UpdateProduct(ctx, tx, product); err != nil {
+			return fmt.Errorf("updating product: %w", err)
+		}
+		if priceChanged {
+			if err := s.recordPrice(ctx, tx, product.ID, product.Price, product.UpdatedAt); err != nil {
+				return fmt.Errorf("recording product price: %w", err)
+			}
 		}
 
-		// process each order item
-		for i, item := range req.Items {
-			// get product
-			product, err := s.repo.GetProductByID(ctx, item.ProductID)
+		if delta != 0 {
+			warehouse, err := s.repo.GetDefaultWarehouse(ctx)
 			if err != nil {
-				return fmt.Errorf("getting product %s: %w", item.ProductID, err)
+				return fmt.Errorf("getting default warehouse: %w", err)
 			}
+			if err := s.repo.AdjustWarehouseStock(ctx, tx, warehouse.ID, product.ID, delta); err != nil {
+				return fmt.Errorf("adjusting product stock: %w", err)
+			}
+		}
+		if stockChanged {
+			product.StockQuantity = doc.StockQuantity
+		}
+		return s.recordProductEvents(ctx, tx, outbox.EventProductUpdated, *product)
+	})
+	if err != nil {
+		return nil, err
+	}
 
-			// check stock
-			if product.StockQuantity < item.Quantity {
-				return pkg.ErrInsufficientStock
+	if delta != 0 {
+		s.notifyStockChanges(ctx, before)
+	}
+	return product, nil
+}
+
+func (s *serviceImpl) DeleteProduct(ctx context.Context, id string) error {
+	return s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		product, err := s.repo.DeleteProduct(ctx, tx, id)
+		if err != nil {
+			return fmt.Errorf("deleting product: %w", err)
+		}
+		return s.recordProductEvents(ctx, tx, outbox.EventProductDeleted, *product)
+	})
+}
+
+// ArchiveProduct hides a product from listing and ordering while keeping it
+// available to the orders that reference it
+func (s *serviceImpl) ArchiveProduct(ctx context.Context, id string) (*models.Product, error) {
+	return s.setProductArchived(ctx, id, true)
+}
+
+func (s *serviceImpl) UnarchiveProduct(ctx context.Context, id string) (*models.Product, error) {
+	return s.setProductArchived(ctx, id, false)
+}
+
+func (s *serviceImpl) setProductArchived(ctx context.Context, id string, archived bool) (*models.Product, error) {
+	var product *models.Product
+	err := s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		var err error
+		product, err = s.repo.SetProductArchived(ctx, tx, id, archived)
+		if err != nil {
+			if archived {
+				return fmt.Errorf("archiving product: %w", err)
 			}
+			return fmt.Errorf("unarchiving product: %w", err)
+		}
+		return s.recordProductEvents(ctx, tx, outbox.EventProductUpdated, *product)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// maxOrderAttempts is how often placing an order is tried when prices keep changing under it
+const maxOrderAttempts = 3
+
+// CreateOrder places an order for the user making the request
+func (s *serviceImpl) CreateOrder(ctx context.Context, req *models.CreateOrderRequest) error {
+	actor, err := auth.ActorFrom(ctx)
+	if err != nil {
+		return err
+	}
+
+	shipping, billing, err := s.orderAddresses(ctx, actor.UserID, req)
+	if err != nil {
+		return err
+	}
+
+	// the goods are priced again when the order is placed; should their value have
+	// changed in between, shipping is rated anew
+	for attempt := 1; ; attempt++ {
+		err = s.placeOrder(ctx, actor.UserID, req, shipping, billing)
+		if !errors.Is(err, errStaleShippingCharge) {
+			return err
+		}
+		if attempt == maxOrderAttempts {
+			return pkg.ErrPricesChanged
+		}
+	}
+}
 
-			// update stock
-			product.StockQuantity -= item.Quantity
-			if err := s.repo.UpdateProduct(ctx, product); err != nil {
-				return fmt.Errorf("updating product stock: %w", err)
+// placeOrder prices, allocates and creates an order. Shipping is rated before the order
+// transaction starts, so a slow carrier doesn't keep stock and coupons locked.
+func (s *serviceImpl) placeOrder(ctx context.Context, userID string, req *models.CreateOrderRequest, shipping, billing *models.OrderAddress) error {
+	var charge *shippingCharge
+	if req.ShippingMethod != "" {
+		preview, err := s.pricedOrder(ctx, userID, req, shipping)
+		if err != nil {
+			return err
+		}
+		charge, err = s.rateShipping(ctx, preview, req.ShippingMethod, shipping)
+		if err != nil {
+			return err
+		}
+	}
+
+	before := make(stockLevels)
+	err := s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		order, redemption, err := s.priceOrder(ctx, tx, userID, req, shipping)
+		if err != nil {
+			return err
+		}
+		if charge != nil {
+			if err := charge.apply(order); err != nil {
+				return err
 			}
+		}
+		order.ID = pkg.GenerateID()
+		order.UserID = userID
+		order.Status = models.StatusPending
+		order.ShippingAddress = shipping
+		order.BillingAddress = billing
+
+		lines := make([]inventory.Line, len(order.Items))
+		productIDs := make([]string, len(order.Items))
+		for i, item := range order.Items {
+			lines[i] = inventory.Line{ProductID: item.ProductID, Quantity: item.Quantity}
+			productIDs[i] = item.ProductID
+			before[item.ProductID] = item.Product.StockQuantity
+		}
 
-			// create order item
-			order.Items[i] = models.OrderItem{
-				ProductID: product.ID,
-				Quantity:  item.Quantity,
-				UnitPrice: product.Price,
-				Product:   product,
+		// pick the fulfilling warehouses
+		levels, err := s.repo.LockWarehouseStock(ctx, tx, productIDs)
+		if err != nil {
+			return fmt.Errorf("locking warehouse stock: %w", err)
+		}
+
+		allocations, err := s.fulfilment.Allocate(lines, levels)
+		if err != nil {
+			if errors.Is(err, inventory.ErrUnfulfillable) {
+				return pkg.ErrInsufficientStock
 			}
+			return fmt.Errorf("allocating order items: %w", err)
+		}
 
-			// add to total
-			order.TotalAmount += product.Price * float64(item.Quantity)
+		// update stock
+		for i := range order.Items {
+			order.Items[i].Allocations = allocations[i]
+			for _, allocation := range allocations[i] {
+				err := s.repo.AdjustWarehouseStock(ctx, tx, allocation.WarehouseID, order.Items[i].ProductID, -allocation.Quantity)
+				if err != nil {
+					return fmt.Errorf("updating warehouse stock: %w", err)
+				}
+			}
 		}
 
 		// create order
-		err := s.repo.CreateOrder(ctx, tx, order)
+		err = s.repo.CreateOrder(ctx, tx, order)
 		if err != nil {
 			return fmt.Errorf("creating order: %w", err)
 		}
+		if _, err := s.recordEvent(ctx, tx, outbox.AggregateOrder, order.ID, outbox.EventOrderCreated, order); err != nil {
+			return err
+		}
+		if err := s.notifyOrderPlaced(ctx, tx, order); err != nil {
+			return err
+		}
+
+		// record the coupon use against its limits
+		if redemption != nil {
+			redemption.ID = pkg.GenerateID()
+			redemption.OrderID = order.ID
+			if err := s.repo.CreateCouponRedemption(ctx, tx, redemption); err != nil {
+				return fmt.Errorf("redeeming coupon: %w", err)
+			}
+		}
 
 		return err
 	})
-	return err
+	if err != nil {
+		return err
+	}
+
+	s.notifyStockChanges(ctx, before)
+	return nil
 }
 
 func (s *serviceImpl) GetOrderByID(ctx context.Context, id string) (*models.Order, error) {
@@ -204,73 +533,221 @@ func (s *serviceImpl) GetOrderByID(ctx context.Context, id string) (*models.Orde
 	if err != nil {
 		return nil, fmt.Errorf("getting order: %w", err)
 	}
+	if err := auth.Authorize(ctx, auth.View, order.UserID); err != nil {
+		return nil, err
+	}
 	return order, nil
 }
 
-func (s *serviceImpl) GetUserOrders(ctx context.Context, userID string) ([]models.Order, error) {
-	orders, err := s.repo.GetOrderByUserID(ctx, userID)
+// GetUserOrders returns a page of the orders of the user making the request, newest first,
+// with a summary of all their orders that match the filter
+func (s *serviceImpl) GetUserOrders(ctx context.Context, filter models.UserOrderFilter) (*models.UserOrderPage, error) {
+	actor, err := auth.ActorFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var after *models.OrderCursor
+	if filter.Cursor != "" {
+		after, err = decodeOrderCursor(filter.Cursor)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", pkg.ErrInvalidInput, err)
+		}
+	}
+	limit := filter.Limit
+	if limit == 0 {
+		limit = defaultOrderPageSize
+	}
+
+	// one more order than asked for tells whether there is a next page
+	orders, err := s.repo.ListUserOrders(ctx, actor.UserID, filter, after, limit+1)
 	if err != nil {
 		return nil, fmt.Errorf("getting user orders: %w", err)
 	}
-	return orders, nil
-}
 
-func (s *serviceImpl) UpdateStatus(ctx context.Context, id string, status models.OrderStatus) error {
-	// get current order
-	order, err := s.repo.GetOrderByID(ctx, id)
+	page := &models.UserOrderPage{Orders: orders}
+	if len(orders) > limit {
+		page.Orders = orders[:limit]
+		page.NextCursor = encodeOrderCursor(&page.Orders[limit-1])
+	}
+	if page.Orders == nil {
+		page.Orders = []models.Order{}
+	}
+
+	if slices.Contains(filter.Include, "items") && len(page.Orders) > 0 {
+		ids := make([]string, len(page.Orders))
+		for i := range page.Orders {
+			ids[i] = page.Orders[i].ID
+		}
+		items, err := s.repo.ListOrderItems(ctx, ids)
+		if err != nil {
+			return nil, fmt.Errorf("getting order items: %w", err)
+		}
+		for i := range page.Orders {
+			page.Orders[i].Items = items[page.Orders[i].ID]
+		}
+	}
+
+	summary, err := s.repo.UserOrderSummary(ctx, actor.UserID, filter)
 	if err != nil {
-		return fmt.Errorf("getting order: %w", err)
+		return nil, fmt.Errorf("summing up user orders: %w", err)
 	}
+	page.Summary = *summary
+	return page, nil
+}
+
+// encodeOrderCursor returns the opaque cursor of the page after the order
+func encodeOrderCursor(order *models.Order) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(order.CreatedAt.Format(time.RFC3339Nano) + "|" + order.ID))
+}
 
-	// validate status transition
-	if order.Status == models.StatusCancelled || order.Status == models.StatusDelivered {
-		return fmt.Errorf("cannot update %s order", order.Status)
+func decodeOrderCursor(cursor string) (*models.OrderCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return nil, errors.New("malformed cursor")
 	}
 
-	if err := s.repo.UpdateOrderStatus(ctx, id, status); err != nil {
-		return fmt.Errorf("updating order status: %w", err)
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return nil, errors.New("malformed cursor")
 	}
-	return nil
+	return &models.OrderCursor{CreatedAt: t, ID: id}, nil
 }
 
-func (s *serviceImpl) CancelOrder(ctx context.Context, id string, userID string) error {
-	// get order
-	order, err := s.repo.GetOrderByID(ctx, id)
+// defaultOrderPageSize is the page size of order lists that don't ask for one
+const defaultOrderPageSize = 20
+
+// ListOrders returns a page of all customers' orders
+func (s *serviceImpl) ListOrders(ctx context.Context, filter models.OrderFilter) (*models.OrderPage, error) {
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PageSize == 0 {
+		filter.PageSize = defaultOrderPageSize
+	}
+
+	orders, total, err := s.repo.ListOrders(ctx, filter)
 	if err != nil {
-		return fmt.Errorf("getting order: %w", err)
+		return nil, fmt.Errorf("listing orders: %w", err)
 	}
+	if orders == nil {
+		orders = []models.Order{}
+	}
+	return &models.OrderPage{Orders: orders, Total: total, Page: filter.Page, PageSize: filter.PageSize}, nil
+}
 
-	// verify ownership
-	if order.UserID != userID {
-		return pkg.ErrUnauthorized
+// BulkUpdateStatus moves each of the orders to status as UpdateStatus does. Orders are updated
+// one by one, so an order that can't be moved doesn't hold up the others; the returned errors
+// line up with ids and are nil for the orders that were updated.
+func (s *serviceImpl) BulkUpdateStatus(ctx context.Context, ids []string, status models.OrderStatus) []error {
+	errs := make([]error, len(ids))
+	for i, id := range ids {
+		errs[i] = s.UpdateStatus(ctx, id, status)
 	}
+	return errs
+}
 
-	// verify status
-	if order.Status != models.StatusPending {
-		return pkg.ErrOrderNotPending
+// UpdateStatus moves an order to another status by hand, as allowed by OrderStatus.CanTransitionTo.
+// Cancelling an order gives back its stock and coupon use, and refunds what is left of its payment.
+func (s *serviceImpl) UpdateStatus(ctx context.Context, id string, status models.OrderStatus) error {
+	before := make(stockLevels)
+	err := s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		order, err := s.lockOrder(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		// validate status transition
+		if !order.Status.CanTransitionTo(status) {
+			return fmt.Errorf("%w: %s to %s", pkg.ErrInvalidStatusTransition, order.Status, status)
+		}
+
+		if status == models.StatusCancelled {
+			return s.cancelOrder(ctx, tx, order, before)
+		}
+		return s.updateOrderStatus(ctx, tx, order, status)
+	})
+	if err != nil {
+		return err
 	}
 
+	s.notifyStockChanges(ctx, before)
+	return nil
+}
+
+func (s *serviceImpl) CancelOrder(ctx context.Context, id string) error {
+	before := make(stockLevels)
+
 	// start transaction
-	err = s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
-		// update order status
-		if err := s.repo.UpdateOrderStatus(ctx, id, models.StatusCancelled); err != nil {
-			return fmt.Errorf("updating order status: %w", err)
+	err := s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		// get order, locked so it can't be paid for while it is cancelled
+		order, err := s.lockOrder(ctx, tx, id)
+		if err != nil {
+			return err
 		}
 
-		// restore product stock
-		for _, item := range order.Items {
-			product, err := s.repo.GetProductByID(ctx, item.ProductID)
-			if err != nil {
-				return fmt.Errorf("getting product: %w", err)
-			}
-			product.StockQuantity += item.Quantity
+		// verify ownership
+		if err := auth.Authorize(ctx, auth.Manage, order.UserID); err != nil {
+			return err
+		}
 
-			if err := s.repo.UpdateProduct(ctx, product); err != nil {
-				return fmt.Errorf("restoring product stock: %w", err)
-			}
+		// verify status
+		if order.Status != models.StatusPending {
+			return pkg.ErrOrderNotPending
+		}
 
+		return s.cancelOrder(ctx, tx, order, before)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.notifyStockChanges(ctx, before)
+	return nil
+}
+
+// cancelOrder cancels a locked order that hasn't shipped and gives back its stock and coupon
+// use, refunding what is left of its payment if it was paid for. The stock levels before the
+// change are added to before.
+func (s *serviceImpl) cancelOrder(ctx context.Context, tx pgx.Tx, order *models.Order, before stockLevels) error {
+	for _, item := range order.Items {
+		before[item.ProductID] = item.Product.StockQuantity
+	}
+
+	if captured := order.CapturedPayment(); captured != nil {
+		refund := &models.Refund{
+			ID:      pkg.GenerateID(),
+			OrderID: order.ID,
+			Amount:  pkg.RoundMoney(captured.Amount - captured.RefundedAmount),
+			Reason:  "order cancelled",
+		}
+		if err := s.refundPayment(ctx, tx, order, refund, nil); err != nil {
+			return err
 		}
+	}
+
+	// update order status
+	if err := s.updateOrderStatus(ctx, tx, order, models.StatusCancelled); err != nil {
 		return err
-	})
-	return err
+	}
+
+	// restore stock to the warehouses it was allocated from
+	for _, item := range order.Items {
+		for _, allocation := range item.Allocations {
+			err := s.repo.AdjustWarehouseStock(ctx, tx, allocation.WarehouseID, item.ProductID, allocation.Quantity)
+			if err != nil {
+				return fmt.Errorf("restoring product stock: %w", err)
+			}
+		}
+	}
+
+	// cancelled orders don't count against coupon limits
+	if err := s.repo.DeleteOrderCouponRedemptions(ctx, tx, order.ID); err != nil {
+		return fmt.Errorf("releasing coupon redemptions: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/internal/payment"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+func TestGetOrderByIDAuthorization(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		id      string
+		wantErr error
+	}{
+		{name: "owner", ctx: customer("user-1")},
+		{name: "admin", ctx: admin("admin-1")},
+		{name: "other customer", ctx: customer("user-2"), wantErr: pkg.ErrNotFound},
+		{name: "missing order", ctx: customer("user-1"), id: "order-2", wantErr: pkg.ErrNotFound},
+		{name: "anonymous", ctx: context.Background(), wantErr: pkg.ErrUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMemRepo()
+			repo.addOrder(pendingOrder("order-1", "user-1", 10))
+			s := newTestService(repo, payment.NewFakeProvider())
+
+			id := tt.id
+			if id == "" {
+				id = "order-1"
+			}
+			order, err := s.GetOrderByID(tt.ctx, id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetOrderByID() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && order.ID != "order-1" {
+				t.Errorf("GetOrderByID() returned order %s", order.ID)
+			}
+		})
+	}
+}
+
+// TestManagingAnotherUsersOrder checks that only the customer acts on their order through
+// the customer endpoints; admins cancel orders through UpdateStatus
+func TestManagingAnotherUsersOrder(t *testing.T) {
+	operations := []struct {
+		name string
+		call func(s *serviceImpl, ctx context.Context) error
+	}{
+		{
+			name: "cancel",
+			call: func(s *serviceImpl, ctx context.Context) error {
+				return s.CancelOrder(ctx, "order-1")
+			},
+		},
+		{
+			name: "pay",
+			call: func(s *serviceImpl, ctx context.Context) error {
+				_, err := s.PayOrder(ctx, "order-1", &models.PayOrderRequest{PaymentToken: "tok_visa"})
+				return err
+			},
+		},
+	}
+
+	actors := []struct {
+		name    string
+		ctx     context.Context
+		wantErr error
+	}{
+		{name: "other customer", ctx: customer("user-2"), wantErr: pkg.ErrNotFound},
+		{name: "admin", ctx: admin("admin-1"), wantErr: pkg.ErrUnauthorized},
+		{name: "anonymous", ctx: context.Background(), wantErr: pkg.ErrUnauthorized},
+	}
+
+	for _, op := range operations {
+		for _, actor := range actors {
+			t.Run(op.name+" by "+actor.name, func(t *testing.T) {
+				repo := newMemRepo()
+				repo.addOrder(pendingOrder("order-1", "user-1", 10))
+				s := newTestService(repo, payment.NewFakeProvider())
+
+				if err := op.call(s, actor.ctx); !errors.Is(err, actor.wantErr) {
+					t.Fatalf("error = %v, want %v", err, actor.wantErr)
+				}
+				if got := repo.orderStatus(t, "order-1"); got != models.StatusPending {
+					t.Errorf("order status = %s, want %s", got, models.StatusPending)
+				}
+				if statuses := paymentStatuses(t, repo, "order-1"); len(statuses) != 0 {
+					t.Errorf("payments = %v, want none", statuses)
+				}
+			})
+		}
+	}
+}
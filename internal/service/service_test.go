@@ -0,0 +1,496 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/auth"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/internal/payment"
+	"github.com/zde37/instashop-task/internal/repository"
+	"github.com/zde37/instashop-task/internal/stream"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// memRepo keeps the data of service tests in memory. It implements the repository methods
+// the tested flows use; calling any other method panics on the nil embedded interface.
+// Transactions aren't rolled back.
+type memRepo struct {
+	repository.Repository
+
+	mu        sync.Mutex
+	orders    map[string]*models.Order
+	payments  []*models.Payment
+	invoices  map[string]*models.Invoice
+	events    int64
+	addresses map[string]*models.Address
+
+	subscriptions map[string]*models.WebhookSubscription
+	deliveries    map[string]*models.WebhookDelivery
+	// paymentEvents holds the stored payment events by provider and event ID
+	paymentEvents map[string]*models.PaymentEvent
+	refunds       map[string]*models.Refund
+	creditNotes   []*models.Invoice
+	jobs          []*models.Job
+
+	// invoiceErr makes looking up invoices fail, which fails confirming orders
+	invoiceErr error
+}
+
+func newMemRepo() *memRepo {
+	return &memRepo{
+		orders:        make(map[string]*models.Order),
+		invoices:      make(map[string]*models.Invoice),
+		addresses:     make(map[string]*models.Address),
+		subscriptions: make(map[string]*models.WebhookSubscription),
+		deliveries:    make(map[string]*models.WebhookDelivery),
+		paymentEvents: make(map[string]*models.PaymentEvent),
+		refunds:       make(map[string]*models.Refund),
+	}
+}
+
+func (r *memRepo) WithTransaction(ctx context.Context, fn func(pgx.Tx) error) error {
+	return fn(nil)
+}
+
+func (r *memRepo) addOrder(order *models.Order) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.orders[order.ID] = order
+}
+
+func (r *memRepo) LockOrder(ctx context.Context, tx pgx.Tx, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.orders[id]; !ok {
+		return pkg.ErrNotFound
+	}
+	return nil
+}
+
+func (r *memRepo) GetOrderByID(ctx context.Context, id string) (*models.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	order, ok := r.orders[id]
+	if !ok {
+		return nil, pkg.ErrNotFound
+	}
+
+	loaded := *order
+	loaded.Payments = nil
+	for _, p := range r.payments {
+		if p.OrderID == id {
+			loaded.Payments = append(loaded.Payments, *p)
+		}
+	}
+	return &loaded, nil
+}
+
+func (r *memRepo) UpdateOrderStatus(ctx context.Context, tx pgx.Tx, id string, status models.OrderStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	order, ok := r.orders[id]
+	if !ok {
+		return pkg.ErrNotFound
+	}
+	order.Status = status
+	return nil
+}
+
+func (r *memRepo) CreatePayment(ctx context.Context, tx pgx.Tx, payment *models.Payment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	payment.CreatedAt = time.Now()
+	payment.UpdatedAt = payment.CreatedAt
+	stored := *payment
+	r.payments = append(r.payments, &stored)
+	return nil
+}
+
+func (r *memRepo) UpdatePayment(ctx context.Context, tx pgx.Tx, payment *models.Payment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, stored := range r.payments {
+		if stored.ID == payment.ID {
+			payment.UpdatedAt = time.Now()
+			*stored = *payment
+			return nil
+		}
+	}
+	return pkg.ErrNotFound
+}
+
+func (r *memRepo) LockPayment(ctx context.Context, tx pgx.Tx, id string) (*models.Payment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, stored := range r.payments {
+		if stored.ID == id {
+			payment := *stored
+			return &payment, nil
+		}
+	}
+	return nil, pkg.ErrNotFound
+}
+
+func (r *memRepo) ListPendingPayments(ctx context.Context, before time.Time, limit int) ([]models.Payment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var pending []models.Payment
+	for _, p := range r.payments {
+		if p.Status == models.PaymentPending && p.CreatedAt.Before(before) && len(pending) < limit {
+			pending = append(pending, *p)
+		}
+	}
+	return pending, nil
+}
+
+func (r *memRepo) LockPaymentByReference(ctx context.Context, tx pgx.Tx, provider, reference string) (*models.Payment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, stored := range r.payments {
+		if stored.Provider == provider && stored.Reference == reference {
+			payment := *stored
+			return &payment, nil
+		}
+	}
+	return nil, pkg.ErrNotFound
+}
+
+func (r *memRepo) CreatePaymentEvent(ctx context.Context, tx pgx.Tx, event *models.PaymentEvent) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := event.Provider + "/" + event.EventID
+	if _, ok := r.paymentEvents[key]; ok {
+		return false, nil
+	}
+	event.ReceivedAt = time.Now()
+	r.paymentEvents[key] = event
+	return true, nil
+}
+
+// payment returns the stored state of a payment
+func (r *memRepo) payment(t *testing.T, id string) models.Payment {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range r.payments {
+		if p.ID == id {
+			return *p
+		}
+	}
+	t.Fatalf("payment %s not stored", id)
+	return models.Payment{}
+}
+
+// age moves the creation of every stored payment and refund back by d
+func (r *memRepo) age(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range r.payments {
+		p.CreatedAt = p.CreatedAt.Add(-d)
+	}
+	for _, refund := range r.refunds {
+		refund.CreatedAt = refund.CreatedAt.Add(-d)
+	}
+}
+
+func (r *memRepo) orderStatus(t *testing.T, id string) models.OrderStatus {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	order, ok := r.orders[id]
+	if !ok {
+		t.Fatalf("order %s not stored", id)
+	}
+	return order.Status
+}
+
+func (r *memRepo) CreateOutboxEvent(ctx context.Context, tx pgx.Tx, event *models.OutboxEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events++
+	event.Position = r.events
+	event.CreatedAt = time.Now()
+	return nil
+}
+
+func (r *memRepo) Notify(ctx context.Context, tx pgx.Tx, channel, payload string) error {
+	return nil
+}
+
+func (r *memRepo) GetOrderInvoice(ctx context.Context, orderID string) (*models.Invoice, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.invoiceErr != nil {
+		return nil, r.invoiceErr
+	}
+	invoice, ok := r.invoices[orderID]
+	if !ok {
+		return nil, pkg.ErrNotFound
+	}
+	return invoice, nil
+}
+
+func (r *memRepo) NextInvoiceNumber(ctx context.Context, tx pgx.Tx, kind models.InvoiceKind, year int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.invoices) + 1, nil
+}
+
+func (r *memRepo) CreateInvoice(ctx context.Context, tx pgx.Tx, invoice *models.Invoice) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if invoice.Kind == models.InvoiceKindCreditNote {
+		r.creditNotes = append(r.creditNotes, invoice)
+		return nil
+	}
+	r.invoices[invoice.OrderID] = invoice
+	return nil
+}
+
+func (r *memRepo) CreateRefund(ctx context.Context, tx pgx.Tx, refund *models.Refund) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	refund.CreatedAt = time.Now()
+	stored := *refund
+	r.refunds[refund.ID] = &stored
+	return nil
+}
+
+func (r *memRepo) LockRefund(ctx context.Context, tx pgx.Tx, id string) (*models.Refund, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.refunds[id]
+	if !ok {
+		return nil, pkg.ErrNotFound
+	}
+	refund := *stored
+	return &refund, nil
+}
+
+func (r *memRepo) UpdateRefund(ctx context.Context, tx pgx.Tx, refund *models.Refund) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.refunds[refund.ID]; !ok {
+		return pkg.ErrNotFound
+	}
+	stored := *refund
+	r.refunds[refund.ID] = &stored
+	return nil
+}
+
+func (r *memRepo) ListPendingRefunds(ctx context.Context, before time.Time, limit int) ([]models.Refund, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var pending []models.Refund
+	for _, refund := range r.refunds {
+		if refund.Status == models.RefundPending && refund.CreatedAt.Before(before) && len(pending) < limit {
+			pending = append(pending, *refund)
+		}
+	}
+	return pending, nil
+}
+
+func (r *memRepo) CreateJob(ctx context.Context, tx pgx.Tx, job *models.Job) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs = append(r.jobs, job)
+	return nil
+}
+
+func (r *memRepo) hasInvoice(orderID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.invoices[orderID]
+	return ok
+}
+
+// testWebhookSecret signs the payment webhooks sent to test services
+const testWebhookSecret = "whsec_test"
+
+func (r *memRepo) addAddress(address *models.Address) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.addresses[address.ID] = address
+}
+
+func (r *memRepo) GetAddress(ctx context.Context, id string) (*models.Address, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	address, ok := r.addresses[id]
+	if !ok {
+		return nil, pkg.ErrNotFound
+	}
+	loaded := *address
+	return &loaded, nil
+}
+
+func (r *memRepo) GetDefaultAddress(ctx context.Context, userID string) (*models.Address, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, address := range r.addresses {
+		if address.UserID == userID && address.IsDefault {
+			loaded := *address
+			return &loaded, nil
+		}
+	}
+	return nil, pkg.ErrNotFound
+}
+
+func (r *memRepo) LockAddressBook(ctx context.Context, tx pgx.Tx, userID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, address := range r.addresses {
+		if address.UserID == userID && address.IsDefault {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memRepo) ClearDefaultAddress(ctx context.Context, tx pgx.Tx, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, address := range r.addresses {
+		if address.UserID == userID {
+			address.IsDefault = false
+		}
+	}
+	return nil
+}
+
+func (r *memRepo) UpdateAddress(ctx context.Context, tx pgx.Tx, address *models.Address) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.addresses[address.ID]; !ok {
+		return pkg.ErrNotFound
+	}
+	stored := *address
+	r.addresses[address.ID] = &stored
+	return nil
+}
+
+func (r *memRepo) DeleteAddress(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.addresses[id]; !ok {
+		return pkg.ErrNotFound
+	}
+	delete(r.addresses, id)
+	return nil
+}
+
+func (r *memRepo) GetWebhookSubscription(ctx context.Context, id string) (*models.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	subscription, ok := r.subscriptions[id]
+	if !ok {
+		return nil, pkg.ErrNotFound
+	}
+	loaded := *subscription
+	return &loaded, nil
+}
+
+func (r *memRepo) CreateWebhookDelivery(ctx context.Context, tx pgx.Tx, delivery *models.WebhookDelivery) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delivery.Status = models.DeliveryPending
+	delivery.NextAttemptAt = time.Now()
+	stored := *delivery
+	r.deliveries[delivery.ID] = &stored
+	return true, nil
+}
+
+func (r *memRepo) ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var claimed []models.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if delivery.Status == models.DeliveryPending && !delivery.NextAttemptAt.After(now) && len(claimed) < limit {
+			delivery.NextAttemptAt = now.Add(lease)
+			claimed = append(claimed, *delivery)
+		}
+	}
+	return claimed, nil
+}
+
+func (r *memRepo) RecordWebhookAttempt(ctx context.Context, tx pgx.Tx, delivery *models.WebhookDelivery, attempt *models.WebhookDeliveryAttempt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.deliveries[delivery.ID]
+	if !ok {
+		return pkg.ErrNotFound
+	}
+	history := append(stored.History, *attempt)
+	*stored = *delivery
+	stored.History = history
+	return nil
+}
+
+func (r *memRepo) GetWebhookDelivery(ctx context.Context, subscriptionID, id string) (*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delivery, ok := r.deliveries[id]
+	if !ok || delivery.SubscriptionID != subscriptionID {
+		return nil, pkg.ErrNotFound
+	}
+	loaded := *delivery
+	return &loaded, nil
+}
+
+func (r *memRepo) ResetWebhookDelivery(ctx context.Context, subscriptionID, id string, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delivery, ok := r.deliveries[id]
+	if !ok || delivery.SubscriptionID != subscriptionID {
+		return pkg.ErrNotFound
+	}
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = now
+	return nil
+}
+
+// newTestService returns a service on repo that takes payments with provider and accepts
+// webhooks signed with testWebhookSecret
+func newTestService(repo repository.Repository, provider payment.Provider) *serviceImpl {
+	return New(repo, nil, nil, nil, nil, nil, provider, payment.NewVerifier(testWebhookSecret), nil, nil,
+		stream.NewBroker(), nil).(*serviceImpl)
+}
+
+func customer(id string) context.Context {
+	return auth.WithActor(context.Background(), auth.Actor{UserID: id, Role: models.RoleCustomer})
+}
+
+func admin(id string) context.Context {
+	return auth.WithActor(context.Background(), auth.Actor{UserID: id, Role: models.RoleAdmin})
+}
+
+func pendingOrder(id, userID string, total float64) *models.Order {
+	return &models.Order{
+		ID:          id,
+		UserID:      userID,
+		Status:      models.StatusPending,
+		TotalAmount: total,
+		Subtotal:    total,
+		Items:       []models.OrderItem{},
+		CreatedAt:   time.Now(),
+	}
+}
+
+// paymentStatuses lists the statuses of the payments of an order, oldest first
+func paymentStatuses(t *testing.T, repo *memRepo, orderID string) []models.PaymentStatus {
+	t.Helper()
+	order, err := repo.GetOrderByID(context.Background(), orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statuses []models.PaymentStatus
+	for _, p := range order.Payments {
+		statuses = append(statuses, p.Status)
+	}
+	return statuses
+}
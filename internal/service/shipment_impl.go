@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// CreateShipment records a shipment of some of an order's items and moves the order to
+// partially shipped or shipped accordingly
+func (s *serviceImpl) CreateShipment(ctx context.Context, orderID string, req *models.CreateShipmentRequest) (*models.Shipment, error) {
+	shipment := &models.Shipment{
+		ID:             pkg.GenerateID(),
+		OrderID:        orderID,
+		Carrier:        req.Carrier,
+		TrackingNumber: strings.TrimSpace(req.TrackingNumber),
+		ShippedAt:      time.Now(),
+	}
+	if req.ShippedAt != nil {
+		shipment.ShippedAt = *req.ShippedAt
+	}
+
+	err := s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		order, err := s.lockOrder(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+		if !order.Status.IsShippable() {
+			return fmt.Errorf("%w: %s orders can't be shipped", pkg.ErrInvalidStatusTransition, order.Status)
+		}
+
+		shipment.Items, err = shipmentItems(order, req.Items)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.CreateShipment(ctx, tx, shipment); err != nil {
+			return fmt.Errorf("creating shipment: %w", err)
+		}
+
+		order.Shipments = append(order.Shipments, *shipment)
+		if err := s.syncShipmentStatus(ctx, tx, order); err != nil {
+			return err
+		}
+		return s.notifyOrderShipped(ctx, tx, order, shipment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return shipment, nil
+}
+
+// MarkShipmentDelivered records the delivery of a shipment. The order is delivered once
+// all of its items have shipped and every shipment has arrived.
+func (s *serviceImpl) MarkShipmentDelivered(ctx context.Context, orderID, shipmentID string) (*models.Shipment, error) {
+	var shipment *models.Shipment
+	err := s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		order, err := s.lockOrder(ctx, tx, orderID)
+		if err != nil {
+			return err
+		}
+
+		for i := range order.Shipments {
+			if order.Shipments[i].ID == shipmentID {
+				shipment = &order.Shipments[i]
+			}
+		}
+		if shipment == nil {
+			return fmt.Errorf("getting shipment: %w", pkg.ErrNotFound)
+		}
+		if shipment.DeliveredAt != nil {
+			return nil
+		}
+
+		now := time.Now()
+		if err := s.repo.MarkShipmentDelivered(ctx, tx, shipmentID, now); err != nil {
+			return fmt.Errorf("marking shipment delivered: %w", err)
+		}
+		shipment.DeliveredAt = &now
+		return s.syncShipmentStatus(ctx, tx, order)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return shipment, nil
+}
+
+// lockOrder locks an order until tx ends and loads it
+func (s *serviceImpl) lockOrder(ctx context.Context, tx pgx.Tx, id string) (*models.Order, error) {
+	if err := s.repo.LockOrder(ctx, tx, id); err != nil {
+		return nil, fmt.Errorf("locking order: %w", err)
+	}
+
+	order, err := s.repo.GetOrderByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting order: %w", err)
+	}
+	return order, nil
+}
+
+// syncShipmentStatus stores the status that follows from the order's shipments
+func (s *serviceImpl) syncShipmentStatus(ctx context.Context, tx pgx.Tx, order *models.Order) error {
+	status := order.ShipmentStatus()
+	if status == order.Status {
+		return nil
+	}
+
+	return s.updateOrderStatus(ctx, tx, order, status)
+}
+
+// shipmentItems checks the requested quantities against what is left to ship of each
+// order item. Without requested items, everything that is left is shipped.
+func shipmentItems(order *models.Order, req []models.CreateShipmentItemRequest) ([]models.ShipmentItem, error) {
+	shipped := order.ShippedQuantities()
+	remaining := make(map[string]int, len(order.Items))
+	for _, item := range order.Items {
+		remaining[item.ID] = item.Quantity - shipped[item.ID]
+	}
+
+	var items []models.ShipmentItem
+	if len(req) == 0 {
+		for _, item := range order.Items {
+			if remaining[item.ID] > 0 {
+				items = append(items, models.ShipmentItem{OrderItemID: item.ID, Quantity: remaining[item.ID]})
+			}
+		}
+		if len(items) == 0 {
+			return nil, fmt.Errorf("%w: all items have already shipped", pkg.ErrInvalidInput)
+		}
+		return items, nil
+	}
+
+	for _, item := range req {
+		left, ok := remaining[item.OrderItemID]
+		if !ok {
+			return nil, fmt.Errorf("%w: order item %s is not part of the order", pkg.ErrInvalidInput, item.OrderItemID)
+		}
+		if item.Quantity > left {
+			return nil, fmt.Errorf("%w: only %d of order item %s left to ship", pkg.ErrInvalidInput, left, item.OrderItemID)
+		}
+		remaining[item.OrderItemID] -= item.Quantity
+		items = append(items, models.ShipmentItem{OrderItemID: item.OrderItemID, Quantity: item.Quantity})
+	}
+	return items, nil
+}
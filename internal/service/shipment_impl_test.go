@@ -0,0 +1,32 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/internal/payment"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+func TestCreateShipmentOfUnshippableOrder(t *testing.T) {
+	// shipped orders count as paid, so orders that weren't paid for can't be shipped
+	for _, status := range []models.OrderStatus{models.StatusPending, models.StatusCancelled, models.StatusDelivered} {
+		t.Run(string(status), func(t *testing.T) {
+			repo := newMemRepo()
+			order := pendingOrder("order-1", "user-1", 10)
+			order.Status = status
+			order.Items = []models.OrderItem{{ID: "item-1", OrderID: "order-1", ProductID: "product-1", Quantity: 1, UnitPrice: 10}}
+			repo.addOrder(order)
+			s := newTestService(repo, payment.NewFakeProvider())
+
+			_, err := s.CreateShipment(admin("admin-1"), "order-1", &models.CreateShipmentRequest{Carrier: "local", TrackingNumber: "TRACK-1"})
+			if !errors.Is(err, pkg.ErrInvalidStatusTransition) {
+				t.Fatalf("CreateShipment() error = %v, want %v", err, pkg.ErrInvalidStatusTransition)
+			}
+			if got := repo.orderStatus(t, "order-1"); got != status {
+				t.Errorf("order status = %s, want %s", got, status)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/zde37/instashop-task/internal/auth"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/internal/shipping"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+func (s *serviceImpl) CreateShippingMethod(ctx context.Context, req *models.CreateShippingMethodRequest) (*models.ShippingMethod, error) {
+	method := &models.ShippingMethod{ID: pkg.GenerateID()}
+	if err := s.applyShippingMethodRequest(method, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateShippingMethod(ctx, method); err != nil {
+		return nil, fmt.Errorf("creating shipping method: %w", err)
+	}
+	return method, nil
+}
+
+func (s *serviceImpl) ListShippingMethods(ctx context.Context) ([]models.ShippingMethod, error) {
+	methods, err := s.repo.ListShippingMethods(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("listing shipping methods: %w", err)
+	}
+	return methods, nil
+}
+
+func (s *serviceImpl) UpdateShippingMethod(ctx context.Context, id string, req *models.CreateShippingMethodRequest) (*models.ShippingMethod, error) {
+	method := &models.ShippingMethod{ID: id}
+	if err := s.applyShippingMethodRequest(method, req); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateShippingMethod(ctx, method); err != nil {
+		return nil, fmt.Errorf("updating shipping method: %w", err)
+	}
+	return method, nil
+}
+
+func (s *serviceImpl) DeleteShippingMethod(ctx context.Context, id string) error {
+	if err := s.repo.DeleteShippingMethod(ctx, id); err != nil {
+		return fmt.Errorf("deleting shipping method: %w", err)
+	}
+	return nil
+}
+
+// QuoteShipping rates the cart with every active shipping method that delivers to the
+// destination. Free shipping thresholds apply to the cart value after promotions and the coupon.
+func (s *serviceImpl) QuoteShipping(ctx context.Context, req *models.ShippingQuoteRequest) ([]models.ShippingOption, error) {
+	actor, err := auth.ActorFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	destination, err := s.orderAddress(ctx, req.AddressID, req.Address)
+	if err != nil {
+		return nil, err
+	}
+	if destination == nil {
+		address, err := s.repo.GetDefaultAddress(ctx, actor.UserID)
+		if err != nil {
+			if errors.Is(err, pkg.ErrNotFound) {
+				return nil, fmt.Errorf("%w: a destination address is required", pkg.ErrInvalidInput)
+			}
+			return nil, fmt.Errorf("getting default address: %w", err)
+		}
+		destination = address.Snapshot()
+	}
+
+	// price the cart with the coupon, so free shipping thresholds match those of the order
+	cart := &models.CreateOrderRequest{Items: req.Items, CouponCode: req.CouponCode}
+	order, err := s.pricedOrder(ctx, actor.UserID, cart, destination)
+	if err != nil {
+		return nil, err
+	}
+
+	methods, err := s.repo.ListShippingMethods(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("listing shipping methods: %w", err)
+	}
+
+	shipment := shipmentRequest(destination, order)
+	options := []models.ShippingOption{}
+	for i := range methods {
+		method := &methods[i]
+		if !method.ShipsTo(destination.Country) {
+			continue
+		}
+
+		rate, err := s.carriers.Rate(ctx, method, shipment)
+		if err != nil {
+			// one failing carrier shouldn't hide the other options
+			slog.Error("failed to rate shipment", slog.String("method", method.Code), slog.String("err", err.Error()))
+			continue
+		}
+		options = append(options, models.ShippingOption{
+			Method:        method.Code,
+			Name:          method.Name,
+			Carrier:       method.Carrier,
+			Amount:        rate.Amount,
+			EstimatedDays: rate.EstimatedDays,
+		})
+	}
+	return options, nil
+}
+
+// errStaleShippingCharge means that a shipping charge was rated for goods of a different value
+var errStaleShippingCharge = errors.New("shipping charge rated for a different order value")
+
+// shippingCharge is what a shipping method costs for the goods of a priced order
+type shippingCharge struct {
+	method string
+	amount float64
+	// orderValue is the value of the goods the charge was rated for
+	orderValue float64
+}
+
+// rateShipping asks the carrier of the shipping method with the given code what delivering
+// the goods of a priced order to destination costs. The carrier is called over the network,
+// so this must not run inside a transaction.
+func (s *serviceImpl) rateShipping(ctx context.Context, order *models.Order, code string, destination *models.OrderAddress) (*shippingCharge, error) {
+	if destination == nil {
+		return nil, fmt.Errorf("%w: a shipping address is required to quote shipping", pkg.ErrInvalidInput)
+	}
+
+	method, err := s.repo.GetShippingMethodByCode(ctx, strings.TrimSpace(code))
+	if err != nil {
+		if errors.Is(err, pkg.ErrNotFound) {
+			return nil, pkg.ErrShippingUnavailable
+		}
+		return nil, fmt.Errorf("getting shipping method: %w", err)
+	}
+	if !method.IsActive || !method.ShipsTo(destination.Country) {
+		return nil, pkg.ErrShippingUnavailable
+	}
+
+	shipment := shipmentRequest(destination, order)
+	rate, err := s.carriers.Rate(ctx, method, shipment)
+	if err != nil {
+		return nil, fmt.Errorf("rating shipment: %w", err)
+	}
+	return &shippingCharge{method: method.Code, amount: rate.Amount, orderValue: shipment.OrderValue}, nil
+}
+
+// apply adds the charge to an order. It fails with errStaleShippingCharge if the goods
+// of the order are no longer worth what the charge was rated for, as that may move the
+// order across a free shipping threshold.
+func (c *shippingCharge) apply(order *models.Order) error {
+	if orderValue(order) != c.orderValue {
+		return errStaleShippingCharge
+	}
+	order.ShippingMethod = &c.method
+	order.ShippingTotal = c.amount
+	order.TotalAmount = pkg.RoundMoney(order.TotalAmount + c.amount)
+	return nil
+}
+
+// shipmentRequest describes the goods of a priced order for the carriers
+func shipmentRequest(destination *models.OrderAddress, order *models.Order) shipping.Request {
+	req := shipping.Request{
+		Destination: destination,
+		Items:       make([]shipping.Item, len(order.Items)),
+		OrderValue:  orderValue(order),
+	}
+	for i, item := range order.Items {
+		req.Items[i] = shipping.Item{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			WeightKg:  item.Product.WeightKg,
+			LengthCm:  item.Product.LengthCm,
+			WidthCm:   item.Product.WidthCm,
+			HeightCm:  item.Product.HeightCm,
+		}
+	}
+	return req
+}
+
+// orderValue is the value of the goods of a priced order after discounts
+func orderValue(order *models.Order) float64 {
+	return pkg.RoundMoney(order.Subtotal - order.DiscountTotal)
+}
+
+func (s *serviceImpl) applyShippingMethodRequest(method *models.ShippingMethod, req *models.CreateShippingMethodRequest) error {
+	if _, ok := s.carriers[req.Carrier]; !ok {
+		return fmt.Errorf("%w: unknown carrier %q", pkg.ErrInvalidInput, req.Carrier)
+	}
+
+	method.Code = strings.TrimSpace(req.Code)
+	method.Name = req.Name
+	method.Carrier = req.Carrier
+	method.RateType = req.RateType
+	method.BaseRate = req.BaseRate
+	method.PerKgRate = req.PerKgRate
+	method.FreeOver = req.FreeOver
+	method.Countries = make([]string, len(req.Countries))
+	for i, country := range req.Countries {
+		method.Countries[i] = strings.ToUpper(country)
+	}
+	method.EstimatedDays = req.EstimatedDays
+	method.IsActive = req.IsActive == nil || *req.IsActive
+	return nil
+}
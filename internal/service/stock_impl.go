@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/mail"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/internal/notifier"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+func (s *serviceImpl) ListLowStockProducts(ctx context.Context) ([]models.Product, error) {
+	products, err := s.repo.ListLowStockProducts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing low stock products: %w", err)
+	}
+	return products, nil
+}
+
+func (s *serviceImpl) SubscribeBackInStock(ctx context.Context, userID, productID string) (*models.StockSubscription, error) {
+	product, err := s.repo.GetProductByID(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("getting product: %w", err)
+	}
+	if !product.IsAvailable() {
+		return nil, pkg.ErrProductUnavailable
+	}
+	if product.StockQuantity > 0 {
+		return nil, pkg.ErrProductInStock
+	}
+
+	subscription := &models.StockSubscription{
+		ID:        pkg.GenerateID(),
+		UserID:    userID,
+		ProductID: productID,
+	}
+	if err := s.repo.CreateStockSubscription(ctx, subscription); err != nil {
+		return nil, fmt.Errorf("creating stock subscription: %w", err)
+	}
+	return subscription, nil
+}
+
+func (s *serviceImpl) UnsubscribeBackInStock(ctx context.Context, userID, productID string) error {
+	if err := s.repo.DeleteStockSubscription(ctx, userID, productID); err != nil {
+		return fmt.Errorf("deleting stock subscription: %w", err)
+	}
+	return nil
+}
+
+// stockLevels maps product IDs to their stock quantity before a change
+type stockLevels map[string]int
+
+// notifyStockChanges compares the current stock of the products in before with their
+// previous levels and sends the threshold and back-in-stock notifications, which also fan
+// out to the channels the users chose. It must be called after the transaction that changed
+// the stock has committed. Failures are logged rather than returned, since the stock change
+// itself has already succeeded.
+func (s *serviceImpl) notifyStockChanges(ctx context.Context, before stockLevels) {
+	for productID, previous := range before {
+		product, err := s.repo.GetProductByID(ctx, productID)
+		if err != nil {
+			slog.Error("failed to load product for stock notifications", slog.String("product_id", productID), slog.String("err", err.Error()))
+			continue
+		}
+
+		wasLow := previous <= product.ReorderThreshold
+		switch {
+		case !wasLow && product.IsLowStock():
+			s.notifyStockLevel(ctx, product, previous, notifier.EventLowStock, "Low stock",
+				fmt.Sprintf("%s is down to %d units, at or below its reorder threshold of %d.",
+					product.Name, product.StockQuantity, product.ReorderThreshold), mail.TemplateLowStock)
+		case wasLow && !product.IsLowStock():
+			s.notifyStockLevel(ctx, product, previous, notifier.EventStockReplenished, "Stock replenished",
+				fmt.Sprintf("%s is back above its reorder threshold of %d with %d units.",
+					product.Name, product.ReorderThreshold, product.StockQuantity), mail.TemplateStockReplenished)
+		}
+
+		if previous == 0 && product.StockQuantity > 0 {
+			s.notifyBackInStock(ctx, product)
+		}
+	}
+}
+
+func (s *serviceImpl) notifyBackInStock(ctx context.Context, product *models.Product) {
+	subscriptions, err := s.repo.ListPendingStockSubscriptions(ctx, product.ID)
+	if err != nil {
+		slog.Error("failed to list stock subscriptions", slog.String("product_id", product.ID), slog.String("err", err.Error()))
+		return
+	}
+
+	data := map[string]any{
+		"product_id":   product.ID,
+		"product_name": product.Name,
+	}
+	for _, subscription := range subscriptions {
+		err := s.notifier.Notify(ctx, notifier.Notification{
+			Event:  notifier.EventBackInStock,
+			UserID: subscription.UserID,
+			Email:  subscription.UserEmail,
+			Data:   data,
+		})
+		if err != nil {
+			slog.Error("failed to send back in stock notification", slog.String("subscription_id", subscription.ID), slog.String("err", err.Error()))
+			continue
+		}
+
+		delivered := s.deliver(ctx, notifier.EventBackInStock, func(tx pgx.Tx) error {
+			return s.fanOut(ctx, tx, subscription.UserID, subscription.UserEmail, userNotification{
+				event:    notifier.EventBackInStock,
+				title:    "Back in stock",
+				body:     fmt.Sprintf("%s is available again.", product.Name),
+				data:     data,
+				template: mail.TemplateBackInStock,
+				mailData: mail.StockData{Product: product},
+			})
+		})
+		if !delivered {
+			continue
+		}
+
+		if err := s.repo.MarkStockSubscriptionNotified(ctx, subscription.ID); err != nil {
+			slog.Error("failed to mark stock subscription notified", slog.String("subscription_id", subscription.ID), slog.String("err", err.Error()))
+		}
+	}
+}
+
+// notifyStockLevel tells the administrators that a product's stock crossed its reorder threshold
+func (s *serviceImpl) notifyStockLevel(ctx context.Context, product *models.Product, previous int, event, title, body string, template mail.Template) {
+	data := stockNotificationData(product, previous)
+	s.notify(ctx, notifier.Notification{Event: event, Data: data})
+
+	s.deliver(ctx, event, func(tx pgx.Tx) error {
+		return s.notifyAdmins(ctx, tx, userNotification{
+			event:    event,
+			title:    title,
+			body:     body,
+			data:     data,
+			template: template,
+			mailData: mail.StockData{Product: product, PreviousQuantity: previous},
+		})
+	})
+}
+
+// notify sends a notification and logs a failure instead of returning it
+func (s *serviceImpl) notify(ctx context.Context, notification notifier.Notification) {
+	if err := s.notifier.Notify(ctx, notification); err != nil {
+		slog.Error("failed to send notification", slog.String("event", notification.Event), slog.String("err", err.Error()))
+	}
+}
+
+func stockNotificationData(product *models.Product, previous int) map[string]any {
+	return map[string]any{
+		"product_id":        product.ID,
+		"product_name":      product.Name,
+		"previous_quantity": previous,
+		"stock_quantity":    product.StockQuantity,
+		"reorder_threshold": product.ReorderThreshold,
+	}
+}
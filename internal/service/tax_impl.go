@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+func (s *serviceImpl) CreateTaxRate(ctx context.Context, req *models.CreateTaxRateRequest) (*models.TaxRate, error) {
+	rate := &models.TaxRate{ID: pkg.GenerateID()}
+	applyTaxRateRequest(rate, req)
+
+	if err := s.repo.CreateTaxRate(ctx, rate); err != nil {
+		return nil, fmt.Errorf("creating tax rate: %w", err)
+	}
+	return rate, nil
+}
+
+func (s *serviceImpl) ListTaxRates(ctx context.Context) ([]models.TaxRate, error) {
+	rates, err := s.repo.ListTaxRates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing tax rates: %w", err)
+	}
+	return rates, nil
+}
+
+func (s *serviceImpl) UpdateTaxRate(ctx context.Context, id string, req *models.CreateTaxRateRequest) (*models.TaxRate, error) {
+	rate := &models.TaxRate{ID: id}
+	applyTaxRateRequest(rate, req)
+
+	if err := s.repo.UpdateTaxRate(ctx, rate); err != nil {
+		return nil, fmt.Errorf("updating tax rate: %w", err)
+	}
+	return rate, nil
+}
+
+func (s *serviceImpl) DeleteTaxRate(ctx context.Context, id string) error {
+	if err := s.repo.DeleteTaxRate(ctx, id); err != nil {
+		return fmt.Errorf("deleting tax rate: %w", err)
+	}
+	return nil
+}
+
+func applyTaxRateRequest(rate *models.TaxRate, req *models.CreateTaxRateRequest) {
+	rate.Region = strings.ToUpper(strings.TrimSpace(req.Region))
+	rate.TaxClass = req.TaxClass
+	rate.Name = req.Name
+	rate.Rate = req.Rate
+}
+
+// taxClassOrDefault puts products without a tax class in the standard class
+func taxClassOrDefault(class models.TaxClass) models.TaxClass {
+	if class == "" {
+		return models.TaxClassStandard
+	}
+	return class
+}
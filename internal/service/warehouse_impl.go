@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+func (s *serviceImpl) CreateWarehouse(ctx context.Context, req *models.CreateWarehouseRequest) (*models.Warehouse, error) {
+	warehouse := &models.Warehouse{
+		ID:       pkg.GenerateID(),
+		Code:     req.Code,
+		Name:     req.Name,
+		Priority: req.Priority,
+		IsActive: req.IsActive == nil || *req.IsActive,
+	}
+
+	if err := s.repo.CreateWarehouse(ctx, warehouse); err != nil {
+		return nil, fmt.Errorf("creating warehouse: %w", err)
+	}
+	return warehouse, nil
+}
+
+func (s *serviceImpl) ListWarehouses(ctx context.Context) ([]models.Warehouse, error) {
+	warehouses, err := s.repo.ListWarehouses(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing warehouses: %w", err)
+	}
+	return warehouses, nil
+}
+
+func (s *serviceImpl) UpdateWarehouse(ctx context.Context, id string, req *models.CreateWarehouseRequest) (*models.Warehouse, error) {
+	warehouse, err := s.repo.GetWarehouseByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting warehouse: %w", err)
+	}
+
+	warehouse.Code = req.Code
+	warehouse.Name = req.Name
+	warehouse.Priority = req.Priority
+	if req.IsActive != nil {
+		warehouse.IsActive = *req.IsActive
+	}
+
+	if err := s.repo.UpdateWarehouse(ctx, warehouse); err != nil {
+		return nil, fmt.Errorf("updating warehouse: %w", err)
+	}
+	return warehouse, nil
+}
+
+func (s *serviceImpl) GetProductStock(ctx context.Context, productID string) ([]models.WarehouseStock, error) {
+	if _, err := s.repo.GetProductByID(ctx, productID); err != nil {
+		return nil, fmt.Errorf("getting product: %w", err)
+	}
+
+	levels, err := s.repo.ListProductStock(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("listing product stock: %w", err)
+	}
+	return levels, nil
+}
+
+func (s *serviceImpl) SetWarehouseStock(ctx context.Context, warehouseID, productID string, quantity int) error {
+	if _, err := s.repo.GetWarehouseByID(ctx, warehouseID); err != nil {
+		return fmt.Errorf("getting warehouse: %w", err)
+	}
+	product, err := s.repo.GetProductByID(ctx, productID)
+	if err != nil {
+		return fmt.Errorf("getting product: %w", err)
+	}
+
+	err = s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		if err := s.repo.SetWarehouseStock(ctx, tx, warehouseID, productID, quantity); err != nil {
+			return fmt.Errorf("setting warehouse stock: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.notifyStockChanges(ctx, stockLevels{product.ID: product.StockQuantity})
+	return nil
+}
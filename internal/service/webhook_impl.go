@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/internal/payment"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// HandlePaymentWebhook processes a payment event sent by the provider. The signature must
+// match the body and be recent, so captured requests can't be replayed later. Each event
+// is stored and applied once; redelivered events are acknowledged without effect. Captures
+// must be of the payment's amount, and are refunded if the order can't be confirmed any more.
+func (s *serviceImpl) HandlePaymentWebhook(ctx context.Context, payload []byte, signature string) error {
+	if err := s.webhooks.Verify(signature, payload); err != nil {
+		return fmt.Errorf("%w: %v", pkg.ErrInvalidSignature, err)
+	}
+
+	event, err := payment.ParseEvent(payload)
+	if err != nil {
+		return fmt.Errorf("%w: %v", pkg.ErrInvalidInput, err)
+	}
+
+	var lateCapture *models.Payment
+	err = s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		created, err := s.repo.CreatePaymentEvent(ctx, tx, &models.PaymentEvent{
+			ID:       pkg.GenerateID(),
+			Provider: s.payments.Name(),
+			EventID:  event.ID,
+			Type:     event.Type,
+			Payload:  payload,
+		})
+		if err != nil {
+			return fmt.Errorf("storing payment event: %w", err)
+		}
+		if !created {
+			slog.Info("ignoring duplicate payment event", slog.String("event_id", event.ID))
+			return nil
+		}
+
+		lateCapture, err = s.applyPaymentEvent(ctx, tx, event)
+		return err
+	})
+	if err != nil || lateCapture == nil {
+		return err
+	}
+
+	// the order can't take the payment any more, so the capture is refunded as PayOrder
+	// refunds late captures; if that fails the payment stays pending for reconciliation
+	lateCapture.Status = models.PaymentCaptured
+	if err := s.completePayment(context.WithoutCancel(ctx), lateCapture); err != nil && lateCapture.Status != models.PaymentRefunded {
+		slog.Error("failed to refund payment captured too late", slog.String("payment_id", lateCapture.ID), slog.String("err", err.Error()))
+	}
+	return nil
+}
+
+// applyPaymentEvent moves the payment, and with it the order, to the state reported by the
+// event. A payment captured for an order that is no longer pending is left as it is and
+// returned, to be refunded once tx has committed.
+func (s *serviceImpl) applyPaymentEvent(ctx context.Context, tx pgx.Tx, event payment.Event) (*models.Payment, error) {
+	switch event.Type {
+	case payment.EventCaptured, payment.EventFailed, payment.EventRefunded:
+	default:
+		slog.Info("ignoring payment event", slog.String("event_id", event.ID), slog.String("type", event.Type))
+		return nil, nil
+	}
+
+	p, err := s.repo.LockPaymentByReference(ctx, tx, s.payments.Name(), event.Data.Reference)
+	if err != nil {
+		return nil, fmt.Errorf("getting payment: %w", err)
+	}
+	order, err := s.lockOrder(ctx, tx, p.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch event.Type {
+	case payment.EventCaptured:
+		if pkg.RoundMoney(event.Data.Amount) != pkg.RoundMoney(p.Amount) {
+			return nil, fmt.Errorf("%w: captured %.2f of a payment of %.2f", pkg.ErrInvalidInput, event.Data.Amount, p.Amount)
+		}
+		if !p.Status.InProgress() {
+			return nil, nil
+		}
+		if order.Status != models.StatusPending {
+			return p, nil
+		}
+		p.Status = models.PaymentCaptured
+		if err := s.repo.UpdatePayment(ctx, tx, p); err != nil {
+			return nil, fmt.Errorf("updating payment: %w", err)
+		}
+		if err := s.updateOrderStatus(ctx, tx, order, models.StatusConfirmed); err != nil {
+			return nil, err
+		}
+		if _, err := s.issueInvoice(ctx, tx, order); err != nil {
+			return nil, err
+		}
+
+	case payment.EventFailed:
+		// the order stays pending so the customer can pay again
+		if !p.Status.InProgress() {
+			return nil, nil
+		}
+		p.Status = models.PaymentFailed
+		p.FailureReason = event.Data.FailureReason
+		if err := s.repo.UpdatePayment(ctx, tx, p); err != nil {
+			return nil, fmt.Errorf("updating payment: %w", err)
+		}
+
+	case payment.EventRefunded:
+		// refunds made through the API have been recorded already
+		if event.Data.RefundReference != "" {
+			recorded, err := s.repo.RefundRecorded(ctx, tx, event.Data.RefundReference)
+			if err != nil {
+				return nil, err
+			}
+			if recorded {
+				return nil, nil
+			}
+		}
+
+		p.RefundedAmount = pkg.RoundMoney(p.RefundedAmount + event.Data.Amount)
+		if p.RefundedAmount >= p.Amount {
+			p.Status = models.PaymentRefunded
+		}
+		if err := s.repo.UpdatePayment(ctx, tx, p); err != nil {
+			return nil, fmt.Errorf("updating payment: %w", err)
+		}
+		return nil, s.recordRefund(ctx, tx, order, &models.Refund{
+			ID:                pkg.GenerateID(),
+			OrderID:           order.ID,
+			PaymentID:         &p.ID,
+			ProviderReference: event.Data.RefundReference,
+			Status:            models.RefundSucceeded,
+			Amount:            event.Data.Amount,
+			Reason:            "refunded by payment provider",
+		}, nil)
+	}
+	return nil, nil
+}
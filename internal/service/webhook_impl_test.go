@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/internal/payment"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// unsettledPayment stores a payment of the order that waits for the provider to report its
+// outcome. PayOrder leaves payments pending when it doesn't learn the outcome.
+func unsettledPayment(t *testing.T, repo *memRepo, orderID, reference string) {
+	t.Helper()
+	err := repo.CreatePayment(context.Background(), nil, &models.Payment{
+		ID:        pkg.GenerateID(),
+		OrderID:   orderID,
+		Provider:  payment.Fake,
+		Reference: reference,
+		Amount:    10,
+		Status:    models.PaymentPending,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandlePaymentWebhook(t *testing.T) {
+	payload := []byte(`{"id":"evt_1","type":"payment.captured","data":{"reference":"pay_1","amount":10}}`)
+	signer := payment.NewSigner(testWebhookSecret)
+
+	tests := []struct {
+		name      string
+		signature string
+		wantErr   error
+		wantOrder models.OrderStatus
+	}{
+		{
+			name:      "valid signature",
+			signature: signer.Sign(payload, time.Now()),
+			wantOrder: models.StatusConfirmed,
+		},
+		{
+			name:      "bad signature",
+			signature: payment.NewSigner("other").Sign(payload, time.Now()),
+			wantErr:   pkg.ErrInvalidSignature,
+			wantOrder: models.StatusPending,
+		},
+		{
+			name:      "replayed",
+			signature: signer.Sign(payload, time.Now().Add(-payment.SignatureTolerance-time.Minute)),
+			wantErr:   pkg.ErrInvalidSignature,
+			wantOrder: models.StatusPending,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMemRepo()
+			repo.addOrder(pendingOrder("order-1", "user-1", 10))
+			unsettledPayment(t, repo, "order-1", "pay_1")
+			s := newTestService(repo, payment.NewFakeProvider())
+
+			err := s.HandlePaymentWebhook(context.Background(), payload, tt.signature)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("HandlePaymentWebhook() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := repo.orderStatus(t, "order-1"); got != tt.wantOrder {
+				t.Errorf("order status = %s, want %s", got, tt.wantOrder)
+			}
+			if tt.wantErr != nil && len(repo.paymentEvents) != 0 {
+				t.Errorf("rejected event was stored")
+			}
+		})
+	}
+}
+
+func TestHandlePaymentWebhookDuplicateEvent(t *testing.T) {
+	repo := newMemRepo()
+	repo.addOrder(pendingOrder("order-1", "user-1", 10))
+	unsettledPayment(t, repo, "order-1", "pay_1")
+	s := newTestService(repo, payment.NewFakeProvider())
+	signer := payment.NewSigner(testWebhookSecret)
+
+	captured := []byte(`{"id":"evt_1","type":"payment.captured","data":{"reference":"pay_1","amount":10}}`)
+	if err := s.HandlePaymentWebhook(context.Background(), captured, signer.Sign(captured, time.Now())); err != nil {
+		t.Fatal(err)
+	}
+
+	// put the order and payment back as they were, so only the event ID keeps the event
+	// from being applied again
+	if err := repo.UpdateOrderStatus(context.Background(), nil, "order-1", models.StatusPending); err != nil {
+		t.Fatal(err)
+	}
+	repo.payments[0].Status = models.PaymentPending
+	delete(repo.invoices, "order-1")
+
+	// providers redeliver events they didn't see acknowledged, signed anew
+	if err := s.HandlePaymentWebhook(context.Background(), captured, signer.Sign(captured, time.Now())); err != nil {
+		t.Fatalf("redelivered event: %v", err)
+	}
+	if len(repo.paymentEvents) != 1 {
+		t.Errorf("stored %d events, want 1", len(repo.paymentEvents))
+	}
+	if got := repo.orderStatus(t, "order-1"); got != models.StatusPending {
+		t.Errorf("redelivered event moved the order to %s", got)
+	}
+	if repo.hasInvoice("order-1") {
+		t.Errorf("redelivered event issued another invoice")
+	}
+}
+
+func TestHandlePaymentWebhookSettlesUnknownOutcome(t *testing.T) {
+	tests := []struct {
+		name        string
+		event       string
+		wantPayment models.PaymentStatus
+		wantOrder   models.OrderStatus
+	}{
+		{name: "captured", event: payment.EventCaptured, wantPayment: models.PaymentCaptured, wantOrder: models.StatusConfirmed},
+		{name: "failed", event: payment.EventFailed, wantPayment: models.PaymentFailed, wantOrder: models.StatusPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMemRepo()
+			repo.addOrder(pendingOrder("order-1", "user-1", 10))
+			s := newTestService(repo, payment.NewFakeProvider())
+
+			// the capture's answer is lost, so the payment is left pending
+			p, err := s.PayOrder(customer("user-1"), "order-1", &models.PayOrderRequest{PaymentToken: payment.TokenCaptureTimeout})
+			if err != nil {
+				t.Fatalf("PayOrder() error = %v", err)
+			}
+			if p.Status != models.PaymentPending {
+				t.Fatalf("PayOrder() left the payment %s, want %s", p.Status, models.PaymentPending)
+			}
+
+			payload := []byte(`{"id":"evt_1","type":"` + tt.event + `","data":{"reference":"` + p.Reference + `","amount":10}}`)
+			signature := payment.NewSigner(testWebhookSecret).Sign(payload, time.Now())
+			if err := s.HandlePaymentWebhook(context.Background(), payload, signature); err != nil {
+				t.Fatalf("HandlePaymentWebhook() error = %v", err)
+			}
+			if got := repo.payment(t, p.ID).Status; got != tt.wantPayment {
+				t.Errorf("payment status = %s, want %s", got, tt.wantPayment)
+			}
+			if got := repo.orderStatus(t, "order-1"); got != tt.wantOrder {
+				t.Errorf("order status = %s, want %s", got, tt.wantOrder)
+			}
+		})
+	}
+}
+
+func TestHandlePaymentWebhookCaptureOfCancelledOrder(t *testing.T) {
+	repo := newMemRepo()
+	repo.addOrder(pendingOrder("order-1", "user-1", 10))
+	provider := payment.NewFakeProvider()
+	s := newTestService(repo, provider)
+
+	p, err := s.PayOrder(customer("user-1"), "order-1", &models.PayOrderRequest{PaymentToken: payment.TokenCaptureTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the order expires while the capture is in flight
+	if err := repo.UpdateOrderStatus(context.Background(), nil, "order-1", models.StatusCancelled); err != nil {
+		t.Fatal(err)
+	}
+
+	payload := []byte(`{"id":"evt_1","type":"payment.captured","data":{"reference":"` + p.Reference + `","amount":10}}`)
+	signature := payment.NewSigner(testWebhookSecret).Sign(payload, time.Now())
+	if err := s.HandlePaymentWebhook(context.Background(), payload, signature); err != nil {
+		t.Fatalf("HandlePaymentWebhook() error = %v", err)
+	}
+	if got := repo.payment(t, p.ID).Status; got != models.PaymentRefunded {
+		t.Errorf("payment status = %s, want %s", got, models.PaymentRefunded)
+	}
+	if transaction, _ := provider.Lookup(context.Background(), p.ID); !transaction.Refunded {
+		t.Errorf("provider has %+v, want the payment refunded", transaction)
+	}
+	if got := repo.orderStatus(t, "order-1"); got != models.StatusCancelled {
+		t.Errorf("order status = %s, want %s", got, models.StatusCancelled)
+	}
+	if repo.hasInvoice("order-1") {
+		t.Error("cancelled order was invoiced")
+	}
+}
+
+func TestHandlePaymentWebhookCaptureOfOtherAmount(t *testing.T) {
+	repo := newMemRepo()
+	repo.addOrder(pendingOrder("order-1", "user-1", 10))
+	unsettledPayment(t, repo, "order-1", "pay_1")
+	s := newTestService(repo, payment.NewFakeProvider())
+
+	payload := []byte(`{"id":"evt_1","type":"payment.captured","data":{"reference":"pay_1","amount":1}}`)
+	err := s.HandlePaymentWebhook(context.Background(), payload, payment.NewSigner(testWebhookSecret).Sign(payload, time.Now()))
+	if !errors.Is(err, pkg.ErrInvalidInput) {
+		t.Fatalf("HandlePaymentWebhook() error = %v, want %v", err, pkg.ErrInvalidInput)
+	}
+	if got := repo.payments[0].Status; got != models.PaymentPending {
+		t.Errorf("payment status = %s, want %s", got, models.PaymentPending)
+	}
+	if got := repo.orderStatus(t, "order-1"); got != models.StatusPending {
+		t.Errorf("order status = %s, want %s", got, models.StatusPending)
+	}
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/internal/webhook"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+const (
+	// webhookBatchSize is the number of deliveries sent per run
+	webhookBatchSize = 20
+	// webhookLease holds claimed deliveries back from other senders while a batch is sent
+	webhookLease = 5 * time.Minute
+	// webhookMaxAttempts is the number of failed attempts after which a delivery is dead
+	webhookMaxAttempts = 10
+	// webhookBaseBackoff and webhookMaxBackoff bound the wait between attempts
+	webhookBaseBackoff = 30 * time.Second
+	webhookMaxBackoff  = 4 * time.Hour
+	// defaultWebhookDeliveryLimit is the number of deliveries listed when no limit is asked for
+	defaultWebhookDeliveryLimit = 50
+)
+
+func (s *serviceImpl) CreateWebhookSubscription(ctx context.Context, req *models.CreateWebhookSubscriptionRequest) (*models.WebhookSubscription, error) {
+	if req.Secret == "" {
+		return nil, fmt.Errorf("%w: a secret is required", pkg.ErrInvalidInput)
+	}
+
+	subscription := &models.WebhookSubscription{ID: pkg.GenerateID()}
+	applyWebhookSubscriptionRequest(subscription, req)
+
+	if err := s.repo.CreateWebhookSubscription(ctx, subscription); err != nil {
+		return nil, fmt.Errorf("creating webhook subscription: %w", err)
+	}
+	return subscription, nil
+}
+
+func (s *serviceImpl) GetWebhookSubscription(ctx context.Context, id string) (*models.WebhookSubscription, error) {
+	subscription, err := s.repo.GetWebhookSubscription(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting webhook subscription: %w", err)
+	}
+	return subscription, nil
+}
+
+func (s *serviceImpl) ListWebhookSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	subscriptions, err := s.repo.ListWebhookSubscriptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing webhook subscriptions: %w", err)
+	}
+	return subscriptions, nil
+}
+
+// UpdateWebhookSubscription changes a subscription. Without a secret in req the current one
+// is kept. Deliveries that are queued already go to the new URL.
+func (s *serviceImpl) UpdateWebhookSubscription(ctx context.Context, id string, req *models.CreateWebhookSubscriptionRequest) (*models.WebhookSubscription, error) {
+	subscription, err := s.repo.GetWebhookSubscription(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting webhook subscription: %w", err)
+	}
+	applyWebhookSubscriptionRequest(subscription, req)
+
+	if err := s.repo.UpdateWebhookSubscription(ctx, subscription); err != nil {
+		return nil, fmt.Errorf("updating webhook subscription: %w", err)
+	}
+	return subscription, nil
+}
+
+func (s *serviceImpl) DeleteWebhookSubscription(ctx context.Context, id string) error {
+	if err := s.repo.DeleteWebhookSubscription(ctx, id); err != nil {
+		return fmt.Errorf("deleting webhook subscription: %w", err)
+	}
+	return nil
+}
+
+// ListWebhookDeliveries returns the most recent deliveries of a subscription
+func (s *serviceImpl) ListWebhookDeliveries(ctx context.Context, subscriptionID string, filter models.WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	if _, err := s.repo.GetWebhookSubscription(ctx, subscriptionID); err != nil {
+		return nil, fmt.Errorf("getting webhook subscription: %w", err)
+	}
+
+	limit := filter.Limit
+	if limit == 0 {
+		limit = defaultWebhookDeliveryLimit
+	}
+	deliveries, err := s.repo.ListWebhookDeliveries(ctx, subscriptionID, filter.Status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("listing webhook deliveries: %w", err)
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+	return deliveries, nil
+}
+
+// GetWebhookDelivery returns a delivery with the history of its attempts
+func (s *serviceImpl) GetWebhookDelivery(ctx context.Context, subscriptionID, id string) (*models.WebhookDelivery, error) {
+	delivery, err := s.repo.GetWebhookDelivery(ctx, subscriptionID, id)
+	if err != nil {
+		return nil, fmt.Errorf("getting webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+// RedeliverWebhook queues a delivery to be sent again straight away, whatever its status.
+// It gets a fresh set of attempts; the history of the earlier ones is kept.
+func (s *serviceImpl) RedeliverWebhook(ctx context.Context, subscriptionID, id string) (*models.WebhookDelivery, error) {
+	if err := s.repo.ResetWebhookDelivery(ctx, subscriptionID, id, time.Now()); err != nil {
+		return nil, fmt.Errorf("resetting webhook delivery: %w", err)
+	}
+	return s.GetWebhookDelivery(ctx, subscriptionID, id)
+}
+
+// DeliverWebhooks sends the webhook deliveries that are due. Failed deliveries are retried
+// with exponential backoff until they have failed webhookMaxAttempts times, when they are
+// dead and only sent again when redelivered. It is run periodically by the job runner.
+func (s *serviceImpl) DeliverWebhooks(ctx context.Context) error {
+	deliveries, err := s.repo.ClaimWebhookDeliveries(ctx, time.Now(), webhookLease, webhookBatchSize)
+	if err != nil {
+		return fmt.Errorf("claiming webhook deliveries: %w", err)
+	}
+
+	subscriptions := make(map[string]*models.WebhookSubscription)
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		subscription, ok := subscriptions[delivery.SubscriptionID]
+		if !ok {
+			subscription, err = s.repo.GetWebhookSubscription(ctx, delivery.SubscriptionID)
+			if err != nil {
+				// deleted subscriptions take their deliveries with them
+				if errors.Is(err, pkg.ErrNotFound) {
+					continue
+				}
+				return fmt.Errorf("getting webhook subscription: %w", err)
+			}
+			subscriptions[delivery.SubscriptionID] = subscription
+		}
+
+		if err := s.deliverWebhook(ctx, subscription, delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliverWebhook makes one attempt to send a delivery and records its outcome
+func (s *serviceImpl) deliverWebhook(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) error {
+	start := time.Now()
+	statusCode, sendErr := s.sender.Send(ctx, webhook.Request{
+		URL:        subscription.URL,
+		Secret:     subscription.Secret,
+		DeliveryID: delivery.ID,
+		EventType:  delivery.EventType,
+		Body:       delivery.Payload,
+	})
+	if ctx.Err() != nil {
+		// shutting down; the delivery is sent again once its lease runs out
+		return ctx.Err()
+	}
+
+	attempt := &models.WebhookDeliveryAttempt{
+		ID:          pkg.GenerateID(),
+		DeliveryID:  delivery.ID,
+		DurationMs:  int(time.Since(start).Milliseconds()),
+		AttemptedAt: start,
+	}
+	if statusCode != 0 {
+		attempt.StatusCode = &statusCode
+	}
+
+	delivery.Attempts++
+	delivery.LastStatusCode = attempt.StatusCode
+	switch {
+	case sendErr == nil:
+		now := time.Now()
+		delivery.Status = models.DeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	case delivery.Attempts >= webhookMaxAttempts:
+		attempt.Error = sendErr.Error()
+		delivery.Status = models.DeliveryDead
+		delivery.LastError = attempt.Error
+		slog.Warn("webhook delivery is dead", slog.String("delivery_id", delivery.ID),
+			slog.String("subscription_id", subscription.ID), slog.String("err", attempt.Error))
+	default:
+		attempt.Error = sendErr.Error()
+		delivery.NextAttemptAt = time.Now().Add(pkg.Backoff(delivery.Attempts, webhookBaseBackoff, webhookMaxBackoff))
+		delivery.LastError = attempt.Error
+	}
+
+	err := s.repo.WithTransaction(ctx, func(tx pgx.Tx) error {
+		return s.repo.RecordWebhookAttempt(ctx, tx, delivery, attempt)
+	})
+	if err != nil {
+		return fmt.Errorf("recording webhook attempt: %w", err)
+	}
+	return nil
+}
+
+// queueWebhookDeliveries queues a delivery of an outbox event for each active subscription
+// to its type. Events relayed again aren't queued twice.
+func (s *serviceImpl) queueWebhookDeliveries(ctx context.Context, tx pgx.Tx, event *models.OutboxEvent) error {
+	subscriptions, err := s.repo.ListEventSubscriptions(ctx, tx, event.Type)
+	if err != nil {
+		return fmt.Errorf("listing event subscriptions: %w", err)
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(webhook.Payload{
+		ID:            event.ID,
+		Type:          event.Type,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		CreatedAt:     event.CreatedAt,
+		Data:          event.Payload,
+	})
+	if err != nil {
+		return fmt.Errorf("encoding webhook payload: %w", err)
+	}
+
+	for _, subscription := range subscriptions {
+		delivery := &models.WebhookDelivery{
+			ID:             pkg.GenerateID(),
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        payload,
+		}
+		if _, err := s.repo.CreateWebhookDelivery(ctx, tx, delivery); err != nil {
+			return fmt.Errorf("queueing webhook delivery: %w", err)
+		}
+	}
+	return nil
+}
+
+// applyWebhookSubscriptionRequest copies the fields of req onto subscription.
+// Subscriptions are active unless req says otherwise.
+func applyWebhookSubscriptionRequest(subscription *models.WebhookSubscription, req *models.CreateWebhookSubscriptionRequest) {
+	subscription.URL = req.URL
+	subscription.EventTypes = req.EventTypes
+	if req.Secret != "" {
+		subscription.Secret = req.Secret
+	}
+	subscription.IsActive = req.IsActive == nil || *req.IsActive
+}
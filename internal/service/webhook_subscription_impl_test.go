@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/internal/payment"
+	"github.com/zde37/instashop-task/internal/webhook"
+)
+
+// webhookTimeout is how long test receivers get to answer
+const webhookTimeout = 50 * time.Millisecond
+
+// receiver is a webhook endpoint that answers each request with the next of its status
+// codes, or doesn't answer in time for a status of 0
+type receiver struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	statuses    []int
+	deliveryIDs []string
+	stop        chan struct{}
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	r := &receiver{statuses: statuses, stop: make(chan struct{})}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		r.deliveryIDs = append(r.deliveryIDs, req.Header.Get(webhook.DeliveryHeader))
+		status := http.StatusOK
+		if len(r.statuses) > 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		r.mu.Unlock()
+
+		if status == 0 {
+			<-r.stop
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(func() {
+		close(r.stop)
+		r.Close()
+	})
+	return r
+}
+
+func (r *receiver) received() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.deliveryIDs
+}
+
+// newWebhookTest returns a service that sends webhooks to the receiver, with a delivery to
+// it queued
+func newWebhookTest(t *testing.T, r *receiver) (*serviceImpl, *memRepo) {
+	repo := newMemRepo()
+	repo.subscriptions["subscription-1"] = &models.WebhookSubscription{
+		ID:         "subscription-1",
+		URL:        r.URL,
+		EventTypes: []string{"order.status_changed"},
+		Secret:     "whsec_test",
+		IsActive:   true,
+	}
+	_, err := repo.CreateWebhookDelivery(context.Background(), nil, &models.WebhookDelivery{
+		ID:             "delivery-1",
+		SubscriptionID: "subscription-1",
+		EventID:        "event-1",
+		EventType:      "order.status_changed",
+		Payload:        []byte(`{"id":"event-1"}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestService(repo, payment.NewFakeProvider())
+	s.sender = webhook.NewHTTPSender(webhookTimeout)
+	return s, repo
+}
+
+// deliver runs one delivery round and returns the state of the delivery after it
+func deliver(t *testing.T, s *serviceImpl, repo *memRepo) *models.WebhookDelivery {
+	t.Helper()
+	if err := s.DeliverWebhooks(context.Background()); err != nil {
+		t.Fatalf("DeliverWebhooks() error = %v", err)
+	}
+	delivery, err := repo.GetWebhookDelivery(context.Background(), "subscription-1", "delivery-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return delivery
+}
+
+// makeDue lets the delivery be retried straight away
+func makeDue(repo *memRepo) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+	repo.deliveries["delivery-1"].NextAttemptAt = time.Now()
+}
+
+func TestDeliverWebhooksRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		wantStatus *int
+	}{
+		{name: "server error", status: http.StatusBadGateway, wantStatus: ptr(http.StatusBadGateway)},
+		{name: "timeout", status: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo := newWebhookTest(t, newReceiver(t, tt.status, tt.status))
+
+			// the wait doubles with every failed attempt
+			for attempt, wait := range []time.Duration{webhookBaseBackoff, 2 * webhookBaseBackoff} {
+				start := time.Now()
+				delivery := deliver(t, s, repo)
+				if delivery.Status != models.DeliveryPending || delivery.Attempts != attempt+1 {
+					t.Fatalf("after attempt %d the delivery is %s with %d attempts", attempt+1, delivery.Status, delivery.Attempts)
+				}
+				if due := delivery.NextAttemptAt; due.Before(start.Add(wait)) || due.After(time.Now().Add(wait)) {
+					t.Errorf("attempt %d retried in %s, want %s", attempt+1, due.Sub(start), wait)
+				}
+				if !equalStatus(delivery.LastStatusCode, tt.wantStatus) || delivery.LastError == "" {
+					t.Errorf("last status %v with error %q, want %v with an error", delivery.LastStatusCode, delivery.LastError, tt.wantStatus)
+				}
+				makeDue(repo)
+			}
+
+			delivery := deliver(t, s, repo)
+			if delivery.Status != models.DeliverySucceeded || delivery.DeliveredAt == nil || delivery.LastError != "" {
+				t.Errorf("third attempt left the delivery %s, delivered at %v, error %q", delivery.Status, delivery.DeliveredAt, delivery.LastError)
+			}
+			if len(delivery.History) != 3 {
+				t.Errorf("recorded %d attempts, want 3", len(delivery.History))
+			}
+		})
+	}
+}
+
+func TestDeliverWebhooksSkipsDeliveriesNotDue(t *testing.T) {
+	r := newReceiver(t, http.StatusInternalServerError)
+	s, repo := newWebhookTest(t, r)
+
+	deliver(t, s, repo)
+	if delivery := deliver(t, s, repo); delivery.Attempts != 1 {
+		t.Errorf("delivery attempted %d times before its retry was due", delivery.Attempts)
+	}
+	if got := len(r.received()); got != 1 {
+		t.Errorf("receiver got %d requests, want 1", got)
+	}
+}
+
+func TestDeliverWebhooksDeadLettersAndRedelivers(t *testing.T) {
+	r := newReceiver(t, http.StatusInternalServerError)
+	s, repo := newWebhookTest(t, r)
+	repo.deliveries["delivery-1"].Attempts = webhookMaxAttempts - 1
+
+	delivery := deliver(t, s, repo)
+	if delivery.Status != models.DeliveryDead || delivery.Attempts != webhookMaxAttempts {
+		t.Fatalf("last attempt left the delivery %s with %d attempts", delivery.Status, delivery.Attempts)
+	}
+
+	// dead deliveries are only sent again when redelivered
+	makeDue(repo)
+	deliver(t, s, repo)
+	if got := len(r.received()); got != 1 {
+		t.Fatalf("dead delivery sent %d times, want 1", got)
+	}
+
+	delivery, err := s.RedeliverWebhook(context.Background(), "subscription-1", "delivery-1")
+	if err != nil {
+		t.Fatalf("RedeliverWebhook() error = %v", err)
+	}
+	if delivery.Status != models.DeliveryPending || delivery.Attempts != 0 {
+		t.Errorf("redelivered delivery is %s with %d attempts, want pending with 0", delivery.Status, delivery.Attempts)
+	}
+
+	delivery = deliver(t, s, repo)
+	if delivery.Status != models.DeliverySucceeded {
+		t.Errorf("redelivery left the delivery %s", delivery.Status)
+	}
+	// receivers dedupe on the delivery ID, which stays the same
+	if got := r.received(); len(got) != 2 || got[0] != "delivery-1" || got[1] != "delivery-1" {
+		t.Errorf("receiver got deliveries %v, want delivery-1 twice", got)
+	}
+	if len(delivery.History) != 2 {
+		t.Errorf("recorded %d attempts, want both", len(delivery.History))
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func equalStatus(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
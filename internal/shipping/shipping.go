@@ -0,0 +1,112 @@
+package shipping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// Local is the carrier code of the built-in carrier
+const Local = "local"
+
+// volumetricDivisor converts a parcel's volume in cm³ to its volumetric weight in kg
+const volumetricDivisor = 5000
+
+// ErrUnknownCarrier is returned for shipping methods whose carrier isn't registered
+var ErrUnknownCarrier = errors.New("unknown carrier")
+
+// Item is a product quantity to be shipped
+type Item struct {
+	ProductID string
+	Quantity  int
+	WeightKg  float64
+	LengthCm  float64
+	WidthCm   float64
+	HeightCm  float64
+}
+
+// Request asks a carrier to rate a shipment of the items to the destination.
+// OrderValue is the value of the goods after discounts.
+type Request struct {
+	Destination *models.OrderAddress
+	Items       []Item
+	OrderValue  float64
+}
+
+// BillableWeight is the weight carriers charge for: the actual weight or, for bulky
+// items, the volumetric weight
+func (r Request) BillableWeight() float64 {
+	var total float64
+	for _, item := range r.Items {
+		volumetric := item.LengthCm * item.WidthCm * item.HeightCm / volumetricDivisor
+		total += math.Max(item.WeightKg, volumetric) * float64(item.Quantity)
+	}
+	return total
+}
+
+// Rate is the price of a shipment
+type Rate struct {
+	Amount        float64
+	EstimatedDays int
+}
+
+// Carrier rates shipments for the shipping methods it serves. External carriers
+// implement it on top of their rating APIs.
+type Carrier interface {
+	Code() string
+	Rate(ctx context.Context, method *models.ShippingMethod, req Request) (Rate, error)
+}
+
+// Carriers holds the available carriers by code
+type Carriers map[string]Carrier
+
+// NewCarriers registers the carriers under their codes
+func NewCarriers(carriers ...Carrier) Carriers {
+	registry := make(Carriers, len(carriers))
+	for _, carrier := range carriers {
+		registry[carrier.Code()] = carrier
+	}
+	return registry
+}
+
+// Rate rates the shipment with the method's carrier. Orders that reach the method's
+// free shipping threshold are not charged.
+func (c Carriers) Rate(ctx context.Context, method *models.ShippingMethod, req Request) (Rate, error) {
+	carrier, ok := c[method.Carrier]
+	if !ok {
+		return Rate{}, fmt.Errorf("%w %q", ErrUnknownCarrier, method.Carrier)
+	}
+
+	rate, err := carrier.Rate(ctx, method, req)
+	if err != nil {
+		return Rate{}, err
+	}
+	if method.FreeOver != nil && req.OrderValue >= *method.FreeOver {
+		rate.Amount = 0
+	}
+	return rate, nil
+}
+
+type localCarrier struct{}
+
+// NewLocalCarrier returns the built-in carrier, which charges the rates configured
+// on the shipping methods without calling out to anyone
+func NewLocalCarrier() Carrier {
+	return localCarrier{}
+}
+
+func (localCarrier) Code() string {
+	return Local
+}
+
+func (localCarrier) Rate(_ context.Context, method *models.ShippingMethod, req Request) (Rate, error) {
+	amount := method.BaseRate
+	if method.RateType == models.ShippingWeightBased {
+		amount += method.PerKgRate * math.Ceil(req.BillableWeight())
+	}
+	return Rate{Amount: pkg.RoundMoney(amount), EstimatedDays: method.EstimatedDays}, nil
+}
@@ -0,0 +1,109 @@
+package stream
+
+import (
+	"sync"
+	"time"
+
+	"github.com/zde37/instashop-task/internal/models"
+)
+
+// subscriptionBuffer is the number of events a subscriber may fall behind by before it is
+// dropped. Dropped subscribers resume from their last event when they reconnect.
+const subscriptionBuffer = 64
+
+// OrderStatusEvent is a change of an order's status. ID is the position of the change in
+// the event log, so clients can resume after the last event they saw.
+type OrderStatusEvent struct {
+	ID        int64              `json:"id"`
+	OrderID   string             `json:"order_id"`
+	UserID    string             `json:"user_id"`
+	From      models.OrderStatus `json:"from"`
+	To        models.OrderStatus `json:"to"`
+	ChangedAt time.Time          `json:"changed_at"`
+}
+
+// Broker hands the order status changes published on this instance to its subscribers.
+// It is safe for concurrent use.
+type Broker struct {
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// NewBroker creates a Broker without subscribers
+func NewBroker() *Broker {
+	return &Broker{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives the status changes of one user's orders, or of all orders
+type Subscription struct {
+	broker *Broker
+	userID string
+	all    bool
+	events chan OrderStatusEvent
+}
+
+// Subscribe returns a subscription to the status changes of the user's orders, or of
+// every order if all is set. It must be closed when no longer needed.
+func (b *Broker) Subscribe(userID string, all bool) *Subscription {
+	sub := &Subscription{broker: b, userID: userID, all: all, events: make(chan OrderStatusEvent, subscriptionBuffer)}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(sub.events)
+		return sub
+	}
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// Events delivers the subscribed changes. The channel is closed when the subscription is
+// closed, falls too far behind or the broker shuts down.
+func (s *Subscription) Events() <-chan OrderStatusEvent {
+	return s.events
+}
+
+// Close ends the subscription
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.remove(s)
+}
+
+// Publish hands an event to the matching subscribers without waiting for any of them
+func (b *Broker) Publish(event OrderStatusEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		if !sub.all && sub.userID != event.UserID {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			b.remove(sub)
+		}
+	}
+}
+
+// Close ends all subscriptions and refuses new ones
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subs {
+		b.remove(sub)
+	}
+}
+
+// remove closes a subscription's channel once. b.mu must be held.
+func (b *Broker) remove(sub *Subscription) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	close(sub.events)
+}
@@ -0,0 +1,95 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Channel is the Postgres notification channel that order status changes are announced
+// on when the transaction making them commits
+const Channel = "order_status_changed"
+
+const reconnectDelay = time.Second
+
+// Listener feeds the order status changes announced by any API instance into the broker
+// of this one
+type Listener struct {
+	db     *pgxpool.Pool
+	broker *Broker
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewListener creates a Listener that publishes the announcements on Channel to broker
+func NewListener(db *pgxpool.Pool, broker *Broker) *Listener {
+	return &Listener{db: db, broker: broker}
+}
+
+// Start listens in the background until Stop is called, reconnecting when the
+// connection is lost. Changes announced while reconnecting are missed by live
+// subscribers, who can catch up by resuming from their last event.
+func (l *Listener) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	l.cancel = cancel
+	l.done = make(chan struct{})
+
+	go func() {
+		defer close(l.done)
+		for {
+			err := l.listen(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Error("listening for order status changes failed", slog.String("err", err.Error()))
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(reconnectDelay):
+			}
+		}
+	}()
+}
+
+// Stop stops listening and waits for the listener to return
+func (l *Listener) Stop() {
+	if l.cancel == nil {
+		return
+	}
+	l.cancel()
+	<-l.done
+}
+
+func (l *Listener) listen(ctx context.Context) error {
+	pooled, err := l.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquiring connection: %w", err)
+	}
+	// the connection keeps listening, so it doesn't go back into the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+Channel); err != nil {
+		return fmt.Errorf("listening on %s: %w", Channel, err)
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("waiting for notification: %w", err)
+		}
+
+		var event OrderStatusEvent
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			slog.Error("invalid order status notification", slog.String("payload", notification.Payload), slog.String("err", err.Error()))
+			continue
+		}
+		l.broker.Publish(event)
+	}
+}
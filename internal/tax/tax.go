@@ -0,0 +1,143 @@
+package tax
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/zde37/instashop-task/internal/models"
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// Mode says whether prices already include tax
+type Mode string
+
+const (
+	// Exclusive prices have tax added on top
+	Exclusive Mode = "exclusive"
+	// Inclusive prices already contain their tax
+	Inclusive Mode = "inclusive"
+)
+
+// ParseMode returns the mode named s. An empty name selects Exclusive.
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case "", Exclusive:
+		return Exclusive, nil
+	case Inclusive:
+		return Inclusive, nil
+	default:
+		return "", fmt.Errorf("unknown tax mode %q", s)
+	}
+}
+
+// Line is an order line to be taxed
+type Line struct {
+	ProductID string
+	TaxClass  models.TaxClass
+	// Amount is the line's price after discounts
+	Amount float64
+}
+
+// Request asks for the tax on the lines of an order shipped to Region.
+// An empty region selects the calculator's default.
+type Request struct {
+	Region string
+	Lines  []Line
+}
+
+// LineTax is the tax on one line. Rate is a percentage.
+type LineTax struct {
+	Rate   float64
+	Amount float64
+}
+
+// Result holds the tax of each line, in the order of the lines
+type Result struct {
+	Region    string
+	Inclusive bool
+	Lines     []LineTax
+}
+
+// Total adds up the tax of the lines
+func (r Result) Total() float64 {
+	var total float64
+	for _, line := range r.Lines {
+		total += line.Amount
+	}
+	return pkg.RoundMoney(total)
+}
+
+// Calculator works out the tax on an order. It is implemented locally by the rate table
+// and can be implemented by an external tax provider.
+type Calculator interface {
+	Calculate(ctx context.Context, req Request) (Result, error)
+}
+
+// RateSource looks up the configured tax rates of a region
+type RateSource interface {
+	ListTaxRatesByRegion(ctx context.Context, regions []string) ([]models.TaxRate, error)
+}
+
+type rateTable struct {
+	rates         RateSource
+	mode          Mode
+	defaultRegion string
+}
+
+// NewRateTable returns a Calculator that applies the configured rates. Rates of a region
+// such as "US-CA" take precedence over those of its country "US", and products whose tax
+// class has no rate fall back to the standard class. Lines without any rate are not taxed.
+func NewRateTable(rates RateSource, mode Mode, defaultRegion string) Calculator {
+	return &rateTable{rates: rates, mode: mode, defaultRegion: strings.ToUpper(defaultRegion)}
+}
+
+func (t *rateTable) Calculate(ctx context.Context, req Request) (Result, error) {
+	region := strings.ToUpper(req.Region)
+	if region == "" {
+		region = t.defaultRegion
+	}
+	result := Result{Region: region, Inclusive: t.mode == Inclusive, Lines: make([]LineTax, len(req.Lines))}
+	if region == "" {
+		return result, nil
+	}
+
+	// most specific region first
+	regions := []string{region}
+	if country, _, ok := strings.Cut(region, "-"); ok {
+		regions = append(regions, country)
+	}
+
+	rates, err := t.rates.ListTaxRatesByRegion(ctx, regions)
+	if err != nil {
+		return Result{}, fmt.Errorf("listing tax rates: %w", err)
+	}
+
+	for i, line := range req.Lines {
+		rate, ok := lookupRate(rates, regions, line.TaxClass)
+		if !ok {
+			rate, _ = lookupRate(rates, regions, models.TaxClassStandard)
+		}
+		result.Lines[i] = LineTax{Rate: rate, Amount: t.taxOn(line.Amount, rate)}
+	}
+	return result, nil
+}
+
+// taxOn returns the tax on amount at rate percent
+func (t *rateTable) taxOn(amount, rate float64) float64 {
+	if t.mode == Inclusive {
+		return pkg.RoundMoney(amount - amount/(1+rate/100))
+	}
+	return pkg.RoundMoney(amount * rate / 100)
+}
+
+func lookupRate(rates []models.TaxRate, regions []string, taxClass models.TaxClass) (float64, bool) {
+	for _, region := range regions {
+		for _, rate := range rates {
+			if rate.Region == region && rate.TaxClass == taxClass {
+				return rate.Rate, true
+			}
+		}
+	}
+	return 0, false
+}
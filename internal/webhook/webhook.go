@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// Headers sent with every delivery
+const (
+	// SignatureHeader carries the signature of the body under the subscription's secret,
+	// formatted as described at pkg.SignatureHeader
+	SignatureHeader = "X-Instashop-Signature"
+	// EventHeader carries the event type
+	EventHeader = "X-Instashop-Event"
+	// DeliveryHeader carries the delivery ID, which stays the same when a delivery is retried
+	DeliveryHeader = "X-Instashop-Delivery"
+)
+
+// Request is one attempt to deliver an event to a subscription
+type Request struct {
+	URL        string
+	Secret     string
+	DeliveryID string
+	EventType  string
+	Body       []byte
+}
+
+// Sender sends webhook requests. Implementations must be safe for concurrent use.
+type Sender interface {
+	// Send posts the request and returns the response status code, or 0 if there was no
+	// response. Any status outside 2xx is an error.
+	Send(ctx context.Context, req Request) (statusCode int, err error)
+}
+
+// Sign returns the signature header value for the body sent at the given time
+func Sign(secret string, body []byte, at time.Time) string {
+	return pkg.SignatureHeader([]byte(secret), body, at)
+}
+
+type httpSender struct {
+	client *http.Client
+}
+
+// NewHTTPSender returns a Sender that gives endpoints timeout to answer
+func NewHTTPSender(timeout time.Duration) Sender {
+	return &httpSender{client: &http.Client{Timeout: timeout}}
+}
+
+func (s *httpSender) Send(ctx context.Context, req Request) (int, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return 0, fmt.Errorf("building request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", "Instashop-Webhooks/1.0")
+	httpReq.Header.Set(EventHeader, req.EventType)
+	httpReq.Header.Set(DeliveryHeader, req.DeliveryID)
+	httpReq.Header.Set(SignatureHeader, Sign(req.Secret, req.Body, time.Now()))
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// drain a little of the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Payload is the body of webhook requests: an outbox event with its data
+type Payload struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Data          json.RawMessage `json:"data"`
+}
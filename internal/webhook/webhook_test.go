@@ -0,0 +1,121 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zde37/instashop-task/pkg"
+)
+
+// verifySignature checks a signature header the way receivers do
+func verifySignature(t *testing.T, header, secret string, body []byte) {
+	t.Helper()
+	timestamp, signature, ok := strings.Cut(strings.TrimPrefix(header, "t="), ",v1=")
+	if !ok {
+		t.Fatalf("malformed signature header %q", header)
+	}
+	mac, err := hex.DecodeString(signature)
+	if err != nil {
+		t.Fatalf("signature isn't hex: %v", err)
+	}
+	if !hmac.Equal(mac, pkg.SignatureMAC([]byte(secret), timestamp, body)) {
+		t.Errorf("signature %q doesn't match the body", header)
+	}
+}
+
+func TestHTTPSenderSend(t *testing.T) {
+	req := Request{
+		Secret:     "whsec_test",
+		DeliveryID: "delivery-1",
+		EventType:  "order.status_changed",
+		Body:       []byte(`{"id":"event-1"}`),
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != string(req.Body) {
+			t.Errorf("body = %s, want %s", body, req.Body)
+		}
+		if got := r.Header.Get(EventHeader); got != req.EventType {
+			t.Errorf("%s = %q, want %q", EventHeader, got, req.EventType)
+		}
+		if got := r.Header.Get(DeliveryHeader); got != req.DeliveryID {
+			t.Errorf("%s = %q, want %q", DeliveryHeader, got, req.DeliveryID)
+		}
+		verifySignature(t, r.Header.Get(SignatureHeader), req.Secret, body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	req.URL = server.URL
+	statusCode, err := NewHTTPSender(time.Second).Send(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if statusCode != http.StatusNoContent {
+		t.Errorf("Send() status = %d, want %d", statusCode, http.StatusNoContent)
+	}
+}
+
+func TestHTTPSenderSendFailures(t *testing.T) {
+	tests := []struct {
+		name string
+		// handler answers the request; it may hold on to it until stop is closed
+		handler    func(stop <-chan struct{}) http.HandlerFunc
+		wantStatus int
+	}{
+		{
+			name: "server error",
+			handler: func(<-chan struct{}) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name: "not modified",
+			handler: func(<-chan struct{}) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusNotModified)
+				}
+			},
+			wantStatus: http.StatusNotModified,
+		},
+		{
+			name: "timeout",
+			handler: func(stop <-chan struct{}) http.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request) {
+					<-stop
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stop := make(chan struct{})
+			server := httptest.NewServer(tt.handler(stop))
+			defer server.Close()
+			defer close(stop)
+
+			statusCode, err := NewHTTPSender(50*time.Millisecond).Send(context.Background(), Request{
+				URL:  server.URL,
+				Body: []byte(`{}`),
+			})
+			if err == nil {
+				t.Fatal("Send() succeeded, want an error")
+			}
+			if statusCode != tt.wantStatus {
+				t.Errorf("Send() status = %d, want %d", statusCode, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_order_item_allocations_order_item_id;
+DROP INDEX IF EXISTS idx_warehouse_stock_product_id;
+
+-- Drop trigger
+DROP TRIGGER IF EXISTS trg_warehouse_stock_sync ON warehouse_stock;
+DROP FUNCTION IF EXISTS sync_product_stock_quantity();
+
+-- Drop tables
+DROP TABLE IF EXISTS order_item_allocations;
+DROP TABLE IF EXISTS warehouse_stock;
+DROP TABLE IF EXISTS warehouses;
@@ -0,0 +1,57 @@
+-- Warehouses table
+CREATE TABLE warehouses (
+    id TEXT PRIMARY KEY,
+    code VARCHAR(50) UNIQUE NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0 CHECK (priority >= 0),
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Per-warehouse stock levels
+CREATE TABLE warehouse_stock (
+    warehouse_id TEXT NOT NULL REFERENCES warehouses(id) ON DELETE RESTRICT,
+    product_id TEXT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (warehouse_id, product_id)
+);
+
+-- Warehouse allocations of order items
+CREATE TABLE order_item_allocations (
+    id TEXT PRIMARY KEY,
+    order_item_id TEXT NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    warehouse_id TEXT NOT NULL REFERENCES warehouses(id) ON DELETE RESTRICT,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- products.stock_quantity is the sum of the product's warehouse stock
+CREATE OR REPLACE FUNCTION sync_product_stock_quantity() RETURNS TRIGGER AS $$
+DECLARE
+    target_product_id TEXT := COALESCE(NEW.product_id, OLD.product_id);
+BEGIN
+    UPDATE products
+    SET stock_quantity = (
+        SELECT COALESCE(SUM(quantity), 0) FROM warehouse_stock WHERE product_id = target_product_id
+    )
+    WHERE id = target_product_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_warehouse_stock_sync
+AFTER INSERT OR UPDATE OR DELETE ON warehouse_stock
+FOR EACH ROW EXECUTE FUNCTION sync_product_stock_quantity();
+
+-- Move existing stock into a default warehouse
+INSERT INTO warehouses (id, code, name, priority) VALUES ('default', 'MAIN', 'Main warehouse', 0);
+INSERT INTO warehouse_stock (warehouse_id, product_id, quantity)
+SELECT 'default', id, stock_quantity FROM products;
+INSERT INTO order_item_allocations (id, order_item_id, warehouse_id, quantity)
+SELECT gen_random_uuid()::text, id, 'default', quantity FROM order_items;
+
+-- Indexes
+CREATE INDEX idx_warehouse_stock_product_id ON warehouse_stock(product_id);
+CREATE INDEX idx_order_item_allocations_order_item_id ON order_item_allocations(order_item_id);
@@ -0,0 +1,9 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_stock_subscriptions_product_id;
+DROP INDEX IF EXISTS idx_stock_subscriptions_pending;
+
+-- Drop tables
+DROP TABLE IF EXISTS stock_subscriptions;
+
+-- Drop columns
+ALTER TABLE products DROP COLUMN IF EXISTS reorder_threshold;
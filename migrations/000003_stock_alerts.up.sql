@@ -0,0 +1,15 @@
+-- Reorder threshold per product
+ALTER TABLE products ADD COLUMN reorder_threshold INTEGER NOT NULL DEFAULT 0 CHECK (reorder_threshold >= 0);
+
+-- Back-in-stock subscriptions
+CREATE TABLE stock_subscriptions (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    product_id TEXT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    notified_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Indexes
+CREATE UNIQUE INDEX idx_stock_subscriptions_pending ON stock_subscriptions(user_id, product_id) WHERE notified_at IS NULL;
+CREATE INDEX idx_stock_subscriptions_product_id ON stock_subscriptions(product_id);
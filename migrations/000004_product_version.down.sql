@@ -0,0 +1,2 @@
+-- Drop columns
+ALTER TABLE products DROP COLUMN IF EXISTS version;
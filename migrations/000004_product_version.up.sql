@@ -0,0 +1,2 @@
+-- Version of the product row, incremented on every update
+ALTER TABLE products ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,5 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_products_archived_at;
+
+-- Drop columns
+ALTER TABLE products DROP COLUMN IF EXISTS archived_at;
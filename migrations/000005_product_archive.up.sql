@@ -0,0 +1,5 @@
+-- Archived products are hidden from listing and ordering
+ALTER TABLE products ADD COLUMN archived_at TIMESTAMP WITH TIME ZONE;
+
+-- Indexes
+CREATE INDEX idx_products_archived_at ON products(archived_at);
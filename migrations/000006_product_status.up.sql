@@ -0,0 +1,10 @@
+-- Publishing lifecycle of products, existing products stay live
+ALTER TABLE products ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active';
+ALTER TABLE products ADD COLUMN publish_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE products ADD COLUMN unpublish_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE products ALTER COLUMN status SET DEFAULT 'draft';
+
+-- Indexes
+CREATE INDEX idx_products_status ON products(status);
+CREATE INDEX idx_products_publish_at ON products(publish_at) WHERE status = 'scheduled';
+CREATE INDEX idx_products_unpublish_at ON products(unpublish_at) WHERE status = 'active';
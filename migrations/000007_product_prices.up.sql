@@ -0,0 +1,17 @@
+-- Price history and scheduled price changes
+CREATE TABLE product_prices (
+    id TEXT PRIMARY KEY,
+    product_id TEXT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    price DECIMAL(10,2) NOT NULL CHECK (price > 0),
+    effective_from TIMESTAMP WITH TIME ZONE NOT NULL,
+    effective_to TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT valid_price_window CHECK (effective_to IS NULL OR effective_to > effective_from)
+);
+
+-- Current prices become the first history entry
+INSERT INTO product_prices (id, product_id, price, effective_from)
+SELECT gen_random_uuid()::text, id, price, created_at FROM products;
+
+-- Indexes
+CREATE INDEX idx_product_prices_product_id_effective_from ON product_prices(product_id, effective_from DESC);
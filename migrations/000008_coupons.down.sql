@@ -0,0 +1,17 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_coupon_redemptions_order_id;
+DROP INDEX IF EXISTS idx_coupon_redemptions_coupon_id_user_id;
+DROP INDEX IF EXISTS idx_order_discounts_order_id;
+DROP INDEX IF EXISTS idx_products_category;
+
+-- Drop tables
+DROP TABLE IF EXISTS coupon_redemptions;
+DROP TABLE IF EXISTS order_discounts;
+DROP TABLE IF EXISTS coupons;
+
+-- Drop columns
+ALTER TABLE order_items DROP COLUMN IF EXISTS discount_amount;
+ALTER TABLE orders DROP COLUMN IF EXISTS coupon_code;
+ALTER TABLE orders DROP COLUMN IF EXISTS discount_total;
+ALTER TABLE orders DROP COLUMN IF EXISTS subtotal;
+ALTER TABLE products DROP COLUMN IF EXISTS category;
@@ -0,0 +1,59 @@
+-- Product categories, used to restrict coupons
+ALTER TABLE products ADD COLUMN category VARCHAR(100) NOT NULL DEFAULT '';
+
+-- Coupons table
+CREATE TABLE coupons (
+    id TEXT PRIMARY KEY,
+    code VARCHAR(50) UNIQUE NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    discount_type VARCHAR(20) NOT NULL CHECK (discount_type IN ('percentage', 'fixed')),
+    value DECIMAL(10,2) NOT NULL CHECK (value > 0),
+    min_order_value DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (min_order_value >= 0),
+    max_uses INTEGER CHECK (max_uses > 0),
+    max_uses_per_user INTEGER CHECK (max_uses_per_user > 0),
+    product_ids TEXT[] NOT NULL DEFAULT '{}',
+    categories TEXT[] NOT NULL DEFAULT '{}',
+    starts_at TIMESTAMP WITH TIME ZONE,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT valid_coupon_window CHECK (ends_at IS NULL OR starts_at IS NULL OR ends_at > starts_at),
+    CONSTRAINT valid_percentage CHECK (discount_type <> 'percentage' OR value <= 100)
+);
+
+-- Order totals before and after discounts
+ALTER TABLE orders ADD COLUMN subtotal DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (subtotal >= 0);
+ALTER TABLE orders ADD COLUMN discount_total DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (discount_total >= 0);
+ALTER TABLE orders ADD COLUMN coupon_code VARCHAR(50);
+UPDATE orders SET subtotal = total_amount;
+
+ALTER TABLE order_items ADD COLUMN discount_amount DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (discount_amount >= 0);
+
+-- Discount lines of orders
+CREATE TABLE order_discounts (
+    id TEXT PRIMARY KEY,
+    order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    source VARCHAR(20) NOT NULL,
+    reference_id TEXT NOT NULL,
+    code VARCHAR(50) NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    amount DECIMAL(10,2) NOT NULL CHECK (amount >= 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Coupon usage
+CREATE TABLE coupon_redemptions (
+    id TEXT PRIMARY KEY,
+    coupon_id TEXT NOT NULL REFERENCES coupons(id) ON DELETE RESTRICT,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    amount DECIMAL(10,2) NOT NULL CHECK (amount >= 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Indexes
+CREATE INDEX idx_products_category ON products(category);
+CREATE INDEX idx_order_discounts_order_id ON order_discounts(order_id);
+CREATE INDEX idx_coupon_redemptions_coupon_id_user_id ON coupon_redemptions(coupon_id, user_id);
+CREATE INDEX idx_coupon_redemptions_order_id ON coupon_redemptions(order_id);
@@ -0,0 +1,10 @@
+-- Drop tables
+DROP TABLE IF EXISTS tax_rates;
+
+-- Drop columns
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax_amount;
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax_rate;
+ALTER TABLE orders DROP COLUMN IF EXISTS tax_inclusive;
+ALTER TABLE orders DROP COLUMN IF EXISTS tax_region;
+ALTER TABLE orders DROP COLUMN IF EXISTS tax_total;
+ALTER TABLE products DROP COLUMN IF EXISTS tax_class;
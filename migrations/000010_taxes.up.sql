@@ -0,0 +1,21 @@
+-- Tax rates table
+CREATE TABLE tax_rates (
+    id TEXT PRIMARY KEY,
+    region VARCHAR(20) NOT NULL,
+    tax_class VARCHAR(50) NOT NULL,
+    name VARCHAR(100) NOT NULL DEFAULT '',
+    rate DECIMAL(6,3) NOT NULL CHECK (rate >= 0 AND rate < 100),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (region, tax_class)
+);
+
+ALTER TABLE products ADD COLUMN tax_class VARCHAR(50) NOT NULL DEFAULT 'standard';
+
+-- Tax charged on orders
+ALTER TABLE orders ADD COLUMN tax_total DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (tax_total >= 0);
+ALTER TABLE orders ADD COLUMN tax_region VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN tax_inclusive BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE order_items ADD COLUMN tax_rate DECIMAL(6,3) NOT NULL DEFAULT 0;
+ALTER TABLE order_items ADD COLUMN tax_amount DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (tax_amount >= 0);
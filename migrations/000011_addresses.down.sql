@@ -0,0 +1,10 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_addresses_user_id_default;
+DROP INDEX IF EXISTS idx_addresses_user_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS addresses;
+
+-- Drop columns
+ALTER TABLE orders DROP COLUMN IF EXISTS billing_address;
+ALTER TABLE orders DROP COLUMN IF EXISTS shipping_address;
@@ -0,0 +1,10 @@
+-- Drop tables
+DROP TABLE IF EXISTS shipping_methods;
+
+-- Drop columns
+ALTER TABLE orders DROP COLUMN IF EXISTS shipping_total;
+ALTER TABLE orders DROP COLUMN IF EXISTS shipping_method;
+ALTER TABLE products DROP COLUMN IF EXISTS height_cm;
+ALTER TABLE products DROP COLUMN IF EXISTS width_cm;
+ALTER TABLE products DROP COLUMN IF EXISTS length_cm;
+ALTER TABLE products DROP COLUMN IF EXISTS weight_kg;
@@ -0,0 +1,26 @@
+-- Product weight and dimensions, used to rate shipments
+ALTER TABLE products ADD COLUMN weight_kg DECIMAL(10,3) NOT NULL DEFAULT 0 CHECK (weight_kg >= 0);
+ALTER TABLE products ADD COLUMN length_cm DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (length_cm >= 0);
+ALTER TABLE products ADD COLUMN width_cm DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (width_cm >= 0);
+ALTER TABLE products ADD COLUMN height_cm DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (height_cm >= 0);
+
+-- Shipping methods table
+CREATE TABLE shipping_methods (
+    id TEXT PRIMARY KEY,
+    code VARCHAR(50) UNIQUE NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    carrier VARCHAR(50) NOT NULL,
+    rate_type VARCHAR(20) NOT NULL CHECK (rate_type IN ('flat', 'weight_based')),
+    base_rate DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (base_rate >= 0),
+    per_kg_rate DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (per_kg_rate >= 0),
+    free_over DECIMAL(10,2) CHECK (free_over >= 0),
+    countries TEXT[] NOT NULL DEFAULT '{}',
+    estimated_days INTEGER NOT NULL DEFAULT 0 CHECK (estimated_days >= 0),
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Shipping charged on orders
+ALTER TABLE orders ADD COLUMN shipping_method VARCHAR(50);
+ALTER TABLE orders ADD COLUMN shipping_total DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (shipping_total >= 0);
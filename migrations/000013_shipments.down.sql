@@ -0,0 +1,7 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_shipment_items_shipment_id;
+DROP INDEX IF EXISTS idx_shipments_order_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS shipment_items;
+DROP TABLE IF EXISTS shipments;
@@ -0,0 +1,13 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_refunds_order_id;
+DROP INDEX IF EXISTS idx_return_items_return_id;
+DROP INDEX IF EXISTS idx_returns_status;
+DROP INDEX IF EXISTS idx_returns_order_id;
+
+-- Drop columns
+ALTER TABLE order_items DROP COLUMN IF EXISTS returned_quantity;
+
+-- Drop tables
+DROP TABLE IF EXISTS refunds;
+DROP TABLE IF EXISTS return_items;
+DROP TABLE IF EXISTS returns;
@@ -0,0 +1,41 @@
+-- Return requests (RMAs) of orders
+CREATE TABLE returns (
+    id TEXT PRIMARY KEY,
+    order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    status VARCHAR(20) NOT NULL DEFAULT 'requested' CHECK (status IN ('requested', 'approved', 'rejected', 'received')),
+    note TEXT NOT NULL DEFAULT '',
+    resolution_note TEXT NOT NULL DEFAULT '',
+    received_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Order item quantities to be returned
+CREATE TABLE return_items (
+    id TEXT PRIMARY KEY,
+    return_id TEXT NOT NULL REFERENCES returns(id) ON DELETE CASCADE,
+    order_item_id TEXT NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    reason VARCHAR(30) NOT NULL CHECK (reason IN ('damaged', 'defective', 'wrong_item', 'not_as_described', 'no_longer_needed', 'other')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Money paid back on orders
+CREATE TABLE refunds (
+    id TEXT PRIMARY KEY,
+    order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    return_id TEXT REFERENCES returns(id) ON DELETE SET NULL,
+    amount DECIMAL(10,2) NOT NULL CHECK (amount >= 0),
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Quantity of each order item that has come back
+ALTER TABLE order_items ADD COLUMN returned_quantity INTEGER NOT NULL DEFAULT 0 CHECK (returned_quantity >= 0);
+
+-- Indexes
+CREATE INDEX idx_returns_order_id ON returns(order_id);
+CREATE INDEX idx_returns_status ON returns(status);
+CREATE INDEX idx_return_items_return_id ON return_items(return_id);
+CREATE INDEX idx_refunds_order_id ON refunds(order_id);
@@ -0,0 +1,8 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_payments_order_id;
+
+-- Drop columns
+ALTER TABLE refunds DROP COLUMN IF EXISTS payment_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS payments;
@@ -0,0 +1,19 @@
+-- Payments of orders through the payment provider
+CREATE TABLE payments (
+    id TEXT PRIMARY KEY,
+    order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    reference VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL CHECK (status IN ('authorized', 'captured', 'voided', 'failed', 'refunded')),
+    amount DECIMAL(10,2) NOT NULL CHECK (amount >= 0),
+    refunded_amount DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (refunded_amount >= 0),
+    failure_reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The payment a refund was paid back through
+ALTER TABLE refunds ADD COLUMN payment_id TEXT REFERENCES payments(id) ON DELETE SET NULL;
+
+-- Indexes
+CREATE INDEX idx_payments_order_id ON payments(order_id);
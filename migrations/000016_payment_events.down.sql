@@ -0,0 +1,9 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_refunds_provider_reference;
+DROP INDEX IF EXISTS idx_payments_provider_reference;
+
+-- Drop columns
+ALTER TABLE refunds DROP COLUMN IF EXISTS provider_reference;
+
+-- Drop tables
+DROP TABLE IF EXISTS payment_events;
@@ -0,0 +1,17 @@
+-- Raw payment webhook events, kept for auditing and to process each event once
+CREATE TABLE payment_events (
+    id TEXT PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    received_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT unique_provider_event UNIQUE(provider, event_id)
+);
+
+-- The provider's reference of a refund, so refunds reported by webhooks aren't recorded twice
+ALTER TABLE refunds ADD COLUMN provider_reference VARCHAR(255) NOT NULL DEFAULT '';
+
+-- Indexes
+CREATE UNIQUE INDEX idx_payments_provider_reference ON payments(provider, reference) WHERE reference <> '';
+CREATE INDEX idx_refunds_provider_reference ON refunds(provider_reference) WHERE provider_reference <> '';
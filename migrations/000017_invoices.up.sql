@@ -0,0 +1,32 @@
+-- Last number issued per document kind and year. Numbers are allocated in the transaction
+-- that issues the document, so rolled back documents don't leave gaps.
+CREATE TABLE invoice_sequences (
+    kind VARCHAR(20) NOT NULL,
+    year INTEGER NOT NULL,
+    last_number INTEGER NOT NULL,
+    PRIMARY KEY (kind, year)
+);
+
+-- Invoices and credit notes, frozen at the time they were issued
+CREATE TABLE invoices (
+    id TEXT PRIMARY KEY,
+    order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE RESTRICT,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('invoice', 'credit_note')),
+    number VARCHAR(30) UNIQUE NOT NULL,
+    refund_id TEXT UNIQUE REFERENCES refunds(id) ON DELETE RESTRICT,
+    original_number VARCHAR(30),
+    billing_address JSONB,
+    shipping_address JSONB,
+    lines JSONB NOT NULL,
+    subtotal DECIMAL(10,2) NOT NULL,
+    discount_total DECIMAL(10,2) NOT NULL DEFAULT 0,
+    shipping_total DECIMAL(10,2) NOT NULL DEFAULT 0,
+    tax_total DECIMAL(10,2) NOT NULL DEFAULT 0,
+    tax_inclusive BOOLEAN NOT NULL DEFAULT false,
+    total DECIMAL(10,2) NOT NULL,
+    issued_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Indexes
+CREATE UNIQUE INDEX idx_invoices_order_invoice ON invoices(order_id) WHERE kind = 'invoice';
+CREATE INDEX idx_invoices_order_id ON invoices(order_id);
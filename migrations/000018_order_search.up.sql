@@ -0,0 +1,3 @@
+-- Indexes for filtering and sorting the admin order list
+CREATE INDEX idx_orders_created_at ON orders(created_at);
+CREATE INDEX idx_orders_total_amount ON orders(total_amount);
@@ -0,0 +1,19 @@
+-- Domain events, written in the transaction of the change they describe and published
+-- afterwards by the relay. position orders the events of each aggregate.
+CREATE TABLE outbox_events (
+    position BIGSERIAL PRIMARY KEY,
+    id TEXT NOT NULL UNIQUE,
+    aggregate_type VARCHAR(50) NOT NULL,
+    aggregate_id TEXT NOT NULL,
+    type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP WITH TIME ZONE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT ''
+);
+
+-- Indexes
+CREATE INDEX idx_outbox_events_pending ON outbox_events(position) WHERE published_at IS NULL;
+CREATE INDEX idx_outbox_events_aggregate ON outbox_events(aggregate_type, aggregate_id, position);
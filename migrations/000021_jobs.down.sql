@@ -0,0 +1,6 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_orders_pending_created_at;
+DROP INDEX IF EXISTS idx_sessions_expires_at;
+
+-- Drop tables
+DROP TABLE IF EXISTS jobs;
@@ -0,0 +1,24 @@
+-- Background jobs. Workers claim due jobs with SKIP LOCKED and hold them until locked_until;
+-- jobs whose worker died become due again once that has passed.
+CREATE TABLE jobs (
+    id TEXT PRIMARY KEY,
+    kind VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'running', 'succeeded', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL,
+    run_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    last_error TEXT NOT NULL DEFAULT '',
+    -- jobs with a key are enqueued once, e.g. one per scheduled run across instances
+    unique_key VARCHAR(255) UNIQUE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Indexes
+CREATE INDEX idx_jobs_queued ON jobs(kind, run_at) WHERE status = 'queued';
+CREATE INDEX idx_jobs_running ON jobs(kind, locked_until) WHERE status = 'running';
+CREATE INDEX idx_sessions_expires_at ON sessions(expires_at);
+CREATE INDEX idx_orders_pending_created_at ON orders(created_at) WHERE status = 'pending';
@@ -0,0 +1,6 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_users_role;
+
+-- Drop tables
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notifications;
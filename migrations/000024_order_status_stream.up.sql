@@ -0,0 +1,2 @@
+-- Order status streams resume from the status changes in the outbox
+CREATE INDEX idx_outbox_events_order_status ON outbox_events(position) WHERE type = 'order.status_changed';
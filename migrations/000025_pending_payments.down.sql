@@ -0,0 +1,9 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_payments_pending_created_at;
+DROP INDEX IF EXISTS idx_payments_order_id_pending;
+
+-- Payments whose outcome was never learned count as failed
+UPDATE payments SET status = 'failed', failure_reason = 'outcome unknown' WHERE status = 'pending';
+ALTER TABLE payments DROP CONSTRAINT payments_status_check;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check
+    CHECK (status IN ('authorized', 'captured', 'voided', 'failed', 'refunded'));
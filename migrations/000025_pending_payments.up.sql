@@ -0,0 +1,10 @@
+-- Payments are recorded as pending before the provider is called
+ALTER TABLE payments DROP CONSTRAINT payments_status_check;
+ALTER TABLE payments ADD CONSTRAINT payments_status_check
+    CHECK (status IN ('pending', 'authorized', 'captured', 'voided', 'failed', 'refunded'));
+
+-- An order is paid for by one payment at a time
+CREATE UNIQUE INDEX idx_payments_order_id_pending ON payments(order_id) WHERE status = 'pending';
+
+-- Pending payments are reconciled once they have been pending for a while
+CREATE INDEX idx_payments_pending_created_at ON payments(created_at) WHERE status = 'pending';
@@ -0,0 +1,2 @@
+-- Drop columns
+ALTER TABLE jobs DROP COLUMN IF EXISTS locked_by;
@@ -0,0 +1,3 @@
+-- Jobs remember the runner holding their lease, so a runner whose lease expired can't
+-- record the outcome of a job another runner has taken over
+ALTER TABLE jobs ADD COLUMN locked_by TEXT;
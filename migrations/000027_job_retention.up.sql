@@ -0,0 +1,2 @@
+-- Finished jobs are deleted once they are past their retention
+CREATE INDEX idx_jobs_finished_at ON jobs(status, finished_at) WHERE status IN ('succeeded', 'failed');
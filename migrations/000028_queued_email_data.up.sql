@@ -0,0 +1,12 @@
+-- Password reset codes are issued when the email is sent, not when the reset is requested
+ALTER TABLE password_resets ALTER COLUMN token_hash DROP NOT NULL;
+
+-- Queued emails used to be stored rendered, with password reset codes in their bodies.
+-- Emails that weren't sent yet can't be rendered again, so they are failed, and the bodies
+-- of all of them are dropped.
+UPDATE jobs
+SET status = 'failed', last_error = 'rendered email dropped', finished_at = now(),
+    locked_by = NULL, locked_until = NULL
+WHERE kind = 'send_email' AND payload ? 'text' AND status IN ('queued', 'running');
+
+UPDATE jobs SET payload = '{}' WHERE kind = 'send_email' AND payload ? 'text';
@@ -0,0 +1,2 @@
+-- Resumed order status streams replay the changes recorded shortly before the last one seen
+CREATE INDEX idx_outbox_events_order_status_created_at ON outbox_events(created_at) WHERE type = 'order.status_changed';
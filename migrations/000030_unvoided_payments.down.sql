@@ -0,0 +1 @@
+-- Payments stay pending; which of them were authorized isn't known anymore
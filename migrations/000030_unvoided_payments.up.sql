@@ -0,0 +1,3 @@
+-- Authorizations whose declined capture couldn't be voided were left authorized, where
+-- reconciliation never found them. They are pending now, so it voids them.
+UPDATE payments SET status = 'pending' WHERE status = 'authorized';
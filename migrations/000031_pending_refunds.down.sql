@@ -0,0 +1,5 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_refunds_pending_created_at;
+
+ALTER TABLE refunds DROP COLUMN failure_reason;
+ALTER TABLE refunds DROP COLUMN status;
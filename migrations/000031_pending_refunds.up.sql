@@ -0,0 +1,8 @@
+-- Refunds through the payment provider are recorded as pending and paid back once the
+-- transaction recording them has committed
+ALTER TABLE refunds ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'succeeded'
+    CHECK (status IN ('pending', 'succeeded', 'failed'));
+ALTER TABLE refunds ADD COLUMN failure_reason TEXT NOT NULL DEFAULT '';
+
+-- Indexes
+CREATE INDEX idx_refunds_pending_created_at ON refunds(created_at) WHERE status = 'pending';
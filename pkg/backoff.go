@@ -0,0 +1,13 @@
+package pkg
+
+import "time"
+
+// Backoff is the wait before retrying after the given number of failed attempts, starting
+// at base and doubling with every attempt up to limit
+func Backoff(attempt int, base, limit time.Duration) time.Duration {
+	wait := base
+	for i := 1; i < attempt && wait < limit; i++ {
+		wait *= 2
+	}
+	return min(wait, limit)
+}
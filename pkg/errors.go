@@ -7,8 +7,40 @@ var (
 	ErrDatabase           = errors.New("database error")
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrEmailTaken         = errors.New("email already taken")
+	ErrInvalidResetToken  = errors.New("invalid or expired password reset token")
 	ErrInsufficientStock  = errors.New("insufficient stock")
 	ErrInvalidInput       = errors.New("invalid input")
 	ErrOrderNotPending    = errors.New("order is not in pending status")
 	ErrUnauthorized       = errors.New("unauthorized action")
+	ErrWarehouseCodeTaken = errors.New("warehouse code already taken")
+	ErrNoActiveWarehouse  = errors.New("no active warehouse")
+	ErrAlreadySubscribed  = errors.New("already subscribed")
+	ErrProductInStock     = errors.New("product is in stock")
+	ErrVersionMismatch    = errors.New("resource version mismatch")
+	ErrProductReferenced  = errors.New("product is referenced by orders")
+	ErrProductUnavailable = errors.New("product is not available")
+	ErrPriceInEffect      = errors.New("price change has already taken effect")
+	ErrPricesChanged      = errors.New("prices changed while placing the order")
+
+	ErrInvalidCoupon       = errors.New("invalid coupon")
+	ErrCouponExpired       = errors.New("coupon is not valid at this time")
+	ErrCouponUsageLimit    = errors.New("coupon usage limit reached")
+	ErrCouponMinimumNotMet = errors.New("order value is below the coupon minimum")
+	ErrCouponNotApplicable = errors.New("coupon does not apply to any items")
+	ErrCouponCodeTaken     = errors.New("coupon code already taken")
+	ErrCouponInUse         = errors.New("coupon has been redeemed")
+
+	ErrTaxRateExists = errors.New("tax rate already exists for region and tax class")
+
+	ErrShippingMethodCodeTaken = errors.New("shipping method code already taken")
+	ErrShippingUnavailable     = errors.New("shipping method is not available for this destination")
+
+	ErrInvalidStatusTransition = errors.New("invalid order status transition")
+	ErrOrderNotReturnable      = errors.New("order can't be returned")
+	ErrInvalidReturnStatus     = errors.New("return is not in the required status")
+
+	ErrPaymentDeclined   = errors.New("payment declined")
+	ErrPaymentFailed     = errors.New("payment failed")
+	ErrPaymentInProgress = errors.New("a payment of the order is in progress")
+	ErrInvalidSignature  = errors.New("invalid signature")
 )
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"regexp"
+	"sync"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -62,3 +63,18 @@ func GenerateUsername() string {
 	num := rand.Intn(90) + 10
 	return fmt.Sprintf("%s%s%d", adj, noun, num)
 }
+
+var structValidator = sync.OnceValue(func() *validator.Validate {
+	v := validator.New()
+	v.SetTagName("binding")
+	_ = v.RegisterValidation("validpassword", ValidatePassword)
+	return v
+})
+
+// ValidateStruct validates a struct using its binding tags, the same rules gin applies to request bodies.
+func ValidateStruct(s any) error {
+	if err := structValidator().Struct(s); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return nil
+}
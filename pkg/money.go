@@ -0,0 +1,8 @@
+package pkg
+
+import "math"
+
+// RoundMoney rounds an amount to whole cents, halves away from zero
+func RoundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
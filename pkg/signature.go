@@ -0,0 +1,25 @@
+package pkg
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// SignatureMAC returns the HMAC-SHA256 of "<timestamp>.<body>" under the secret
+func SignatureMAC(secret []byte, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// SignatureHeader returns the signature header value of signed webhooks for a body sent at
+// the given time, "t=<unix timestamp>,v1=<hex SignatureMAC>"
+func SignatureHeader(secret []byte, body []byte, at time.Time) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(SignatureMAC(secret, timestamp, body))
+}